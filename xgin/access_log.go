@@ -0,0 +1,131 @@
+package xgin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xlog"
+	"github.com/xiaoshicae/xone/v2/xutil"
+)
+
+// newLogMiddleware 按 XGin.AccessLog 配置创建日志中间件，返回的 io.Closer 用于关闭访问日志文件（未配置文件时为 nil）
+func newLogMiddleware(skipPaths []string) (gin.HandlerFunc, io.Closer, error) {
+	c := GetConfig().AccessLog
+	opts, err := c.logOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	w, closer, formatter, err := c.output()
+	if err != nil {
+		return nil, nil, err
+	}
+	if w != nil {
+		opts = append(opts, middleware.WithAccessLogOutput(w, formatter))
+	}
+
+	m, err := middleware.NewLogMiddleware(append([]middleware.LogOption{middleware.WithSkipPaths(skipPaths...)}, opts...)...)
+	if err != nil {
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, nil, err
+	}
+	return m, closer, nil
+}
+
+// output 解析访问日志的输出与格式，Format 与 File 均未配置时返回 nil（写入 xlog 应用日志）
+func (c *AccessLogConfig) output() (io.Writer, io.Closer, middleware.AccessLogFormatter, error) {
+	if c == nil || (c.Format == "" && c.File == nil) {
+		return nil, nil, nil, nil
+	}
+	formatter, err := newAccessLogFormatter(c.Format)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if c.File == nil {
+		return os.Stdout, nil, formatter, nil
+	}
+
+	f := accessLogFileConfigMergeDefault(c.File)
+	w, err := xlog.NewRotateWriter(xlog.RotateConfig{
+		Path:       f.Path,
+		Name:       f.Name,
+		RotateTime: xutil.ToDuration(f.RotateTime),
+		MaxSize:    int64(f.MaxSize) << 20,
+		MaxAge:     xutil.ToDuration(f.MaxAge),
+		MaxBackups: f.MaxBackups,
+		Compress:   f.Compress,
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create access log file failed, err=[%v]", err)
+	}
+	return w, w, formatter, nil
+}
+
+// newAccessLogFormatter 内置格式：combined/ecs/otel，为空时默认 ecs
+func newAccessLogFormatter(format string) (middleware.AccessLogFormatter, error) {
+	switch strings.ToLower(format) {
+	case "", "ecs":
+		return middleware.ECSFormatter(xconfig.GetServerName()), nil
+	case "combined":
+		return middleware.CombinedFormatter(), nil
+	case "otel":
+		return middleware.OTelFormatter(xconfig.GetServerName()), nil
+	default:
+		return nil, fmt.Errorf("invalid access log format %q, must be one of combined/ecs/otel", format)
+	}
+}
+
+// logOptions 将 AccessLog 配置转换为日志中间件选项，路由级未配置的字段沿用全局策略
+func (c *AccessLogConfig) logOptions() ([]middleware.LogOption, error) {
+	if c == nil {
+		return nil, nil
+	}
+	body, err := middleware.ParseLogBodyMode(c.Body)
+	if err != nil {
+		return nil, err
+	}
+	policy := middleware.LogPolicy{
+		Body:                body,
+		MaxRequestBodySize:  c.MaxRequestBodySize,
+		MaxResponseBodySize: c.MaxResponseBodySize,
+		SampleRate:          c.SampleRate,
+	}
+
+	opts := []middleware.LogOption{
+		middleware.WithLogPolicy(policy),
+		middleware.WithLogTextContentTypes(c.TextContentTypes...),
+		middleware.WithLogRedactPaths(c.RedactPaths...),
+	}
+	for _, r := range c.MaskRules {
+		opts = append(opts, middleware.WithLogMaskRules(middleware.LogMaskRule{Pattern: r.Pattern, Replacement: r.Replacement}))
+	}
+
+	for _, r := range c.Routes {
+		if r.Path == "" {
+			return nil, fmt.Errorf("route policy path is empty")
+		}
+		rp := policy
+		if r.Body != "" {
+			if rp.Body, err = middleware.ParseLogBodyMode(r.Body); err != nil {
+				return nil, fmt.Errorf("route policy %s: %w", r.Path, err)
+			}
+		}
+		if r.MaxRequestBodySize > 0 {
+			rp.MaxRequestBodySize = r.MaxRequestBodySize
+		}
+		if r.MaxResponseBodySize > 0 {
+			rp.MaxResponseBodySize = r.MaxResponseBodySize
+		}
+		if r.SampleRate > 0 {
+			rp.SampleRate = r.SampleRate
+		}
+		opts = append(opts, middleware.WithLogRoutePolicy(r.Method, r.Path, rp))
+	}
+	return opts, nil
+}
@@ -0,0 +1,128 @@
+// Package auth 提供 XGin 可插拔的认证中间件，内置 JWT、API Key、HMAC 签名三种认证方式
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xlog"
+)
+
+const (
+	// PrincipalContextKey gin.Context 中存储 Principal 的 key
+	PrincipalContextKey = "__xgin__auth__principal__"
+
+	// 认证方式
+	MethodJWT    = "jwt"
+	MethodAPIKey = "apikey"
+	MethodHMAC   = "hmac"
+
+	// 注入 xlog KV 的字段名
+	logKeySubject = "auth_subject"
+	logKeyMethod  = "auth_method"
+)
+
+var (
+	// ErrNoCredentials 请求未携带该认证方式所需的凭证，认证链会继续尝试下一个 Authenticator
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials 凭证存在但校验失败
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrForbidden 认证通过但不满足路由策略
+	ErrForbidden = errors.New("forbidden")
+)
+
+// principalCtxKey context 中存储 Principal 的 key
+type principalCtxKey struct{}
+
+// Principal 认证通过后的调用方身份
+type Principal struct {
+	Subject    string         // 调用方唯一标识（JWT sub、API Key 归属、HMAC AccessKey 等）
+	Method     string         // 认证方式：jwt / apikey / hmac
+	Scopes     []string       // 授权范围
+	Attributes map[string]any // 其它属性（如 JWT claims）
+}
+
+// HasScopes 是否拥有全部指定 scope
+func (p *Principal) HasScopes(scopes ...string) bool {
+	if p == nil {
+		return len(scopes) == 0
+	}
+	for _, s := range scopes {
+		if !slices.Contains(p.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authenticator 认证器接口
+// 请求中没有该认证方式的凭证时返回 ErrNoCredentials（可用 errors.Is 判断），以便认证链尝试下一个认证器
+type Authenticator interface {
+	// Name 认证器名称，用于日志和错误信息
+	Name() string
+
+	// Authenticate 对请求进行认证，成功返回 Principal
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+// PrincipalFromCtx 从 context 获取 Principal
+func PrincipalFromCtx(ctx context.Context) (*Principal, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	p, ok := ctx.Value(principalCtxKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// GetPrincipal 从 gin.Context 获取 Principal
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	if v, ok := c.Get(PrincipalContextKey); ok {
+		if p, ok := v.(*Principal); ok && p != nil {
+			return p, true
+		}
+	}
+	return PrincipalFromCtx(c.Request.Context())
+}
+
+// CtxWithPrincipal 向 context 注入 Principal，同时写入 xlog KV，后续日志自动携带调用方身份
+func CtxWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	if p == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, principalCtxKey{}, p)
+	return xlog.CtxWithKV(ctx, map[string]any{
+		logKeySubject: p.Subject,
+		logKeyMethod:  p.Method,
+	})
+}
+
+// setPrincipal 将 Principal 同时写入 gin.Context 和 request context
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(PrincipalContextKey, p)
+	c.Request = c.Request.WithContext(CtxWithPrincipal(c.Request.Context(), p))
+}
+
+// authenticate 按顺序执行认证链，第一个给出明确结果（成功或非 ErrNoCredentials 错误）的认证器决定结果
+func authenticate(c *gin.Context, authenticators []Authenticator) (*Principal, error) {
+	for _, a := range authenticators {
+		p, err := a.Authenticate(c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, ErrInvalidCredentials
+		}
+		if p.Method == "" {
+			p.Method = a.Name()
+		}
+		return p, nil
+	}
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,570 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/xiaoshicae/xone/v2/xgin/store"
+	"github.com/xiaoshicae/xone/v2/xlog"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// memStore 测试用内存 Store
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte)}
+}
+
+func (s *memStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok, nil
+}
+
+func (s *memStore) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) SetNX(_ context.Context, key string, value []byte, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; ok {
+		return false, nil
+	}
+	s.data[key] = value
+	return true, nil
+}
+
+func (s *memStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+var _ store.Store = (*memStore)(nil)
+
+func signHS256(t *testing.T, secret []byte, claims jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token failed: %v", err)
+	}
+	return s
+}
+
+func serve(h gin.HandlerFunc, req *http.Request) (*httptest.ResponseRecorder, *Principal) {
+	r := gin.New()
+	var got *Principal
+	r.Any("/api/*path", h, func(c *gin.Context) {
+		got, _ = GetPrincipal(c)
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, got
+}
+
+func TestJWTAuthenticatorHS256(t *testing.T) {
+	secret := []byte("test-secret")
+	a, err := NewJWTAuthenticator(WithHMACSecret(secret), WithIssuer("xone"))
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator failed: %v", err)
+	}
+	h := Require(WithAuthenticators(a))
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid", signHS256(t, secret, jwt.MapClaims{"sub": "u1", "iss": "xone", "scope": "a b", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusOK},
+		{"expired", signHS256(t, secret, jwt.MapClaims{"sub": "u1", "iss": "xone", "exp": time.Now().Add(-time.Hour).Unix()}), http.StatusUnauthorized},
+		{"wrong secret", signHS256(t, []byte("other"), jwt.MapClaims{"sub": "u1", "iss": "xone", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized},
+		{"wrong issuer", signHS256(t, secret, jwt.MapClaims{"sub": "u1", "iss": "other", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized},
+		{"missing exp", signHS256(t, secret, jwt.MapClaims{"sub": "u1", "iss": "xone"}), http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/x", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w, p := serve(h, req)
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d, body=%s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusOK {
+				if p == nil || p.Subject != "u1" || p.Method != MethodJWT {
+					t.Fatalf("unexpected principal: %+v", p)
+				}
+				if !p.HasScopes("a", "b") {
+					t.Fatalf("expected scopes a b, got %v", p.Scopes)
+				}
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorNoKey(t *testing.T) {
+	if _, err := NewJWTAuthenticator(); err == nil {
+		t.Fatal("expected error when no key configured")
+	}
+	if _, err := NewJWTAuthenticator(WithPublicKeyPEM("", []byte("bad"))); err == nil {
+		t.Fatal("expected error for invalid pem")
+	}
+}
+
+func TestJWTAuthenticatorRS256PEM(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	a, err := NewJWTAuthenticator(WithPublicKeyPEM("", pemBytes))
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator failed: %v", err)
+	}
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "rs", "exp": time.Now().Add(time.Hour).Unix()}).SignedString(key)
+	req := httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("Authorization", "bearer "+token)
+	w, p := serve(Require(WithAuthenticators(a)), req)
+	if w.Code != http.StatusOK || p.Subject != "rs" {
+		t.Fatalf("expected 200 with subject rs, got %d %+v", w.Code, p)
+	}
+
+	// HS256 token 不允许用于仅配置了公钥的认证器（防算法混淆）
+	hsToken := signHS256(t, pemBytes, jwt.MapClaims{"sub": "rs", "exp": time.Now().Add(time.Hour).Unix()})
+	req = httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("Authorization", "Bearer "+hsToken)
+	w, _ = serve(Require(WithAuthenticators(a)), req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for alg confusion, got %d", w.Code)
+	}
+}
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func TestJWTAuthenticatorJWKSURL(t *testing.T) {
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var (
+		mu      sync.Mutex
+		keys    = []any{rsaJWK("k1", &key1.PublicKey)}
+		fetches atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+
+	a, err := NewJWTAuthenticator(WithJWKSURL(srv.URL, time.Hour), WithJWKSHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator failed: %v", err)
+	}
+	h := Require(WithAuthenticators(a))
+
+	sign := func(key *rsa.PrivateKey, kid string) string {
+		tk := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": kid, "exp": time.Now().Add(time.Hour).Unix()})
+		tk.Header["kid"] = kid
+		s, _ := tk.SignedString(key)
+		return s
+	}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("Authorization", "Bearer "+sign(key1, "k1"))
+		if w, _ := serve(h, req); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("jwks should be cached, fetched %d times", fetches.Load())
+	}
+
+	// 密钥轮换后，未知 kid 在最小刷新间隔内不会触发刷新
+	mu.Lock()
+	keys = append(keys, rsaJWK("k2", &key2.PublicKey))
+	mu.Unlock()
+	req := httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(key2, "k2"))
+	if w, _ := serve(h, req); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before refresh interval, got %d", w.Code)
+	}
+
+	// 超过最小刷新间隔后，未知 kid 会触发刷新
+	a.jwks.mu.Lock()
+	a.jwks.attemptedAt = time.Now().Add(-time.Minute)
+	a.jwks.mu.Unlock()
+	req = httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(key2, "k2"))
+	if w, p := serve(h, req); w.Code != http.StatusOK || p.Subject != "k2" {
+		t.Fatalf("expected 200 after refresh, got %d", w.Code)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("expected 2 fetches, got %d", fetches.Load())
+	}
+}
+
+func TestJWKSCacheRefresh(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	raw, _ := json.Marshal(map[string]any{"keys": []any{rsaJWK("k1", &key.PublicKey)}})
+
+	var (
+		fetches atomic.Int32
+		fail    atomic.Bool
+		release = make(chan struct{})
+	)
+	j := newJWKSCache(func(context.Context) ([]byte, error) {
+		fetches.Add(1)
+		<-release
+		if fail.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return raw, nil
+	}, time.Minute)
+
+	// 并发的首次加载合并为一次拉取
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := j.key(context.Background(), "k1"); err != nil {
+				t.Errorf("key failed: %v", err)
+			}
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("expected 1 fetch, got %d", n)
+	}
+
+	// 缓存过期且端点故障时继续使用旧 key，刷新尝试受最小间隔限制
+	fail.Store(true)
+	j.mu.Lock()
+	j.fetchedAt = time.Now().Add(-time.Hour)
+	j.attemptedAt = time.Now().Add(-time.Hour)
+	j.mu.Unlock()
+	for i := 0; i < 10; i++ {
+		if _, err := j.key(context.Background(), "k1"); err != nil {
+			t.Fatalf("expected stale key, got %v", err)
+		}
+	}
+	for {
+		j.mu.RLock()
+		err := j.lastErr
+		j.mu.RUnlock()
+		if err != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+
+	// 刷新失败后，未知 kid 在最小间隔内直接返回上次的错误，不再拉取
+	if _, err := j.key(context.Background(), "k2"); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected last refresh error, got %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+}
+
+func TestJWTAuthenticatorJWKSFileES256(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pub, _ := key.PublicKey.Bytes() // 0x04 || X || Y
+	jwks := map[string]any{"keys": []any{
+		map[string]any{"kty": "EC", "kid": "e1", "crv": "P-256",
+			"x": base64.RawURLEncoding.EncodeToString(pub[1:33]),
+			"y": base64.RawURLEncoding.EncodeToString(pub[33:])},
+		map[string]any{"kty": "RSA", "kid": "enc", "use": "enc"},
+	}}
+	b, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewJWTAuthenticator(WithJWKSFile(path, 0))
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator failed: %v", err)
+	}
+
+	tk := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "es", "scp": []string{"x"}, "exp": time.Now().Add(time.Hour).Unix()})
+	tk.Header["kid"] = "e1"
+	token, _ := tk.SignedString(key)
+
+	a.opts.ScopeClaim = "scp"
+	req := httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w, p := serve(Require(WithAuthenticators(a), RequireScopes("x")), req)
+	if w.Code != http.StatusOK || p.Subject != "es" {
+		t.Fatalf("expected 200 with subject es, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a := NewAPIKeyAuthenticator(StaticAPIKeys(map[string]*Principal{
+		"k-123": {Subject: "svc-a", Scopes: []string{"read"}},
+	}), WithAPIKeyQuery("api_key"))
+	h := Require(WithAuthenticators(a), RequireScopes("read"))
+
+	req := httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("X-Api-Key", "k-123")
+	if w, p := serve(h, req); w.Code != http.StatusOK || p.Subject != "svc-a" || p.Method != MethodAPIKey {
+		t.Fatalf("expected 200 svc-a, got %d %+v", w.Code, p)
+	}
+
+	req = httptest.NewRequest("GET", "/api/x?api_key=k-123", nil)
+	if w, _ := serve(h, req); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 via query, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("X-Api-Key", "wrong")
+	w, _ := serve(h, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":401`) {
+		t.Fatalf("expected error envelope, got %s", w.Body.String())
+	}
+
+	lookupErr := NewAPIKeyAuthenticator(func(context.Context, string) (*Principal, error) {
+		return nil, errors.New("db down")
+	})
+	req = httptest.NewRequest("GET", "/api/x", nil)
+	req.Header.Set("X-Api-Key", "k")
+	w, _ = serve(Require(WithAuthenticators(lookupErr)), req)
+	if w.Code != http.StatusUnauthorized || strings.Contains(w.Body.String(), "db down") {
+		t.Fatalf("expected 401 without leaking internal error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	secret := []byte("hmac-secret")
+	lookup := func(_ context.Context, ak string) ([]byte, *Principal, error) {
+		if ak != "ak-1" {
+			return nil, nil, nil
+		}
+		return secret, &Principal{Subject: "partner-1"}, nil
+	}
+	a := NewHMACAuthenticator(lookup, newMemStore())
+	h := Require(WithAuthenticators(a))
+
+	newSigned := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/orders?b=2&a=1", strings.NewReader(body))
+		if err := SignRequest(req, "ak-1", secret); err != nil {
+			t.Fatalf("SignRequest failed: %v", err)
+		}
+		return req
+	}
+
+	req := newSigned(`{"amount":1}`)
+	if w, p := serve(h, req); w.Code != http.StatusOK || p.Subject != "partner-1" || p.Method != MethodHMAC {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 重放同一请求
+	replay := httptest.NewRequest("POST", "/api/orders?b=2&a=1", strings.NewReader(`{"amount":1}`))
+	replay.Header = req.Header.Clone()
+	if w, _ := serve(h, replay); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "nonce replayed") {
+		t.Fatalf("expected replay rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 篡改 body
+	tampered := newSigned(`{"amount":1}`)
+	tampered.Body = http.NoBody
+	if w, _ := serve(h, tampered); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected tampered body rejected, got %d", w.Code)
+	}
+
+	// 过期时间戳
+	expired := newSigned("")
+	expired.Header.Set(HeaderTimestamp, "1")
+	if w, _ := serve(h, expired); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "timestamp expired") {
+		t.Fatalf("expected expired rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 未知 AccessKey
+	unknown := httptest.NewRequest("GET", "/api/x", nil)
+	_ = SignRequest(unknown, "ak-2", secret)
+	if w, _ := serve(h, unknown); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unknown ak rejected, got %d", w.Code)
+	}
+}
+
+func TestHMACAuthenticatorNilArgs(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for nil store")
+		}
+	}()
+	NewHMACAuthenticator(func(context.Context, string) ([]byte, *Principal, error) { return nil, nil, nil }, nil)
+}
+
+func TestRequirePolicy(t *testing.T) {
+	a := NewAPIKeyAuthenticator(StaticAPIKeys(map[string]*Principal{
+		"k": {Subject: "svc", Scopes: []string{"read"}},
+	}))
+
+	t.Run("anonymous allowed without credentials", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		w, p := serve(Require(WithAuthenticators(a), AllowAnonymous(true)), req)
+		if w.Code != http.StatusOK || p != nil {
+			t.Fatalf("expected anonymous 200, got %d %+v", w.Code, p)
+		}
+	})
+
+	t.Run("anonymous allowed but bad credentials rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("X-Api-Key", "bad")
+		w, _ := serve(Require(WithAuthenticators(a), AllowAnonymous(true)), req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing scope forbidden", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("X-Api-Key", "k")
+		w, _ := serve(Require(WithAuthenticators(a), RequireScopes("write")), req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("custom authorizer", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("X-Api-Key", "k")
+		w, _ := serve(Require(WithAuthenticators(a), RequireFunc(func(_ *gin.Context, p *Principal) error {
+			return errors.New("tenant mismatch")
+		})), req)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "tenant mismatch") {
+			t.Fatalf("expected 403 tenant mismatch, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("group and route policies chain", func(t *testing.T) {
+		r := gin.New()
+		g := r.Group("/api", Require(WithAuthenticators(a)))
+		called := 0
+		counting := NewAPIKeyAuthenticator(func(ctx context.Context, key string) (*Principal, error) {
+			called++
+			return nil, nil
+		})
+		g.GET("/x", Require(WithAuthenticators(counting), RequireScopes("read")), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.Header.Set("X-Api-Key", "k")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || called != 0 {
+			t.Fatalf("expected principal reused, got %d called=%d", w.Code, called)
+		}
+	})
+
+	t.Run("custom unauthorized handler", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		w, _ := serve(Require(WithAuthenticators(a), WithUnauthorizedHandler(func(c *gin.Context, err error) {
+			c.AbortWithStatus(http.StatusTeapot)
+		})), req)
+		if w.Code != http.StatusTeapot {
+			t.Fatalf("expected 418, got %d", w.Code)
+		}
+	})
+}
+
+func TestPrincipalInCtxAndLogKV(t *testing.T) {
+	a := NewAPIKeyAuthenticator(StaticAPIKeys(map[string]*Principal{"k": {Subject: "svc"}}))
+	r := gin.New()
+	var ctx context.Context
+	r.GET("/x", Require(WithAuthenticators(a)), func(c *gin.Context) {
+		ctx = c.Request.Context()
+	})
+	req := httptest.NewRequest("GET", "/x", nil)
+	req.Header.Set("X-Api-Key", "k")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	p, ok := PrincipalFromCtx(ctx)
+	if !ok || p.Subject != "svc" {
+		t.Fatalf("principal not found in ctx: %+v", p)
+	}
+	kv, _ := ctx.Value(xlog.XLogCtxKVContainerKey).(map[string]any)
+	if kv[logKeySubject] != "svc" || kv[logKeyMethod] != MethodAPIKey {
+		t.Fatalf("principal not in xlog kv: %v", kv)
+	}
+
+	if _, ok := PrincipalFromCtx(context.Background()); ok {
+		t.Fatal("expected no principal")
+	}
+	//nolint:staticcheck // 验证 nil ctx 不会 panic
+	if _, ok := PrincipalFromCtx(nil); ok {
+		t.Fatal("expected no principal for nil ctx")
+	}
+}
+
+func TestScopesFromClaim(t *testing.T) {
+	if s := scopesFromClaim("a b"); len(s) != 2 {
+		t.Fatalf("expected 2 scopes, got %v", s)
+	}
+	if s := scopesFromClaim([]any{"a", 1}); len(s) != 1 {
+		t.Fatalf("expected 1 scope, got %v", s)
+	}
+	if s := scopesFromClaim(nil); s != nil {
+		t.Fatalf("expected nil, got %v", s)
+	}
+}
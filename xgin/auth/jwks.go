@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// defaultJWKSRefreshInterval JWKS 缓存刷新周期
+	defaultJWKSRefreshInterval = 10 * time.Minute
+
+	// minJWKSRefreshInterval 两次刷新尝试（无论成功与否）的最小间隔，避免被恶意 token 或端点故障打爆 JWKS 端点
+	minJWKSRefreshInterval = 30 * time.Second
+
+	// maxJWKSBodySize JWKS 响应体上限 1MB
+	maxJWKSBodySize = 1 << 20
+)
+
+// jwk JSON Web Key（RFC 7517），仅包含签名校验需要的字段
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// jwkSet JSON Web Key Set
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwksLoader 加载 JWKS 原始内容
+type jwksLoader func(ctx context.Context) ([]byte, error)
+
+// jwksCache 带缓存的 JWKS，按 kid 索引公钥
+type jwksCache struct {
+	loader          jwksLoader
+	refreshInterval time.Duration
+
+	sf singleflight.Group // 合并并发刷新
+
+	mu          sync.RWMutex
+	keys        map[string]any // kid -> *rsa.PublicKey / *ecdsa.PublicKey / []byte
+	fetchedAt   time.Time      // 最近一次刷新成功的时间
+	attemptedAt time.Time      // 最近一次刷新尝试的时间，失败也会记录
+	lastErr     error          // 最近一次刷新的错误
+}
+
+func newJWKSCache(loader jwksLoader, refreshInterval time.Duration) *jwksCache {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &jwksCache{loader: loader, refreshInterval: refreshInterval}
+}
+
+// fileJWKSLoader 从本地文件加载 JWKS
+func fileJWKSLoader(path string) jwksLoader {
+	return func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}
+}
+
+// urlJWKSLoader 从 URL 加载 JWKS
+func urlJWKSLoader(url string, client *http.Client) jwksLoader {
+	return func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch jwks failed, url=[%s], status=[%d]", url, resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBodySize))
+	}
+}
+
+// key 按 kid 获取公钥
+//   - 缓存过期但 kid 命中时继续使用旧 key，并在后台刷新，不阻塞请求
+//   - kid 未命中时同步刷新，并发请求合并为一次
+//   - 距上次刷新尝试不足 minJWKSRefreshInterval 时不刷新，端点故障时不会每个请求都去拉取
+func (j *jwksCache) key(ctx context.Context, kid string) (any, error) {
+	j.mu.RLock()
+	k, ok := j.lookup(kid)
+	expired := time.Since(j.fetchedAt) > j.refreshInterval
+	throttled := time.Since(j.attemptedAt) < minJWKSRefreshInterval
+	j.mu.RUnlock()
+
+	if ok {
+		if expired && !throttled {
+			// 后台刷新不随请求取消，结果由下一次请求使用
+			j.sf.DoChan(jwksRefreshKey, func() (any, error) {
+				return nil, j.refresh(context.WithoutCancel(ctx))
+			})
+		}
+		return k, nil
+	}
+
+	if throttled {
+		j.mu.RLock()
+		err := j.lastErr
+		j.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: unknown kid [%s]", ErrInvalidCredentials, kid)
+	}
+
+	if _, err, _ := j.sf.Do(jwksRefreshKey, func() (any, error) {
+		return nil, j.refresh(ctx)
+	}); err != nil {
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if k, ok := j.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown kid [%s]", ErrInvalidCredentials, kid)
+}
+
+// jwksRefreshKey singleflight 的 key，同一缓存只有一个刷新
+const jwksRefreshKey = "jwks"
+
+// lookup 按 kid 查找公钥，无 kid 且 JWKS 中只有一个 key 时直接使用，调用方需持有读锁
+func (j *jwksCache) lookup(kid string) (any, bool) {
+	if k, ok := j.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(j.keys) == 1 {
+		for _, k := range j.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// refresh 重新加载 JWKS，失败时保留旧的 key
+// 在 singleflight 中执行；若等待期间其他刷新刚完成，则直接复用其结果，避免突发请求排队重复拉取
+func (j *jwksCache) refresh(ctx context.Context) error {
+	j.mu.RLock()
+	if time.Since(j.attemptedAt) < minJWKSRefreshInterval {
+		err := j.lastErr
+		j.mu.RUnlock()
+		return err
+	}
+	j.mu.RUnlock()
+
+	keys, err := j.load(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attemptedAt = time.Now()
+	j.lastErr = err
+	if err != nil {
+		return err
+	}
+	j.keys = keys
+	j.fetchedAt = j.attemptedAt
+	return nil
+}
+
+// load 拉取并解析 JWKS
+func (j *jwksCache) load(ctx context.Context) (map[string]any, error) {
+	raw, err := j.loader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load jwks failed, err=[%v]", err)
+	}
+	return parseJWKS(raw)
+}
+
+// parseJWKS 解析 JWKS，忽略非签名用途和不支持的 key
+func parseJWKS(raw []byte) (map[string]any, error) {
+	var set jwkSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks failed, err=[%v]", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+// publicKey 将 jwk 转换为 golang-jwt 可用的校验 key
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeB64URLBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeB64URLBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, size := ecCurve(k.Crv)
+		if curve == nil {
+			return nil, fmt.Errorf("unsupported curve [%s]", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid ec point size")
+		}
+		point := make([]byte, 0, 1+2*size)
+		point = append(point, 4) // 未压缩点格式
+		point = append(point, x...)
+		point = append(point, y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported kty [%s]", k.Kty)
+	}
+}
+
+func ecCurve(crv string) (elliptic.Curve, int) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), 32
+	case "P-384":
+		return elliptic.P384(), 48
+	case "P-521":
+		return elliptic.P521(), 66
+	default:
+		return nil, 0
+	}
+}
+
+func decodeB64URLBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultJWTHeader      = "Authorization"
+	defaultJWTScheme      = "Bearer"
+	defaultJWTScopeClaim  = "scope"
+	defaultJWKSHTTPTimout = 5 * time.Second
+)
+
+// WithHMACSecret 设置 HS256/HS384/HS512 校验密钥
+func WithHMACSecret(secret []byte) JWTOption {
+	return func(o *JWTOptions) {
+		o.HMACSecret = secret
+	}
+}
+
+// WithPublicKey 设置 RS*/ES* 校验公钥，kid 为空表示不区分 kid
+func WithPublicKey(kid string, key crypto.PublicKey) JWTOption {
+	return func(o *JWTOptions) {
+		o.PublicKeys[kid] = key
+	}
+}
+
+// WithPublicKeyPEM 设置 PEM 格式的 RS*/ES* 校验公钥（PKIX 或证书），kid 为空表示不区分 kid
+func WithPublicKeyPEM(kid string, pemBytes []byte) JWTOption {
+	return func(o *JWTOptions) {
+		o.PublicKeyPEMs[kid] = pemBytes
+	}
+}
+
+// WithJWKSFile 从本地文件加载 JWKS，按 refreshInterval 周期重新读取（<=0 使用默认 10m）
+func WithJWKSFile(path string, refreshInterval time.Duration) JWTOption {
+	return func(o *JWTOptions) {
+		o.JWKSFile = path
+		o.JWKSRefreshInterval = refreshInterval
+	}
+}
+
+// WithJWKSURL 从远端 URL 加载 JWKS 并缓存，按 refreshInterval 周期刷新（<=0 使用默认 10m）
+// 遇到未知 kid 时会提前刷新（最小间隔 30s）
+func WithJWKSURL(url string, refreshInterval time.Duration) JWTOption {
+	return func(o *JWTOptions) {
+		o.JWKSURL = url
+		o.JWKSRefreshInterval = refreshInterval
+	}
+}
+
+// WithJWKSHTTPClient 设置拉取 JWKS 的 http.Client，默认 5s 超时
+func WithJWKSHTTPClient(client *http.Client) JWTOption {
+	return func(o *JWTOptions) {
+		o.HTTPClient = client
+	}
+}
+
+// WithAlgorithms 限定允许的签名算法，默认根据配置的 key 推断
+func WithAlgorithms(algs ...string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Algorithms = append(o.Algorithms, algs...)
+	}
+}
+
+// WithIssuer 校验 iss
+func WithIssuer(issuer string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Issuer = issuer
+	}
+}
+
+// WithAudience 校验 aud
+func WithAudience(audience string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Audience = audience
+	}
+}
+
+// WithLeeway 校验 exp/nbf/iat 时允许的时钟偏差
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(o *JWTOptions) {
+		o.Leeway = leeway
+	}
+}
+
+// WithScopeClaim 设置 scope 所在的 claim，支持空格分隔的字符串或字符串数组，默认 "scope"
+func WithScopeClaim(claim string) JWTOption {
+	return func(o *JWTOptions) {
+		o.ScopeClaim = claim
+	}
+}
+
+// WithTokenHeader 设置读取 token 的 header 和 scheme，默认 "Authorization: Bearer <token>"
+// scheme 为空表示 header 值即为 token
+func WithTokenHeader(header, scheme string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Header = header
+		o.Scheme = scheme
+	}
+}
+
+type JWTOption func(*JWTOptions)
+
+type JWTOptions struct {
+	HMACSecret          []byte
+	PublicKeys          map[string]crypto.PublicKey
+	PublicKeyPEMs       map[string][]byte
+	JWKSFile            string
+	JWKSURL             string
+	JWKSRefreshInterval time.Duration
+	HTTPClient          *http.Client
+	Algorithms          []string
+	Issuer              string
+	Audience            string
+	Leeway              time.Duration
+	ScopeClaim          string
+	Header              string
+	Scheme              string
+}
+
+func DefaultJWTOptions() *JWTOptions {
+	return &JWTOptions{
+		PublicKeys:    make(map[string]crypto.PublicKey),
+		PublicKeyPEMs: make(map[string][]byte),
+		Algorithms:    make([]string, 0),
+		ScopeClaim:    defaultJWTScopeClaim,
+		Header:        defaultJWTHeader,
+		Scheme:        defaultJWTScheme,
+	}
+}
+
+// JWTAuthenticator JWT 认证器，支持 HS*/RS*/ES* 签名，公钥可来自静态配置或 JWKS
+type JWTAuthenticator struct {
+	opts       *JWTOptions
+	publicKeys map[string]crypto.PublicKey
+	jwks       *jwksCache
+	parser     *jwt.Parser
+}
+
+// NewJWTAuthenticator 创建 JWT 认证器，至少需要配置一种校验 key
+func NewJWTAuthenticator(opts ...JWTOption) (*JWTAuthenticator, error) {
+	o := DefaultJWTOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	publicKeys := make(map[string]crypto.PublicKey, len(o.PublicKeys)+len(o.PublicKeyPEMs))
+	for kid, k := range o.PublicKeys {
+		publicKeys[kid] = k
+	}
+	for kid, b := range o.PublicKeyPEMs {
+		k, err := parsePublicKeyPEM(b)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key pem failed, kid=[%s], err=[%v]", kid, err)
+		}
+		publicKeys[kid] = k
+	}
+
+	var jwks *jwksCache
+	switch {
+	case o.JWKSURL != "":
+		client := o.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: defaultJWKSHTTPTimout}
+		}
+		jwks = newJWKSCache(urlJWKSLoader(o.JWKSURL, client), o.JWKSRefreshInterval)
+	case o.JWKSFile != "":
+		jwks = newJWKSCache(fileJWKSLoader(o.JWKSFile), o.JWKSRefreshInterval)
+	}
+
+	if len(o.HMACSecret) == 0 && len(publicKeys) == 0 && jwks == nil {
+		return nil, errors.New("no verification key configured, one of HMACSecret/PublicKey/JWKS is required")
+	}
+
+	algs := o.Algorithms
+	if len(algs) == 0 {
+		if len(o.HMACSecret) > 0 {
+			algs = append(algs, "HS256", "HS384", "HS512")
+		}
+		if len(publicKeys) > 0 || jwks != nil {
+			algs = append(algs, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512")
+		}
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(algs),
+		jwt.WithLeeway(o.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if o.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(o.Issuer))
+	}
+	if o.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(o.Audience))
+	}
+
+	return &JWTAuthenticator{
+		opts:       o,
+		publicKeys: publicKeys,
+		jwks:       jwks,
+		parser:     jwt.NewParser(parserOpts...),
+	}, nil
+}
+
+func (a *JWTAuthenticator) Name() string {
+	return MethodJWT
+}
+
+func (a *JWTAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	tokenStr := a.extractToken(c.Request)
+	if tokenStr == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		return a.resolveKey(c, t)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+	return &Principal{
+		Subject:    sub,
+		Method:     MethodJWT,
+		Scopes:     scopesFromClaim(claims[a.opts.ScopeClaim]),
+		Attributes: claims,
+	}, nil
+}
+
+// extractToken 从 header 中提取 token
+func (a *JWTAuthenticator) extractToken(req *http.Request) string {
+	v := strings.TrimSpace(req.Header.Get(a.opts.Header))
+	if v == "" || a.opts.Scheme == "" {
+		return v
+	}
+	prefix := a.opts.Scheme + " "
+	if len(v) <= len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(v[len(prefix):])
+}
+
+// resolveKey 根据算法和 kid 选择校验 key
+func (a *JWTAuthenticator) resolveKey(c *gin.Context, t *jwt.Token) (any, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(a.opts.HMACSecret) > 0 {
+			return a.opts.HMACSecret, nil
+		}
+		// JWKS 中也可能包含 oct key
+		if a.jwks == nil {
+			return nil, fmt.Errorf("%w: hmac secret not configured", ErrInvalidCredentials)
+		}
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if k, ok := a.publicKeys[kid]; ok {
+		return k, nil
+	}
+	if kid != "" {
+		// 未命中指定 kid 时，回退到不区分 kid 的静态公钥
+		if k, ok := a.publicKeys[""]; ok && a.jwks == nil {
+			return k, nil
+		}
+	}
+	if a.jwks != nil {
+		return a.jwks.key(c.Request.Context(), kid)
+	}
+	return nil, fmt.Errorf("%w: no public key for kid [%s]", ErrInvalidCredentials, kid)
+}
+
+// scopesFromClaim 解析 scope claim，支持 "a b c" 和 ["a","b","c"] 两种格式
+func scopesFromClaim(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return strings.Fields(s)
+	case []any:
+		scopes := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	case []string:
+		return s
+	default:
+		return nil
+	}
+}
+
+// parsePublicKeyPEM 解析 PEM 格式公钥，支持 PKIX 公钥、PKCS1 RSA 公钥和证书
+func parsePublicKeyPEM(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
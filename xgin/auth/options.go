@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// WithAuthenticators 设置路由使用的认证器，按顺序尝试
+func WithAuthenticators(authenticators ...Authenticator) Option {
+	return func(o *Options) {
+		o.Authenticators = append(o.Authenticators, authenticators...)
+	}
+}
+
+// AllowAnonymous 是否允许匿名访问，为 true 时请求未携带任何凭证也放行（携带了错误凭证仍会拒绝）
+func AllowAnonymous(allowAnonymous bool) Option {
+	return func(o *Options) {
+		o.AllowAnonymous = allowAnonymous
+	}
+}
+
+// RequireScopes 要求 Principal 拥有全部指定 scope
+func RequireScopes(scopes ...string) Option {
+	return func(o *Options) {
+		o.Scopes = append(o.Scopes, scopes...)
+	}
+}
+
+// RequireFunc 自定义授权校验，返回 error 时以 403 拒绝
+func RequireFunc(f func(c *gin.Context, p *Principal) error) Option {
+	return func(o *Options) {
+		o.Authorizers = append(o.Authorizers, f)
+	}
+}
+
+// WithUnauthorizedHandler 自定义认证失败(401)的响应
+func WithUnauthorizedHandler(h ErrorHandler) Option {
+	return func(o *Options) {
+		o.UnauthorizedHandler = h
+	}
+}
+
+// WithForbiddenHandler 自定义授权失败(403)的响应
+func WithForbiddenHandler(h ErrorHandler) Option {
+	return func(o *Options) {
+		o.ForbiddenHandler = h
+	}
+}
+
+// ErrorHandler 认证/授权失败处理函数，需自行 Abort
+type ErrorHandler func(c *gin.Context, err error)
+
+type Option func(*Options)
+
+type Options struct {
+	Authenticators      []Authenticator
+	AllowAnonymous      bool
+	Scopes              []string
+	Authorizers         []func(c *gin.Context, p *Principal) error
+	UnauthorizedHandler ErrorHandler
+	ForbiddenHandler    ErrorHandler
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Authenticators:      make([]Authenticator, 0),
+		AllowAnonymous:      false,
+		Scopes:              make([]string, 0),
+		Authorizers:         make([]func(c *gin.Context, p *Principal) error, 0),
+		UnauthorizedHandler: defaultUnauthorizedHandler,
+		ForbiddenHandler:    defaultForbiddenHandler,
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xlog"
+)
+
+// Require 声明路由认证策略，在 route register 中按路由或路由组使用
+// 使用示例：
+//
+//	e.GET("/orders", auth.Require(auth.WithAuthenticators(jwtAuth), auth.RequireScopes("orders:read")), listOrders)
+//
+// 若请求已被前置的 Require（如路由组级别）认证过，则复用已有 Principal，仅执行授权校验
+func Require(opts ...Option) gin.HandlerFunc {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *gin.Context) {
+		p, ok := GetPrincipal(c)
+		if !ok {
+			var err error
+			p, err = authenticate(c, o.Authenticators)
+			if err != nil {
+				if errors.Is(err, ErrNoCredentials) && o.AllowAnonymous {
+					c.Next()
+					return
+				}
+				xlog.Warn(c.Request.Context(), "[XGin-Auth] authenticate failed, path=[%s], err=[%v]", c.Request.URL.Path, err)
+				o.UnauthorizedHandler(c, err)
+				return
+			}
+			setPrincipal(c, p)
+		}
+
+		if err := authorize(c, p, o); err != nil {
+			xlog.Warn(c.Request.Context(), "[XGin-Auth] authorize failed, path=[%s], subject=[%s], err=[%v]", c.Request.URL.Path, p.Subject, err)
+			o.ForbiddenHandler(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authorize 校验 Principal 是否满足路由策略
+func authorize(c *gin.Context, p *Principal, o *Options) error {
+	if !p.HasScopes(o.Scopes...) {
+		return fmt.Errorf("%w: scopes %v required", ErrForbidden, o.Scopes)
+	}
+	for _, f := range o.Authorizers {
+		if err := f(c, p); err != nil {
+			if errors.Is(err, ErrForbidden) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", ErrForbidden, err)
+		}
+	}
+	return nil
+}
+
+func defaultUnauthorizedHandler(c *gin.Context, err error) {
+	msg := "unauthorized"
+	if errors.Is(err, ErrInvalidCredentials) {
+		msg = err.Error()
+	}
+	middleware.AbortWithErrorResponse(c, http.StatusUnauthorized, msg)
+}
+
+func defaultForbiddenHandler(c *gin.Context, err error) {
+	middleware.AbortWithErrorResponse(c, http.StatusForbidden, err.Error())
+}
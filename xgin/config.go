@@ -11,6 +11,14 @@ const (
 
 	defaultHost = "0.0.0.0"
 	defaultPort = 8000
+
+	defaultReadHeaderTimeout    = "10s"
+	defaultReadTimeout          = "60s"
+	defaultWriteTimeout         = "60s"
+	defaultIdleTimeout          = "120s"
+	defaultMaxHeaderBytes       = 1 << 20  // 1MB
+	defaultMaxRequestBodySize   = 32 << 20 // 32MB
+	defaultMaxConcurrentStreams = 250
 )
 
 var defaultSchemes = []string{"https", "http"}
@@ -38,9 +46,256 @@ type Config struct {
 	// optional default ""
 	KeyFile string `mapstructure:"KeyFile"`
 
+	// ClientCAFile mTLS 客户端证书 CA bundle，配置后校验客户端证书，仅 TLS 模式生效
+	// optional default ""
+	ClientCAFile string `mapstructure:"ClientCAFile"`
+
+	// ClientAuth 客户端证书校验方式：none/request/require/verify_if_given/require_and_verify
+	// optional default ""（配置 ClientCAFile 时为 require_and_verify，否则为 none）
+	ClientAuth string `mapstructure:"ClientAuth"`
+
+	// Listeners 额外监听，与 Host:Port 同时服务，如明文与 TLS 分端口、Unix socket、HTTP 跳转 HTTPS
+	// optional default nil
+	Listeners []ListenerConfig `mapstructure:"Listeners"`
+
+	// ReadHeaderTimeout 读取请求头的超时时间，防止 slowloris 攻击，"0" 表示不限制
+	// optional default "10s"
+	ReadHeaderTimeout string `mapstructure:"ReadHeaderTimeout"`
+
+	// ReadTimeout 读取整个请求（含 body）的超时时间，"0" 表示不限制
+	// HTTP/1 下超时后请求 context 会被取消，耗时路由可通过 options.GroupDeadline 或 middleware.ExtendDeadline 延长
+	// optional default "60s"
+	ReadTimeout string `mapstructure:"ReadTimeout"`
+
+	// WriteTimeout 从读完请求头到写完响应的超时时间，"0" 表示不限制
+	// 下载等耗时路由可通过 options.GroupDeadline 或 middleware.ExtendDeadline 延长，SSE 按消息设置写超时，不受此限制
+	// optional default "60s"
+	WriteTimeout string `mapstructure:"WriteTimeout"`
+
+	// IdleTimeout keep-alive 连接的空闲超时时间，"0" 时沿用 ReadTimeout
+	// optional default "120s"
+	IdleTimeout string `mapstructure:"IdleTimeout"`
+
+	// MaxHeaderBytes 请求头大小上限（字节）
+	// optional default 1048576
+	MaxHeaderBytes int `mapstructure:"MaxHeaderBytes"`
+
+	// MaxRequestBodySize 请求 body 大小上限（字节），超过返回 413，-1 表示不限制
+	// 路由组可通过 options.GroupMaxBodySize 单独设置
+	// optional default 33554432（32MB）
+	MaxRequestBodySize int64 `mapstructure:"MaxRequestBodySize"`
+
+	// HTTP2 HTTP/2 参数，作用于 TLS 与 h2c 连接
+	// optional default nil（MaxConcurrentStreams 为 250，其余使用 x/net/http2 默认值）
+	HTTP2 *HTTP2Config `mapstructure:"HTTP2"`
+
+	// TrustedProxies 可信代理列表（CIDR 或单个 IP），同时作用于 gin engine 和 middleware.GetClientInfo
+	// 仅当直连方属于可信代理时才读取 Forwarded/X-Forwarded-For/X-Real-IP 头
+	// optional default nil（信任所有代理，兼容旧行为，生产环境建议配置）
+	TrustedProxies []string `mapstructure:"TrustedProxies"`
+
 	// Swagger swagger相关配置
 	// optional default nil
 	Swagger *SwaggerConfig `mapstructure:"Swagger"`
+
+	// AccessLog 请求日志策略（body 记录范围、大小上限、采样率、脱敏规则）
+	// optional default nil（记录请求/响应 body，请求 256KB、响应 4KB，不采样）
+	AccessLog *AccessLogConfig `mapstructure:"AccessLog"`
+}
+
+// HTTP2Config HTTP/2 参数，未配置的字段使用 x/net/http2 默认值
+type HTTP2Config struct {
+	// MaxConcurrentStreams 单连接最大并发 stream 数
+	// optional default 250
+	MaxConcurrentStreams uint32 `mapstructure:"MaxConcurrentStreams"`
+
+	// MaxReadFrameSize 允许客户端发送的最大帧大小（字节），取值 [16384, 16777215]
+	// optional default 0（1MB）
+	MaxReadFrameSize uint32 `mapstructure:"MaxReadFrameSize"`
+
+	// MaxUploadBufferPerConnection 单连接上传流控窗口（字节），最小 65535
+	// optional default 0（1MB）
+	MaxUploadBufferPerConnection int32 `mapstructure:"MaxUploadBufferPerConnection"`
+
+	// MaxUploadBufferPerStream 单 stream 上传流控窗口（字节）
+	// optional default 0（1MB）
+	MaxUploadBufferPerStream int32 `mapstructure:"MaxUploadBufferPerStream"`
+
+	// IdleTimeout 无活跃 stream 的连接空闲超时时间
+	// optional default ""（沿用 XGin.IdleTimeout）
+	IdleTimeout string `mapstructure:"IdleTimeout"`
+
+	// ReadIdleTimeout 连接无数据可读多久后发送 ping 健康检查
+	// optional default ""（不检查）
+	ReadIdleTimeout string `mapstructure:"ReadIdleTimeout"`
+
+	// PingTimeout 等待 ping 响应的超时时间，超时关闭连接
+	// optional default ""（15s）
+	PingTimeout string `mapstructure:"PingTimeout"`
+
+	// WriteByteTimeout 写入阻塞多久（期间无任何字节写出）后关闭连接
+	// optional default ""（不限制）
+	WriteByteTimeout string `mapstructure:"WriteByteTimeout"`
+}
+
+// ListenerConfig 额外监听配置，证书修改后自动重新加载
+type ListenerConfig struct {
+	// Network 监听类型：tcp/unix
+	// optional default "tcp"
+	Network string `mapstructure:"Network"`
+
+	// Host 监听的host，仅 tcp 生效
+	// optional default 同 XGin.Host
+	Host string `mapstructure:"Host"`
+
+	// Port 监听端口，仅 tcp 生效
+	// required（tcp）
+	Port int `mapstructure:"Port"`
+
+	// Path Unix socket 文件路径，启动时清理残留的 socket 文件
+	// required（unix）
+	Path string `mapstructure:"Path"`
+
+	// SocketMode Unix socket 文件权限，八进制，如 "0660"
+	// optional default ""（由 umask 决定）
+	SocketMode string `mapstructure:"SocketMode"`
+
+	// CertFile TLS 证书路径
+	// optional default ""
+	CertFile string `mapstructure:"CertFile"`
+
+	// KeyFile TLS 私钥路径
+	// optional default ""
+	KeyFile string `mapstructure:"KeyFile"`
+
+	// ClientCAFile mTLS 客户端证书 CA bundle
+	// optional default ""
+	ClientCAFile string `mapstructure:"ClientCAFile"`
+
+	// ClientAuth 客户端证书校验方式，同 XGin.ClientAuth
+	// optional default ""
+	ClientAuth string `mapstructure:"ClientAuth"`
+
+	// RedirectToHTTPS 明文监听将全部请求重定向到 HTTPS，不再处理业务请求
+	// optional default false
+	RedirectToHTTPS bool `mapstructure:"RedirectToHTTPS"`
+
+	// RedirectPort 重定向的 HTTPS 端口
+	// optional default 0（XGin 启用 TLS 时为 XGin.Port，否则为首个 TLS 监听的端口，都没有时为 443）
+	RedirectPort int `mapstructure:"RedirectPort"`
+}
+
+// AccessLogConfig 请求日志策略配置
+type AccessLogConfig struct {
+	// Body 记录 body 的范围：none/request/response/both
+	// optional default "both"
+	Body string `mapstructure:"Body"`
+
+	// MaxRequestBodySize 请求 body 读取上限（字节）
+	// optional default 262144
+	MaxRequestBodySize int `mapstructure:"MaxRequestBodySize"`
+
+	// MaxResponseBodySize 响应 body 捕获上限（字节），超过则不记录
+	// optional default 4096
+	MaxResponseBodySize int `mapstructure:"MaxResponseBodySize"`
+
+	// SampleRate 成功请求（status < 400）的采样率，取值 (0, 1]，错误请求始终记录
+	// optional default 1
+	SampleRate float64 `mapstructure:"SampleRate"`
+
+	// TextContentTypes 记录响应 body 的 Content-Type（子串匹配）
+	// optional default ["application/json", "text/", "application/xml", "application/javascript"]
+	TextContentTypes []string `mapstructure:"TextContentTypes"`
+
+	// RedactPaths JSONPath 脱敏规则，作用于请求/响应 JSON body，如 "$.user.card.number"、"$.items[*].token"、"$..password"
+	// optional default nil
+	RedactPaths []string `mapstructure:"RedactPaths"`
+
+	// MaskRules 正则脱敏规则，作用于请求/响应 body，如手机号、邮箱
+	// optional default nil
+	MaskRules []AccessLogMaskRule `mapstructure:"MaskRules"`
+
+	// Routes 路由级策略，按顺序匹配，先匹配者生效
+	// optional default nil
+	Routes []AccessLogRouteConfig `mapstructure:"Routes"`
+
+	// Format 访问日志格式：combined（Apache Combined 文本）/ecs（Elastic Common Schema JSON）/otel（OTel 语义约定 JSON）
+	// 为空且未配置 File 时写入 xlog 应用日志（原有格式）；配置了 File 时默认 ecs
+	// optional default ""
+	Format string `mapstructure:"Format"`
+
+	// File 访问日志单独写入文件，独立切割，不与 xlog 应用日志混合；为 nil 且配置了 Format 时输出到 stdout
+	// optional default nil
+	File *AccessLogFileConfig `mapstructure:"File"`
+}
+
+// AccessLogFileConfig 访问日志文件配置
+type AccessLogFileConfig struct {
+	// Path 日志文件夹路径
+	// optional default "./log"
+	Path string `mapstructure:"Path"`
+
+	// Name 日志文件名称
+	// optional default "access"
+	Name string `mapstructure:"Name"`
+
+	// MaxAge 日志保存最大时间
+	// optional default "7d"
+	MaxAge string `mapstructure:"MaxAge"`
+
+	// RotateTime 日志切割时长
+	// optional default "1d"
+	RotateTime string `mapstructure:"RotateTime"`
+
+	// MaxSize 单个日志文件最大大小（MB）
+	// optional default 0（不按大小切割）
+	MaxSize int `mapstructure:"MaxSize"`
+
+	// MaxBackups 历史日志文件保留个数
+	// optional default 0（不限制，仅按 MaxAge 清理）
+	MaxBackups int `mapstructure:"MaxBackups"`
+
+	// Compress 是否在后台 gzip 压缩历史日志文件
+	// optional default false
+	Compress bool `mapstructure:"Compress"`
+}
+
+// AccessLogRouteConfig 路由级请求日志策略，未配置的字段沿用全局策略
+type AccessLogRouteConfig struct {
+	// Method 匹配的请求方法，为空时匹配全部方法
+	// optional default ""
+	Method string `mapstructure:"Method"`
+
+	// Path 匹配的路由，以 / 结尾时前缀匹配，否则精确匹配路由模板（如 /users/:id）或请求路径
+	// required
+	Path string `mapstructure:"Path"`
+
+	// Body 记录 body 的范围：none/request/response/both
+	// optional default 沿用全局
+	Body string `mapstructure:"Body"`
+
+	// MaxRequestBodySize 请求 body 读取上限（字节）
+	// optional default 沿用全局
+	MaxRequestBodySize int `mapstructure:"MaxRequestBodySize"`
+
+	// MaxResponseBodySize 响应 body 捕获上限（字节）
+	// optional default 沿用全局
+	MaxResponseBodySize int `mapstructure:"MaxResponseBodySize"`
+
+	// SampleRate 成功请求的采样率，取值 (0, 1]
+	// optional default 沿用全局
+	SampleRate float64 `mapstructure:"SampleRate"`
+}
+
+// AccessLogMaskRule 正则脱敏规则
+type AccessLogMaskRule struct {
+	// Pattern 正则表达式
+	// required
+	Pattern string `mapstructure:"Pattern"`
+
+	// Replacement 替换内容，支持 $1 等分组引用
+	// optional default "***FILTERED***"
+	Replacement string `mapstructure:"Replacement"`
 }
 
 // SwaggerConfig swagger相关配置
@@ -94,9 +349,73 @@ func configMergeDefault(c *Config) *Config {
 	if c.Port <= 0 {
 		c.Port = defaultPort
 	}
+	if c.ReadHeaderTimeout == "" {
+		c.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if c.ReadTimeout == "" {
+		c.ReadTimeout = defaultReadTimeout
+	}
+	if c.WriteTimeout == "" {
+		c.WriteTimeout = defaultWriteTimeout
+	}
+	if c.IdleTimeout == "" {
+		c.IdleTimeout = defaultIdleTimeout
+	}
+	if c.MaxHeaderBytes <= 0 {
+		c.MaxHeaderBytes = defaultMaxHeaderBytes
+	}
+	if c.MaxRequestBodySize == 0 {
+		c.MaxRequestBodySize = defaultMaxRequestBodySize
+	}
+	c.HTTP2 = http2ConfigMergeDefault(c.HTTP2)
 	if c.Swagger != nil {
 		c.Swagger = swaggerConfigMergeDefault(c.Swagger)
 	}
+	for i := range c.Listeners {
+		c.Listeners[i] = listenerConfigMergeDefault(c.Listeners[i], c.Host)
+	}
+	if c.AccessLog != nil && c.AccessLog.File != nil {
+		c.AccessLog.File = accessLogFileConfigMergeDefault(c.AccessLog.File)
+	}
+	return c
+}
+
+func http2ConfigMergeDefault(c *HTTP2Config) *HTTP2Config {
+	if c == nil {
+		c = &HTTP2Config{}
+	}
+	if c.MaxConcurrentStreams == 0 {
+		c.MaxConcurrentStreams = defaultMaxConcurrentStreams
+	}
+	return c
+}
+
+func listenerConfigMergeDefault(c ListenerConfig, host string) ListenerConfig {
+	if c.Network == "" {
+		c.Network = "tcp"
+	}
+	if c.Host == "" {
+		c.Host = host
+	}
+	return c
+}
+
+func accessLogFileConfigMergeDefault(c *AccessLogFileConfig) *AccessLogFileConfig {
+	if c == nil {
+		c = &AccessLogFileConfig{}
+	}
+	if c.Path == "" {
+		c.Path = "./log"
+	}
+	if c.Name == "" {
+		c.Name = "access"
+	}
+	if c.MaxAge == "" {
+		c.MaxAge = "7d"
+	}
+	if c.RotateTime == "" {
+		c.RotateTime = "1d"
+	}
 	return c
 }
 
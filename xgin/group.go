@@ -0,0 +1,79 @@
+package xgin
+
+import (
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/options"
+)
+
+// routeGroup WithGroup 注册的路由组
+type routeGroup struct {
+	prefix   string
+	register func(*gin.RouterGroup)
+	o        *options.GroupOptions
+}
+
+// fullPrefix 版本作为最外层前缀，如 version=v1, prefix=/users -> /v1/users
+func (rg *routeGroup) fullPrefix() string {
+	if rg.o.Version == "" {
+		return rg.prefix
+	}
+	return path.Join("/", strings.Trim(rg.o.Version, "/"), rg.prefix)
+}
+
+// handlers 组内中间件链：读写超时 -> 策略覆盖 -> 废弃声明 -> 认证 -> 自定义中间件
+// body 大小上限由全局 body limit 中间件按组前缀匹配，见 bodyLimitRoutes
+func (rg *routeGroup) handlers() []gin.HandlerFunc {
+	handlers := make([]gin.HandlerFunc, 0, len(rg.o.Middlewares)+4)
+
+	if rg.o.Deadline != nil {
+		handlers = append(handlers, middleware.GinXDeadlineMiddleware(*rg.o.Deadline))
+	}
+
+	logBody, metric := rg.o.LogBody, rg.o.Metric
+	if logBody != nil || metric != nil {
+		handlers = append(handlers, func(c *gin.Context) {
+			if logBody != nil {
+				middleware.SetLogBodyCapture(c, *logBody)
+			}
+			if metric != nil && !*metric {
+				middleware.SkipMetric(c)
+			}
+			c.Next()
+		})
+	}
+
+	if rg.o.Deprecated {
+		handlers = append(handlers, middleware.GinXDeprecationMiddleware(
+			middleware.WithDeprecatedSince(rg.o.DeprecatedSince),
+			middleware.WithSunset(rg.o.Sunset),
+			middleware.WithDeprecationLink(rg.o.DeprecationLink),
+		))
+	}
+
+	if rg.o.Auth != nil {
+		handlers = append(handlers, rg.o.Auth)
+	}
+	return append(handlers, rg.o.Middlewares...)
+}
+
+func (rg *routeGroup) registerTo(engine *gin.Engine) {
+	group := engine.Group(rg.fullPrefix(), rg.handlers()...)
+	if rg.register != nil {
+		rg.register(group)
+	}
+}
+
+// bodyLimitRoutes 设置了 GroupMaxBodySize 的组对应的路由级 body 上限
+func bodyLimitRoutes(groups []*routeGroup) []middleware.BodyLimitOption {
+	opts := make([]middleware.BodyLimitOption, 0)
+	for _, rg := range groups {
+		if rg.o.MaxBodySize != nil {
+			opts = append(opts, middleware.WithBodyLimitRoute(rg.fullPrefix(), *rg.o.MaxBodySize))
+		}
+	}
+	return opts
+}
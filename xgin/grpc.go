@@ -0,0 +1,37 @@
+package xgin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithGRPCHandler 与 gRPC 共用端口：HTTP/2 且 Content-Type 为 application/grpc 的请求交给 h 处理，其余请求走 gin
+// 非 TLS 模式下自动启用 h2c，gRPC 客户端需以明文 HTTP/2（prior knowledge）连接
+// 使用示例：
+//
+//	gs := xgrpc.New().WithServiceRegister(func(s *grpc.Server) { pb.RegisterGreeterServer(s, &greeter{}) })
+//	xgin.New().WithRouteRegister(routes).WithGRPCHandler(gs.Handler()).Build().Start()
+func (g *XGin) WithGRPCHandler(h http.Handler) *XGin {
+	g.grpcHandler = h
+	return g
+}
+
+// grpcDispatchHandler 按协议与 Content-Type 分流 gRPC 与普通 HTTP 请求
+// gRPC 流式调用可能长期存在，清除 XGin.ReadTimeout/WriteTimeout 设置的 stream 读写超时，由 gRPC deadline 控制
+func grpcDispatchHandler(httpHandler, grpcHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Time{})
+			_ = rc.SetWriteDeadline(time.Time{})
+			grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
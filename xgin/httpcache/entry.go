@@ -0,0 +1,173 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// skipStoreHeaders 不保存到缓存的响应头
+// Content-Encoding/Vary 由外层压缩中间件按本次请求重新协商，Content-Length/Date 等由 net/http 重新生成
+var skipStoreHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Date":              true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Age":               true,
+	CacheStatusHeader:   true,
+}
+
+// entry 缓存的响应
+type entry struct {
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	ETag       string      `json:"etag,omitempty"`
+	StoredAt   int64       `json:"stored_at"`   // unix ms
+	FreshUntil int64       `json:"fresh_until"` // unix ms
+	StaleUntil int64       `json:"stale_until"` // unix ms
+}
+
+func (e *entry) isFresh(now time.Time) bool {
+	return now.UnixMilli() < e.FreshUntil
+}
+
+func (e *entry) isStale(now time.Time) bool {
+	return now.UnixMilli() < e.StaleUntil
+}
+
+// cacheControl Cache-Control 中与服务端缓存相关的指令
+type cacheControl struct {
+	noStore              bool
+	noCache              bool
+	private              bool
+	public               bool
+	maxAge               time.Duration
+	hasMaxAge            bool
+	sMaxAge              time.Duration
+	hasSMaxAge           bool
+	staleWhileRevalidate time.Duration
+}
+
+func parseCacheControl(value string) cacheControl {
+	var cc cacheControl
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(directive, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		arg = strings.Trim(strings.TrimSpace(arg), `"`)
+		switch name {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "private":
+			cc.private = true
+		case "public":
+			cc.public = true
+		case "max-age":
+			if d, ok := parseSeconds(arg); ok {
+				cc.maxAge, cc.hasMaxAge = d, true
+			}
+		case "s-maxage":
+			if d, ok := parseSeconds(arg); ok {
+				cc.sMaxAge, cc.hasSMaxAge = d, true
+			}
+		case "stale-while-revalidate":
+			if d, ok := parseSeconds(arg); ok {
+				cc.staleWhileRevalidate = d
+			}
+		}
+	}
+	return cc
+}
+
+func parseSeconds(s string) (time.Duration, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// buildKey 默认 key：method + path + 规范化 query + 选定请求头，sha256 后加前缀，避免 key 过长
+func buildKey(c *gin.Context, o *Options) string {
+	if o.KeyFunc != nil {
+		k := o.KeyFunc(c)
+		if k == "" {
+			return ""
+		}
+		return o.KeyPrefix + k
+	}
+
+	req := c.Request
+	var sb strings.Builder
+	sb.WriteString(req.Method)
+	sb.WriteByte(' ')
+	sb.WriteString(req.URL.Path)
+	sb.WriteByte('?')
+	sb.WriteString(normalizeQuery(req.URL.Query()))
+	for _, h := range o.VaryHeaders {
+		sb.WriteByte('\n')
+		sb.WriteString(h)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(req.Header.Values(h), ","))
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return o.KeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// normalizeQuery 按参数名排序，同名参数按值排序，保证参数顺序不同的请求命中同一缓存
+func normalizeQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	for _, vs := range q {
+		sort.Strings(vs)
+	}
+	// url.Values.Encode 按 key 排序
+	return q.Encode()
+}
+
+// computeETag 基于响应 body 生成强 ETag
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatch 按 If-None-Match 的弱比较规则判断是否命中
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func storableHeader(header http.Header) http.Header {
+	out := make(http.Header, len(header))
+	for k, v := range header {
+		if skipStoreHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
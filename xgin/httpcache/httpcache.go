@@ -0,0 +1,286 @@
+package httpcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/store"
+	"github.com/xiaoshicae/xone/v2/xlog"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheStatusHeader 标识本次响应的缓存结果（HIT/STALE/MISS/BYPASS）
+const CacheStatusHeader = "X-Cache"
+
+// Middleware 响应缓存中间件，在 route register 中按路由或路由组使用，仅对 GET 请求生效
+// 使用示例：
+//
+//	st := store.NewCacheStore(xcache.C("page"))
+//	g := e.Group("/catalog", httpcache.Middleware(st, httpcache.WithTTL(30*time.Second), httpcache.WithStaleWhileRevalidate(time.Minute)))
+//
+// 缓存规则：
+//   - key 默认由 method + path + 规范化 query + WithVaryHeaders 指定的请求头组成，可通过 WithKeyFunc 自定义
+//   - 请求 Cache-Control: no-store 时不走缓存；no-cache/max-age=0 时跳过读取，重新生成并写入缓存
+//   - 响应 Cache-Control: no-store/no-cache/private、携带 Set-Cookie 或状态码不在 WithStatusCodes 中时不缓存
+//   - 响应 s-maxage/max-age/stale-while-revalidate 优先于 WithTTL/WithStaleWhileRevalidate
+//   - 响应未设置 ETag 时根据 body 自动生成，请求 If-None-Match 命中时返回 304
+//   - 同一 key 的并发未命中请求通过 singleflight 合并，只执行一次 handler
+//   - 携带 Authorization 的请求默认不缓存，除非设置了 WithKeyFunc 或 WithVaryHeaders("Authorization")
+func Middleware(st store.Store, opts ...Option) gin.HandlerFunc {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	initMetricCollectors()
+
+	m := &cacheMiddleware{
+		st:           st,
+		o:            o,
+		statusCodes:  make(map[int]bool, len(o.StatusCodes)),
+		authIsolated: o.KeyFunc != nil,
+	}
+	for _, code := range o.StatusCodes {
+		m.statusCodes[code] = true
+	}
+	for _, h := range o.VaryHeaders {
+		if h == "Authorization" {
+			m.authIsolated = true
+		}
+	}
+	return m.handle
+}
+
+type cacheMiddleware struct {
+	st           store.Store
+	o            *Options
+	statusCodes  map[int]bool
+	authIsolated bool // key 是否已按调用方凭证隔离
+	sf           singleflight.Group
+}
+
+// fillResult singleflight 共享的生成结果，entry 为 nil 表示响应不可缓存
+type fillResult struct {
+	entry *entry
+}
+
+func (m *cacheMiddleware) handle(c *gin.Context) {
+	name := m.o.Name
+	if name == "" {
+		name = c.FullPath()
+	}
+
+	if c.Request.Method != http.MethodGet {
+		c.Next()
+		return
+	}
+
+	reqCC := parseCacheControl(c.GetHeader("Cache-Control"))
+	if reqCC.noStore || (!m.authIsolated && c.GetHeader("Authorization") != "") {
+		recordResult(name, resultBypass)
+		c.Header(CacheStatusHeader, "BYPASS")
+		c.Next()
+		return
+	}
+
+	key := buildKey(c, m.o)
+	if key == "" {
+		recordResult(name, resultBypass)
+		c.Header(CacheStatusHeader, "BYPASS")
+		c.Next()
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !reqCC.noCache && !(reqCC.hasMaxAge && reqCC.maxAge == 0) {
+		if e := m.load(ctx, key); e != nil {
+			now := time.Now()
+			if e.isFresh(now) {
+				recordResult(name, resultHit)
+				serveEntry(c, e, "HIT", now)
+				return
+			}
+			if e.isStale(now) {
+				recordResult(name, resultStale)
+				m.revalidate(c, key)
+				serveEntry(c, e, "STALE", now)
+				return
+			}
+		}
+	}
+
+	// 未命中：同 key 并发请求合并，由 leader 执行 handler，其余请求复用结果
+	var bw *bufferWriter
+	var panicked any
+	v, _, _ := m.sf.Do(key, func() (res any, err error) {
+		bw = &bufferWriter{ResponseWriter: c.Writer, maxBodySize: m.o.MaxBodySize}
+		origWriter := c.Writer
+		c.Writer = bw
+		defer func() {
+			c.Writer = origWriter
+			// singleflight 在存在 DoChan 等待者时会将 panic 抛到新 goroutine 导致进程退出
+			// 因此在此捕获，返回空结果后由当前请求重新抛出，交给 recover 中间件处理
+			if r := recover(); r != nil {
+				panicked = r
+				res = &fillResult{}
+			}
+		}()
+
+		c.Next()
+		return m.fill(c, key, bw.Status(), bw.Header(), bw.buf.Bytes(), bw.passthrough), nil
+	})
+	if panicked != nil {
+		panic(panicked)
+	}
+
+	if bw != nil {
+		// 当前请求即 leader，写出缓冲的响应
+		recordResult(name, resultMiss)
+		m.writeLeaderResponse(c, bw, v.(*fillResult).entry)
+		return
+	}
+
+	res := v.(*fillResult)
+	if res.entry == nil {
+		// leader 的响应不可缓存，当前请求自行执行 handler
+		recordResult(name, resultMiss)
+		c.Header(CacheStatusHeader, "MISS")
+		c.Next()
+		return
+	}
+	recordResult(name, resultCoalesced)
+	serveEntry(c, res.entry, "HIT", time.Now())
+}
+
+// fill 根据 handler 的响应生成缓存条目并写入 store，不可缓存时返回空 entry
+func (m *cacheMiddleware) fill(c *gin.Context, key string, status int, header http.Header, body []byte, passthrough bool) *fillResult {
+	if passthrough || !m.statusCodes[status] || header.Get("Set-Cookie") != "" {
+		return &fillResult{}
+	}
+
+	resCC := parseCacheControl(header.Get("Cache-Control"))
+	if resCC.noStore || resCC.noCache || resCC.private {
+		return &fillResult{}
+	}
+
+	ttl := m.o.TTL
+	if resCC.hasSMaxAge {
+		ttl = resCC.sMaxAge
+	} else if resCC.hasMaxAge {
+		ttl = resCC.maxAge
+	}
+	if ttl <= 0 {
+		return &fillResult{}
+	}
+	swr := m.o.StaleWhileRevalidate
+	if resCC.staleWhileRevalidate > 0 {
+		swr = resCC.staleWhileRevalidate
+	}
+
+	if header.Get("ETag") == "" {
+		header.Set("ETag", computeETag(body))
+	}
+
+	now := time.Now()
+	e := &entry{
+		Status:     status,
+		Header:     storableHeader(header),
+		Body:       append([]byte(nil), body...),
+		ETag:       header.Get("ETag"),
+		StoredAt:   now.UnixMilli(),
+		FreshUntil: now.Add(ttl).UnixMilli(),
+		StaleUntil: now.Add(ttl + swr).UnixMilli(),
+	}
+
+	data, err := json.Marshal(e)
+	if err == nil {
+		err = m.st.Set(context.WithoutCancel(c.Request.Context()), key, data, ttl+swr)
+	}
+	if err != nil {
+		xlog.Warn(c.Request.Context(), "[XGin-HttpCache] save response failed, key=[%s], err=[%v]", key, err)
+	}
+	return &fillResult{entry: e}
+}
+
+// writeLeaderResponse 写出 leader 缓冲的响应，If-None-Match 命中时返回 304
+func (m *cacheMiddleware) writeLeaderResponse(c *gin.Context, bw *bufferWriter, e *entry) {
+	if bw.passthrough {
+		return
+	}
+	c.Writer.Header().Set(CacheStatusHeader, "MISS")
+	if e != nil && etagMatch(c.GetHeader("If-None-Match"), e.ETag) {
+		c.Writer.WriteHeader(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if bw.buf.Len() > 0 {
+		_, _ = c.Writer.Write(bw.buf.Bytes())
+	}
+}
+
+// revalidate 后台执行路由 handler 刷新缓存，同一 key 同时只有一个刷新任务
+// 刷新使用当前请求的 Copy，只执行路由的最终 handler，不经过缓存中间件之后注册的中间件
+func (m *cacheMiddleware) revalidate(c *gin.Context, key string) {
+	handler := c.Handler()
+	cp := c.Copy()
+	req := c.Request
+
+	// DoChan 在新 goroutine 中执行，结果 channel 带缓冲，不读取也不会泄漏
+	m.sf.DoChan(key, func() (res any, err error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), m.o.RevalidateTimeout)
+		defer cancel()
+		cp.Request = req.Clone(ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				xlog.Error(ctx, "[XGin-HttpCache] revalidate panic, key=[%s], err=[%v]", key, r)
+				res, err = &fillResult{}, fmt.Errorf("revalidate panic: %v", r)
+			}
+		}()
+
+		rw := newRecorderWriter()
+		cp.Writer = rw
+		handler(cp)
+		return m.fill(cp, key, rw.Status(), rw.Header(), rw.buf.Bytes(), false), nil
+	})
+}
+
+func (m *cacheMiddleware) load(ctx context.Context, key string) *entry {
+	data, ok, err := m.st.Get(ctx, key)
+	if err != nil {
+		xlog.Warn(ctx, "[XGin-HttpCache] load response failed, key=[%s], err=[%v]", key, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil
+	}
+	return &e
+}
+
+// serveEntry 输出缓存的响应并中断后续 handler
+func serveEntry(c *gin.Context, e *entry, cacheStatus string, now time.Time) {
+	h := c.Writer.Header()
+	for k, v := range e.Header {
+		h[k] = v
+	}
+	h.Set(CacheStatusHeader, cacheStatus)
+	h.Set("Age", strconv.FormatInt(max(0, now.UnixMilli()-e.StoredAt)/1000, 10))
+
+	if etagMatch(c.GetHeader("If-None-Match"), e.ETag) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	c.Status(e.Status)
+	if len(e.Body) > 0 {
+		_, _ = c.Writer.Write(e.Body)
+	}
+	c.Abort()
+}
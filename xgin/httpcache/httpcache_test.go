@@ -0,0 +1,408 @@
+package httpcache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/xiaoshicae/xone/v2/xgin/store"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// memStore 测试用内存 Store
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte)}
+}
+
+func (s *memStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok, nil
+}
+
+func (s *memStore) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) SetNX(_ context.Context, key string, value []byte, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; ok {
+		return false, nil
+	}
+	s.data[key] = value
+	return true, nil
+}
+
+func (s *memStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+var _ store.Store = (*memStore)(nil)
+
+// counterHandler 返回递增序号的 handler，便于判断是否命中缓存
+func counterHandler(calls *int32, mutate ...func(c *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n := atomic.AddInt32(calls, 1)
+		for _, f := range mutate {
+			f(c)
+		}
+		c.String(http.StatusOK, "resp-"+strconv.Itoa(int(n)))
+	}
+}
+
+func newEngine(st store.Store, handler gin.HandlerFunc, opts ...Option) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.GET("/items", Middleware(st, opts...), handler)
+	return r
+}
+
+func get(r http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func counterValue(name, result string) float64 {
+	m := &dto.Metric{}
+	_ = cacheRequests.WithLabelValues(name, result).Write(m)
+	return m.GetCounter().GetValue()
+}
+
+func TestMiddleware_HitAndMiss(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls), WithName("hit-miss"))
+
+	first := get(r, "/items?b=2&a=1")
+	second := get(r, "/items?a=1&b=2")
+
+	if calls != 1 {
+		t.Fatalf("handler should run once, got %d", calls)
+	}
+	if first.Header().Get(CacheStatusHeader) != "MISS" || second.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("unexpected X-Cache %q / %q", first.Header().Get(CacheStatusHeader), second.Header().Get(CacheStatusHeader))
+	}
+	if second.Body.String() != "resp-1" {
+		t.Errorf("expected cached body, got %q", second.Body.String())
+	}
+	if first.Header().Get("ETag") == "" || first.Header().Get("ETag") != second.Header().Get("ETag") {
+		t.Errorf("etag should be generated and stable, got %q / %q", first.Header().Get("ETag"), second.Header().Get("ETag"))
+	}
+	if second.Header().Get("Age") == "" || second.Header().Get("Content-Type") == "" {
+		t.Errorf("cached response should carry Age and original headers, got %v", second.Header())
+	}
+	if counterValue("hit-miss", resultMiss) != 1 || counterValue("hit-miss", resultHit) != 1 {
+		t.Errorf("unexpected metrics miss=%v hit=%v", counterValue("hit-miss", resultMiss), counterValue("hit-miss", resultHit))
+	}
+
+	get(r, "/items?a=2")
+	if calls != 2 {
+		t.Errorf("different query should miss, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_IfNoneMatch(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls))
+
+	first := get(r, "/items")
+	etag := first.Header().Get("ETag")
+
+	w := get(r, "/items", "If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("expected 304 without body, got %d %q", w.Code, w.Body.String())
+	}
+
+	// 缓存未命中时，leader 生成的响应同样支持 304
+	r2 := newEngine(newMemStore(), func(c *gin.Context) { c.String(http.StatusOK, "const") })
+	w = get(r2, "/items", "If-None-Match", computeETag([]byte("const")))
+	if w.Code != http.StatusNotModified || w.Header().Get(CacheStatusHeader) != "MISS" {
+		t.Fatalf("expected 304 on miss with matching etag, got %d", w.Code)
+	}
+
+	w = get(r, "/items", "If-None-Match", `"other"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for mismatched etag, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_VaryHeaders(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls), WithVaryHeaders("accept-language"))
+
+	get(r, "/items", "Accept-Language", "en")
+	get(r, "/items", "Accept-Language", "zh")
+	get(r, "/items", "Accept-Language", "en")
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestMiddleware_KeyFunc(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls), WithKeyFunc(func(c *gin.Context) string {
+		return c.Query("id")
+	}))
+
+	get(r, "/items?id=1&ts=1")
+	get(r, "/items?id=1&ts=2")
+	if calls != 1 {
+		t.Errorf("custom key should ignore ts, got %d calls", calls)
+	}
+	get(r, "/items")
+	get(r, "/items")
+	if calls != 3 {
+		t.Errorf("empty key should bypass cache, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_RequestCacheControl(t *testing.T) {
+	var calls int32
+	st := newMemStore()
+	r := newEngine(st, counterHandler(&calls))
+
+	w := get(r, "/items", "Cache-Control", "no-store")
+	if w.Header().Get(CacheStatusHeader) != "BYPASS" || len(st.data) != 0 {
+		t.Fatalf("no-store request should bypass cache")
+	}
+
+	get(r, "/items")
+	w = get(r, "/items", "Cache-Control", "no-cache")
+	if calls != 3 || w.Body.String() != "resp-3" {
+		t.Fatalf("no-cache request should refresh, got calls=%d body=%q", calls, w.Body.String())
+	}
+	w = get(r, "/items")
+	if w.Body.String() != "resp-3" {
+		t.Errorf("refreshed response should be cached, got %q", w.Body.String())
+	}
+}
+
+func TestMiddleware_ResponseNotCacheable(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *gin.Context)
+	}{
+		{"no-store", func(c *gin.Context) { c.Header("Cache-Control", "no-store") }},
+		{"private", func(c *gin.Context) { c.Header("Cache-Control", "private, max-age=60") }},
+		{"max-age=0", func(c *gin.Context) { c.Header("Cache-Control", "max-age=0") }},
+		{"set-cookie", func(c *gin.Context) { c.Header("Set-Cookie", "sid=1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			r := newEngine(newMemStore(), counterHandler(&calls, tt.mutate))
+			get(r, "/items")
+			get(r, "/items")
+			if calls != 2 {
+				t.Errorf("response should not be cached, got %d calls", calls)
+			}
+		})
+	}
+
+	var calls int32
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.String(http.StatusNotFound, "not found")
+	})
+	get(r, "/items")
+	get(r, "/items")
+	if calls != 2 {
+		t.Errorf("404 should not be cached by default, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_AuthorizationBypass(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls))
+	get(r, "/items", "Authorization", "Bearer a")
+	get(r, "/items", "Authorization", "Bearer a")
+	if calls != 2 {
+		t.Errorf("authorized requests should bypass by default, got %d calls", calls)
+	}
+
+	calls = 0
+	r = newEngine(newMemStore(), counterHandler(&calls), WithVaryHeaders("Authorization"))
+	get(r, "/items", "Authorization", "Bearer a")
+	get(r, "/items", "Authorization", "Bearer a")
+	get(r, "/items", "Authorization", "Bearer b")
+	if calls != 2 {
+		t.Errorf("authorized requests should be cached per credential, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_MaxBodySize(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.String(http.StatusOK, strings.Repeat("x", 64))
+	}, WithMaxBodySize(16))
+
+	w := get(r, "/items")
+	if w.Body.Len() != 64 {
+		t.Fatalf("large body should be passed through, got %d bytes", w.Body.Len())
+	}
+	get(r, "/items")
+	if calls != 2 {
+		t.Errorf("large body should not be cached, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_Singleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.String(http.StatusOK, "shared")
+	}, WithName("singleflight"))
+
+	const n = 8
+	var wg sync.WaitGroup
+	results := make(chan *httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- get(r, "/items")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if calls != 1 {
+		t.Fatalf("handler should run once for concurrent misses, got %d", calls)
+	}
+	for w := range results {
+		if w.Body.String() != "shared" {
+			t.Errorf("unexpected body %q", w.Body.String())
+		}
+	}
+}
+
+func TestMiddleware_StaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), counterHandler(&calls), WithTTL(30*time.Millisecond), WithStaleWhileRevalidate(time.Minute))
+
+	get(r, "/items")
+	time.Sleep(50 * time.Millisecond)
+
+	w := get(r, "/items")
+	if w.Header().Get(CacheStatusHeader) != "STALE" || w.Body.String() != "resp-1" {
+		t.Fatalf("expected stale response, got %q %q", w.Header().Get(CacheStatusHeader), w.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&calls) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("background revalidation should run handler, got %d calls", calls)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if w = get(r, "/items"); w.Header().Get(CacheStatusHeader) == "HIT" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if w.Body.String() != "resp-2" {
+		t.Errorf("expected revalidated body, got %q", w.Body.String())
+	}
+}
+
+func TestMiddleware_ResponseMaxAgeOverridesTTL(t *testing.T) {
+	var calls int32
+	st := newMemStore()
+	r := newEngine(st, counterHandler(&calls, func(c *gin.Context) {
+		c.Header("Cache-Control", "public, s-maxage=120, max-age=1, stale-while-revalidate=30")
+	}))
+
+	get(r, "/items")
+	for _, data := range st.data {
+		e := parseEntryForTest(t, data)
+		if got := time.Duration(e.FreshUntil-e.StoredAt) * time.Millisecond; got != 120*time.Second {
+			t.Errorf("expected s-maxage ttl 120s, got %v", got)
+		}
+		if got := time.Duration(e.StaleUntil-e.FreshUntil) * time.Millisecond; got != 30*time.Second {
+			t.Errorf("expected swr 30s, got %v", got)
+		}
+	}
+}
+
+func TestMiddleware_PanicNotCached(t *testing.T) {
+	var calls int32
+	st := newMemStore()
+	r := newEngine(st, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	if w := get(r, "/items"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if w := get(r, "/items"); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("expected handler to run after panic, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl(`No-Store, max-age="10", s-maxage=20, stale-while-revalidate=5, private, bogus`)
+	if !cc.noStore || !cc.private || cc.maxAge != 10*time.Second || cc.sMaxAge != 20*time.Second || cc.staleWhileRevalidate != 5*time.Second {
+		t.Errorf("unexpected parse result %+v", cc)
+	}
+	if cc := parseCacheControl("max-age=-1"); cc.hasMaxAge {
+		t.Error("negative max-age should be ignored")
+	}
+}
+
+func TestEtagMatch(t *testing.T) {
+	if !etagMatch(`W/"a", "b"`, `"a"`) || !etagMatch("*", `"a"`) || !etagMatch(`"a"`, `W/"a"`) {
+		t.Error("expected weak comparison match")
+	}
+	if etagMatch(`"b"`, `"a"`) || etagMatch("", `"a"`) {
+		t.Error("unexpected match")
+	}
+}
+
+func parseEntryForTest(t *testing.T, data []byte) *entry {
+	t.Helper()
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatalf("invalid entry: %v", err)
+	}
+	return &e
+}
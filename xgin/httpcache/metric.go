@@ -0,0 +1,44 @@
+package httpcache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+)
+
+// 缓存结果，用于 X-Cache 响应头与指标 label
+const (
+	resultHit       = "hit"       // 命中新鲜缓存
+	resultStale     = "stale"     // 命中过期缓存（stale-while-revalidate），后台刷新
+	resultMiss      = "miss"      // 未命中，执行 handler
+	resultCoalesced = "coalesced" // 未命中，但合并到并发中的同 key 请求（singleflight）
+	resultBypass    = "bypass"    // 请求声明 no-store 或携带凭证等，不走缓存
+)
+
+var (
+	metricOnce    sync.Once
+	cacheRequests *prometheus.CounterVec
+)
+
+func initMetricCollectors() {
+	metricOnce.Do(func() {
+		cfg := xmetric.GetConfig()
+
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Name:        "http_cache_requests_total",
+			Help:        "HTTP 响应缓存请求数（按结果 hit/stale/miss/coalesced/bypass）",
+			ConstLabels: xmetric.GetConstLabels(),
+		}, []string{"name", "result"})
+
+		if rc, ok := xmetric.SafeRegister(counter).(*prometheus.CounterVec); ok {
+			counter = rc
+		}
+		cacheRequests = counter
+	})
+}
+
+func recordResult(name, result string) {
+	cacheRequests.WithLabelValues(name, result).Inc()
+}
@@ -0,0 +1,123 @@
+package httpcache
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTTL               = time.Minute
+	defaultMaxBodySize       = 1 << 20 // 1MB
+	defaultRevalidateTimeout = 10 * time.Second
+	defaultKeyPrefix         = "xgin:cache:"
+)
+
+// KeyFunc 自定义缓存 key 生成函数，返回空字符串表示本次请求不走缓存
+type KeyFunc func(c *gin.Context) string
+
+// WithName 设置缓存名称，用于指标 label 区分不同路由组，默认使用路由模板
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
+
+// WithTTL 设置响应新鲜期，响应 Cache-Control 中的 s-maxage/max-age 优先，默认 1m
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		if ttl > 0 {
+			o.TTL = ttl
+		}
+	}
+}
+
+// WithStaleWhileRevalidate 设置过期后仍可返回旧响应的时长，期间后台刷新缓存
+// 响应 Cache-Control 中的 stale-while-revalidate 优先，默认 0（不启用）
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.StaleWhileRevalidate = d
+		}
+	}
+}
+
+// WithVaryHeaders 设置参与缓存 key 计算的请求头（如 Accept-Language）
+// 包含 Authorization 时，携带 Authorization 的请求也会被缓存（按凭证隔离）
+func WithVaryHeaders(headers ...string) Option {
+	return func(o *Options) {
+		for _, h := range headers {
+			o.VaryHeaders = append(o.VaryHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithKeyFunc 自定义缓存 key，设置后忽略默认的 method + path + query + header 规则
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *Options) {
+		o.KeyFunc = f
+	}
+}
+
+// WithKeyPrefix 设置存储 key 前缀，默认 "xgin:cache:"
+func WithKeyPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+// WithStatusCodes 设置可缓存的响应状态码，默认仅 200
+func WithStatusCodes(codes ...int) Option {
+	return func(o *Options) {
+		if len(codes) > 0 {
+			o.StatusCodes = codes
+		}
+	}
+}
+
+// WithMaxBodySize 设置可缓存的最大响应 body，超出时直接透传不缓存，默认 1MB
+func WithMaxBodySize(size int) Option {
+	return func(o *Options) {
+		if size > 0 {
+			o.MaxBodySize = size
+		}
+	}
+}
+
+// WithRevalidateTimeout 设置后台刷新的超时时间，默认 10s
+func WithRevalidateTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.RevalidateTimeout = d
+		}
+	}
+}
+
+type Option func(*Options)
+
+type Options struct {
+	Name                 string
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	VaryHeaders          []string
+	KeyFunc              KeyFunc
+	KeyPrefix            string
+	StatusCodes          []int
+	MaxBodySize          int
+	RevalidateTimeout    time.Duration
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Name:                 "",
+		TTL:                  defaultTTL,
+		StaleWhileRevalidate: 0,
+		VaryHeaders:          make([]string, 0),
+		KeyFunc:              nil,
+		KeyPrefix:            defaultKeyPrefix,
+		StatusCodes:          []int{http.StatusOK},
+		MaxBodySize:          defaultMaxBodySize,
+		RevalidateTimeout:    defaultRevalidateTimeout,
+	}
+}
@@ -0,0 +1,125 @@
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferWriter 缓冲 handler 输出，便于在写出前补充 ETag 并处理 If-None-Match
+// body 超过上限或 handler 主动 Flush（流式响应）时切换为透传，本次响应不缓存
+type bufferWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	maxBodySize int
+	passthrough bool
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.maxBodySize {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *bufferWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow 缓冲期间延后写出 header，保证 ETag 等响应头能被补充
+func (w *bufferWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *bufferWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *bufferWriter) Size() int {
+	if !w.passthrough && w.buf.Len() > 0 {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *bufferWriter) Flush() {
+	_ = w.startPassthrough()
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap 供 http.ResponseController 访问底层 writer（设置写超时等）
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bufferWriter) startPassthrough() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+var errHijackNotSupported = errors.New("httpcache: hijack not supported during revalidation")
+
+// recorderWriter 后台刷新时使用的 gin.ResponseWriter，仅在内存中记录响应
+type recorderWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+	wrote  bool
+}
+
+func newRecorderWriter() *recorderWriter {
+	return &recorderWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *recorderWriter) Header() http.Header { return w.header }
+
+func (w *recorderWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.buf.Write(b)
+}
+
+func (w *recorderWriter) WriteString(s string) (int, error) {
+	w.wrote = true
+	return w.buf.WriteString(s)
+}
+
+func (w *recorderWriter) WriteHeader(code int) {
+	if code > 0 && !w.wrote {
+		w.status = code
+	}
+}
+
+func (w *recorderWriter) WriteHeaderNow()     { w.wrote = true }
+func (w *recorderWriter) Status() int         { return w.status }
+func (w *recorderWriter) Size() int           { return w.buf.Len() }
+func (w *recorderWriter) Written() bool       { return w.wrote }
+func (w *recorderWriter) Flush()              {}
+func (w *recorderWriter) Pusher() http.Pusher { return nil }
+
+func (w *recorderWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *recorderWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errHijackNotSupported
+}
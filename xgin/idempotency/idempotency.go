@@ -0,0 +1,279 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/store"
+	"github.com/xiaoshicae/xone/v2/xlog"
+)
+
+const (
+	stateProcessing = "processing"
+	stateCompleted  = "completed"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// skipReplayHeaders 不保存也不重放的响应头
+// Content-Encoding/Vary 由外层压缩中间件按本次请求重新协商，Content-Length/Date 等由 net/http 重新生成
+var skipReplayHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Vary":              true,
+	"Date":              true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	ReplayedHeader:      true,
+}
+
+// record 存储中的幂等记录
+type record struct {
+	State       string      `json:"state"`
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Middleware 幂等中间件，在 route register 中按路由或路由组使用
+// 使用示例：
+//
+//	st := store.NewRedisStore(xredis.C(), "")
+//	e.POST("/orders", idempotency.Middleware(st, idempotency.WithScope(userID)), createOrder)
+//
+// 处理规则：
+//   - 首次请求：写入处理中标记后执行 handler，完成后保存状态码、响应头与 body
+//   - 并发重复请求（首次尚未完成）：409
+//   - 已完成的重复请求：重放保存的响应，并附带 Idempotent-Replayed: true
+//   - 相同幂等键但请求指纹（method + uri + body）不同：422
+//
+// handler 返回 5xx 或 panic 时删除记录，允许客户端重试
+func Middleware(st store.Store, opts ...Option) gin.HandlerFunc {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	methods := make(map[string]bool, len(o.Methods))
+	for _, m := range o.Methods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	return func(c *gin.Context) {
+		if !methods[c.Request.Method] {
+			c.Next()
+			return
+		}
+
+		idemKey := strings.TrimSpace(c.GetHeader(o.Header))
+		if idemKey == "" {
+			if o.Required {
+				middleware.AbortWithErrorResponse(c, http.StatusBadRequest, o.Header+" header is required")
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(idemKey) > defaultMaxKeyLen {
+			middleware.AbortWithErrorResponse(c, http.StatusBadRequest, o.Header+" header is too long")
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c.Request, o.MaxBodySize)
+		if err != nil {
+			middleware.AbortWithErrorResponse(c, http.StatusRequestEntityTooLarge, "request body too large for idempotency check")
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := storageKey(c, o, idemKey)
+
+		marker, _ := json.Marshal(record{State: stateProcessing, Fingerprint: fingerprint})
+		acquired, err := st.SetNX(ctx, key, marker, o.LockTTL)
+		if err != nil {
+			xlog.Error(ctx, "[XGin-Idempotency] acquire key failed, key=[%s], err=[%v]", key, err)
+			middleware.AbortWithErrorResponse(c, http.StatusServiceUnavailable, "idempotency store unavailable")
+			return
+		}
+		if !acquired {
+			handleDuplicate(c, st, key, fingerprint)
+			return
+		}
+
+		process(c, st, key, fingerprint, o)
+	}
+}
+
+// process 首次请求：执行 handler 并保存响应
+func process(c *gin.Context, st store.Store, key, fingerprint string, o *Options) {
+	// 请求结束后客户端可能已断开，使用不可取消的 ctx 保证记录写入
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	origWriter := c.Writer
+	cw := &captureWriter{ResponseWriter: origWriter}
+	c.Writer = cw
+
+	completed := false
+	defer func() {
+		c.Writer = origWriter
+		if !completed {
+			// handler panic，释放处理中标记，允许客户端重试
+			_ = st.Del(ctx, key)
+		}
+	}()
+
+	c.Next()
+	completed = true
+
+	status := cw.Status()
+	if status >= http.StatusInternalServerError {
+		if err := st.Del(ctx, key); err != nil {
+			xlog.Warn(ctx, "[XGin-Idempotency] release key failed, key=[%s], err=[%v]", key, err)
+		}
+		return
+	}
+
+	rec := record{
+		State:       stateCompleted,
+		Fingerprint: fingerprint,
+		Status:      status,
+		Header:      replayableHeader(cw.Header()),
+		Body:        cw.body.Bytes(),
+	}
+	data, err := json.Marshal(rec)
+	if err == nil {
+		err = st.Set(ctx, key, data, o.TTL)
+	}
+	if err != nil {
+		xlog.Warn(ctx, "[XGin-Idempotency] save response failed, key=[%s], err=[%v]", key, err)
+	}
+}
+
+// handleDuplicate 重复请求：按记录状态返回 409/422 或重放响应
+func handleDuplicate(c *gin.Context, st store.Store, key, fingerprint string) {
+	ctx := c.Request.Context()
+	data, ok, err := st.Get(ctx, key)
+	if err != nil {
+		xlog.Error(ctx, "[XGin-Idempotency] load record failed, key=[%s], err=[%v]", key, err)
+		middleware.AbortWithErrorResponse(c, http.StatusServiceUnavailable, "idempotency store unavailable")
+		return
+	}
+
+	var rec record
+	if !ok || json.Unmarshal(data, &rec) != nil {
+		// 记录在 SetNX 与 Get 之间过期或被释放，按并发冲突处理，由客户端稍后重试
+		middleware.AbortWithErrorResponse(c, http.StatusConflict, "request with the same idempotency key is being processed")
+		return
+	}
+
+	if rec.Fingerprint != fingerprint {
+		middleware.AbortWithErrorResponse(c, http.StatusUnprocessableEntity, "idempotency key reused with a different request")
+		return
+	}
+
+	if rec.State != stateCompleted {
+		middleware.AbortWithErrorResponse(c, http.StatusConflict, "request with the same idempotency key is being processed")
+		return
+	}
+
+	h := c.Writer.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set(ReplayedHeader, "true")
+	c.Status(rec.Status)
+	if len(rec.Body) > 0 {
+		_, _ = c.Writer.Write(rec.Body)
+	}
+	c.Abort()
+}
+
+// requestFingerprint 计算请求指纹 sha256(method \n uri \n body)，读取后恢复 body 供 handler 使用
+func requestFingerprint(req *http.Request, maxBodySize int64) (string, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > maxBodySize {
+			return "", errBodyTooLarge
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(req.URL.RequestURI()))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// storageKey 存储 key = prefix + [scope:] + method + route + ":" + idemKey
+func storageKey(c *gin.Context, o *Options, idemKey string) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	var sb strings.Builder
+	sb.WriteString(o.KeyPrefix)
+	if o.Scope != nil {
+		if scope := o.Scope(c); scope != "" {
+			sb.WriteString(scope)
+			sb.WriteByte(':')
+		}
+	}
+	sb.WriteString(c.Request.Method)
+	sb.WriteString(route)
+	sb.WriteByte(':')
+	sb.WriteString(idemKey)
+	return sb.String()
+}
+
+func replayableHeader(header http.Header) http.Header {
+	out := make(http.Header, len(header))
+	for k, v := range header {
+		if skipReplayHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// captureWriter 在写出响应的同时保存一份 body 用于后续重放
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap 供 http.ResponseController 访问底层 writer（设置写超时等）
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
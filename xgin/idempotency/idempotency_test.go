@@ -0,0 +1,300 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/store"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// memStore 测试用内存 Store
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte)}
+}
+
+func (s *memStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	v, ok := s.data[key]
+	return v, ok, nil
+}
+
+func (s *memStore) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) SetNX(_ context.Context, key string, value []byte, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.data[key]; ok {
+		return false, nil
+	}
+	s.data[key] = value
+	return true, nil
+}
+
+func (s *memStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+var _ store.Store = (*memStore)(nil)
+
+func newEngine(st store.Store, handler gin.HandlerFunc, opts ...Option) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.POST("/orders", Middleware(st, opts...), handler)
+	r.GET("/orders", Middleware(st, opts...), handler)
+	return r
+}
+
+func doRequest(r http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(DefaultHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_ReplayCompleted(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("X-Order-Seq", "1")
+		c.JSON(http.StatusCreated, gin.H{"seq": n})
+	})
+
+	first := doRequest(r, "POST", "k1", `{"amount":100}`)
+	second := doRequest(r, "POST", "k1", `{"amount":100}`)
+
+	if calls != 1 {
+		t.Fatalf("handler should run once, got %d", calls)
+	}
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("expected 201 twice, got %d / %d", first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("replayed body mismatch: %q vs %q", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("X-Order-Seq") != "1" {
+		t.Error("replayed response should keep headers")
+	}
+	if second.Header().Get(ReplayedHeader) != "true" || first.Header().Get(ReplayedHeader) != "" {
+		t.Error("only replayed response should carry Idempotent-Replayed")
+	}
+}
+
+func TestMiddleware_FingerprintMismatch(t *testing.T) {
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	doRequest(r, "POST", "k1", `{"amount":100}`)
+	w := doRequest(r, "POST", "k1", `{"amount":200}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_ConcurrentDuplicate(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- doRequest(r, "POST", "k1", `{}`) }()
+
+	<-entered
+	w := doRequest(r, "POST", "k1", `{}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Fatalf("first request expected 200, got %d", first.Code)
+	}
+}
+
+func TestMiddleware_ServerErrorReleasesKey(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	if w := doRequest(r, "POST", "k1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if w := doRequest(r, "POST", "k1", `{}`); w.Code != http.StatusOK {
+		t.Fatalf("retry after 5xx should run handler again, got %d", w.Code)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	st := newMemStore()
+	r := newEngine(st, func(c *gin.Context) {
+		panic("boom")
+	})
+
+	if w := doRequest(r, "POST", "k1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if len(st.data) != 0 {
+		t.Errorf("key should be released after panic, got %v", st.data)
+	}
+}
+
+func TestMiddleware_HandlerReadsBody(t *testing.T) {
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		var req map[string]any
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+
+	w := doRequest(r, "POST", "k1", `{"amount":100}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"amount":100`) {
+		t.Fatalf("handler should read the original body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMiddleware_MissingKey(t *testing.T) {
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	r := newEngine(newMemStore(), handler)
+	if w := doRequest(r, "POST", "", `{}`); w.Code != http.StatusOK {
+		t.Fatalf("missing key should pass through, got %d", w.Code)
+	}
+
+	r = newEngine(newMemStore(), handler, Required(true))
+	w := doRequest(r, "POST", "", `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("missing key should be rejected when required, got %d", w.Code)
+	}
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["code"] != float64(http.StatusBadRequest) {
+		t.Errorf("expected error envelope, got %s", w.Body.String())
+	}
+
+	if w := doRequest(r, "POST", strings.Repeat("k", 256), `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("too long key should be rejected, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_MethodNotProtected(t *testing.T) {
+	var calls int32
+	r := newEngine(newMemStore(), func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.Status(http.StatusOK)
+	})
+
+	doRequest(r, "GET", "k1", "")
+	doRequest(r, "GET", "k1", "")
+	if calls != 2 {
+		t.Errorf("GET should not be protected by default, got %d calls", calls)
+	}
+}
+
+func TestMiddleware_BodyTooLarge(t *testing.T) {
+	r := newEngine(newMemStore(), func(c *gin.Context) { c.Status(http.StatusOK) }, WithMaxBodySize(8))
+	if w := doRequest(r, "POST", "k1", `{"amount":100}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_StoreError(t *testing.T) {
+	st := newMemStore()
+	st.err = errors.New("redis down")
+	r := newEngine(st, func(c *gin.Context) { c.Status(http.StatusOK) })
+	if w := doRequest(r, "POST", "k1", `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_Scope(t *testing.T) {
+	var calls int32
+	st := newMemStore()
+	r := newEngine(st, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.Status(http.StatusOK)
+	}, WithScope(func(c *gin.Context) string { return c.GetHeader("X-User") }))
+
+	for _, user := range []string{"alice", "bob"} {
+		req := httptest.NewRequest("POST", "/orders", strings.NewReader(`{}`))
+		req.Header.Set(DefaultHeader, "k1")
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 {
+		t.Errorf("same key in different scopes should not conflict, got %d calls", calls)
+	}
+	if _, ok := st.data["xgin:idem:alice:POST/orders:k1"]; !ok {
+		t.Errorf("unexpected storage keys %v", st.data)
+	}
+}
+
+func TestReplayableHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Set("Content-Encoding", "gzip")
+	h.Set("Content-Length", "10")
+	h.Set("Vary", "Accept-Encoding")
+	h.Set("X-Request-Id", "abc")
+
+	out := replayableHeader(h)
+	if out.Get("Content-Type") == "" || out.Get("X-Request-Id") == "" {
+		t.Errorf("expected business headers kept, got %v", out)
+	}
+	if out.Get("Content-Encoding") != "" || out.Get("Content-Length") != "" || out.Get("Vary") != "" {
+		t.Errorf("transport headers should be dropped, got %v", out)
+	}
+}
@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultHeader = "Idempotency-Key"
+
+	// ReplayedHeader 重放响应时附带的响应头，便于客户端区分首次响应与重放响应
+	ReplayedHeader = "Idempotent-Replayed"
+
+	defaultTTL         = 24 * time.Hour
+	defaultLockTTL     = time.Minute
+	defaultMaxBodySize = 1 << 20 // 1MB
+	defaultMaxKeyLen   = 255
+	defaultKeyPrefix   = "xgin:idem:"
+)
+
+// WithHeader 设置携带幂等键的请求头，默认 Idempotency-Key
+func WithHeader(header string) Option {
+	return func(o *Options) {
+		if header != "" {
+			o.Header = header
+		}
+	}
+}
+
+// WithMethods 设置需要幂等保护的 HTTP 方法，默认 POST、PATCH
+func WithMethods(methods ...string) Option {
+	return func(o *Options) {
+		if len(methods) > 0 {
+			o.Methods = methods
+		}
+	}
+}
+
+// Required 是否要求必须携带幂等键，为 true 时缺失则返回 400，默认 false（缺失时直接放行）
+func Required(required bool) Option {
+	return func(o *Options) {
+		o.Required = required
+	}
+}
+
+// WithTTL 设置已完成响应的保存时长，默认 24h
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		if ttl > 0 {
+			o.TTL = ttl
+		}
+	}
+}
+
+// WithLockTTL 设置处理中标记的过期时长，默认 1m
+// 应大于接口最长处理时间，进程崩溃时标记到期后自动释放
+func WithLockTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		if ttl > 0 {
+			o.LockTTL = ttl
+		}
+	}
+}
+
+// WithMaxBodySize 设置计算请求指纹时允许的最大 body，超出返回 413，默认 1MB
+func WithMaxBodySize(size int64) Option {
+	return func(o *Options) {
+		if size > 0 {
+			o.MaxBodySize = size
+		}
+	}
+}
+
+// WithKeyPrefix 设置存储 key 前缀，默认 "xgin:idem:"
+func WithKeyPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+// WithScope 设置幂等键的隔离维度（如用户 ID、租户 ID），避免不同调用方的幂等键冲突
+func WithScope(f func(c *gin.Context) string) Option {
+	return func(o *Options) {
+		o.Scope = f
+	}
+}
+
+type Option func(*Options)
+
+type Options struct {
+	Header      string
+	Methods     []string
+	Required    bool
+	TTL         time.Duration
+	LockTTL     time.Duration
+	MaxBodySize int64
+	KeyPrefix   string
+	Scope       func(c *gin.Context) string
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Header:      DefaultHeader,
+		Methods:     []string{"POST", "PATCH"},
+		Required:    false,
+		TTL:         defaultTTL,
+		LockTTL:     defaultLockTTL,
+		MaxBodySize: defaultMaxBodySize,
+		KeyPrefix:   defaultKeyPrefix,
+		Scope:       nil,
+	}
+}
@@ -0,0 +1,217 @@
+package xgin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xiaoshicae/xone/v2/xerror"
+	"github.com/xiaoshicae/xone/v2/xutil"
+)
+
+// listenerServer 额外监听及其 http server
+type listenerServer struct {
+	name string // 日志中的监听描述，如 tcp://0.0.0.0:8443、unix:///run/app.sock
+	srv  *http.Server
+	ln   net.Listener
+	tls  bool
+}
+
+func (s *listenerServer) serve() error {
+	if s.tls {
+		return s.srv.ServeTLS(s.ln, "", "")
+	}
+	return s.srv.Serve(s.ln)
+}
+
+// newListenerServers 按 Listeners 配置创建监听，任一失败时关闭已创建的监听并返回错误
+// tlsHandler 用于 TLS 监听，plainHandler 用于明文监听（可能包装了 h2c）
+func newListenerServers(c *Config, tlsHandler, plainHandler http.Handler) ([]*listenerServer, error) {
+	servers := make([]*listenerServer, 0, len(c.Listeners))
+	closeAll := func() {
+		for _, s := range servers {
+			_ = s.ln.Close()
+		}
+	}
+	for i, lc := range c.Listeners {
+		s, err := newListenerServer(c, lc, tlsHandler, plainHandler)
+		if err != nil {
+			closeAll()
+			return nil, xerror.Newf("xgin", "run", "invalid Listeners[%d], err=[%v]", i, err)
+		}
+		servers = append(servers, s)
+	}
+	return servers, nil
+}
+
+func newListenerServer(c *Config, lc ListenerConfig, tlsHandler, plainHandler http.Handler) (*listenerServer, error) {
+	useTLS := lc.CertFile != "" && lc.KeyFile != ""
+	if (lc.CertFile == "") != (lc.KeyFile == "") {
+		return nil, errors.New("TLS config incomplete: CertFile and KeyFile must be both set or both empty")
+	}
+	if useTLS && lc.RedirectToHTTPS {
+		return nil, errors.New("RedirectToHTTPS is only allowed on plain listeners")
+	}
+
+	srv := newHTTPServer(c, "", plainHandler)
+	if useTLS {
+		tlsConfig, err := newTLSConfig(lc.CertFile, lc.KeyFile, lc.ClientCAFile, lc.ClientAuth)
+		if err != nil {
+			return nil, err
+		}
+		srv.Handler = tlsHandler
+		srv.TLSConfig = tlsConfig
+		if err := configureHTTP2(srv, c.HTTP2); err != nil {
+			return nil, err
+		}
+	} else if lc.RedirectToHTTPS {
+		srv.Handler = httpsRedirectHandler(redirectPort(c, lc))
+	}
+
+	ln, name, err := listen(lc)
+	if err != nil {
+		return nil, err
+	}
+	srv.Addr = ln.Addr().String()
+	return &listenerServer{name: name, srv: srv, ln: ln, tls: useTLS}, nil
+}
+
+func listen(lc ListenerConfig) (net.Listener, string, error) {
+	switch strings.ToLower(lc.Network) {
+	case "tcp":
+		if lc.Port <= 0 {
+			return nil, "", errors.New("port is required for tcp listener")
+		}
+		addr := net.JoinHostPort(lc.Host, strconv.Itoa(lc.Port))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, "", err
+		}
+		return ln, "tcp://" + addr, nil
+	case "unix":
+		if lc.Path == "" {
+			return nil, "", errors.New("path is required for unix listener")
+		}
+		if err := removeStaleSocket(lc.Path); err != nil {
+			return nil, "", err
+		}
+		ln, err := net.Listen("unix", lc.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		if lc.SocketMode != "" {
+			mode, err := strconv.ParseUint(lc.SocketMode, 8, 32)
+			if err == nil {
+				err = os.Chmod(lc.Path, os.FileMode(mode))
+			}
+			if err != nil {
+				_ = ln.Close()
+				return nil, "", fmt.Errorf("set SocketMode %q failed, err=[%v]", lc.SocketMode, err)
+			}
+		}
+		return ln, "unix://" + lc.Path, nil
+	default:
+		return nil, "", fmt.Errorf("invalid Network %q, must be tcp or unix", lc.Network)
+	}
+}
+
+// removeStaleSocket 清理上次异常退出残留的 socket 文件，路径存在但不是 socket 时报错
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("path %q exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+// redirectPort XGin 启用 TLS 时为 XGin.Port，否则为首个 TLS 监听的端口，都没有时为 443
+func redirectPort(c *Config, lc ListenerConfig) int {
+	if lc.RedirectPort > 0 {
+		return lc.RedirectPort
+	}
+	if c.CertFile != "" && c.KeyFile != "" {
+		return c.Port
+	}
+	for _, l := range c.Listeners {
+		if l.CertFile != "" && l.KeyFile != "" && strings.EqualFold(l.Network, "tcp") {
+			return l.Port
+		}
+	}
+	return 443
+}
+
+// httpsRedirectHandler 将请求重定向到同一 host 的 HTTPS 端口，GET/HEAD 使用 301，其余方法使用 308 保留请求方法与 body
+func httpsRedirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6
+		}
+		if port != 443 {
+			host += ":" + strconv.Itoa(port)
+		}
+
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// serveListeners 异步启动额外监听，任一监听异常退出时关闭主 server，错误写入 errChan
+func serveListeners(servers []*listenerServer, main *http.Server, errChan chan<- error) {
+	for _, s := range servers {
+		xutil.InfoIfEnableDebug("gin server listen on: %s, tls=[%v]", s.name, s.tls)
+		go func() {
+			if err := s.serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				select {
+				case errChan <- xerror.Newf("xgin", "run", "listener %s serve failed, err=[%v]", s.name, err):
+				default:
+				}
+				_ = main.Close()
+			}
+		}()
+	}
+}
+
+// shutdownServers 并发优雅关闭主 server 与全部额外监听
+func shutdownServers(ctx context.Context, main *http.Server, servers []*listenerServer) error {
+	errs := make([]error, len(servers)+1)
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.srv.Shutdown(ctx); err != nil {
+				errs[i+1] = fmt.Errorf("listener %s shutdown failed, err=[%v]", s.name, err)
+			}
+		}()
+	}
+	errs[0] = main.Shutdown(ctx)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// closeListeners 主 server 启动失败时立即关闭额外监听
+func closeListeners(servers []*listenerServer) {
+	for _, s := range servers {
+		_ = s.srv.Close()
+		_ = s.ln.Close()
+	}
+}
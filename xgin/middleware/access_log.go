@@ -0,0 +1,315 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ecsVersion 输出的 Elastic Common Schema 版本
+const ecsVersion = "8.11.0"
+
+// AccessLogEntry 一次请求的访问日志，供 AccessLogFormatter 格式化
+// header 已过滤敏感头，body 已按日志策略截断与脱敏，未记录时为空
+type AccessLogEntry struct {
+	Time               time.Time     // 请求开始时间
+	Duration           time.Duration // 处理耗时
+	Method             string
+	Scheme             string // http/https，可信代理时取代理头
+	Host               string
+	Path               string
+	Query              string
+	URI                string // 原始 RequestURI
+	Route              string // 路由模板，如 /users/:id，未匹配路由时为空
+	Handler            string
+	Proto              string // 如 HTTP/1.1
+	ClientIP           string
+	UserAgent          string
+	Referer            string
+	RequestHeader      http.Header
+	RequestContentType string
+	RequestSize        int64 // 请求 Content-Length，未知时为 -1
+	RequestBody        string
+	Status             int
+	ResponseHeader     http.Header
+	ResponseSize       int // 响应 body 字节数
+	ResponseBody       string
+	Streamed           bool // SSE/WebSocket/chunked 流式响应
+	TraceID            string
+	SpanID             string
+	Errors             []string // c.Errors
+}
+
+// severity 5xx 为 error，4xx 为 warn，其余为 info
+func (e *AccessLogEntry) severity() string {
+	switch {
+	case e.Status >= 500:
+		return "error"
+	case e.Status >= 400:
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// message 形如 GET /users/:id 200
+func (e *AccessLogEntry) message() string {
+	route := e.Route
+	if route == "" {
+		route = e.Path
+	}
+	return e.Method + " " + route + " " + strconv.Itoa(e.Status)
+}
+
+// protoVersion HTTP/1.1 -> 1.1
+func (e *AccessLogEntry) protoVersion() string {
+	return strings.TrimPrefix(e.Proto, "HTTP/")
+}
+
+// AccessLogFormatter 访问日志格式化器，返回单行内容（不含换行符）
+type AccessLogFormatter interface {
+	Format(e *AccessLogEntry) ([]byte, error)
+}
+
+// AccessLogFormatterFunc 函数形式的 AccessLogFormatter
+type AccessLogFormatterFunc func(e *AccessLogEntry) ([]byte, error)
+
+func (f AccessLogFormatterFunc) Format(e *AccessLogEntry) ([]byte, error) {
+	return f(e)
+}
+
+// WithAccessLogOutput 访问日志按 formatter 格式写入 w（每条一行），不再写入 xlog 应用日志
+// w 需并发安全，如 xlog.RotateWriter、os.Stdout
+func WithAccessLogOutput(w io.Writer, formatter AccessLogFormatter) LogOption {
+	return func(o *LogOptions) {
+		o.Output = w
+		o.Formatter = formatter
+	}
+}
+
+// CombinedFormatter Apache Combined Log Format：
+// %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+func CombinedFormatter() AccessLogFormatter {
+	return AccessLogFormatterFunc(func(e *AccessLogEntry) ([]byte, error) {
+		b := make([]byte, 0, 256)
+		b = append(b, dashIfEmpty(e.ClientIP)...)
+		b = append(b, " - - ["...)
+		b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+		b = append(b, "] \""...)
+		b = append(b, e.Method...)
+		b = append(b, ' ')
+		b = append(b, e.URI...)
+		b = append(b, ' ')
+		b = append(b, e.Proto...)
+		b = append(b, "\" "...)
+		b = strconv.AppendInt(b, int64(e.Status), 10)
+		b = append(b, ' ')
+		if e.ResponseSize > 0 {
+			b = strconv.AppendInt(b, int64(e.ResponseSize), 10)
+		} else {
+			b = append(b, '-')
+		}
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, dashIfEmpty(e.Referer))
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, dashIfEmpty(e.UserAgent))
+		return b, nil
+	})
+}
+
+// ECSFormatter Elastic Common Schema JSON，serviceName 写入 service.name
+func ECSFormatter(serviceName string) AccessLogFormatter {
+	return AccessLogFormatterFunc(func(e *AccessLogEntry) ([]byte, error) {
+		outcome := "success"
+		if e.Status >= 500 {
+			outcome = "failure"
+		}
+		request := map[string]any{
+			"method": e.Method,
+			"body":   ecsBody(e.RequestSize, e.RequestBody),
+		}
+		if e.RequestContentType != "" {
+			request["mime_type"] = e.RequestContentType
+		}
+		if e.Referer != "" {
+			request["referrer"] = e.Referer
+		}
+		doc := map[string]any{
+			"@timestamp": e.Time.UTC().Format(time.RFC3339Nano),
+			"message":    e.message(),
+			"ecs":        map[string]any{"version": ecsVersion},
+			"log":        map[string]any{"level": e.severity(), "logger": "access"},
+			"event": map[string]any{
+				"kind":     "event",
+				"category": []string{"web"},
+				"type":     []string{"access"},
+				"outcome":  outcome,
+				"duration": e.Duration.Nanoseconds(),
+			},
+			"http": map[string]any{
+				"version": e.protoVersion(),
+				"request": request,
+				"response": map[string]any{
+					"status_code": e.Status,
+					"body":        ecsBody(int64(e.ResponseSize), e.ResponseBody),
+				},
+			},
+			"url": map[string]any{
+				"original": e.URI,
+				"path":     e.Path,
+				"query":    e.Query,
+				"scheme":   e.Scheme,
+				"domain":   e.Host,
+			},
+			"client":     map[string]any{"ip": e.ClientIP},
+			"user_agent": map[string]any{"original": e.UserAgent},
+		}
+		if serviceName != "" {
+			doc["service"] = map[string]any{"name": serviceName}
+		}
+		if e.TraceID != "" {
+			doc["trace"] = map[string]any{"id": e.TraceID}
+			doc["span"] = map[string]any{"id": e.SpanID}
+		}
+		if len(e.Errors) > 0 {
+			doc["error"] = map[string]any{"message": strings.Join(e.Errors, "; ")}
+		}
+		return json.Marshal(doc)
+	})
+}
+
+func ecsBody(size int64, content string) map[string]any {
+	body := make(map[string]any, 2)
+	if size >= 0 {
+		body["bytes"] = size
+	}
+	if content != "" {
+		body["content"] = content
+	}
+	return body
+}
+
+// OTelFormatter OpenTelemetry 日志数据模型 JSON，attributes 使用 HTTP 语义约定字段名
+// body 内容不属于语义约定，以 http.request.body.content/http.response.body.content 输出
+func OTelFormatter(serviceName string) AccessLogFormatter {
+	return AccessLogFormatterFunc(func(e *AccessLogEntry) ([]byte, error) {
+		attrs := map[string]any{
+			"http.request.method":          e.Method,
+			"http.response.status_code":    e.Status,
+			"http.response.body.size":      e.ResponseSize,
+			"http.server.request.duration": e.Duration.Seconds(),
+			"url.path":                     e.Path,
+			"url.scheme":                   e.Scheme,
+			"server.address":               e.Host,
+			"client.address":               e.ClientIP,
+			"network.protocol.name":        "http",
+			"network.protocol.version":     e.protoVersion(),
+		}
+		putIfNotEmpty(attrs, "url.query", e.Query)
+		putIfNotEmpty(attrs, "http.route", e.Route)
+		putIfNotEmpty(attrs, "user_agent.original", e.UserAgent)
+		putIfNotEmpty(attrs, "http.request.body.content", e.RequestBody)
+		putIfNotEmpty(attrs, "http.response.body.content", e.ResponseBody)
+		if e.RequestSize >= 0 {
+			attrs["http.request.body.size"] = e.RequestSize
+		}
+		if e.Status >= 500 {
+			attrs["error.type"] = strconv.Itoa(e.Status)
+		}
+
+		record := map[string]any{
+			"timestamp":       e.Time.UTC().Format(time.RFC3339Nano),
+			"severity_text":   strings.ToUpper(e.severity()),
+			"severity_number": otelSeverityNumber(e.Status),
+			"body":            e.message(),
+			"attributes":      attrs,
+		}
+		if serviceName != "" {
+			record["resource"] = map[string]any{"service.name": serviceName}
+		}
+		if e.TraceID != "" {
+			record["trace_id"] = e.TraceID
+			record["span_id"] = e.SpanID
+		}
+		return json.Marshal(record)
+	})
+}
+
+// otelSeverityNumber INFO=9, WARN=13, ERROR=17
+func otelSeverityNumber(status int) int {
+	switch {
+	case status >= 500:
+		return 17
+	case status >= 400:
+		return 13
+	default:
+		return 9
+	}
+}
+
+func putIfNotEmpty(m map[string]any, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// newAccessLogEntry 从请求上下文构建访问日志
+func newAccessLogEntry(c *gin.Context, begin time.Time, elapsed time.Duration) *AccessLogEntry {
+	req := c.Request
+	info := GetClientInfo(c)
+	e := &AccessLogEntry{
+		Time:               begin,
+		Duration:           elapsed,
+		Method:             req.Method,
+		Scheme:             info.Scheme,
+		Host:               info.Host,
+		Path:               req.URL.Path,
+		Query:              req.URL.RawQuery,
+		URI:                req.RequestURI,
+		Route:              c.FullPath(),
+		Handler:            GetHandlerSimpleName(c.HandlerName()),
+		Proto:              req.Proto,
+		ClientIP:           info.IP,
+		UserAgent:          req.UserAgent(),
+		Referer:            req.Referer(),
+		RequestHeader:      filterSensitiveHeaders(req.Header),
+		RequestContentType: req.Header.Get("Content-Type"),
+		RequestSize:        req.ContentLength,
+		Status:             c.Writer.Status(),
+		ResponseHeader:     filterSensitiveHeaders(c.Writer.Header()),
+		ResponseSize:       max(c.Writer.Size(), 0),
+	}
+	if e.URI == "" {
+		e.URI = req.URL.RequestURI()
+	}
+	if sc := trace.SpanFromContext(req.Context()).SpanContext(); sc.IsValid() {
+		e.TraceID = sc.TraceID().String()
+		e.SpanID = sc.SpanID().String()
+	}
+	if len(c.Errors) > 0 {
+		e.Errors = c.Errors.Errors()
+	}
+	return e
+}
+
+// writeAccessLog 格式化并以单次 Write 写入一行，格式化或写入失败时忽略
+func writeAccessLog(w io.Writer, f AccessLogFormatter, e *AccessLogEntry) {
+	line, err := f.Format(e)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(append(line, '\n'))
+}
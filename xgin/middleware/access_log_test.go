@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testAccessLogEntry() *AccessLogEntry {
+	return &AccessLogEntry{
+		Time:               time.Date(2026, 10, 10, 13, 55, 36, 0, time.FixedZone("CST", 8*3600)),
+		Duration:           1500 * time.Millisecond,
+		Method:             "POST",
+		Scheme:             "https",
+		Host:               "api.example.com",
+		Path:               "/users/1",
+		Query:              "dry_run=true",
+		URI:                "/users/1?dry_run=true",
+		Route:              "/users/:id",
+		Proto:              "HTTP/1.1",
+		ClientIP:           "203.0.113.5",
+		UserAgent:          "curl/8.0",
+		RequestContentType: "application/json",
+		RequestSize:        13,
+		RequestBody:        `{"name":"x"}`,
+		Status:             503,
+		ResponseSize:       2326,
+		TraceID:            "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:             "00f067aa0ba902b7",
+		Errors:             []string{"upstream timeout"},
+	}
+}
+
+func TestCombinedFormatter(t *testing.T) {
+	e := testAccessLogEntry()
+	line, err := CombinedFormatter().Format(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `203.0.113.5 - - [10/Oct/2026:13:55:36 +0800] "POST /users/1?dry_run=true HTTP/1.1" 503 2326 "-" "curl/8.0"`
+	if string(line) != want {
+		t.Errorf("got  %s\nwant %s", line, want)
+	}
+
+	e.ResponseSize, e.ClientIP, e.Referer = 0, "", "https://example.com/"
+	line, _ = CombinedFormatter().Format(e)
+	if !strings.HasPrefix(string(line), "- - - [") || !strings.Contains(string(line), `503 - "https://example.com/"`) {
+		t.Errorf("unexpected line %s", line)
+	}
+}
+
+func TestECSFormatter(t *testing.T) {
+	line, err := ECSFormatter("demo").Format(testAccessLogEntry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) any {
+		var cur any = doc
+		for _, k := range strings.Split(path, ".") {
+			cur = cur.(map[string]any)[k]
+		}
+		return cur
+	}
+	checks := map[string]any{
+		"@timestamp":                "2026-10-10T05:55:36Z",
+		"ecs.version":               ecsVersion,
+		"log.level":                 "error",
+		"event.outcome":             "failure",
+		"event.duration":            float64(1500 * time.Millisecond),
+		"http.version":              "1.1",
+		"http.request.method":       "POST",
+		"http.request.body.bytes":   float64(13),
+		"http.request.body.content": `{"name":"x"}`,
+		"http.response.status_code": float64(503),
+		"http.response.body.bytes":  float64(2326),
+		"url.path":                  "/users/1",
+		"url.query":                 "dry_run=true",
+		"url.scheme":                "https",
+		"client.ip":                 "203.0.113.5",
+		"user_agent.original":       "curl/8.0",
+		"service.name":              "demo",
+		"trace.id":                  "4bf92f3577b34da6a3ce929d0e0e4736",
+		"error.message":             "upstream timeout",
+		"message":                   "POST /users/:id 503",
+	}
+	for k, want := range checks {
+		if got := get(k); got != want {
+			t.Errorf("%s = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func TestOTelFormatter(t *testing.T) {
+	e := testAccessLogEntry()
+	e.Status = 404
+	line, err := OTelFormatter("demo").Format(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["severity_text"] != "WARN" || record["severity_number"] != float64(13) {
+		t.Errorf("unexpected severity %v %v", record["severity_text"], record["severity_number"])
+	}
+	if record["trace_id"] != e.TraceID || record["span_id"] != e.SpanID {
+		t.Errorf("unexpected trace context %v %v", record["trace_id"], record["span_id"])
+	}
+	if record["resource"].(map[string]any)["service.name"] != "demo" {
+		t.Errorf("unexpected resource %v", record["resource"])
+	}
+	attrs := record["attributes"].(map[string]any)
+	checks := map[string]any{
+		"http.request.method":          "POST",
+		"http.route":                   "/users/:id",
+		"http.response.status_code":    float64(404),
+		"http.server.request.duration": 1.5,
+		"url.scheme":                   "https",
+		"client.address":               "203.0.113.5",
+		"network.protocol.version":     "1.1",
+	}
+	for k, want := range checks {
+		if attrs[k] != want {
+			t.Errorf("%s = %v, want %v", k, attrs[k], want)
+		}
+	}
+	if _, ok := attrs["error.type"]; ok {
+		t.Error("4xx should not set error.type")
+	}
+}
+
+func TestLogMiddleware_AccessLogOutput(t *testing.T) {
+	var buf bytes.Buffer
+	m, err := NewLogMiddleware(WithAccessLogOutput(&buf, CombinedFormatter()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(m)
+	r.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1?x=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], `"GET /users/1?x=1 HTTP/1.1" 200 5 "-" "test-agent"`) {
+		t.Errorf("unexpected line %s", lines[0])
+	}
+}
+
+func TestLogMiddleware_AccessLogOutputDefaultFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	m, err := NewLogMiddleware(WithAccessLogOutput(&buf, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(m)
+	r.POST("/echo", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.Data(http.StatusOK, "application/json", body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"password":"p"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("expected ECS json, got %q", buf.String())
+	}
+	request := doc["http"].(map[string]any)["request"].(map[string]any)
+	if content := request["body"].(map[string]any)["content"]; content != `{"password":"***FILTERED***"}` {
+		t.Errorf("request body should be redacted, got %v", content)
+	}
+}
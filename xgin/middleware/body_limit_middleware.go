@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitOption 请求 body 大小限制中间件配置
+type BodyLimitOption func(*BodyLimitOptions)
+
+type BodyLimitOptions struct {
+	Routes []BodyLimitRoute // 路由级上限，按前缀最长匹配
+}
+
+// BodyLimitRoute 路由级请求 body 上限
+type BodyLimitRoute struct {
+	Prefix string // 路由前缀，按路径段匹配，如 /upload 匹配 /upload、/upload/:id，不匹配 /uploads
+	Limit  int64  // 上限（字节），<= 0 表示不限制
+}
+
+// WithBodyLimitRoute 为指定路由前缀设置单独的上限，如上传接口放宽限制，limit <= 0 表示不限制
+func WithBodyLimitRoute(prefix string, limit int64) BodyLimitOption {
+	return func(o *BodyLimitOptions) {
+		o.Routes = append(o.Routes, BodyLimitRoute{Prefix: prefix, Limit: limit})
+	}
+}
+
+// GinXBodyLimitMiddleware 限制请求 body 大小，超过 limit 字节时返回 413，limit <= 0 表示不限制（路由级上限仍生效）
+// Content-Length 已超限的请求直接拒绝；chunked 等未声明长度的请求在读取超限时读取方收到错误，
+// handler 未写响应时由中间件返回 413
+func GinXBodyLimitMiddleware(limit int64, opts ...BodyLimitOption) gin.HandlerFunc {
+	o := &BodyLimitOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	routes := append([]BodyLimitRoute{}, o.Routes...)
+	// 前缀长的优先匹配
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].Prefix) > len(routes[j].Prefix) })
+
+	return func(c *gin.Context) {
+		maxSize := bodyLimitOf(c.FullPath(), limit, routes)
+		if maxSize <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxSize {
+			abortBodyTooLarge(c)
+			return
+		}
+
+		body := &maxBytesBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)}
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded && !c.Writer.Written() {
+			abortBodyTooLarge(c)
+		}
+	}
+}
+
+// IsBodyTooLarge 判断读取请求 body 的错误是否由超过大小限制导致，handler 可据此返回 413
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func bodyLimitOf(fullPath string, limit int64, routes []BodyLimitRoute) int64 {
+	for _, r := range routes {
+		if matchPathPrefix(fullPath, r.Prefix) {
+			return r.Limit
+		}
+	}
+	return limit
+}
+
+// matchPathPrefix 按路径段匹配前缀
+func matchPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func abortBodyTooLarge(c *gin.Context) {
+	// 不再读取剩余 body，响应后关闭连接
+	c.Header("Connection", "close")
+	AbortWithErrorResponse(c, http.StatusRequestEntityTooLarge, "request body too large")
+}
+
+// maxBytesBody 记录读取是否超限
+type maxBytesBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && IsBodyTooLarge(err) {
+		b.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chunkedBody 隐藏长度，模拟未声明 Content-Length 的请求
+type chunkedBody struct{ io.Reader }
+
+func newBodyLimitEngine(limit int64, opts ...BodyLimitOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXBodyLimitMiddleware(limit, opts...))
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	}
+	r.POST("/echo", echo)
+	r.POST("/upload/:name", echo)
+	r.POST("/uploads", echo)
+	r.POST("/bind", func(c *gin.Context) {
+		var v map[string]any
+		if err := c.ShouldBindJSON(&v); err != nil {
+			if IsBodyTooLarge(err) {
+				c.String(http.StatusRequestEntityTooLarge, "custom")
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestGinXBodyLimitMiddleware_ContentLength(t *testing.T) {
+	r := newBodyLimitEngine(8)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("12345678")))
+	if w.Code != http.StatusOK || w.Body.String() != "12345678" {
+		t.Fatalf("expected 200 within limit, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("123456789")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "request body too large") || w.Header().Get("Connection") != "close" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+}
+
+func TestGinXBodyLimitMiddleware_Chunked(t *testing.T) {
+	r := newBodyLimitEngine(8)
+
+	req := httptest.NewRequest("POST", "/echo", chunkedBody{strings.NewReader("0123456789")})
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 when read exceeds limit, got %d", w.Code)
+	}
+
+	// handler 已自行响应时不覆盖
+	req = httptest.NewRequest("POST", "/bind", chunkedBody{strings.NewReader(`{"a":"0123456789"}`)})
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != "custom" {
+		t.Fatalf("expected handler response kept, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestGinXBodyLimitMiddleware_Routes(t *testing.T) {
+	r := newBodyLimitEngine(4, WithBodyLimitRoute("/upload", 0), WithBodyLimitRoute("/", 6))
+	body := "0123456789"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/upload/a.txt", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /upload unlimited, got %d", w.Code)
+	}
+
+	// 按路径段匹配，/uploads 不属于 /upload，落到 "/" 规则
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/uploads", strings.NewReader("012345")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /uploads limited by / rule, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/uploads", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestGinXBodyLimitMiddleware_Disabled(t *testing.T) {
+	r := newBodyLimitEngine(0)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader(strings.Repeat("x", 1024))))
+	if w.Code != http.StatusOK || w.Body.Len() != 1024 {
+		t.Fatalf("expected unlimited body, got %d %d", w.Code, w.Body.Len())
+	}
+}
+
+func TestMatchPathPrefix(t *testing.T) {
+	cases := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/upload", "/upload", true},
+		{"/upload/:name", "/upload/", true},
+		{"/uploads", "/upload", false},
+		{"/any", "/", true},
+	}
+	for _, tc := range cases {
+		if got := matchPathPrefix(tc.path, tc.prefix); got != tc.want {
+			t.Errorf("matchPathPrefix(%q, %q) = %v, want %v", tc.path, tc.prefix, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfoContextKey gin.Context 中缓存 ClientInfo 的 key
+const ClientInfoContextKey = "__xgin__client_info__"
+
+// ClientInfo 经过可信代理解析后的客户端信息
+// 日志、限流、IP 过滤等需要客户端身份的逻辑应统一通过 GetClientInfo 获取，保证结果一致
+type ClientInfo struct {
+	IP     string // 客户端 IP
+	Scheme string // 客户端使用的协议（http/https）
+	Host   string // 客户端请求的 Host
+}
+
+// trustedProxySet 可信代理网段集合
+type trustedProxySet struct {
+	nets []*net.IPNet
+}
+
+// trustedProxies 当前生效的可信代理，nil 表示信任所有代理（兼容未配置 TrustedProxies 的旧行为）
+var trustedProxies atomic.Pointer[trustedProxySet]
+
+// SetTrustedProxies 设置可信代理列表（CIDR 或单个 IP），线程安全
+// 传入空列表表示信任所有代理，与未配置时的行为一致
+func SetTrustedProxies(proxies []string) error {
+	if len(proxies) == 0 {
+		trustedProxies.Store(nil)
+		return nil
+	}
+	nets, err := parseCIDRs(proxies)
+	if err != nil {
+		return err
+	}
+	trustedProxies.Store(&trustedProxySet{nets: nets})
+	return nil
+}
+
+// parseCIDRs 解析 CIDR 列表，单个 IP 按 /32 或 /128 处理
+func parseCIDRs(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: p}
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// isTrustedProxy 判断 IP 是否为可信代理
+func isTrustedProxy(ip net.IP) bool {
+	set := trustedProxies.Load()
+	if set == nil {
+		return true
+	}
+	for _, n := range set.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientInfo 获取客户端信息，同一请求内只解析一次
+func GetClientInfo(c *gin.Context) ClientInfo {
+	if v, ok := c.Get(ClientInfoContextKey); ok {
+		if info, ok := v.(ClientInfo); ok {
+			return info
+		}
+	}
+	info := ParseClientInfo(c.Request)
+	c.Set(ClientInfoContextKey, info)
+	return info
+}
+
+// ParseClientIP 解析客户端 IP，等价于 ParseClientInfo(req).IP
+func ParseClientIP(req *http.Request) string {
+	return ParseClientInfo(req).IP
+}
+
+// ParseClientInfo 解析客户端 IP、协议和 Host
+// 仅当直连方（RemoteAddr）是可信代理时才读取代理头，优先级：Forwarded(RFC 7239) > X-Forwarded-For > X-Real-IP
+// 代理链从右向左遍历，跳过可信代理，第一个非可信地址即为客户端 IP；全部可信时取最左侧地址
+func ParseClientInfo(req *http.Request) ClientInfo {
+	info := ClientInfo{
+		IP:     remoteIP(req),
+		Scheme: "http",
+		Host:   req.Host,
+	}
+	if req.TLS != nil {
+		info.Scheme = "https"
+	}
+
+	rip := net.ParseIP(info.IP)
+	if rip == nil || !isTrustedProxy(rip) {
+		return info
+	}
+
+	if req.Header.Get("Forwarded") != "" {
+		hops := parseForwarded(req.Header.Values("Forwarded"))
+		if idx := resolveClientHop(len(hops), func(i int) string { return hops[i].forIP }); idx >= 0 {
+			hop := hops[idx]
+			info.IP = hop.forIP
+			if hop.proto != "" {
+				info.Scheme = strings.ToLower(hop.proto)
+			}
+			if hop.host != "" {
+				info.Host = hop.host
+			}
+		}
+		return info
+	}
+
+	if req.Header.Get("X-Forwarded-For") != "" {
+		ips := splitHeaderList(req.Header.Values("X-Forwarded-For"))
+		if idx := resolveClientHop(len(ips), func(i int) string { return ips[i] }); idx >= 0 {
+			info.IP = ips[idx]
+			if proto := pickForwardedValue(req.Header.Values("X-Forwarded-Proto"), len(ips), idx); proto != "" {
+				info.Scheme = strings.ToLower(proto)
+			}
+			if host := pickForwardedValue(req.Header.Values("X-Forwarded-Host"), len(ips), idx); host != "" {
+				info.Host = host
+			}
+		}
+		return info
+	}
+
+	if header := strings.TrimSpace(req.Header.Get("X-Real-IP")); net.ParseIP(header) != nil {
+		info.IP = header
+	}
+	return info
+}
+
+// resolveClientHop 从右向左遍历代理链，返回客户端所在的下标，链中出现无法解析的地址时返回 -1
+func resolveClientHop(n int, ipAt func(i int) string) int {
+	for i := n - 1; i >= 0; i-- {
+		ip := net.ParseIP(ipAt(i))
+		if ip == nil {
+			return -1
+		}
+		if i == 0 || !isTrustedProxy(ip) {
+			return i
+		}
+	}
+	return -1
+}
+
+// pickForwardedValue 选取 X-Forwarded-Proto/Host 的值
+// 与 X-Forwarded-For 数量一致时取客户端对应位置的值，否则取最右侧（离服务最近的代理写入的）值
+func pickForwardedValue(values []string, hopCount, idx int) string {
+	list := splitHeaderList(values)
+	if len(list) == 0 {
+		return ""
+	}
+	if len(list) == hopCount {
+		return list[idx]
+	}
+	return list[len(list)-1]
+}
+
+// remoteIP 从 RemoteAddr 中提取 IP
+func remoteIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// splitHeaderList 拆分逗号分隔的多值 header（可能出现多行）
+func splitHeaderList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// forwardedHop Forwarded 头中的单个代理节点
+type forwardedHop struct {
+	forIP string
+	proto string
+	host  string
+}
+
+// parseForwarded 解析 RFC 7239 Forwarded 头，如：for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]:4711"
+func parseForwarded(values []string) []forwardedHop {
+	hops := make([]forwardedHop, 0, 2)
+	for _, v := range values {
+		for _, element := range splitQuoted(v, ',') {
+			hop := forwardedHop{}
+			for _, pair := range splitQuoted(element, ';') {
+				k, val, ok := strings.Cut(pair, "=")
+				if !ok {
+					continue
+				}
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				switch strings.ToLower(strings.TrimSpace(k)) {
+				case "for":
+					hop.forIP = forwardedNodeIP(val)
+				case "proto":
+					hop.proto = val
+				case "host":
+					hop.host = val
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// forwardedNodeIP 提取 Forwarded node 中的 IP，去掉端口和 IPv6 方括号
+// "unknown" 或混淆标识（_xxx）返回原值，由调用方按无效地址处理
+func forwardedNodeIP(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// splitQuoted 按分隔符拆分字符串，忽略引号内的分隔符
+func splitQuoted(s string, sep byte) []string {
+	parts := make([]string, 0, 2)
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuote = !inQuote
+		case sep:
+			if !inQuote {
+				if part := strings.TrimSpace(s[start:i]); part != "" {
+					parts = append(parts, part)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
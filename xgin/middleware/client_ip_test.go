@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	defer SetTrustedProxies(nil)
+
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Fatal("expected error for invalid ip")
+	}
+	if err := SetTrustedProxies([]string{"10.0.0.0/99"}); err == nil {
+		t.Fatal("expected error for invalid cidr")
+	}
+}
+
+func TestParseClientInfo(t *testing.T) {
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		headers    map[string]string
+		tls        bool
+		expected   ClientInfo
+	}{
+		{
+			name:       "untrusted remote ignores spoofed XFF",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "203.0.113.9:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "2.2.2.2"},
+			expected:   ClientInfo{IP: "203.0.113.9", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "walk XFF from right skipping trusted hops",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.5, 10.0.0.1"},
+			expected:   ClientInfo{IP: "203.0.113.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "all hops trusted returns leftmost",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.1.1.1, 10.0.0.1"},
+			expected:   ClientInfo{IP: "10.1.1.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "invalid hop falls back to remote",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, garbage"},
+			expected:   ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "XFF proto and host",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "HTTPS", "X-Forwarded-Host": "api.example.com"},
+			expected:   ClientInfo{IP: "1.1.1.1", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "Forwarded header",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": `for=1.1.1.1, for="203.0.113.7:4711";proto=https;host="shop.example.com", for=10.0.0.1`},
+			expected:   ClientInfo{IP: "203.0.113.7", Scheme: "https", Host: "shop.example.com"},
+		},
+		{
+			name:       "Forwarded IPv6",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": `For="[2001:db8:cafe::17]:4711"`},
+			expected:   ClientInfo{IP: "2001:db8:cafe::17", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded takes precedence over XFF",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": "for=1.1.1.1", "X-Forwarded-For": "2.2.2.2"},
+			expected:   ClientInfo{IP: "1.1.1.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded obfuscated identifier falls back to remote",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": "for=_hidden, for=unknown"},
+			expected:   ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Real-IP": "1.1.1.1"},
+			expected:   ClientInfo{IP: "1.1.1.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "TLS scheme",
+			trusted:    nil,
+			remoteAddr: "203.0.113.9:1234",
+			tls:        true,
+			expected:   ClientInfo{IP: "203.0.113.9", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "trust all keeps legacy leftmost behavior",
+			trusted:    nil,
+			remoteAddr: "203.0.113.9:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2"},
+			expected:   ClientInfo{IP: "1.1.1.1", Scheme: "http", Host: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "http://example.com/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			got := ParseClientInfo(req)
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+			if ParseClientIP(req) != tt.expected.IP {
+				t.Errorf("ParseClientIP should agree with ParseClientInfo")
+			}
+		})
+	}
+}
+
+func TestGetClientInfoCached(t *testing.T) {
+	defer SetTrustedProxies(nil)
+	_ = SetTrustedProxies([]string{"10.0.0.0/8"})
+
+	r := gin.New()
+	var first, second ClientInfo
+	r.GET("/test", func(c *gin.Context) {
+		first = GetClientInfo(c)
+		c.Request.Header.Set("X-Forwarded-For", "9.9.9.9")
+		second = GetClientInfo(c)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if first.IP != "1.1.1.1" || second != first {
+		t.Errorf("expected cached client info 1.1.1.1, got %+v / %+v", first, second)
+	}
+}
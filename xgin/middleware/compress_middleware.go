@@ -0,0 +1,427 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+
+	// defaultCompressMinSize 默认最小压缩阈值 1KB，过小的响应压缩收益低于开销
+	defaultCompressMinSize = 1024
+
+	// brotliQuality 在线压缩使用的 brotli 质量，兼顾压缩率与 CPU 开销
+	brotliQuality = 4
+)
+
+// defaultCompressEncodings 服务端编码偏好顺序，客户端 q 值相同时按此顺序选择
+var defaultCompressEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// defaultCompressContentTypes 默认允许压缩的 Content-Type（前缀匹配，忽略参数部分）
+// text/event-stream 默认不压缩，避免中间代理缓冲导致事件延迟，需要时可通过 WithCompressContentTypes 开启
+var defaultCompressContentTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/xml",
+	"text/javascript",
+	"text/csv",
+	"application/json",
+	"application/problem+json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"image/svg+xml",
+}
+
+// CompressOptions 压缩中间件配置
+type CompressOptions struct {
+	MinSize      int      // 最小压缩阈值（字节），响应小于该值时不压缩
+	ContentTypes []string // 允许压缩的 Content-Type 列表
+	Encodings    []string // 支持的编码及服务端偏好顺序
+	SkipPaths    []string // 忽略压缩的路由列表
+}
+
+// CompressOption 配置函数类型
+type CompressOption func(*CompressOptions)
+
+// WithCompressMinSize 设置最小压缩阈值，<= 0 时使用默认值 1KB
+func WithCompressMinSize(size int) CompressOption {
+	return func(o *CompressOptions) {
+		if size > 0 {
+			o.MinSize = size
+		}
+	}
+}
+
+// WithCompressContentTypes 设置允许压缩的 Content-Type，覆盖默认列表
+func WithCompressContentTypes(contentTypes ...string) CompressOption {
+	return func(o *CompressOptions) {
+		if len(contentTypes) > 0 {
+			o.ContentTypes = contentTypes
+		}
+	}
+}
+
+// WithCompressEncodings 设置支持的编码及偏好顺序，可选 zstd、br、gzip，未知编码会被忽略
+func WithCompressEncodings(encodings ...string) CompressOption {
+	return func(o *CompressOptions) {
+		if len(encodings) > 0 {
+			o.Encodings = encodings
+		}
+	}
+}
+
+// WithCompressSkipPaths 设置忽略压缩的路由，匹配规则与 WithSkipPaths 一致
+func WithCompressSkipPaths(paths ...string) CompressOption {
+	return func(o *CompressOptions) {
+		o.SkipPaths = append(o.SkipPaths, paths...)
+	}
+}
+
+// compressor 各编码器的统一抽象，gzip/brotli/zstd 均满足
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressorPools 按编码复用编码器，避免每请求分配大块状态
+var compressorPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotliQuality)
+	}},
+	EncodingZstd: {New: func() any {
+		w, _ := zstd.NewWriter(io.Discard,
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithWindowSize(1<<20),
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+		)
+		return w
+	}},
+}
+
+// GinXCompressMiddleware 响应压缩中间件，根据 Accept-Encoding 协商 zstd/br/gzip
+// 需注册在 LogMiddleware 之前，使日志中间件捕获的是压缩前的 body
+// 使用示例：GinXCompressMiddleware(WithCompressMinSize(2048), WithCompressSkipPaths("/download/"))
+func GinXCompressMiddleware(opts ...CompressOption) gin.HandlerFunc {
+	options := &CompressOptions{
+		MinSize:      defaultCompressMinSize,
+		ContentTypes: defaultCompressContentTypes,
+		Encodings:    defaultCompressEncodings,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	encodings := make([]string, 0, len(options.Encodings))
+	for _, e := range options.Encodings {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if _, ok := compressorPools[e]; ok {
+			encodings = append(encodings, e)
+		}
+	}
+
+	contentTypes := make([]string, 0, len(options.ContentTypes))
+	for _, ct := range options.ContentTypes {
+		contentTypes = append(contentTypes, strings.ToLower(strings.TrimSpace(ct)))
+	}
+
+	exactSkip := make(map[string]bool)
+	prefixSkip := make([]string, 0)
+	for _, p := range options.SkipPaths {
+		if strings.HasSuffix(p, "/") {
+			prefixSkip = append(prefixSkip, p)
+		} else {
+			exactSkip[p] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(encodings) == 0 || c.Request.Method == http.MethodHead ||
+			shouldSkipLog(c.Request.URL.Path, exactSkip, prefixSkip) || isUpgradeRequest(c.Request) {
+			c.Next()
+			return
+		}
+
+		origWriter := c.Writer
+		cw := &compressWriter{
+			ResponseWriter: origWriter,
+			encoding:       negotiateEncoding(c.Request.Header.Get("Accept-Encoding"), encodings),
+			minSize:        options.MinSize,
+			contentTypes:   contentTypes,
+		}
+		c.Writer = cw
+
+		// handler panic 时丢弃未输出的缓冲，交由 recover 中间件写出错误响应
+		finished := false
+		defer func() {
+			if !finished {
+				cw.discard()
+			}
+			c.Writer = origWriter
+		}()
+
+		c.Next()
+
+		cw.finish()
+		finished = true
+	}
+}
+
+// compressWriter 缓冲响应直到达到阈值再决定是否压缩
+// 决定压缩后清除 Content-Length 并设置 Content-Encoding，后续写入经编码器输出到下层 writer
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding     string // 协商出的编码，空表示客户端不接受压缩
+	minSize      int
+	contentTypes []string
+
+	buf     bytes.Buffer
+	decided bool
+	enc     compressor
+	size    int // 写入的未压缩字节数
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.size += len(b)
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() < w.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow 延后到 decide 时再真正写出 header，保证 Content-Encoding 能被设置
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Written() bool {
+	return w.size > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if w.size > 0 {
+		return w.size
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush 流式响应（含 SSE）立即做出压缩决定，并依次刷新编码器与下层 writer
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap 供 http.ResponseController 访问底层 writer（设置写超时等）
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide 根据状态码、header 与缓冲内容决定是否压缩，并写出缓冲数据
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	if w.shouldCompress() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// 压缩后的实体与原实体字节不同，强 ETag 降级为弱 ETag
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = compressorPools[w.encoding].Get().(compressor)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	h := w.Header()
+	ct := h.Get("Content-Type")
+	if ct == "" && w.buf.Len() > 0 {
+		ct = http.DetectContentType(w.buf.Bytes())
+		h.Set("Content-Type", ct)
+	}
+	if !matchContentType(ct, w.contentTypes) {
+		return false
+	}
+
+	// 响应可压缩时无论本次是否压缩都需声明 Vary，避免共享缓存返回错误编码
+	h.Add("Vary", "Accept-Encoding")
+
+	if w.encoding == "" {
+		return false
+	}
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-transform") {
+		return false
+	}
+	return true
+}
+
+// finish 请求结束时输出剩余缓冲（不足阈值则原样输出），关闭编码器并归还 pool
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if w.buf.Len() < w.minSize {
+			// 未达到阈值，原样输出（仍按 Content-Type 声明 Vary）
+			w.encoding = ""
+		}
+		_ = w.decide()
+	}
+	w.release()
+}
+
+// discard 丢弃尚未输出的缓冲并释放编码器
+func (w *compressWriter) discard() {
+	if !w.decided {
+		w.decided = true
+		w.buf.Reset()
+	}
+	w.release()
+}
+
+// release 关闭编码器并归还 pool
+func (w *compressWriter) release() {
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(io.Discard)
+		compressorPools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// matchContentType 判断 Content-Type（忽略参数部分）是否在允许列表中
+func matchContentType(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return false
+	}
+	if idx := strings.IndexByte(contentType, ';'); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, a := range allowed {
+		if strings.HasPrefix(contentType, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding 解析 Accept-Encoding，返回 q 值最高的支持编码，q 值相同时按服务端偏好顺序
+// 未携带 Accept-Encoding 或所有编码 q=0 时返回空字符串
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+
+	qValues := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseQValue(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		qValues[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qValues[enc]
+		if !ok {
+			if wildcard < 0 {
+				continue
+			}
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// parseQValue 解析 "gzip;q=0.8" 形式的条目，未声明 q 时默认为 1
+func parseQValue(part string) (string, float64) {
+	segments := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(segments[0]))
+	q := 1.0
+	for _, seg := range segments[1:] {
+		seg = strings.TrimSpace(seg)
+		if len(seg) > 2 && (seg[0] == 'q' || seg[0] == 'Q') && seg[1] == '=' {
+			v, err := strconv.ParseFloat(seg[2:], 64)
+			if err != nil {
+				return "", 0
+			}
+			q = v
+		}
+	}
+	return name, q
+}
+
+// isUpgradeRequest 判断是否为协议升级请求（如 WebSocket），此类请求不做压缩包装
+func isUpgradeRequest(req *http.Request) bool {
+	return strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade")
+}
@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+var largeJSON = `{"data":"` + strings.Repeat("abcdefgh", 512) + `"}`
+
+func newCompressEngine(opts ...CompressOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXCompressMiddleware(opts...))
+	r.GET("/large", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(largeJSON))
+	})
+	r.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096))
+	})
+	r.GET("/no-content", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/no-transform", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-transform")
+		c.Data(http.StatusOK, "application/json", []byte(largeJSON))
+	})
+	return r
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		r = gr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("zstd reader: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(out)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := defaultCompressEncodings
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"*", "zstd"},
+		{"*;q=0.5, zstd;q=0", "br"},
+		{"gzip;q=0", ""},
+		{"GZIP", "gzip"},
+		{"gzip;q=abc", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.accept, supported); got != tt.expected {
+			t.Errorf("negotiateEncoding(%q) expected %q, got %q", tt.accept, tt.expected, got)
+		}
+	}
+
+	if got := negotiateEncoding("gzip, br, zstd", []string{EncodingGzip, EncodingZstd}); got != "gzip" {
+		t.Errorf("server preference should win on tie, got %q", got)
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	if !matchContentType("Application/JSON; charset=utf-8", defaultCompressContentTypes) {
+		t.Error("json should be compressible")
+	}
+	if matchContentType("image/png", defaultCompressContentTypes) {
+		t.Error("png should not be compressible")
+	}
+	if matchContentType("text/event-stream", defaultCompressContentTypes) {
+		t.Error("event-stream should not be compressible by default")
+	}
+	if matchContentType("", defaultCompressContentTypes) {
+		t.Error("empty content type should not be compressible")
+	}
+}
+
+func TestCompressMiddleware_Encodings(t *testing.T) {
+	r := newCompressEngine()
+	for _, enc := range []string{EncodingGzip, EncodingBrotli, EncodingZstd} {
+		t.Run(enc, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/large", nil)
+			req.Header.Set("Accept-Encoding", enc)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != enc {
+				t.Fatalf("expected Content-Encoding %s, got %q", enc, got)
+			}
+			if w.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length should be removed")
+			}
+			if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+				t.Error("Vary: Accept-Encoding should be set")
+			}
+			if w.Body.Len() >= len(largeJSON) {
+				t.Errorf("body should be smaller after compression, got %d", w.Body.Len())
+			}
+			if got := decode(t, enc, w.Body.Bytes()); got != largeJSON {
+				t.Error("decoded body mismatch")
+			}
+		})
+	}
+}
+
+func TestCompressMiddleware_Skips(t *testing.T) {
+	r := newCompressEngine(WithCompressSkipPaths("/skip/"))
+	r.GET("/skip/large", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(largeJSON))
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		vary   bool
+	}{
+		{"below min size", "/small", "gzip", true},
+		{"content type not allowed", "/binary", "gzip", false},
+		{"no accept encoding", "/large", "", true},
+		{"no content", "/no-content", "gzip", false},
+		{"no-transform", "/no-transform", "gzip", true},
+		{"skip path", "/skip/large", "gzip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Fatalf("expected no Content-Encoding, got %q", got)
+			}
+			if got := strings.Contains(w.Header().Get("Vary"), "Accept-Encoding"); got != tt.vary {
+				t.Errorf("expected Vary set=%v, got %v", tt.vary, got)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != `{"ok":true}` {
+		t.Errorf("small body should be passed through, got %q", w.Body.String())
+	}
+}
+
+func TestCompressMiddleware_StrongETagWeakened(t *testing.T) {
+	r := newCompressEngine()
+	r.GET("/etag", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.Data(http.StatusOK, "application/json", []byte(largeJSON))
+	})
+	req := httptest.NewRequest("GET", "/etag", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("ETag"); got != `W/"v1"` {
+		t.Errorf("expected weak etag, got %q", got)
+	}
+}
+
+func TestCompressMiddleware_ContentTypeSniff(t *testing.T) {
+	r := newCompressEngine()
+	r.GET("/sniff", func(c *gin.Context) {
+		_, _ = c.Writer.Write([]byte("<html>" + strings.Repeat("x", 2048) + "</html>"))
+	})
+	req := httptest.NewRequest("GET", "/sniff", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Errorf("sniffed html should be compressed, headers=%v", w.Header())
+	}
+}
+
+func TestCompressMiddleware_StreamFlush(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXCompressMiddleware(WithCompressContentTypes("text/event-stream")))
+	r.GET("/sse", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			c.SSEvent("message", "hello")
+			c.Writer.Flush()
+		}
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/sse", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("expected gzip stream, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(gr)
+	events := 0
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data:") {
+			events++
+		}
+	}
+	if events != 3 {
+		t.Errorf("expected 3 events, got %d", events)
+	}
+}
+
+func TestCompressMiddleware_SSENotCompressedByDefault(t *testing.T) {
+	r := newCompressEngine()
+	r.GET("/sse", func(c *gin.Context) {
+		c.SSEvent("message", "hello")
+		c.Writer.Flush()
+	})
+	req := httptest.NewRequest("GET", "/sse", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Error("event-stream should not be compressed by default")
+	}
+	if !w.Flushed || !strings.Contains(w.Body.String(), "data:hello") {
+		t.Errorf("event should be flushed as-is, body=%q", w.Body.String())
+	}
+}
+
+func TestCompressMiddleware_PanicDiscardsBuffer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXRecoverMiddleware(nil))
+	r.Use(GinXCompressMiddleware())
+	r.GET("/panic", func(c *gin.Context) {
+		_, _ = c.Writer.Write([]byte(`{"partial":`))
+		panic("boom")
+	})
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "partial") {
+		t.Errorf("partial body should be discarded, got %q", w.Body.String())
+	}
+}
+
+func TestCompressMiddleware_LogCapturesUncompressedBody(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXCompressMiddleware(WithCompressMinSize(16)))
+	r.Use(LogMiddleware())
+	body := `{"message":"` + strings.Repeat("z", 64) + `"}`
+	r.GET("/json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(body))
+	})
+
+	req := httptest.NewRequest("GET", "/json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("expected gzip response")
+	}
+	if got := decode(t, EncodingGzip, w.Body.Bytes()); got != body {
+		t.Fatalf("decoded body mismatch: %q", got)
+	}
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected log entry")
+	}
+	if entry.Data["response_body"] != body {
+		t.Errorf("log should capture uncompressed body, got %v", entry.Data["response_body"])
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExtendDeadline 通过 http.ResponseController 重设当前连接的读写超时为 now+d，d <= 0 时清除超时
+// 用于下载、长轮询等耗时超过 XGin.WriteTimeout/ReadTimeout 的路由：
+// 超过 WriteTimeout 后响应无法写出，超过 ReadTimeout 后 HTTP/1 连接的请求 context 会被取消
+// 底层连接不支持时返回 http.ErrNotSupported
+func ExtendDeadline(c *gin.Context, d time.Duration) error {
+	var deadline time.Time
+	if d > 0 {
+		deadline = time.Now().Add(d)
+	}
+	rc := http.NewResponseController(c.Writer)
+	return errors.Join(rc.SetWriteDeadline(deadline), rc.SetReadDeadline(deadline))
+}
+
+// GinXDeadlineMiddleware 路由级读写超时，覆盖 XGin.WriteTimeout/ReadTimeout，d <= 0 表示不限制
+// 使用示例：
+//
+//	e.GET("/export", middleware.GinXDeadlineMiddleware(10*time.Minute), exportHandler)
+func GinXDeadlineMiddleware(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = ExtendDeadline(c, d)
+		c.Next()
+	}
+}
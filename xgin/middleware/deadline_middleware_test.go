@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinXDeadlineMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	slow := func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	}
+	r.GET("/slow", slow)
+	r.GET("/extended", GinXDeadlineMiddleware(2*time.Second), slow)
+	r.GET("/unlimited", GinXDeadlineMiddleware(0), slow)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	get := func(path string) (string, error) {
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if _, err := get("/slow"); err == nil {
+		t.Fatal("expected write timeout for route without deadline extension")
+	}
+	for _, path := range []string{"/extended", "/unlimited"} {
+		body, err := get(path)
+		if err != nil || body != "done" {
+			t.Fatalf("%s: expected done, got %q err=%v", path, body, err)
+		}
+	}
+}
+
+func TestExtendDeadline_NotSupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if err := ExtendDeadline(c, time.Second); err == nil {
+		t.Fatal("expected error for writer without deadline support")
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeprecationOption 废弃声明中间件配置
+type DeprecationOption func(*DeprecationOptions)
+
+type DeprecationOptions struct {
+	Since  time.Time // 废弃时间，为零值时 Deprecation 头输出 true
+	Sunset time.Time // 计划下线时间，为零值时不输出 Sunset 头
+	Link   string    // 迁移说明文档地址，以 Link rel="deprecation" 输出
+}
+
+// WithDeprecatedSince 设置废弃时间，按 RFC 9745 输出 Deprecation: @<unix 秒>
+func WithDeprecatedSince(t time.Time) DeprecationOption {
+	return func(o *DeprecationOptions) {
+		o.Since = t
+	}
+}
+
+// WithSunset 设置计划下线时间，按 RFC 8594 输出 Sunset: <HTTP-date>
+func WithSunset(t time.Time) DeprecationOption {
+	return func(o *DeprecationOptions) {
+		o.Sunset = t
+	}
+}
+
+// WithDeprecationLink 设置迁移说明文档地址
+func WithDeprecationLink(url string) DeprecationOption {
+	return func(o *DeprecationOptions) {
+		o.Link = url
+	}
+}
+
+// GinXDeprecationMiddleware 为已废弃的路由输出 Deprecation/Sunset/Link 响应头
+// 使用示例：
+//
+//	v1 := e.Group("/v1", middleware.GinXDeprecationMiddleware(
+//		middleware.WithSunset(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)),
+//		middleware.WithDeprecationLink("https://example.com/migrate-v2"),
+//	))
+func GinXDeprecationMiddleware(opts ...DeprecationOption) gin.HandlerFunc {
+	options := &DeprecationOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	deprecation := "true"
+	if !options.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(options.Since.Unix(), 10)
+	}
+	sunset := ""
+	if !options.Sunset.IsZero() {
+		sunset = options.Sunset.UTC().Format(http.TimeFormat)
+	}
+	link := ""
+	if options.Link != "" {
+		link = "<" + options.Link + `>; rel="deprecation"; type="text/html"`
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		if sunset != "" {
+			h.Set("Sunset", sunset)
+		}
+		if link != "" {
+			h.Add("Link", link)
+		}
+		c.Next()
+	}
+}
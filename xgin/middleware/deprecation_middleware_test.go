@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinXDeprecationMiddleware_Default(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/old", GinXDeprecationMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation true, got %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "" {
+		t.Errorf("expected no Sunset header, got %q", got)
+	}
+	if got := w.Header().Get("Link"); got != "" {
+		t.Errorf("expected no Link header, got %q", got)
+	}
+}
+
+func TestGinXDeprecationMiddleware_WithOptions(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 0, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/old", GinXDeprecationMiddleware(
+		WithDeprecatedSince(since),
+		WithSunset(sunset),
+		WithDeprecationLink("https://example.com/migrate"),
+	), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+
+	if got := w.Header().Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("unexpected Deprecation %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Wed, 30 Dec 2026 16:00:00 GMT" {
+		t.Errorf("unexpected Sunset %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://example.com/migrate>; rel="deprecation"; type="text/html"` {
+		t.Errorf("unexpected Link %q", got)
+	}
+}
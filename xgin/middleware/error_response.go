@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse XGin 内置中间件统一的错误响应结构
+type ErrorResponse struct {
+	Code    int    `json:"code"`              // HTTP 状态码
+	Msg     string `json:"msg"`               // 错误描述
+	Details any    `json:"details,omitempty"` // 错误详情（如字段级校验错误）
+}
+
+// AbortWithErrorResponse 以统一错误结构响应并中断后续 handler
+// details 可选，仅取第一个
+func AbortWithErrorResponse(c *gin.Context, status int, msg string, details ...any) {
+	resp := ErrorResponse{Code: status, Msg: msg}
+	if len(details) > 0 {
+		resp.Details = details[0]
+	}
+	c.AbortWithStatusJSON(status, resp)
+}
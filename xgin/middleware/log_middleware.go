@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,37 +19,95 @@ import (
 const (
 	FilteredValue = "***FILTERED***"
 
+	// unparsableBodyValue 需要脱敏的 JSON body 无法解析（如超过读取上限被截断）时记录的占位值，避免敏感字段原样输出
+	unparsableBodyValue = "[unparsable body omitted]"
+
 	// maxRequestBodySize 请求 body 读取上限 256KB
 	maxRequestBodySize = 256 * 1024
 
 	// maxResponseBodyCapture 响应 body 捕获上限 4KB
 	maxResponseBodyCapture = 4 * 1024
+
+	// LogBodyCaptureContextKey 当前请求是否记录请求/响应 body，由路由组策略设置
+	LogBodyCaptureContextKey = "__xgin_log_body_capture__"
 )
 
+// SetLogBodyCapture 设置当前请求的日志是否记录请求/响应 body，需在 LogMiddleware 之后的中间件或 handler 中调用
+func SetLogBodyCapture(c *gin.Context, enable bool) {
+	c.Set(LogBodyCaptureContextKey, enable)
+}
+
+// logBodyCaptureEnabled 未设置时默认记录
+func logBodyCaptureEnabled(c *gin.Context) bool {
+	v, ok := c.Get(LogBodyCaptureContextKey)
+	if !ok {
+		return true
+	}
+	enable, ok := v.(bool)
+	return !ok || enable
+}
+
 // newlineReplacer 复用的换行符替换器，避免每请求创建新实例
 var newlineReplacer = strings.NewReplacer("\r\n", "", "\r", "", "\n", "")
 
-// responseBodyWriter 包装 gin.ResponseWriter，捕获响应 body（仅文本类型且 <= 4KB）
+// responseBodyWriter 包装 gin.ResponseWriter，捕获响应 body（仅文本类型且不超过 limit，默认 4KB）
 type responseBodyWriter struct {
 	gin.ResponseWriter
 	body        *bytes.Buffer
 	captureBody bool
+	limit       int  // 捕获上限，<= 0 时使用 maxResponseBodyCapture
+	truncated   bool // 响应超过捕获上限，不再记录
+	streamed    bool // handler 调用过 Flush，视为流式响应
 }
 
 func (w *responseBodyWriter) Write(b []byte) (int, error) {
-	if w.captureBody && w.body.Len()+len(b) <= maxResponseBodyCapture {
-		w.body.Write(b)
+	if w.captureBody && !w.truncated {
+		limit := w.limit
+		if limit <= 0 {
+			limit = maxResponseBodyCapture
+		}
+		if w.body.Len()+len(b) <= limit {
+			w.body.Write(b)
+		} else {
+			w.truncated = true
+			w.body.Reset()
+		}
 	}
 	return w.ResponseWriter.Write(b)
 }
 
+// Flush 流式响应（SSE/chunked）不再捕获 body，只记录统计信息
+func (w *responseBodyWriter) Flush() {
+	if !w.streamed {
+		w.streamed = true
+		w.captureBody = false
+		w.body.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap 供 http.ResponseController 访问底层 writer（设置写超时等）
+func (w *responseBodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// defaultTextContentTypes 默认记录响应 body 的 Content-Type
+var defaultTextContentTypes = []string{"application/json", "text/", "application/xml", "application/javascript"}
+
 // isTextContentType 判断是否为文本类型的 Content-Type
 func isTextContentType(contentType string) bool {
+	return containsContentType(contentType, defaultTextContentTypes)
+}
+
+// containsContentType Content-Type 包含任一类型（忽略大小写）即匹配
+func containsContentType(contentType string, types []string) bool {
 	ct := strings.ToLower(contentType)
-	return strings.Contains(ct, "application/json") ||
-		strings.Contains(ct, "text/") ||
-		strings.Contains(ct, "application/xml") ||
-		strings.Contains(ct, "application/javascript")
+	for _, t := range types {
+		if strings.Contains(ct, t) {
+			return true
+		}
+	}
+	return false
 }
 
 // formatElapsed 将耗时格式化为人性化字符串
@@ -92,7 +149,14 @@ var (
 
 // LogOptions 日志中间件配置
 type LogOptions struct {
-	SkipPaths []string // 忽略日志记录的路由列表
+	SkipPaths        []string           // 忽略日志记录的路由列表
+	Policy           LogPolicy          // 默认日志策略
+	RoutePolicies    []logRoutePolicy   // 路由级日志策略
+	TextContentTypes []string           // 记录响应 body 的 Content-Type，为空时使用默认列表
+	RedactPaths      []string           // JSONPath 脱敏规则
+	MaskRules        []LogMaskRule      // 正则脱敏规则
+	Output           io.Writer          // 访问日志输出，为 nil 时写入 xlog 应用日志
+	Formatter        AccessLogFormatter // 访问日志格式，Output 非 nil 时生效，默认 ECS
 }
 
 // LogOption 配置函数类型
@@ -161,15 +225,65 @@ var rbwPool = sync.Pool{
 	},
 }
 
-// LogMiddleware 请求日志中间件
+// LogMiddleware 请求日志中间件，无效的脱敏规则会被忽略并输出 Warn 日志，需要校验时使用 NewLogMiddleware
 // 使用示例：LogMiddleware(WithSkipPaths("/health", "/metrics", "/api/internal/"))
 func LogMiddleware(opts ...LogOption) gin.HandlerFunc {
+	m, err := NewLogMiddleware(opts...)
+	if err != nil {
+		logrus.Warnf("[XGin-LogMiddleware] invalid redact rules ignored, err=[%v]", err)
+		m, _ = NewLogMiddleware(append(opts, func(o *LogOptions) {
+			o.RedactPaths, o.MaskRules = nil, nil
+		})...)
+	}
+	return m
+}
+
+// NewLogMiddleware 创建请求日志中间件，JSONPath 或正则脱敏规则无效时返回 error
+// 使用示例：
+//
+//	NewLogMiddleware(
+//		WithLogPolicy(LogPolicy{Body: LogBodyRequest}),
+//		WithLogRoutePolicy("", "/api/health/", LogPolicy{Body: LogBodyNone, SampleRate: 0.01}),
+//		WithLogRedactPaths("$.user.card.number"),
+//		WithLogMaskRules(LogMaskRule{Pattern: `1[3-9]\d{9}`}),
+//	)
+func NewLogMiddleware(opts ...LogOption) (gin.HandlerFunc, error) {
 	// 应用配置
-	options := &LogOptions{}
+	options := &LogOptions{Policy: DefaultLogPolicy()}
 	for _, opt := range opts {
 		opt(options)
 	}
 
+	redactor, err := newLogRedactor(options.RedactPaths, options.MaskRules)
+	if err != nil {
+		return nil, err
+	}
+	defaultPolicy := options.Policy.normalize()
+	routePolicies := make([]logRoutePolicy, len(options.RoutePolicies))
+	for i, rp := range options.RoutePolicies {
+		rp.policy = rp.policy.normalize()
+		routePolicies[i] = rp
+	}
+	textContentTypes := defaultTextContentTypes
+	if len(options.TextContentTypes) > 0 {
+		textContentTypes = make([]string, len(options.TextContentTypes))
+		for i, t := range options.TextContentTypes {
+			textContentTypes[i] = strings.ToLower(t)
+		}
+	}
+	formatter := options.Formatter
+	if formatter == nil {
+		formatter = ECSFormatter("")
+	}
+	policyOf := func(c *gin.Context) LogPolicy {
+		for i := range routePolicies {
+			if routePolicies[i].match(c) {
+				return routePolicies[i].policy
+			}
+		}
+		return defaultPolicy
+	}
+
 	// 预处理 skipPaths：精确匹配用 map，前缀匹配用 slice
 	exactSkip := make(map[string]bool)
 	prefixSkip := make([]string, 0)
@@ -189,16 +303,24 @@ func LogMiddleware(opts ...LogOption) gin.HandlerFunc {
 		}
 
 		begin := time.Now()
+		policy := policyOf(c)
 
 		// 在处理前准备 body 快照（不消耗原始 body）
-		bodyBytes, bodyBuf := getBodySnapshot(c.Request)
+		var bodyBytes []byte
+		var bodyBuf *bytes.Buffer
+		if policy.Body.request() {
+			bodyBytes, bodyBuf = getBodySnapshotLimit(c.Request, policy.MaxRequestBodySize)
+		}
 
 		// 从 pool 获取 responseBodyWriter，保存原始 writer 以便归还后恢复
 		origWriter := c.Writer
 		rbw := rbwPool.Get().(*responseBodyWriter)
 		rbw.ResponseWriter = origWriter
 		rbw.body.Reset()
-		rbw.captureBody = true
+		rbw.captureBody = policy.Body.response()
+		rbw.limit = policy.MaxResponseBodySize
+		rbw.truncated = false
+		rbw.streamed = false
 		c.Writer = rbw
 
 		// 继续处理
@@ -206,28 +328,67 @@ func LogMiddleware(opts ...LogOption) gin.HandlerFunc {
 
 		elapsed := time.Since(begin)
 
+		// 成功请求按采样率记录，未采中时直接归还 writer
+		if !policy.sampled(c) {
+			c.Writer = origWriter
+			rbw.ResponseWriter = nil
+			rbwPool.Put(rbw)
+			return
+		}
+
 		// 如果 body 未预读，则从读取过程中捕获的 buffer 获取（拷贝一份，避免引用被后续修改）
-		if bodyBytes == nil && bodyBuf != nil {
+		captureBody := logBodyCaptureEnabled(c)
+		if !captureBody {
+			bodyBytes = nil
+		} else if bodyBytes == nil && bodyBuf != nil {
 			bodyBytes = append([]byte(nil), bodyBuf.Bytes()...)
 		}
 
-		requestInfo := ParseRequestInfoWithBody(c.Request, bodyBytes)
-		requestInfo["process_latency"] = elapsed.Milliseconds()
-		requestInfo["process_latency_human"] = formatElapsed(elapsed)
-		requestInfo["response_header"] = ToJsonString(filterSensitiveHeaders(c.Writer.Header()))
-		requestInfo["response_status"] = c.Writer.Status()
+		requestBody := redactor.redact(bodyBytes, c.Request.Header.Get("Content-Type"))
 
-		// 捕获响应 body（仅文本类型且 <= 4KB）
-		respContentType := c.Writer.Header().Get("Content-Type")
-		if isTextContentType(respContentType) && rbw.body.Len() > 0 && rbw.body.Len() <= maxResponseBodyCapture {
-			requestInfo["response_body"] = rbw.body.String()
+		// 捕获响应 body（仅文本类型且不超过上限），流式响应只记录统计信息
+		stats, isStream := GetStreamStats(c)
+		responseBody := ""
+		if !isStream && !rbw.streamed && captureBody && rbw.captureBody && !rbw.truncated {
+			respContentType := c.Writer.Header().Get("Content-Type")
+			if containsContentType(respContentType, textContentTypes) && rbw.body.Len() > 0 {
+				responseBody = redactor.redact(rbw.body.Bytes(), respContentType)
+			}
 		}
+		streamed := isStream || rbw.streamed
 
 		// 恢复原始 writer（防止外层中间件访问已归还的 rbw），然后归还 pool
 		c.Writer = origWriter
 		rbw.ResponseWriter = nil
 		rbwPool.Put(rbw)
 
+		// 配置了访问日志输出时按格式写入，不再写入应用日志
+		if options.Output != nil {
+			e := newAccessLogEntry(c, begin, elapsed)
+			e.RequestBody = requestBody
+			e.ResponseBody = responseBody
+			e.Streamed = streamed
+			writeAccessLog(options.Output, formatter, e)
+			return
+		}
+
+		requestInfo := ParseRequestInfo(c.Request)
+		requestInfo["request_clientIP"] = GetClientInfo(c).IP
+		requestInfo["request_body"] = requestBody
+		requestInfo["process_latency"] = elapsed.Milliseconds()
+		requestInfo["process_latency_human"] = formatElapsed(elapsed)
+		requestInfo["response_header"] = ToJsonString(filterSensitiveHeaders(c.Writer.Header()))
+		requestInfo["response_status"] = c.Writer.Status()
+		if isStream {
+			for k, v := range stats.logFields() {
+				requestInfo[k] = v
+			}
+		} else if streamed {
+			requestInfo["response_streamed"] = true
+		} else if responseBody != "" {
+			requestInfo["response_body"] = responseBody
+		}
+
 		// 构建日志描述：METHOD 路由 (HandlerName)
 		route := c.FullPath()
 		if route == "" {
@@ -238,7 +399,7 @@ func LogMiddleware(opts ...LogOption) gin.HandlerFunc {
 			desc += " (" + handlerName + ")"
 		}
 		logrus.WithContext(c.Request.Context()).WithFields(requestInfo).Infof("[XGin-LogMiddleware] %s request processed.", desc)
-	}
+	}, nil
 }
 
 // shouldSkipLog 检查是否应该跳过日志记录
@@ -313,6 +474,11 @@ func (b *bodyCapture) Close() error {
 // 优先使用 GetBody 获取副本，不消耗原 Body
 // 若无法获取副本，则包装 Body，在下游读取时捕获（最多 maxRequestBodySize）
 func getBodySnapshot(req *http.Request) ([]byte, *bytes.Buffer) {
+	return getBodySnapshotLimit(req, maxRequestBodySize)
+}
+
+// getBodySnapshotLimit 同 getBodySnapshot，最多读取 limit 字节
+func getBodySnapshotLimit(req *http.Request, limit int) ([]byte, *bytes.Buffer) {
 	if req == nil || req.Body == nil || req.Body == http.NoBody {
 		return nil, nil
 	}
@@ -334,41 +500,17 @@ func getBodySnapshot(req *http.Request) ([]byte, *bytes.Buffer) {
 		body, err := req.GetBody()
 		if err == nil {
 			defer body.Close()
-			bodyBytes, _ := io.ReadAll(io.LimitReader(body, maxRequestBodySize))
+			bodyBytes, _ := io.ReadAll(io.LimitReader(body, int64(limit)))
 			return bodyBytes, nil
 		}
 	}
 
 	// 降级：包装 Body，在下游读取时捕获快照（不影响读取）
 	buf := &bytes.Buffer{}
-	req.Body = &bodyCapture{rc: req.Body, buf: buf, limit: maxRequestBodySize}
+	req.Body = &bodyCapture{rc: req.Body, buf: buf, limit: limit}
 	return nil, buf
 }
 
-func ParseClientIP(req *http.Request) string {
-	// 需要上游nginx配置 X-Forwarded-For 或者 X-Real-IP
-	if header := req.Header.Get("X-Forwarded-For"); header != "" {
-		ip := header
-		if idx := strings.IndexByte(header, ','); idx >= 0 {
-			ip = header[:idx]
-		}
-		ip = strings.TrimSpace(ip)
-		if net.ParseIP(ip) != nil {
-			return ip
-		}
-	}
-
-	if header := req.Header.Get("X-Real-IP"); net.ParseIP(header) != nil {
-		return header
-	}
-
-	// 回退到 RemoteAddr
-	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
-		return host
-	}
-	return req.RemoteAddr
-}
-
 func ToJsonString(v any) string {
 	s, _ := json.Marshal(v)
 	return string(s)
@@ -511,8 +653,8 @@ func filterJSONBody(bodyBytes []byte) string {
 
 	var data any
 	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		// JSON 解析失败，回退为去换行后的字符串
-		return newlineReplacer.Replace(string(bodyBytes))
+		// 可能包含敏感字段但无法解析（如被截断），不能原样输出
+		return unparsableBodyValue
 	}
 
 	fieldMap := getSensitiveFieldMap()
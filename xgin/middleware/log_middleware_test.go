@@ -14,6 +14,8 @@ import (
 
 	. "github.com/bytedance/mockey"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -318,6 +320,12 @@ func TestFilterJSONBodyInvalidJSON(t *testing.T) {
 	if result != string(invalidJSON) {
 		t.Error("invalid JSON should be returned as-is")
 	}
+
+	// 被截断且包含敏感字段的 body 不输出原文
+	truncated := []byte(`{"username":"john","password":"secret","data":"aaaa`)
+	if result := filterJSONBody(truncated); result != unparsableBodyValue {
+		t.Errorf("truncated body with sensitive field should be omitted, got %s", result)
+	}
 }
 
 func TestFilterFormBodyEmptyPair(t *testing.T) {
@@ -1021,3 +1029,61 @@ func TestFilterJSONBody_FastPath(t *testing.T) {
 		t.Errorf("fast path should return body as-is, got %s", result)
 	}
 }
+
+func TestLogMiddleware_BodyCaptureDisabled(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LogMiddleware())
+	r.POST("/secret", func(c *gin.Context) {
+		SetLogBodyCapture(c, false)
+		c.JSON(http.StatusOK, gin.H{"token": "t"})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/secret", strings.NewReader(`{"card":"1234"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected log entry")
+	}
+	if body := entry.Data["request_body"]; body != "" {
+		t.Errorf("request body should not be logged, got %v", body)
+	}
+	if _, ok := entry.Data["response_body"]; ok {
+		t.Errorf("response body should not be logged, got %v", entry.Data["response_body"])
+	}
+	if entry.Data["response_status"] != http.StatusOK {
+		t.Errorf("access log should still be written, got %v", entry.Data["response_status"])
+	}
+}
+
+func TestLogMiddleware_ClientIPFromClientInfo(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LogMiddleware())
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Forwarded", "for=203.0.113.5;proto=https")
+	r.ServeHTTP(w, req)
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected log entry")
+	}
+	if ip := entry.Data["request_clientIP"]; ip != "203.0.113.5" {
+		t.Errorf("expected client ip from Forwarded, got %v", ip)
+	}
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogBodyMode 请求日志记录 body 的范围
+type LogBodyMode string
+
+const (
+	LogBodyNone     LogBodyMode = "none"     // 不记录 body
+	LogBodyRequest  LogBodyMode = "request"  // 仅记录请求 body
+	LogBodyResponse LogBodyMode = "response" // 仅记录响应 body
+	LogBodyBoth     LogBodyMode = "both"     // 记录请求和响应 body（默认）
+)
+
+// ParseLogBodyMode 解析 body 记录范围，空字符串返回默认值 both
+func ParseLogBodyMode(s string) (LogBodyMode, error) {
+	switch m := LogBodyMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return LogBodyBoth, nil
+	case LogBodyNone, LogBodyRequest, LogBodyResponse, LogBodyBoth:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid body mode %q, must be one of none/request/response/both", s)
+	}
+}
+
+func (m LogBodyMode) request() bool {
+	return m == LogBodyRequest || m == LogBodyBoth
+}
+
+func (m LogBodyMode) response() bool {
+	return m == LogBodyResponse || m == LogBodyBoth
+}
+
+// LogPolicy 请求日志策略
+type LogPolicy struct {
+	Body                LogBodyMode // body 记录范围，默认 both
+	MaxRequestBodySize  int         // 请求 body 读取上限，默认 256KB
+	MaxResponseBodySize int         // 响应 body 捕获上限，超过则不记录，默认 4KB
+	SampleRate          float64     // 成功请求（status < 400 且无 c.Errors）的采样率，取值 (0, 1]，<= 0 表示全部记录；错误请求始终记录
+}
+
+// DefaultLogPolicy 默认策略：记录全部 body，请求 256KB、响应 4KB，不采样
+func DefaultLogPolicy() LogPolicy {
+	return LogPolicy{
+		Body:                LogBodyBoth,
+		MaxRequestBodySize:  maxRequestBodySize,
+		MaxResponseBodySize: maxResponseBodyCapture,
+		SampleRate:          1,
+	}
+}
+
+// normalize 零值字段使用默认值
+func (p LogPolicy) normalize() LogPolicy {
+	d := DefaultLogPolicy()
+	if p.Body == "" {
+		p.Body = d.Body
+	}
+	if p.MaxRequestBodySize <= 0 {
+		p.MaxRequestBodySize = d.MaxRequestBodySize
+	}
+	if p.MaxResponseBodySize <= 0 {
+		p.MaxResponseBodySize = d.MaxResponseBodySize
+	}
+	if p.SampleRate <= 0 || p.SampleRate > 1 {
+		p.SampleRate = d.SampleRate
+	}
+	return p
+}
+
+// sampled 错误请求始终记录，成功请求按采样率记录
+func (p LogPolicy) sampled(c *gin.Context) bool {
+	if p.SampleRate >= 1 || c.Writer.Status() >= 400 || len(c.Errors) > 0 {
+		return true
+	}
+	return rand.Float64() < p.SampleRate
+}
+
+// logRoutePolicy 路由级日志策略
+type logRoutePolicy struct {
+	method string // 为空时匹配全部方法
+	path   string // 以 / 结尾时前缀匹配，否则精确匹配路由模板或请求路径
+	policy LogPolicy
+}
+
+func (r *logRoutePolicy) match(c *gin.Context) bool {
+	if r.method != "" && !strings.EqualFold(r.method, c.Request.Method) {
+		return false
+	}
+	if strings.HasSuffix(r.path, "/") {
+		return strings.HasPrefix(c.Request.URL.Path, r.path)
+	}
+	return r.path == c.FullPath() || r.path == c.Request.URL.Path
+}
+
+// LogMaskRule 正则脱敏规则，Replacement 支持 $1 等分组引用，为空时替换为 FilteredValue
+type LogMaskRule struct {
+	Pattern     string
+	Replacement string
+}
+
+type logMasker struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// WithLogPolicy 设置默认日志策略
+func WithLogPolicy(p LogPolicy) LogOption {
+	return func(o *LogOptions) {
+		o.Policy = p
+	}
+}
+
+// WithLogRoutePolicy 设置路由级日志策略，按注册顺序匹配，先匹配者生效
+// method 为空时匹配全部方法；path 以 / 结尾时前缀匹配，否则精确匹配路由模板（如 /users/:id）或请求路径
+func WithLogRoutePolicy(method, path string, p LogPolicy) LogOption {
+	return func(o *LogOptions) {
+		o.RoutePolicies = append(o.RoutePolicies, logRoutePolicy{method: method, path: path, policy: p})
+	}
+}
+
+// WithLogTextContentTypes 设置记录响应 body 的 Content-Type（子串匹配），覆盖默认的 json/text/xml/javascript
+func WithLogTextContentTypes(contentTypes ...string) LogOption {
+	return func(o *LogOptions) {
+		o.TextContentTypes = append(o.TextContentTypes, contentTypes...)
+	}
+}
+
+// WithLogRedactPaths 按 JSONPath 脱敏请求/响应 JSON body，如 $.user.card.number、$.items[*].token、$..password
+func WithLogRedactPaths(paths ...string) LogOption {
+	return func(o *LogOptions) {
+		o.RedactPaths = append(o.RedactPaths, paths...)
+	}
+}
+
+// WithLogMaskRules 按正则脱敏请求/响应 body 中的值，如手机号、邮箱
+func WithLogMaskRules(rules ...LogMaskRule) LogOption {
+	return func(o *LogOptions) {
+		o.MaskRules = append(o.MaskRules, rules...)
+	}
+}
+
+// logRedactor 预编译的 JSONPath 与正则脱敏规则
+type logRedactor struct {
+	paths   []jsonPath
+	maskers []logMasker
+}
+
+func newLogRedactor(paths []string, rules []LogMaskRule) (*logRedactor, error) {
+	r := &logRedactor{}
+	for _, p := range paths {
+		jp, err := parseJSONPath(p)
+		if err != nil {
+			return nil, err
+		}
+		r.paths = append(r.paths, jp)
+	}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask pattern %q: %w", rule.Pattern, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = FilteredValue
+		}
+		r.maskers = append(r.maskers, logMasker{re: re, replacement: replacement})
+	}
+	return r, nil
+}
+
+// redact 依次应用敏感字段、JSONPath、正则脱敏
+func (r *logRedactor) redact(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var s string
+	if len(r.paths) > 0 && strings.Contains(contentType, "application/json") {
+		s = filterJSONBodyWithPaths(body, r.paths)
+	} else {
+		s = filterSensitiveBody(body, contentType)
+	}
+	for _, m := range r.maskers {
+		s = m.re.ReplaceAllString(s, m.replacement)
+	}
+	return s
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestParseLogBodyMode(t *testing.T) {
+	tests := map[string]LogBodyMode{
+		"":          LogBodyBoth,
+		"none":      LogBodyNone,
+		"Request":   LogBodyRequest,
+		" response": LogBodyResponse,
+		"BOTH":      LogBodyBoth,
+	}
+	for in, want := range tests {
+		got, err := ParseLogBodyMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLogBodyMode(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseLogBodyMode("all"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
+
+func TestLogPolicyNormalize(t *testing.T) {
+	p := LogPolicy{}.normalize()
+	if p != DefaultLogPolicy() {
+		t.Errorf("zero policy should normalize to default, got %+v", p)
+	}
+
+	p = LogPolicy{Body: LogBodyNone, MaxRequestBodySize: 10, MaxResponseBodySize: 20, SampleRate: 0.5}.normalize()
+	if p.Body != LogBodyNone || p.MaxRequestBodySize != 10 || p.MaxResponseBodySize != 20 || p.SampleRate != 0.5 {
+		t.Errorf("explicit values should be kept, got %+v", p)
+	}
+
+	if p := (LogPolicy{SampleRate: 2}).normalize(); p.SampleRate != 1 {
+		t.Errorf("sample rate > 1 should fall back to 1, got %v", p.SampleRate)
+	}
+}
+
+// newPolicyTestEngine 创建挂载日志中间件的 engine，返回捕获日志的 hook
+func newPolicyTestEngine(t *testing.T, opts ...LogOption) (*gin.Engine, *logtest.Hook) {
+	t.Helper()
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	m, err := NewLogMiddleware(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(m)
+	echo := func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.Data(http.StatusOK, "application/json", body)
+	}
+	r.POST("/users/:id", echo)
+	r.POST("/internal/sync", echo)
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/fail", func(c *gin.Context) { c.String(http.StatusInternalServerError, "fail") })
+	return r, hook
+}
+
+func postJSON(r *gin.Engine, path, body string) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestLogMiddleware_BodyModes(t *testing.T) {
+	tests := []struct {
+		mode         LogBodyMode
+		wantRequest  bool
+		wantResponse bool
+	}{
+		{LogBodyBoth, true, true},
+		{LogBodyRequest, true, false},
+		{LogBodyResponse, false, true},
+		{LogBodyNone, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			r, hook := newPolicyTestEngine(t, WithLogPolicy(LogPolicy{Body: tt.mode}))
+			postJSON(r, "/users/1", `{"name":"bob"}`)
+
+			entry := hook.LastEntry()
+			if entry == nil {
+				t.Fatal("expected log entry")
+			}
+			if got := entry.Data["request_body"] != ""; got != tt.wantRequest {
+				t.Errorf("request body logged=%v, want %v (%v)", got, tt.wantRequest, entry.Data["request_body"])
+			}
+			if _, got := entry.Data["response_body"]; got != tt.wantResponse {
+				t.Errorf("response body logged=%v, want %v", got, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestLogMiddleware_RoutePolicy(t *testing.T) {
+	r, hook := newPolicyTestEngine(t,
+		WithLogRoutePolicy(http.MethodPost, "/users/:id", LogPolicy{Body: LogBodyRequest, MaxRequestBodySize: 5}),
+		WithLogRoutePolicy("", "/internal/", LogPolicy{Body: LogBodyNone}),
+	)
+
+	postJSON(r, "/users/1", `{"name":"bob"}`)
+	entry := hook.LastEntry()
+	if entry.Data["request_body"] != `{"nam` {
+		t.Errorf("request body should be truncated to 5 bytes, got %v", entry.Data["request_body"])
+	}
+	if _, ok := entry.Data["response_body"]; ok {
+		t.Error("response body should not be logged for request-only policy")
+	}
+
+	postJSON(r, "/internal/sync", `{"name":"bob"}`)
+	entry = hook.LastEntry()
+	if entry.Data["request_body"] != "" {
+		t.Errorf("prefix policy should disable body, got %v", entry.Data["request_body"])
+	}
+	if _, ok := entry.Data["response_body"]; ok {
+		t.Error("prefix policy should disable response body")
+	}
+}
+
+func TestLogMiddleware_ResponseBodyLimit(t *testing.T) {
+	r, hook := newPolicyTestEngine(t, WithLogPolicy(LogPolicy{MaxResponseBodySize: 8}))
+
+	postJSON(r, "/users/1", `{"a":1}`)
+	if hook.LastEntry().Data["response_body"] != `{"a":1}` {
+		t.Errorf("small response should be logged, got %v", hook.LastEntry().Data["response_body"])
+	}
+
+	postJSON(r, "/users/1", `{"name":"bob"}`)
+	if _, ok := hook.LastEntry().Data["response_body"]; ok {
+		t.Error("response over limit should not be logged")
+	}
+}
+
+func TestLogMiddleware_Sampling(t *testing.T) {
+	r, hook := newPolicyTestEngine(t, WithLogPolicy(LogPolicy{SampleRate: 1e-9}))
+
+	for i := 0; i < 20; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	if n := len(hook.AllEntries()); n != 0 {
+		t.Errorf("successful requests should be sampled out, got %d entries", n)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if n := len(hook.AllEntries()); n != 1 {
+		t.Errorf("error requests should always be logged, got %d entries", n)
+	}
+}
+
+func TestLogMiddleware_TextContentTypes(t *testing.T) {
+	r, hook := newPolicyTestEngine(t, WithLogTextContentTypes("text/plain"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if hook.LastEntry().Data["response_body"] != "ok" {
+		t.Errorf("text/plain should be logged, got %v", hook.LastEntry().Data["response_body"])
+	}
+
+	postJSON(r, "/users/1", `{"a":1}`)
+	if _, ok := hook.LastEntry().Data["response_body"]; ok {
+		t.Error("json response should not be logged when not configured")
+	}
+}
+
+func TestLogMiddleware_Redaction(t *testing.T) {
+	r, hook := newPolicyTestEngine(t,
+		WithLogRedactPaths("$.user.card.number"),
+		WithLogMaskRules(
+			LogMaskRule{Pattern: `(1[3-9]\d)\d{4}(\d{4})`, Replacement: "$1****$2"},
+			LogMaskRule{Pattern: `[\w.+-]+@[\w-]+\.[\w.]+`},
+		),
+	)
+
+	postJSON(r, "/users/1", `{"user":{"card":{"number":"4111111111111111"},"phone":"13812345678","email":"bob@example.com"}}`)
+	entry := hook.LastEntry()
+	want := `{"user":{"card":{"number":"***FILTERED***"},"email":"***FILTERED***","phone":"138****5678"}}`
+	if entry.Data["request_body"] != want {
+		t.Errorf("unexpected request body %v", entry.Data["request_body"])
+	}
+	if entry.Data["response_body"] != want {
+		t.Errorf("response body should be redacted too, got %v", entry.Data["response_body"])
+	}
+}
+
+func TestNewLogMiddleware_InvalidRules(t *testing.T) {
+	if _, err := NewLogMiddleware(WithLogRedactPaths("user.card")); err == nil {
+		t.Error("expected error for invalid redact path")
+	}
+	if _, err := NewLogMiddleware(WithLogMaskRules(LogMaskRule{Pattern: "("})); err == nil {
+		t.Error("expected error for invalid mask pattern")
+	}
+
+	// LogMiddleware 忽略无效规则，仍然可用
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LogMiddleware(WithLogMaskRules(LogMaskRule{Pattern: "("})))
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
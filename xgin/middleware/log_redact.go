@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSeg JSONPath 的一段
+type jsonPathSeg struct {
+	key       string // 对象字段名，wildcard 时为空
+	index     int    // 数组下标，isIndex 时有效
+	isIndex   bool
+	wildcard  bool // * 或 [*]：匹配全部字段或元素
+	recursive bool // ..key：在任意深度匹配 key
+}
+
+// jsonPath 预解析的 JSONPath，仅支持脱敏需要的子集：$.a.b、$.a[0]、$.a[*].b、$.a.*、$..key
+type jsonPath []jsonPathSeg
+
+func parseJSONPath(s string) (jsonPath, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid redact path %q: %s", s, reason)
+	}
+	if !strings.HasPrefix(s, "$") {
+		return nil, invalid("must start with $")
+	}
+	rest := s[1:]
+	path := make(jsonPath, 0)
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			name, remain := splitPathKey(rest[2:])
+			if name == "" || name == "*" {
+				return nil, invalid("recursive descent requires a field name")
+			}
+			path = append(path, jsonPathSeg{key: name, recursive: true})
+			rest = remain
+		case rest[0] == '.':
+			name, remain := splitPathKey(rest[1:])
+			if name == "" {
+				return nil, invalid("empty field name")
+			}
+			if name == "*" {
+				path = append(path, jsonPathSeg{wildcard: true})
+			} else {
+				path = append(path, jsonPathSeg{key: name})
+			}
+			rest = remain
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, invalid("unclosed [")
+			}
+			inner := rest[1:end]
+			switch {
+			case inner == "*":
+				path = append(path, jsonPathSeg{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				path = append(path, jsonPathSeg{key: inner[1 : len(inner)-1]})
+			default:
+				idx, err := strconv.Atoi(inner)
+				if err != nil || idx < 0 {
+					return nil, invalid("index must be a non-negative integer, * or quoted name")
+				}
+				path = append(path, jsonPathSeg{index: idx, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, invalid("unexpected character " + strconv.Quote(rest[:1]))
+		}
+	}
+	if len(path) == 0 {
+		return nil, invalid("path is empty")
+	}
+	return path, nil
+}
+
+// splitPathKey 取出下一个 . 或 [ 之前的字段名
+func splitPathKey(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// redact 将匹配的值替换为 FilteredValue
+func (p jsonPath) redact(data any) {
+	if len(p) == 0 {
+		return
+	}
+	seg, next := p[0], p[1:]
+	last := len(next) == 0
+
+	if seg.recursive {
+		redactRecursive(data, seg.key, next)
+		return
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		if seg.isIndex {
+			return
+		}
+		for k, child := range v {
+			if !seg.wildcard && k != seg.key {
+				continue
+			}
+			if last {
+				v[k] = FilteredValue
+			} else {
+				next.redact(child)
+			}
+		}
+	case []any:
+		if seg.isIndex {
+			if seg.index < len(v) {
+				if last {
+					v[seg.index] = FilteredValue
+				} else {
+					next.redact(v[seg.index])
+				}
+			}
+			return
+		}
+		if !seg.wildcard {
+			return
+		}
+		for i, child := range v {
+			if last {
+				v[i] = FilteredValue
+			} else {
+				next.redact(child)
+			}
+		}
+	}
+}
+
+// redactRecursive 在任意深度查找 key，并对其值继续应用剩余路径
+func redactRecursive(data any, key string, next jsonPath) {
+	switch v := data.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if k == key {
+				if len(next) == 0 {
+					v[k] = FilteredValue
+					continue
+				}
+				next.redact(child)
+			}
+			redactRecursive(child, key, next)
+		}
+	case []any:
+		for _, child := range v {
+			redactRecursive(child, key, next)
+		}
+	}
+}
+
+// filterJSONBodyWithPaths 在敏感字段过滤的基础上按 JSONPath 脱敏，无法解析（如被截断）时返回占位值，不输出原文
+func filterJSONBodyWithPaths(bodyBytes []byte, paths []jsonPath) string {
+	var data any
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		return unparsableBodyValue
+	}
+
+	filterAnySensitiveFields(data, getSensitiveFieldMap())
+	for _, p := range paths {
+		p.redact(data)
+	}
+
+	result, err := json.Marshal(data)
+	if err != nil {
+		return newlineReplacer.Replace(string(bodyBytes))
+	}
+	return string(result)
+}
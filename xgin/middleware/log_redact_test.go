@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	valid := []string{"$.user.card.number", "$.items[0].token", "$.items[*].token", "$.data.*", "$..password", "$['user']['card no']"}
+	for _, p := range valid {
+		if _, err := parseJSONPath(p); err != nil {
+			t.Errorf("expected %q to be valid, got %v", p, err)
+		}
+	}
+
+	invalid := []string{"", "user.card", "$", "$.", "$..", "$..*", "$.items[", "$.items[-1]", "$.items[x]", "$user"}
+	for _, p := range invalid {
+		if _, err := parseJSONPath(p); err == nil {
+			t.Errorf("expected %q to be invalid", p)
+		}
+	}
+}
+
+func TestJSONPathRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{"nested field", "$.user.card.number", `{"user":{"card":{"number":"4111","exp":"12/30"}}}`, `{"user":{"card":{"exp":"12/30","number":"***FILTERED***"}}}`},
+		{"array index", "$.items[1]", `{"items":["a","b"]}`, `{"items":["a","***FILTERED***"]}`},
+		{"array wildcard", "$.items[*].sn", `{"items":[{"sn":"1"},{"sn":"2","id":3}]}`, `{"items":[{"sn":"***FILTERED***"},{"id":3,"sn":"***FILTERED***"}]}`},
+		{"object wildcard", "$.cards.*", `{"cards":{"a":"1","b":"2"}}`, `{"cards":{"a":"***FILTERED***","b":"***FILTERED***"}}`},
+		{"recursive descent", "$..cvv", `{"a":{"cvv":"1"},"b":[{"cvv":"2"}]}`, `{"a":{"cvv":"***FILTERED***"},"b":[{"cvv":"***FILTERED***"}]}`},
+		{"recursive with tail", "$..card.number", `{"x":{"card":{"number":"1","bin":"4"}}}`, `{"x":{"card":{"bin":"4","number":"***FILTERED***"}}}`},
+		{"quoted key", "$['card no']", `{"card no":"1","other":"2"}`, `{"card no":"***FILTERED***","other":"2"}`},
+		{"missing path", "$.user.phone", `{"user":{"name":"bob"}}`, `{"user":{"name":"bob"}}`},
+		{"type mismatch", "$.user[0]", `{"user":{"name":"bob"}}`, `{"user":{"name":"bob"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data any
+			if err := json.Unmarshal([]byte(tt.body), &data); err != nil {
+				t.Fatal(err)
+			}
+			p.redact(data)
+			got, _ := json.Marshal(data)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterJSONBodyWithPaths(t *testing.T) {
+	p, _ := parseJSONPath("$.user.card")
+	got := filterJSONBodyWithPaths([]byte(`{"user":{"card":"4111","password":"x"}}`), []jsonPath{p})
+	if got != `{"user":{"card":"***FILTERED***","password":"***FILTERED***"}}` {
+		t.Errorf("unexpected result %s", got)
+	}
+
+	// 被截断的 JSON 无法按路径脱敏，不输出原文
+	if got := filterJSONBodyWithPaths([]byte(`{"user":{"card":"4111","name":"abc`), []jsonPath{p}); got != unparsableBodyValue {
+		t.Errorf("unexpected fallback %q", got)
+	}
+}
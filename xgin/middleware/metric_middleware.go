@@ -48,6 +48,14 @@ func initMetricCollectors() {
 	})
 }
 
+// MetricSkipContextKey 当前请求是否跳过请求指标采集，由路由组策略设置
+const MetricSkipContextKey = "__xgin_metric_skip__"
+
+// SkipMetric 当前请求不采集 http_requests_total/http_request_duration_ms，需在 GinXMetricMiddleware 之后调用
+func SkipMetric(c *gin.Context) {
+	c.Set(MetricSkipContextKey, true)
+}
+
 // GinXMetricMiddleware 返回 Gin HTTP 请求指标中间件
 // 采集指标：http_requests_total（请求数量+状态码）、http_request_duration_ms（请求耗时+状态码）
 func GinXMetricMiddleware() gin.HandlerFunc {
@@ -58,6 +66,10 @@ func GinXMetricMiddleware() gin.HandlerFunc {
 
 		c.Next()
 
+		if c.GetBool(MetricSkipContextKey) {
+			return
+		}
+
 		status := strconv.Itoa(c.Writer.Status())
 		path := c.FullPath()
 		if path == "" {
@@ -67,6 +79,10 @@ func GinXMetricMiddleware() gin.HandlerFunc {
 		durationMs := float64(time.Since(start).Milliseconds())
 
 		requestsTotal.WithLabelValues(method, path, status).Inc()
+		// 流式请求（SSE/WebSocket）时长由 http_stream_duration_ms 单独记录，避免长连接污染请求耗时分布
+		if _, ok := GetStreamStats(c); ok {
+			return
+		}
 		requestDuration.WithLabelValues(method, path, status).Observe(durationMs)
 	}
 }
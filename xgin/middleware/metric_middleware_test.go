@@ -342,3 +342,32 @@ func TestInitMetricCollectors_Idempotent(t *testing.T) {
 		So(first, ShouldEqual, second) // sync.Once 保证同一实例
 	})
 }
+
+func TestGinXMetricMiddleware_SkipMetric(t *testing.T) {
+	PatchConvey("TestGinXMetricMiddleware-SkipMetric 跳过指标采集", t, func() {
+		resetMetricMiddlewareState()
+		testRegistry := prometheus.NewRegistry()
+		Mock(xmetric.GetConfig).Return(&xmetric.Config{}).Build()
+		Mock(xmetric.SafeRegister).To(func(c prometheus.Collector) prometheus.Collector {
+			testRegistry.MustRegister(c)
+			return c
+		}).Build()
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(GinXMetricMiddleware())
+		r.GET("/internal/stats", func(c *gin.Context) {
+			SkipMetric(c)
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/internal/stats", nil))
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		metrics, err := testRegistry.Gather()
+		So(err, ShouldBeNil)
+		So(findFamily(metrics, "http_requests_total"), ShouldBeNil)
+		So(findFamily(metrics, "http_request_duration_ms"), ShouldBeNil)
+	})
+}
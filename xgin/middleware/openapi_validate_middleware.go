@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/trans"
+	"github.com/xiaoshicae/xone/v2/xlog"
+)
+
+// maxContractResponseCapture 响应校验时捕获的最大 body，超过则跳过校验
+const maxContractResponseCapture = 1 << 20 // 1MB
+
+// OpenAPIValidateOption OpenAPI 契约校验中间件配置
+type OpenAPIValidateOption func(*OpenAPIValidateOptions)
+
+type OpenAPIValidateOptions struct {
+	ValidateResponse bool     // 非 release 模式下是否校验响应并记录违反契约的日志
+	SkipPaths        []string // 跳过校验的路由，匹配规则同 LogSkipPaths
+}
+
+// WithOpenAPIValidateResponse 非 release 模式下校验响应，违反契约时记录 Warn 日志（不影响响应）
+func WithOpenAPIValidateResponse(enable bool) OpenAPIValidateOption {
+	return func(o *OpenAPIValidateOptions) {
+		o.ValidateResponse = enable
+	}
+}
+
+// WithOpenAPIValidateSkipPaths 设置跳过契约校验的路由
+func WithOpenAPIValidateSkipPaths(paths ...string) OpenAPIValidateOption {
+	return func(o *OpenAPIValidateOptions) {
+		o.SkipPaths = append(o.SkipPaths, paths...)
+	}
+}
+
+// GinXOpenAPIValidateMiddleware 按 OpenAPI 3 文档校验请求的 path/query/header 参数与 JSON body
+// 文档在创建中间件时加载并校验，加载失败返回 error；文档中未定义的路由不做校验
+// 校验失败返回 400，details 为 []trans.VErrKV，Field 形如 query.page、path.id、body.items.0.name
+// 认证由 xgin/auth 负责，此处不校验 security 要求；servers 仅保留路径部分参与匹配
+func GinXOpenAPIValidateMiddleware(file string, opts ...OpenAPIValidateOption) (gin.HandlerFunc, error) {
+	options := &OpenAPIValidateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	router, err := loadOpenAPIRouter(file)
+	if err != nil {
+		return nil, err
+	}
+
+	exactSkip := make(map[string]bool)
+	prefixSkip := make([]string, 0)
+	for _, p := range options.SkipPaths {
+		if strings.HasSuffix(p, "/") {
+			prefixSkip = append(prefixSkip, p)
+		} else {
+			exactSkip[p] = true
+		}
+	}
+
+	reqOptions := &openapi3filter.Options{
+		MultiError:          true,
+		SkipSettingDefaults: true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+	}
+	respOptions := &openapi3filter.Options{
+		MultiError:            true,
+		IncludeResponseStatus: true,
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkipLog(c.Request.URL.Path, exactSkip, prefixSkip) || isUpgradeRequest(c.Request) {
+			c.Next()
+			return
+		}
+
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			// 文档中未定义的路由或方法，交由 gin 处理
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    reqOptions,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			AbortWithErrorResponse(c, http.StatusBadRequest, "invalid request", contractViolations(err))
+			return
+		}
+
+		if !options.ValidateResponse || gin.Mode() == gin.ReleaseMode {
+			c.Next()
+			return
+		}
+
+		origWriter := c.Writer
+		tw := &teeWriter{ResponseWriter: origWriter}
+		c.Writer = tw
+		c.Next()
+		c.Writer = origWriter
+
+		if tw.skipped {
+			return
+		}
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 origWriter.Status(),
+			Header:                 origWriter.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(tw.body.Bytes())),
+			Options:                respOptions,
+		})
+		if err != nil {
+			xlog.Warn(c.Request.Context(), "[XGin-OpenAPIValidate] response violates contract, route=[%s %s], status=[%d], err=[%v]",
+				c.Request.Method, route.Path, origWriter.Status(), err)
+		}
+	}, nil
+}
+
+// loadOpenAPIRouter 加载并校验文档，servers 改写为仅路径部分，避免因 Host 不同（代理、内网地址）无法匹配
+func loadOpenAPIRouter(file string) (routers.Router, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, err
+	}
+
+	doc.Servers = pathOnlyServers(doc.Servers)
+	for _, item := range doc.Paths.Map() {
+		item.Servers = pathOnlyServers(item.Servers)
+	}
+	return gorillamux.NewRouter(doc)
+}
+
+func pathOnlyServers(servers openapi3.Servers) openapi3.Servers {
+	if len(servers) == 0 {
+		return servers
+	}
+	result := make(openapi3.Servers, 0, len(servers))
+	seen := make(map[string]bool)
+	for _, s := range servers {
+		u := s.URL
+		if i := strings.Index(u, "://"); i >= 0 {
+			u = u[i+3:]
+			if j := strings.Index(u, "/"); j >= 0 {
+				u = u[j:]
+			} else {
+				u = ""
+			}
+		}
+		u = strings.TrimSuffix(u, "/")
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		result = append(result, &openapi3.Server{URL: u, Variables: s.Variables})
+	}
+	return result
+}
+
+// contractViolations 将 kin-openapi 的校验错误展开为字段级错误
+func contractViolations(err error) []trans.VErrKV {
+	out := make([]trans.VErrKV, 0)
+	collectViolations("", err, &out)
+	return out
+}
+
+func collectViolations(field string, err error, out *[]trans.VErrKV) {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, sub := range e {
+			collectViolations(field, sub, out)
+		}
+	case *openapi3filter.RequestError:
+		f := field
+		switch {
+		case e.Parameter != nil:
+			f = e.Parameter.In + "." + e.Parameter.Name
+		case e.RequestBody != nil:
+			f = "body"
+		}
+		if e.Err != nil {
+			collectViolations(f, e.Err, out)
+			return
+		}
+		*out = append(*out, trans.VErrKV{Field: f, Transl: e.Reason})
+	case *openapi3.SchemaError:
+		f := field
+		if p := e.JSONPointer(); len(p) > 0 {
+			f = strings.TrimPrefix(f+"."+strings.Join(p, "."), ".")
+		}
+		*out = append(*out, trans.VErrKV{Field: f, Transl: e.Reason})
+	default:
+		var se *openapi3.SchemaError
+		if errors.As(err, &se) {
+			collectViolations(field, se, out)
+			return
+		}
+		*out = append(*out, trans.VErrKV{Field: field, Transl: err.Error()})
+	}
+}
+
+// teeWriter 在输出响应的同时保留一份 body 用于契约校验，流式或过大的响应跳过校验
+type teeWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	skipped bool
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	if !w.skipped {
+		if w.body.Len()+len(b) > maxContractResponseCapture {
+			w.skipped = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *teeWriter) Flush() {
+	w.skipped = true
+	w.body.Reset()
+	w.ResponseWriter.Flush()
+}
+
+func (w *teeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
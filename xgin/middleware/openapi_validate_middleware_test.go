@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+const testOpenAPIDoc = `
+openapi: 3.0.3
+info:
+  title: demo
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema: {type: integer, minimum: 1}
+        - name: X-Tenant
+          in: header
+          required: true
+          schema: {type: string}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id: {type: integer}
+                  name: {type: string}
+  /users:
+    get:
+      parameters:
+        - name: page
+          in: query
+          schema: {type: integer, minimum: 1}
+      responses:
+        "200": {description: ok}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name: {type: string, minLength: 2}
+                tags:
+                  type: array
+                  items: {type: string, minLength: 1}
+      responses:
+        "201": {description: created}
+`
+
+type contractErrorResponse struct {
+	Code    int `json:"code"`
+	Details []struct {
+		Field  string
+		Transl string
+	} `json:"details"`
+}
+
+func newContractEngine(t *testing.T, opts ...OpenAPIValidateOption) *gin.Engine {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(file, []byte(testOpenAPIDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validate, err := GinXOpenAPIValidateMiddleware(file, opts...)
+	if err != nil {
+		t.Fatalf("load document failed: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(validate)
+	v1 := r.Group("/v1")
+	v1.GET("/users/:id", func(c *gin.Context) {
+		if c.Param("id") == "2" {
+			c.JSON(http.StatusOK, gin.H{"id": "bad"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 1, "name": "alice"})
+	})
+	v1.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	v1.POST("/users", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	v1.GET("/undocumented", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func serveContract(r *gin.Engine, req *http.Request) (*httptest.ResponseRecorder, contractErrorResponse) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp contractErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func fieldsOf(resp contractErrorResponse) []string {
+	fields := make([]string, 0, len(resp.Details))
+	for _, d := range resp.Details {
+		fields = append(fields, d.Field)
+	}
+	return fields
+}
+
+func TestOpenAPIValidate_Request(t *testing.T) {
+	r := newContractEngine(t)
+
+	req := httptest.NewRequest("GET", "/v1/users/1", nil)
+	req.Header.Set("X-Tenant", "t1")
+	if w, _ := serveContract(r, req); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, resp := serveContract(r, httptest.NewRequest("GET", "/v1/users/0", nil))
+	if w.Code != http.StatusBadRequest || resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	fields := strings.Join(fieldsOf(resp), ",")
+	if !strings.Contains(fields, "path.id") || !strings.Contains(fields, "header.X-Tenant") {
+		t.Errorf("expected path and header violations, got %v", resp.Details)
+	}
+
+	if w, resp := serveContract(r, httptest.NewRequest("GET", "/v1/users?page=abc", nil)); w.Code != http.StatusBadRequest || fieldsOf(resp)[0] != "query.page" {
+		t.Errorf("expected query violation, got %d %s", w.Code, w.Body.String())
+	}
+
+	body := httptest.NewRequest("POST", "/v1/users", strings.NewReader(`{"name":"a","tags":["ok",""]}`))
+	body.Header.Set("Content-Type", "application/json")
+	w, resp = serveContract(r, body)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	fields = strings.Join(fieldsOf(resp), ",")
+	if !strings.Contains(fields, "body.name") || !strings.Contains(fields, "body.tags.1") {
+		t.Errorf("expected body field violations, got %v", resp.Details)
+	}
+
+	valid := httptest.NewRequest("POST", "/v1/users", strings.NewReader(`{"name":"alice"}`))
+	valid.Header.Set("Content-Type", "application/json")
+	if w, _ := serveContract(r, valid); w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 文档中未定义的路由不校验
+	if w, _ := serveContract(r, httptest.NewRequest("GET", "/v1/undocumented", nil)); w.Code != http.StatusOK {
+		t.Errorf("undocumented route should pass, got %d", w.Code)
+	}
+}
+
+func TestOpenAPIValidate_SkipPaths(t *testing.T) {
+	r := newContractEngine(t, WithOpenAPIValidateSkipPaths("/v1/users"))
+	if w, _ := serveContract(r, httptest.NewRequest("GET", "/v1/users?page=abc", nil)); w.Code != http.StatusOK {
+		t.Errorf("skipped path should not be validated, got %d", w.Code)
+	}
+}
+
+func TestOpenAPIValidate_Response(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	r := newContractEngine(t, WithOpenAPIValidateResponse(true))
+
+	req := httptest.NewRequest("GET", "/v1/users/1", nil)
+	req.Header.Set("X-Tenant", "t1")
+	serveContract(r, req)
+	for _, e := range hook.AllEntries() {
+		if strings.Contains(e.Message, "violates contract") {
+			t.Fatalf("valid response should not be reported: %s", e.Message)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/v1/users/2", nil)
+	req.Header.Set("X-Tenant", "t1")
+	w, _ := serveContract(r, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"bad"`) {
+		t.Fatalf("response should not be modified, got %d %s", w.Code, w.Body.String())
+	}
+	found := false
+	for _, e := range hook.AllEntries() {
+		if strings.Contains(e.Message, "violates contract") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected contract violation log")
+	}
+}
+
+func TestOpenAPIValidate_LoadError(t *testing.T) {
+	if _, err := GinXOpenAPIValidateMiddleware(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	file := filepath.Join(t.TempDir(), "invalid.yaml")
+	_ = os.WriteFile(file, []byte("openapi: 3.0.3\ninfo: {}\npaths: {}\n"), 0o644)
+	if _, err := GinXOpenAPIValidateMiddleware(file); err == nil {
+		t.Error("expected error for invalid document")
+	}
+}
+
+func TestPathOnlyServers(t *testing.T) {
+	got := pathOnlyServers(openapi3.Servers{
+		{URL: "https://api.example.com/v1/"},
+		{URL: "http://{env}.example.com/v1"},
+		{URL: "https://api.example.com"},
+		{URL: "/v2"},
+	})
+	urls := make([]string, 0, len(got))
+	for _, s := range got {
+		urls = append(urls, s.URL)
+	}
+	if strings.Join(urls, ",") != "/v1,,/v2" {
+		t.Errorf("unexpected servers %v", urls)
+	}
+}
@@ -9,10 +9,19 @@ import (
 
 // GinXSessionMiddleware session中间件
 // 提前注入一些请求上下文(log上下文容器等，保证日志kv tag能从一开始就初始化好，后续能在整个请求带下去)
+// 请求头命中 XLog.DebugHeader 时标记请求级 debug，该请求不受全局级别限制输出 debug 日志
+// 配置 XLog.Baggage 时从 W3C baggage header 中提取白名单内的 KV
 func GinXSessionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		kvs := xlog.BaggageKV(c.Request.Header)
+		if kvs == nil {
+			kvs = make(map[string]any)
+		}
+		if xlog.DebugRequested(c.Request.Header) {
+			kvs[xlog.XLogDebugKey] = true
+		}
 		ctx := c.Request.Context()
-		ctx = ctxWithKV(ctx, make(map[string]any))
+		ctx = ctxWithKV(ctx, kvs)
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next() // 继续处理
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bytedance/mockey"
 	"github.com/gin-gonic/gin"
 	"github.com/xiaoshicae/xone/v2/xlog"
 )
@@ -35,6 +36,28 @@ func TestGinXSessionMiddleware(t *testing.T) {
 	}
 }
 
+func TestGinXSessionMiddlewareBaggage(t *testing.T) {
+	defer mockey.Mock(xlog.BaggageKV).Return(map[string]any{"user_id": "u1"}).Build().UnPatch()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinXSessionMiddleware())
+
+	var kvs map[string]any
+	r.GET("/test", func(c *gin.Context) {
+		kvs, _ = c.Request.Context().Value(xlog.XLogCtxKVContainerKey).(map[string]any)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("baggage", "user_id=u1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if kvs["user_id"] != "u1" {
+		t.Errorf("expected user_id extracted from baggage, got %v", kvs)
+	}
+}
+
 func TestCtxWithKVNewContext(t *testing.T) {
 	ctx := context.Background()
 	kvs := map[string]interface{}{"key1": "value1"}
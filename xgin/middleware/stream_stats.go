@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+)
+
+const (
+	// StreamStatsContextKey 流式请求统计在 gin.Context 中的 key
+	StreamStatsContextKey = "__xgin_stream_stats__"
+
+	StreamTypeSSE       = "sse"
+	StreamTypeWebSocket = "websocket"
+)
+
+// streamDurationBuckets 流时长桶边界（毫秒），覆盖 100ms ~ 30min
+var streamDurationBuckets = []float64{100, 500, 1000, 5000, 10000, 30000, 60000, 300000, 600000, 1800000}
+
+var (
+	streamMetricOnce sync.Once
+	streamActive     *prometheus.GaugeVec
+	streamTTFB       *prometheus.HistogramVec
+	streamDuration   *prometheus.HistogramVec
+	streamMessages   *prometheus.CounterVec
+	streamBytes      *prometheus.CounterVec
+)
+
+func initStreamMetricCollectors() {
+	streamMetricOnce.Do(func() {
+		ns := xmetric.GetConfig().Namespace
+		cl := xmetric.GetConstLabels()
+
+		active := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns, Name: "http_stream_active", Help: "当前活跃的流式连接数", ConstLabels: cl,
+		}, []string{"type", "path"})
+		ttfb := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "http_stream_ttfb_ms", Help: "流式响应首条消息耗时（毫秒）",
+			Buckets: xmetric.GetHttpDurationBuckets(), ConstLabels: cl,
+		}, []string{"type", "path"})
+		duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "http_stream_duration_ms", Help: "流式连接持续时长（毫秒）",
+			Buckets: streamDurationBuckets, ConstLabels: cl,
+		}, []string{"type", "path"})
+		messages := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "http_stream_messages_total", Help: "流式连接消息数", ConstLabels: cl,
+		}, []string{"type", "path", "direction"})
+		bytes := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "http_stream_bytes_total", Help: "流式连接传输字节数", ConstLabels: cl,
+		}, []string{"type", "path", "direction"})
+
+		if r, ok := xmetric.SafeRegister(active).(*prometheus.GaugeVec); ok {
+			active = r
+		}
+		if r, ok := xmetric.SafeRegister(ttfb).(*prometheus.HistogramVec); ok {
+			ttfb = r
+		}
+		if r, ok := xmetric.SafeRegister(duration).(*prometheus.HistogramVec); ok {
+			duration = r
+		}
+		if r, ok := xmetric.SafeRegister(messages).(*prometheus.CounterVec); ok {
+			messages = r
+		}
+		if r, ok := xmetric.SafeRegister(bytes).(*prometheus.CounterVec); ok {
+			bytes = r
+		}
+		streamActive, streamTTFB, streamDuration, streamMessages, streamBytes = active, ttfb, duration, messages, bytes
+	})
+}
+
+// StreamStats 流式请求（SSE/WebSocket）统计，由 xgin/stream 记录，Log/Metric 中间件读取
+// 各计数字段支持并发更新（WebSocket 读写通常位于不同 goroutine）
+type StreamStats struct {
+	Type string
+	path string
+
+	start       time.Time
+	ttfb        atomic.Int64 // 纳秒，0 表示尚未发送首条消息
+	duration    atomic.Int64 // 纳秒，Finish 后有效
+	msgSent     atomic.Int64
+	msgReceived atomic.Int64
+	bytesSent   atomic.Int64
+	bytesRecv   atomic.Int64
+	finished    atomic.Bool
+}
+
+// NewStreamStats 开始统计流式请求，写入 gin.Context 并计入活跃连接数，结束时需调用 Finish
+func NewStreamStats(c *gin.Context, streamType string) *StreamStats {
+	initStreamMetricCollectors()
+
+	path := c.FullPath()
+	if path == "" {
+		path = "unknown"
+	}
+	s := &StreamStats{Type: streamType, path: path, start: time.Now()}
+	c.Set(StreamStatsContextKey, s)
+	streamActive.WithLabelValues(streamType, path).Inc()
+	return s
+}
+
+// GetStreamStats 获取当前请求的流式统计，非流式请求返回 false
+func GetStreamStats(c *gin.Context) (*StreamStats, bool) {
+	v, ok := c.Get(StreamStatsContextKey)
+	if !ok {
+		return nil, false
+	}
+	s, ok := v.(*StreamStats)
+	return s, ok
+}
+
+// RecordSent 记录一条发出的消息，首条消息记录 TTFB
+func (s *StreamStats) RecordSent(n int) {
+	if s.ttfb.Load() == 0 {
+		s.ttfb.CompareAndSwap(0, int64(max(time.Since(s.start), time.Nanosecond)))
+	}
+	s.msgSent.Add(1)
+	s.bytesSent.Add(int64(n))
+}
+
+// RecordSentBytes 记录发出的非消息字节（如 SSE 心跳），不计入消息数与 TTFB
+func (s *StreamStats) RecordSentBytes(n int) {
+	s.bytesSent.Add(int64(n))
+}
+
+// RecordReceived 记录一条收到的消息
+func (s *StreamStats) RecordReceived(n int) {
+	s.msgReceived.Add(1)
+	s.bytesRecv.Add(int64(n))
+}
+
+// Finish 结束统计并上报指标，重复调用无副作用
+func (s *StreamStats) Finish() {
+	if !s.finished.CompareAndSwap(false, true) {
+		return
+	}
+	d := time.Since(s.start)
+	s.duration.Store(int64(d))
+
+	streamActive.WithLabelValues(s.Type, s.path).Dec()
+	streamDuration.WithLabelValues(s.Type, s.path).Observe(float64(d.Milliseconds()))
+	if ttfb := s.ttfb.Load(); ttfb > 0 {
+		streamTTFB.WithLabelValues(s.Type, s.path).Observe(float64(time.Duration(ttfb).Milliseconds()))
+	}
+	streamMessages.WithLabelValues(s.Type, s.path, "sent").Add(float64(s.msgSent.Load()))
+	streamBytes.WithLabelValues(s.Type, s.path, "sent").Add(float64(s.bytesSent.Load()))
+	if s.msgReceived.Load() > 0 {
+		streamMessages.WithLabelValues(s.Type, s.path, "received").Add(float64(s.msgReceived.Load()))
+		streamBytes.WithLabelValues(s.Type, s.path, "received").Add(float64(s.bytesRecv.Load()))
+	}
+}
+
+// TTFB 首条消息耗时，未发送消息时为 0
+func (s *StreamStats) TTFB() time.Duration { return time.Duration(s.ttfb.Load()) }
+
+// Duration 流持续时长，Finish 前返回当前已持续时长
+func (s *StreamStats) Duration() time.Duration {
+	if s.finished.Load() {
+		return time.Duration(s.duration.Load())
+	}
+	return time.Since(s.start)
+}
+
+func (s *StreamStats) MessagesSent() int64     { return s.msgSent.Load() }
+func (s *StreamStats) MessagesReceived() int64 { return s.msgReceived.Load() }
+func (s *StreamStats) BytesSent() int64        { return s.bytesSent.Load() }
+func (s *StreamStats) BytesReceived() int64    { return s.bytesRecv.Load() }
+
+// logFields 供 LogMiddleware 输出的流式统计字段
+func (s *StreamStats) logFields() map[string]any {
+	return map[string]any{
+		"stream_type":              s.Type,
+		"stream_ttfb_ms":           s.TTFB().Milliseconds(),
+		"stream_duration_ms":       s.Duration().Milliseconds(),
+		"stream_messages_sent":     s.MessagesSent(),
+		"stream_messages_received": s.MessagesReceived(),
+		"stream_bytes_sent":        s.BytesSent(),
+		"stream_bytes_received":    s.BytesReceived(),
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/bytedance/mockey"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+)
+
+// resetStreamMetricState 重置流式指标全局状态
+func resetStreamMetricState() {
+	streamMetricOnce = sync.Once{}
+	streamActive, streamTTFB, streamDuration, streamMessages, streamBytes = nil, nil, nil, nil, nil
+}
+
+func TestStreamStats_Record(t *testing.T) {
+	PatchConvey("TestStreamStats-记录消息与指标", t, func() {
+		resetStreamMetricState()
+		testRegistry := prometheus.NewRegistry()
+		Mock(xmetric.GetConfig).Return(&xmetric.Config{}).Build()
+		Mock(xmetric.SafeRegister).To(func(c prometheus.Collector) prometheus.Collector {
+			testRegistry.MustRegister(c)
+			return c
+		}).Build()
+
+		gin.SetMode(gin.TestMode)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/events", nil)
+
+		s := NewStreamStats(c, StreamTypeSSE)
+		got, ok := GetStreamStats(c)
+		So(ok, ShouldBeTrue)
+		So(got, ShouldEqual, s)
+
+		So(s.TTFB(), ShouldEqual, 0)
+		time.Sleep(2 * time.Millisecond)
+		s.RecordSent(10)
+		s.RecordSent(5)
+		s.RecordSentBytes(8)
+		s.RecordReceived(3)
+		So(s.TTFB(), ShouldBeGreaterThan, 0)
+		So(s.MessagesSent(), ShouldEqual, 2)
+		So(s.BytesSent(), ShouldEqual, 23)
+		So(s.MessagesReceived(), ShouldEqual, 1)
+		So(s.BytesReceived(), ShouldEqual, 3)
+
+		s.Finish()
+		d := s.Duration()
+		s.Finish()
+		So(s.Duration(), ShouldEqual, d)
+
+		metrics, err := testRegistry.Gather()
+		So(err, ShouldBeNil)
+		active := findFamily(metrics, "http_stream_active")
+		So(active, ShouldNotBeNil)
+		So(*active.Metric[0].Gauge.Value, ShouldEqual, 0)
+		So(findFamily(metrics, "http_stream_ttfb_ms").Metric[0].Histogram.GetSampleCount(), ShouldEqual, 1)
+		So(findFamily(metrics, "http_stream_duration_ms").Metric[0].Histogram.GetSampleCount(), ShouldEqual, 1)
+		for _, m := range findFamily(metrics, "http_stream_messages_total").Metric {
+			switch labelValue(m, "direction") {
+			case "sent":
+				So(*m.Counter.Value, ShouldEqual, 2)
+			case "received":
+				So(*m.Counter.Value, ShouldEqual, 1)
+			}
+		}
+	})
+}
+
+func TestGinXMetricMiddleware_SkipsDurationForStream(t *testing.T) {
+	PatchConvey("TestGinXMetricMiddleware-流式请求不记录请求耗时", t, func() {
+		resetMetricMiddlewareState()
+		resetStreamMetricState()
+		testRegistry := prometheus.NewRegistry()
+		Mock(xmetric.GetConfig).Return(&xmetric.Config{}).Build()
+		Mock(xmetric.SafeRegister).To(func(c prometheus.Collector) prometheus.Collector {
+			testRegistry.MustRegister(c)
+			return c
+		}).Build()
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(GinXMetricMiddleware())
+		r.GET("/events", func(c *gin.Context) {
+			s := NewStreamStats(c, StreamTypeSSE)
+			defer s.Finish()
+			c.String(http.StatusOK, "data: hi\n\n")
+			s.RecordSent(10)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		metrics, err := testRegistry.Gather()
+		So(err, ShouldBeNil)
+		So(findFamily(metrics, "http_requests_total"), ShouldNotBeNil)
+		So(findFamily(metrics, "http_request_duration_ms"), ShouldBeNil)
+		So(findFamily(metrics, "http_stream_duration_ms"), ShouldNotBeNil)
+	})
+}
+
+func TestLogMiddleware_StreamedResponse(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LogMiddleware())
+	r.GET("/flush", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		_, _ = c.Writer.WriteString("chunk-1")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("chunk-2")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/flush", nil))
+
+	if w.Body.String() != "chunk-1chunk-2" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected log entry")
+	}
+	if entry.Data["response_streamed"] != true {
+		t.Errorf("expected response_streamed=true, got %v", entry.Data["response_streamed"])
+	}
+	if _, ok := entry.Data["response_body"]; ok {
+		t.Errorf("streamed response body should not be captured, got %v", entry.Data["response_body"])
+	}
+}
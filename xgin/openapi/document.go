@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+)
+
+// errorResponseType 统一错误结构，与 middleware.AbortWithErrorResponse 输出一致
+var errorResponseType = reflect.TypeOf(middleware.ErrorResponse{})
+
+// doc 进程内的 OpenAPI 文档，类型化路由注册时写入，XGin 在文档路径上输出
+var doc = newDocument()
+
+type document struct {
+	mu     sync.Mutex
+	o      *Options
+	routes []*routeInfo
+	cached []byte // 序列化结果缓存，路由或配置变化时清空
+}
+
+// routeInfo 类型化路由的元信息，生成文档时转换为 Operation
+type routeInfo struct {
+	method   string
+	path     string // OpenAPI 格式路径，如 /users/{id}
+	respType reflect.Type
+	plan     *bindPlan
+	o        *RouteOptions
+}
+
+func newDocument() *document {
+	return &document{o: DefaultOptions(), routes: make([]*routeInfo, 0)}
+}
+
+// Configure 设置文档信息（标题、服务地址、认证方式等），可多次调用
+func Configure(opts ...Option) {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	for _, opt := range opts {
+		opt(doc.o)
+	}
+	doc.cached = nil
+}
+
+// Path 文档的访问路径
+func Path() string {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	return doc.o.Path
+}
+
+// Document 生成当前的 OpenAPI 文档
+func Document() *Spec {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	return doc.build()
+}
+
+// SpecHandler 输出 OpenAPI 文档（JSON）
+func SpecHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		doc.mu.Lock()
+		if doc.cached == nil {
+			data, err := json.Marshal(doc.build())
+			if err != nil {
+				doc.mu.Unlock()
+				middleware.AbortWithErrorResponse(c, http.StatusInternalServerError, "build openapi document failed")
+				return
+			}
+			doc.cached = data
+		}
+		data := doc.cached
+		doc.mu.Unlock()
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
+
+func (d *document) addRoute(ri *routeInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes = append(d.routes, ri)
+	d.cached = nil
+}
+
+func (d *document) build() *Spec {
+	title := d.o.Title
+	if title == "" {
+		title = xconfig.GetServerName()
+	}
+	version := d.o.Version
+	if version == "" {
+		version = xconfig.GetServerVersion()
+	}
+
+	reg := newSchemaRegistry()
+	errorSchema := reg.schemaOf(errorResponseType)
+
+	spec := &Spec{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Description: d.o.Description, Version: version},
+		Servers: d.o.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+	for _, ri := range d.routes {
+		item := spec.Paths[ri.path]
+		if item == nil {
+			item = &PathItem{}
+			spec.Paths[ri.path] = item
+		}
+		op := d.buildOperation(reg, ri, errorSchema)
+		switch ri.method {
+		case http.MethodGet:
+			item.Get = op
+		case http.MethodPost:
+			item.Post = op
+		case http.MethodPut:
+			item.Put = op
+		case http.MethodPatch:
+			item.Patch = op
+		case http.MethodDelete:
+			item.Delete = op
+		}
+	}
+
+	spec.Components.Schemas = reg.schemas
+	if len(d.o.SecuritySchemes) > 0 {
+		spec.Components.SecuritySchemes = d.o.SecuritySchemes
+	}
+	return spec
+}
+
+func (d *document) buildOperation(reg *schemaRegistry, ri *routeInfo, errorSchema *Schema) *Operation {
+	o := ri.o
+	op := &Operation{
+		OperationID: o.OperationID,
+		Summary:     o.Summary,
+		Description: o.Description,
+		Tags:        o.Tags,
+		Deprecated:  o.Deprecated,
+		Parameters:  ri.plan.parameters(reg),
+		Responses:   make(map[string]*Response),
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(ri.method, ri.path)
+	}
+	if ri.plan.body {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: ri.plan.bodySchema(reg)}},
+		}
+	}
+
+	success := &Response{Description: http.StatusText(o.SuccessStatus)}
+	if !isEmptyType(ri.respType) {
+		success.Content = map[string]*MediaType{"application/json": {Schema: reg.schemaOf(ri.respType)}}
+	}
+	op.Responses[strconv.Itoa(o.SuccessStatus)] = success
+
+	security := o.Security
+	if security == nil {
+		security = d.o.DefaultSecurity
+	}
+	errorStatuses := append([]int{http.StatusBadRequest, http.StatusInternalServerError}, o.ErrorStatuses...)
+	if len(security) > 0 {
+		for _, name := range security {
+			op.Security = append(op.Security, SecurityRequirement{name: {}})
+		}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, status := range errorStatuses {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]*MediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+	return op
+}
@@ -0,0 +1,348 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/trans"
+)
+
+type address struct {
+	City string `json:"city" binding:"required"`
+}
+
+type createUserReq struct {
+	TenantID string    `uri:"tenant"`
+	TraceID  string    `header:"X-Trace-Id"`
+	DryRun   bool      `form:"dry_run"`
+	Name     string    `json:"name" binding:"required,min=2,max=32" description:"用户名"`
+	Email    string    `json:"email" validate:"omitempty,email"`
+	Age      int       `json:"age" binding:"gte=0,lt=150" example:"18"`
+	Role     string    `json:"role" binding:"oneof=admin member"`
+	Tags     []string  `json:"tags" binding:"max=5,dive,min=1"`
+	Address  *address  `json:"address"`
+	Birthday time.Time `json:"birthday"`
+	Secret   string    `json:"-"`
+}
+
+type user struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Friends []*user  `json:"friends,omitempty"`
+	Address *address `json:"address,omitempty"`
+}
+
+type listUsersReq struct {
+	Page    int    `form:"page,default=1" binding:"min=1"`
+	Keyword string `form:"keyword"`
+}
+
+func resetDoc() {
+	doc = newDocument()
+	gin.SetMode(gin.TestMode)
+}
+
+func TestToOpenAPIPath(t *testing.T) {
+	tests := map[string]string{
+		"/users":               "/users",
+		"/users/:id":           "/users/{id}",
+		"/files/*path":         "/files/{path}",
+		"/t/:tenant/users/:id": "/t/{tenant}/users/{id}",
+	}
+	for in, want := range tests {
+		if got := toOpenAPIPath(in); got != want {
+			t.Errorf("toOpenAPIPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := operationID(http.MethodGet, "/users/{id}"); got != "getUsersId" {
+		t.Errorf("unexpected operationId %q", got)
+	}
+	if got := joinPaths("/api/", "/users/"); got != "/api/users/" {
+		t.Errorf("unexpected joined path %q", got)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	reg := newSchemaRegistry()
+	s := reg.schemaOf(reflect.TypeFor[user]())
+	if s.Ref != "#/components/schemas/user" {
+		t.Fatalf("expected ref, got %+v", s)
+	}
+	u := reg.schemas["user"]
+	if u == nil || u.Type != "object" {
+		t.Fatalf("user schema not registered: %+v", reg.schemas)
+	}
+	// 自引用结构体
+	if u.Properties["friends"].Items.Ref != "#/components/schemas/user" {
+		t.Errorf("unexpected friends schema %+v", u.Properties["friends"])
+	}
+	if reg.schemas["address"].Required[0] != "city" {
+		t.Errorf("expected address.city required")
+	}
+
+	req := reg.schemaOf(reflect.TypeFor[createUserReq]())
+	rs := reg.schemas[strings.TrimPrefix(req.Ref, "#/components/schemas/")]
+	name := rs.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 32 || name.Description != "用户名" {
+		t.Errorf("unexpected name schema %+v", name)
+	}
+	if rs.Properties["email"].Format != "email" {
+		t.Errorf("expected email format")
+	}
+	age := rs.Properties["age"]
+	if *age.Minimum != 0 || *age.ExclusiveMaximum != 150 || age.Examples[0] != int64(18) {
+		t.Errorf("unexpected age schema %+v", age)
+	}
+	if !reflect.DeepEqual(rs.Properties["role"].Enum, []any{"admin", "member"}) {
+		t.Errorf("unexpected role enum %v", rs.Properties["role"].Enum)
+	}
+	tags := rs.Properties["tags"]
+	if *tags.MaxItems != 5 || tags.MinItems != nil {
+		t.Errorf("rules after dive should be ignored, got %+v", tags)
+	}
+	if rs.Properties["birthday"].Format != "date-time" {
+		t.Errorf("expected date-time format")
+	}
+	if _, ok := rs.Properties["Secret"]; ok {
+		t.Errorf("json:\"-\" field should be skipped")
+	}
+}
+
+func TestHandle_Document(t *testing.T) {
+	resetDoc()
+	Configure(
+		WithTitle("demo"),
+		WithVersion("v1.0.0"),
+		WithServers("https://api.example.com"),
+		WithSecurityScheme("bearer", BearerJWTScheme()),
+		WithDefaultSecurity("bearer"),
+	)
+
+	r := gin.New()
+	g := r.Group("/api")
+	Handle(g, http.MethodPost, "/tenants/:tenant/users", func(c *gin.Context, req *createUserReq) (*user, error) {
+		return &user{}, nil
+	}, Summary("创建用户"), Tags("user"), SuccessStatus(http.StatusCreated), ErrorStatus(http.StatusConflict))
+	Handle(g, http.MethodGet, "/users", func(c *gin.Context, req *listUsersReq) (*[]user, error) {
+		return nil, nil
+	}, Security())
+
+	spec := Document()
+	if spec.OpenAPI != Version || spec.Info.Title != "demo" || spec.Servers[0].URL != "https://api.example.com" {
+		t.Fatalf("unexpected spec info %+v", spec)
+	}
+
+	create := spec.Paths["/api/tenants/{tenant}/users"].Post
+	if create == nil {
+		t.Fatalf("expected post operation, got %+v", spec.Paths)
+	}
+	if create.OperationID != "postApiTenantsTenantUsers" || create.Summary != "创建用户" {
+		t.Errorf("unexpected operation %+v", create)
+	}
+	params := map[string]*Parameter{}
+	for _, p := range create.Parameters {
+		params[p.In+":"+p.Name] = p
+	}
+	if p := params["path:tenant"]; p == nil || !p.Required {
+		t.Errorf("expected required path param tenant, got %+v", create.Parameters)
+	}
+	if params["header:X-Trace-Id"] == nil || params["query:dry_run"] == nil {
+		t.Errorf("expected header and query params, got %+v", create.Parameters)
+	}
+	body := create.RequestBody.Content["application/json"].Schema
+	if _, ok := body.Properties["name"]; !ok || len(body.Properties) != 7 {
+		t.Errorf("body should only contain json fields, got %v", body.Properties)
+	}
+	for _, status := range []string{"201", "400", "401", "403", "409", "500"} {
+		if create.Responses[status] == nil {
+			t.Errorf("expected response %s", status)
+		}
+	}
+	if create.Responses["400"].Content["application/json"].Schema.Ref != "#/components/schemas/ErrorResponse" {
+		t.Errorf("error responses should reference ErrorResponse")
+	}
+	if len(create.Security) != 1 || create.Security[0]["bearer"] == nil {
+		t.Errorf("expected default security, got %v", create.Security)
+	}
+
+	list := spec.Paths["/api/users"].Get
+	if list.RequestBody != nil || len(list.Security) != 0 || list.Responses["401"] != nil {
+		t.Errorf("unexpected list operation %+v", list)
+	}
+	if list.Responses["200"].Content["application/json"].Schema.Items.Ref != "#/components/schemas/user" {
+		t.Errorf("unexpected list response %+v", list.Responses["200"])
+	}
+	page := list.Parameters[0]
+	if page.Name != "page" || page.Schema.Default != int64(1) || *page.Schema.Minimum != 1 {
+		t.Errorf("unexpected page param %+v", page.Schema)
+	}
+	if spec.Components.SecuritySchemes["bearer"].Scheme != "bearer" {
+		t.Errorf("expected bearer security scheme")
+	}
+}
+
+func TestHandle_Binding(t *testing.T) {
+	resetDoc()
+	r := gin.New()
+	Handle(r, http.MethodPost, "/tenants/:tenant/users", func(c *gin.Context, req *createUserReq) (*createUserReq, error) {
+		switch req.Name {
+		case "exists":
+			return nil, NewError(http.StatusConflict, "user exists", map[string]string{"name": req.Name})
+		case "boom":
+			return nil, errors.New("db down")
+		case "empty":
+			return nil, nil
+		}
+		return req, nil
+	}, SuccessStatus(http.StatusCreated))
+
+	do := func(body string) (*httptest.ResponseRecorder, middleware.ErrorResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/tenants/t1/users?dry_run=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Trace-Id", "trace-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var er middleware.ErrorResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &er)
+		return w, er
+	}
+
+	w, _ := do(`{"name":"alice","role":"admin"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var got createUserReq
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Name != "alice" {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	// uri/header/query 字段不参与 JSON 输出，单独验证绑定结果
+	var bound *createUserReq
+	r.POST("/bind/:tenant", func(c *gin.Context) {
+		bound = new(createUserReq)
+		if err := newBindPlan(reflect.TypeFor[createUserReq](), http.MethodPost).bind(c, bound); err != nil {
+			t.Errorf("bind failed: %v", err)
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, "/bind/t2?dry_run=true", strings.NewReader(`{"name":"bob","role":"member"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Trace-Id", "trace-2")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if bound.TenantID != "t2" || bound.TraceID != "trace-2" || !bound.DryRun || bound.Name != "bob" {
+		t.Errorf("unexpected bound request %+v", bound)
+	}
+
+	if w, er := do(`{"name":"a","role":"admin"}`); w.Code != http.StatusBadRequest || er.Msg != "invalid request" {
+		t.Errorf("expected validation error, got %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := do(`{"name":`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected bind error, got %d", w.Code)
+	}
+	if w, er := do(`{"name":"exists","role":"admin"}`); w.Code != http.StatusConflict || er.Msg != "user exists" || er.Details == nil {
+		t.Errorf("expected 409, got %d %s", w.Code, w.Body.String())
+	}
+	if w, er := do(`{"name":"boom","role":"admin"}`); w.Code != http.StatusInternalServerError || er.Msg != "internal server error" {
+		t.Errorf("expected 500, got %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := do(`{"name":"empty","role":"admin"}`); w.Code != http.StatusCreated || w.Body.Len() != 0 {
+		t.Errorf("expected empty 201, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestSpecHandler(t *testing.T) {
+	resetDoc()
+	Configure(WithTitle("demo"), WithVersion("v1"), WithPath("/docs/openapi.json"))
+
+	r := gin.New()
+	Handle(r, http.MethodGet, "/ping", func(c *gin.Context, req *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	r.GET(Path(), SpecHandler())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec["openapi"] != "3.1.0" {
+		t.Errorf("unexpected version %v", spec["openapi"])
+	}
+	ping := spec["paths"].(map[string]any)["/ping"].(map[string]any)["get"].(map[string]any)
+	if _, ok := ping["requestBody"]; ok {
+		t.Errorf("GET should not have request body")
+	}
+	if _, ok := ping["responses"].(map[string]any)["200"].(map[string]any)["content"]; ok {
+		t.Errorf("empty response type should not have content")
+	}
+
+	// 注册新路由后缓存失效
+	Handle(r, http.MethodDelete, "/ping", func(c *gin.Context, req *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil))
+	if !strings.Contains(w.Body.String(), `"delete"`) {
+		t.Errorf("document should include newly registered route")
+	}
+}
+
+func TestHandle_Deprecated(t *testing.T) {
+	resetDoc()
+
+	r := gin.New()
+	Handle(r, http.MethodGet, "/legacy", func(c *gin.Context, req *struct{}) (*struct{}, error) {
+		return nil, nil
+	}, Deprecated())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy", nil))
+	if w.Header().Get("Deprecation") != "true" {
+		t.Errorf("expected Deprecation header, got %v", w.Header())
+	}
+	if op := Document().Paths["/legacy"].Get; op == nil || !op.Deprecated {
+		t.Errorf("operation should be marked deprecated")
+	}
+}
+
+func TestWriteErrorWithTranslations(t *testing.T) {
+	if err := trans.RegisterTranslations(trans.WithLocales("en", "zh")); err != nil {
+		t.Fatalf("register translations failed: %v", err)
+	}
+
+	r := gin.New()
+	Handle(r, http.MethodPost, "/i18n", func(c *gin.Context, req *createUserReq) (*struct{}, error) {
+		return nil, nil
+	})
+	req := httptest.NewRequest(http.MethodPost, "/i18n?lang=zh", strings.NewReader(`{"name":"a","role":"admin","address":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Msg     string         `json:"msg"`
+		Details []trans.VErrKV `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 with field errors, got %d %s", w.Code, w.Body.String())
+	}
+	fields := make(map[string]string)
+	for _, kv := range resp.Details {
+		fields[kv.Field] = kv.Transl
+	}
+	if fields["Name"] != "Name长度必须至少为2个字符" || fields["Address.City"] != "City为必填字段" {
+		t.Errorf("unexpected details %v", resp.Details)
+	}
+}
@@ -0,0 +1,160 @@
+package openapi
+
+import "net/http"
+
+const defaultPath = "/openapi.json"
+
+// WithPath 设置文档的访问路径，默认 /openapi.json
+// 不能与 swagger UI 路由（/swagger/*any）重叠
+func WithPath(path string) Option {
+	return func(o *Options) {
+		if path != "" {
+			o.Path = path
+		}
+	}
+}
+
+// WithTitle 设置文档标题，默认使用 xconfig 中的服务名
+func WithTitle(title string) Option {
+	return func(o *Options) {
+		o.Title = title
+	}
+}
+
+// WithDescription 设置文档描述
+func WithDescription(description string) Option {
+	return func(o *Options) {
+		o.Description = description
+	}
+}
+
+// WithVersion 设置文档版本，默认使用 xconfig 中的服务版本
+func WithVersion(version string) Option {
+	return func(o *Options) {
+		o.Version = version
+	}
+}
+
+// WithServers 设置服务地址，如 https://api.example.com
+func WithServers(urls ...string) Option {
+	return func(o *Options) {
+		for _, u := range urls {
+			o.Servers = append(o.Servers, Server{URL: u})
+		}
+	}
+}
+
+// WithSecurityScheme 注册认证方式，路由通过 Security(name) 引用
+func WithSecurityScheme(name string, scheme *SecurityScheme) Option {
+	return func(o *Options) {
+		o.SecuritySchemes[name] = scheme
+	}
+}
+
+// WithDefaultSecurity 设置所有路由默认的认证要求，单个路由可通过 Security 覆盖
+func WithDefaultSecurity(names ...string) Option {
+	return func(o *Options) {
+		o.DefaultSecurity = append(o.DefaultSecurity, names...)
+	}
+}
+
+type Option func(*Options)
+
+type Options struct {
+	Path            string
+	Title           string
+	Description     string
+	Version         string
+	Servers         []Server
+	SecuritySchemes map[string]*SecurityScheme
+	DefaultSecurity []string
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Path:            defaultPath,
+		Servers:         make([]Server, 0),
+		SecuritySchemes: make(map[string]*SecurityScheme),
+		DefaultSecurity: make([]string, 0),
+	}
+}
+
+// Summary 设置路由摘要
+func Summary(summary string) RouteOption {
+	return func(o *RouteOptions) {
+		o.Summary = summary
+	}
+}
+
+// Description 设置路由详细描述
+func Description(description string) RouteOption {
+	return func(o *RouteOptions) {
+		o.Description = description
+	}
+}
+
+// Tags 设置路由分组标签
+func Tags(tags ...string) RouteOption {
+	return func(o *RouteOptions) {
+		o.Tags = append(o.Tags, tags...)
+	}
+}
+
+// OperationID 设置 operationId，默认由 method + path 生成
+func OperationID(id string) RouteOption {
+	return func(o *RouteOptions) {
+		o.OperationID = id
+	}
+}
+
+// Security 设置路由的认证要求（引用 WithSecurityScheme 注册的名称），多个名称表示任一满足即可
+// 不传参数表示该路由无需认证，覆盖 WithDefaultSecurity
+func Security(names ...string) RouteOption {
+	return func(o *RouteOptions) {
+		o.Security = append(make([]string, 0), names...)
+	}
+}
+
+// SuccessStatus 设置成功响应的状态码，默认 200
+func SuccessStatus(status int) RouteOption {
+	return func(o *RouteOptions) {
+		o.SuccessStatus = status
+	}
+}
+
+// ErrorStatus 声明路由可能返回的错误状态码，文档中使用统一错误结构
+// 默认已包含 400 和 500，声明了认证要求时额外包含 401 和 403
+func ErrorStatus(statuses ...int) RouteOption {
+	return func(o *RouteOptions) {
+		o.ErrorStatuses = append(o.ErrorStatuses, statuses...)
+	}
+}
+
+// Deprecated 标记路由已废弃，文档中标记 deprecated，响应携带 Deprecation: true 头
+func Deprecated() RouteOption {
+	return func(o *RouteOptions) {
+		o.Deprecated = true
+	}
+}
+
+type RouteOption func(*RouteOptions)
+
+type RouteOptions struct {
+	Summary       string
+	Description   string
+	Tags          []string
+	OperationID   string
+	Security      []string // nil 表示使用文档默认认证要求
+	SuccessStatus int
+	ErrorStatuses []int
+	Deprecated    bool
+}
+
+func DefaultRouteOptions() *RouteOptions {
+	return &RouteOptions{
+		Tags:          make([]string, 0),
+		Security:      nil,
+		SuccessStatus: http.StatusOK,
+		ErrorStatuses: make([]int, 0),
+	}
+}
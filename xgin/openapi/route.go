@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/trans"
+)
+
+// Handler 类型化 handler：req 已完成绑定与校验，返回的 resp 以 JSON 输出，返回 nil 时仅输出状态码
+// 返回 *Error 时按其状态码输出统一错误结构，其它错误输出 500
+type Handler[Req, Resp any] func(c *gin.Context, req *Req) (*Resp, error)
+
+// Error 类型化 handler 返回的业务错误，按 Status 输出统一错误结构
+type Error struct {
+	Status  int
+	Msg     string
+	Details any
+}
+
+// NewError 创建业务错误，details 可选，仅取第一个
+func NewError(status int, msg string, details ...any) *Error {
+	e := &Error{Status: status, Msg: msg}
+	if len(details) > 0 {
+		e.Details = details[0]
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Handle 注册类型化路由，并根据 Req/Resp 的结构生成 OpenAPI 文档
+// Req 字段按 tag 绑定：uri 为路径参数，header 为请求头，form 为 query 参数，
+// POST/PUT/PATCH 请求中带 json tag（或无绑定 tag）的字段为 JSON body；binding/validate tag 同时用于校验和文档约束
+func Handle[Req, Resp any](r gin.IRoutes, method, path string, h Handler[Req, Resp], opts ...RouteOption) gin.IRoutes {
+	o := DefaultRouteOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	plan := newBindPlan(reflect.TypeFor[Req](), method)
+	fullPath := path
+	if bp, ok := r.(interface{ BasePath() string }); ok {
+		fullPath = joinPaths(bp.BasePath(), path)
+	}
+	doc.addRoute(&routeInfo{
+		method:   method,
+		path:     toOpenAPIPath(fullPath),
+		respType: reflect.TypeFor[Resp](),
+		plan:     plan,
+		o:        o,
+	})
+
+	successStatus := o.SuccessStatus
+	handlers := make([]gin.HandlerFunc, 0, 2)
+	if o.Deprecated {
+		// 文档中标记废弃的路由，响应同时携带 Deprecation 头
+		handlers = append(handlers, middleware.GinXDeprecationMiddleware())
+	}
+	return r.Handle(method, path, append(handlers, func(c *gin.Context) {
+		req := new(Req)
+		if err := plan.bind(c, req); err != nil {
+			writeError(c, err)
+			return
+		}
+		resp, err := h(c, req)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if c.Writer.Written() {
+			// handler 已自行输出响应
+			return
+		}
+		if resp == nil {
+			c.Status(successStatus)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		c.JSON(successStatus, resp)
+	})...)
+}
+
+// bindErr 请求参数绑定失败（格式错误），区别于校验失败
+type bindErr struct {
+	err error
+}
+
+func (e *bindErr) Error() string { return e.err.Error() }
+func (e *bindErr) Unwrap() error { return e.err }
+
+func writeError(c *gin.Context, err error) {
+	var he *Error
+	var be *bindErr
+	var ves validator.ValidationErrors
+	switch {
+	case errors.As(err, &he):
+		middleware.AbortWithErrorResponse(c, he.Status, he.Msg, he.Details)
+	case errors.As(err, &be):
+		middleware.AbortWithErrorResponse(c, http.StatusBadRequest, "invalid request", be.Error())
+	case errors.As(err, &ves):
+		// 启用多语言翻译时 details 为按字段组织的 []trans.VErrKV，与契约校验一致
+		var ve *trans.ValidationErr
+		if errors.As(trans.ToErr(c, err), &ve) {
+			middleware.AbortWithErrorResponse(c, http.StatusBadRequest, "invalid request", ve.Errors)
+			return
+		}
+		middleware.AbortWithErrorResponse(c, http.StatusBadRequest, "invalid request", trans.ToZHErrMsg(err))
+	default:
+		_ = c.Error(err)
+		middleware.AbortWithErrorResponse(c, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// bindPlan 根据 Req 结构预先计算需要的绑定步骤与文档参数
+type bindPlan struct {
+	typ    reflect.Type
+	uri    []reflect.StructField
+	header []reflect.StructField
+	query  []reflect.StructField
+	fields []reflect.StructField // body 字段
+	body   bool
+}
+
+func newBindPlan(t reflect.Type, method string) *bindPlan {
+	p := &bindPlan{typ: t}
+	hasBody := method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+
+	if t.Kind() != reflect.Struct {
+		p.body = hasBody
+		return p
+	}
+	for _, f := range structFields(t) {
+		_, isURI := tagName(f, "uri")
+		_, isHeader := tagName(f, "header")
+		_, isQuery := tagName(f, "form")
+		_, hasJSON := f.Tag.Lookup("json")
+		switch {
+		case isURI:
+			p.uri = append(p.uri, f)
+		case isHeader:
+			p.header = append(p.header, f)
+		case hasBody && (hasJSON || !isQuery):
+			p.fields = append(p.fields, f)
+		case isQuery:
+			p.query = append(p.query, f)
+		case !hasJSON:
+			// gin 的 query 绑定对未声明 form tag 的字段使用字段名
+			p.query = append(p.query, f)
+		}
+	}
+	p.body = len(p.fields) > 0
+	return p
+}
+
+// bind 依次绑定 uri/header/query/body，各步骤的校验错误忽略，最后统一校验一次
+func (p *bindPlan) bind(c *gin.Context, req any) error {
+	steps := make([]func() error, 0, 4)
+	if len(p.uri) > 0 {
+		steps = append(steps, func() error { return c.ShouldBindUri(req) })
+	}
+	if len(p.header) > 0 {
+		steps = append(steps, func() error { return c.ShouldBindHeader(req) })
+	}
+	if len(p.query) > 0 {
+		steps = append(steps, func() error { return c.ShouldBindQuery(req) })
+	}
+	if p.body && c.Request.ContentLength != 0 {
+		steps = append(steps, func() error {
+			return c.ShouldBindWith(req, binding.Default(c.Request.Method, c.ContentType()))
+		})
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			var ves validator.ValidationErrors
+			if !errors.As(err, &ves) {
+				return &bindErr{err: err}
+			}
+		}
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(req)
+}
+
+func (p *bindPlan) parameters(reg *schemaRegistry) []*Parameter {
+	params := make([]*Parameter, 0, len(p.uri)+len(p.header)+len(p.query))
+	add := func(fields []reflect.StructField, tag, in string) {
+		for _, f := range fields {
+			name, _ := tagName(f, tag)
+			params = append(params, &Parameter{
+				Name:        name,
+				In:          in,
+				Description: f.Tag.Get("description"),
+				Required:    in == "path" || isRequired(f),
+				Schema:      reg.fieldSchema(f),
+			})
+		}
+	}
+	add(p.uri, "uri", "path")
+	add(p.query, "form", "query")
+	add(p.header, "header", "header")
+	return params
+}
+
+// bodySchema Req 全部为 body 字段时引用 Req 本身，否则只包含 body 字段
+func (p *bindPlan) bodySchema(reg *schemaRegistry) *Schema {
+	if p.typ.Kind() != reflect.Struct || (len(p.uri) == 0 && len(p.header) == 0 && len(p.query) == 0) {
+		return reg.schemaOf(p.typ)
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range p.fields {
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		s.Properties[name] = reg.fieldSchema(f)
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// isEmptyType 无字段的结构体（如 struct{}）不生成响应 body
+func isEmptyType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 0
+}
+
+// toOpenAPIPath 将 gin 路径参数 :id / *path 转换为 {id} / {path}
+func toOpenAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID 由 method 与路径生成，如 GET /users/{id} -> getUsersId
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// joinPaths 与 gin 一致：拼接路径并保留末尾的 /
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(relative, "/")
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
@@ -0,0 +1,332 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	bytesType      = reflect.TypeOf([]byte(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaRegistry 将具名结构体注册到 components/schemas，相同类型只生成一次
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// schemaOf 生成类型的 Schema，具名结构体返回 $ref
+func (r *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "纳秒"}
+	case bytesType:
+		return &Schema{Type: "string", ContentEncoding: "base64"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.register(t)}
+	default:
+		// interface 等无法确定结构的类型
+		return &Schema{}
+	}
+}
+
+// register 注册具名结构体并返回 component 名称，同名不同包的类型追加包名区分
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	name := componentName(t.Name())
+	if _, exists := r.schemas[name]; exists {
+		name = componentName(pkgName(t) + "." + t.Name())
+	}
+	r.names[t] = name
+	// 先占位，避免自引用结构体无限递归
+	r.schemas[name] = &Schema{}
+	*r.schemas[name] = *r.structSchema(t)
+	return name
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range structFields(t) {
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		s.Properties[name] = r.fieldSchema(f)
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// fieldSchema 生成字段 Schema 并应用 description/example/default 与校验规则
+func (r *schemaRegistry) fieldSchema(f reflect.StructField) *Schema {
+	s := r.schemaOf(f.Type)
+	if s.Ref != "" {
+		// $ref 的兄弟字段在 3.1 中有效，但仅保留描述避免覆盖引用的结构
+		if desc := f.Tag.Get("description"); desc != "" {
+			return &Schema{Ref: s.Ref, Description: desc}
+		}
+		return s
+	}
+	if desc := f.Tag.Get("description"); desc != "" {
+		s.Description = desc
+	}
+	if ex, ok := f.Tag.Lookup("example"); ok {
+		s.Examples = []any{parseValue(ex, s.Type)}
+	}
+	if def := formDefault(f); def != "" {
+		s.Default = parseValue(def, s.Type)
+	}
+	applyRules(s, f)
+	return s
+}
+
+// structFields 展开匿名嵌入结构体的字段，跳过未导出字段
+func structFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// jsonName 字段的 JSON 名称，json:"-" 时返回 false
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// tagName 读取 uri/form/header 等绑定 tag 的名称
+func tagName(f reflect.StructField, key string) (string, bool) {
+	tag, ok := f.Tag.Lookup(key)
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// formDefault gin form tag 中的默认值，如 form:"page,default=1"
+func formDefault(f reflect.StructField) string {
+	_, opts, _ := strings.Cut(f.Tag.Get("form"), ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if v, ok := strings.CutPrefix(opt, "default="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// validateRules 合并 binding 与 validate tag 中的校验规则，dive 之后的规则作用于元素，忽略
+func validateRules(f reflect.StructField) []string {
+	rules := make([]string, 0)
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(f.Tag.Get(key), ",") {
+			if rule == "dive" {
+				break
+			}
+			if rule != "" {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range validateRules(f) {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules 将 validator 规则映射为 JSON Schema 约束
+func applyRules(s *Schema, f reflect.StructField) {
+	for _, rule := range validateRules(f) {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "gte":
+			setBound(s, param, true, false)
+		case "max", "lte":
+			setBound(s, param, false, false)
+		case "gt":
+			setBound(s, param, true, true)
+		case "lt":
+			setBound(s, param, false, true)
+		case "len":
+			setBound(s, param, true, false)
+			setBound(s, param, false, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, parseValue(strings.Trim(v, "'"), s.Type))
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri", "http_url":
+			s.Format = "uri"
+		case "uuid", "uuid4", "uuid_rfc4122", "uuid4_rfc4122":
+			s.Format = "uuid"
+		case "ipv4":
+			s.Format = "ipv4"
+		case "ipv6":
+			s.Format = "ipv6"
+		case "hostname", "hostname_rfc1123":
+			s.Format = "hostname"
+		}
+	}
+}
+
+// setBound 按类型设置上下限：字符串为长度，数组为元素个数，数值为取值范围
+func setBound(s *Schema, param string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "string", "array":
+		v := int(n)
+		if exclusive && lower {
+			v++
+		} else if exclusive {
+			v--
+		}
+		switch {
+		case s.Type == "string" && lower:
+			s.MinLength = &v
+		case s.Type == "string":
+			s.MaxLength = &v
+		case lower:
+			s.MinItems = &v
+		default:
+			s.MaxItems = &v
+		}
+	case "integer", "number":
+		switch {
+		case lower && exclusive:
+			s.ExclusiveMinimum = &n
+		case lower:
+			s.Minimum = &n
+		case exclusive:
+			s.ExclusiveMaximum = &n
+		default:
+			s.Maximum = &n
+		}
+	}
+}
+
+// parseValue 按 Schema 类型解析 tag 中的字面量
+func parseValue(v, typ string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// componentName 将类型名转换为合法的 component 名称（泛型类型名包含 [ ] 等字符）
+func componentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func pkgName(t reflect.Type) string {
+	p := t.PkgPath()
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		p = p[i+1:]
+	}
+	return p
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
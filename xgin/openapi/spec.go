@@ -0,0 +1,130 @@
+package openapi
+
+// Version 生成文档使用的 OpenAPI 版本
+const Version = "3.1.0"
+
+// Spec OpenAPI 3.1 文档，仅包含 xgin 生成用到的字段
+type Spec struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem 同一路径下各 HTTP 方法的操作
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path/query/header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityRequirement 安全要求，key 为 SecurityScheme 名称，value 为所需 scope
+type SecurityRequirement map[string][]string
+
+// SecurityScheme 认证方式描述
+type SecurityScheme struct {
+	Type         string `json:"type"` // http/apiKey/oauth2/openIdConnect
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`   // apiKey 参数名
+	In           string `json:"in,omitempty"`     // apiKey 位置：header/query/cookie
+	Scheme       string `json:"scheme,omitempty"` // http 认证方案，如 bearer
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema JSON Schema（OpenAPI 3.1 与 JSON Schema 2020-12 对齐）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Examples             []any              `json:"examples,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+}
+
+// BearerJWTScheme Authorization: Bearer <JWT>，对应 auth.NewJWTAuthenticator
+func BearerJWTScheme() *SecurityScheme {
+	return &SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+}
+
+// APIKeyScheme API Key 认证，in 为 header/query/cookie，对应 auth.NewAPIKeyAuthenticator
+func APIKeyScheme(in, name string) *SecurityScheme {
+	return &SecurityScheme{Type: "apiKey", In: in, Name: name}
+}
+
+// HMACScheme HMAC 签名认证，对应 auth.NewHMACAuthenticator
+// OpenAPI 无法完整描述签名流程，以 X-Access-Key 头表示，签名规则写入 description
+func HMACScheme() *SecurityScheme {
+	return &SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        "X-Access-Key",
+		Description: "HMAC-SHA256 签名，需同时携带 X-Timestamp、X-Nonce、X-Signature 请求头",
+	}
+}
@@ -0,0 +1,95 @@
+package options
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupMiddleware 设置路由组的中间件链，仅作用于组内路由
+func GroupMiddleware(m ...gin.HandlerFunc) GroupOption {
+	return func(o *GroupOptions) {
+		o.Middlewares = append(o.Middlewares, m...)
+	}
+}
+
+// GroupAuth 设置路由组的认证策略，如 auth.Require(auth.RequireScopes("admin"))，先于 GroupMiddleware 执行
+func GroupAuth(policy gin.HandlerFunc) GroupOption {
+	return func(o *GroupOptions) {
+		o.Auth = policy
+	}
+}
+
+// GroupLogBody 覆盖组内请求日志是否记录请求/响应 body（日志本身仍然输出）
+func GroupLogBody(enable bool) GroupOption {
+	return func(o *GroupOptions) {
+		o.LogBody = &enable
+	}
+}
+
+// GroupMetric 覆盖组内请求是否采集 http_requests_total/http_request_duration_ms
+func GroupMetric(enable bool) GroupOption {
+	return func(o *GroupOptions) {
+		o.Metric = &enable
+	}
+}
+
+// GroupVersion 设置 API 版本，版本作为路径前缀，如 WithGroup("/users", ..., GroupVersion("v1")) 对应 /v1/users
+func GroupVersion(version string) GroupOption {
+	return func(o *GroupOptions) {
+		o.Version = version
+	}
+}
+
+// GroupDeprecated 标记组内路由已废弃，响应自动携带 Deprecation 头，since 为零值时输出 Deprecation: true
+func GroupDeprecated(since time.Time) GroupOption {
+	return func(o *GroupOptions) {
+		o.Deprecated = true
+		o.DeprecatedSince = since
+	}
+}
+
+// GroupSunset 设置组内路由计划下线时间（隐含已废弃），响应自动携带 Deprecation/Sunset 头，link 为迁移说明地址（可为空）
+func GroupSunset(sunset time.Time, link string) GroupOption {
+	return func(o *GroupOptions) {
+		o.Deprecated = true
+		o.Sunset = sunset
+		o.DeprecationLink = link
+	}
+}
+
+// GroupMaxBodySize 覆盖组内请求 body 大小上限（XGin.MaxRequestBodySize），如上传接口放宽限制，size <= 0 表示不限制
+func GroupMaxBodySize(size int64) GroupOption {
+	return func(o *GroupOptions) {
+		o.MaxBodySize = &size
+	}
+}
+
+// GroupDeadline 覆盖组内请求的读写超时（XGin.WriteTimeout/ReadTimeout），用于下载、长轮询等耗时路由，d <= 0 表示不限制
+func GroupDeadline(d time.Duration) GroupOption {
+	return func(o *GroupOptions) {
+		o.Deadline = &d
+	}
+}
+
+type GroupOption func(*GroupOptions)
+
+type GroupOptions struct {
+	Middlewares     []gin.HandlerFunc
+	Auth            gin.HandlerFunc
+	LogBody         *bool // nil 表示沿用全局配置
+	Metric          *bool // nil 表示沿用全局配置
+	Version         string
+	Deprecated      bool
+	DeprecatedSince time.Time
+	Sunset          time.Time
+	DeprecationLink string
+	MaxBodySize     *int64         // nil 表示沿用全局配置
+	Deadline        *time.Duration // nil 表示沿用全局配置
+}
+
+func DefaultGroupOptions() *GroupOptions {
+	return &GroupOptions{
+		Middlewares: make([]gin.HandlerFunc, 0),
+	}
+}
@@ -0,0 +1,69 @@
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDefaultGroupOptions(t *testing.T) {
+	opts := DefaultGroupOptions()
+
+	if len(opts.Middlewares) != 0 || opts.Auth != nil {
+		t.Error("group should have no middleware by default")
+	}
+	if opts.LogBody != nil || opts.Metric != nil {
+		t.Error("group should inherit log body and metric settings by default")
+	}
+	if opts.Version != "" || opts.Deprecated {
+		t.Error("group should not be versioned or deprecated by default")
+	}
+	if opts.MaxBodySize != nil || opts.Deadline != nil {
+		t.Error("group should inherit body size and deadline settings by default")
+	}
+}
+
+func TestGroupOptions(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	noop := func(c *gin.Context) {}
+
+	opts := DefaultGroupOptions()
+	GroupMiddleware(noop, noop)(opts)
+	GroupAuth(noop)(opts)
+	GroupLogBody(false)(opts)
+	GroupMetric(true)(opts)
+	GroupVersion("v1")(opts)
+	GroupDeprecated(since)(opts)
+	GroupMaxBodySize(100 << 20)(opts)
+	GroupDeadline(0)(opts)
+
+	if len(opts.Middlewares) != 2 || opts.Auth == nil {
+		t.Errorf("unexpected middlewares %d, auth %v", len(opts.Middlewares), opts.Auth != nil)
+	}
+	if opts.LogBody == nil || *opts.LogBody {
+		t.Error("LogBody should be false")
+	}
+	if opts.Metric == nil || !*opts.Metric {
+		t.Error("Metric should be true")
+	}
+	if opts.Version != "v1" {
+		t.Errorf("unexpected Version %q", opts.Version)
+	}
+	if !opts.Deprecated || !opts.DeprecatedSince.Equal(since) {
+		t.Errorf("unexpected deprecation %v %v", opts.Deprecated, opts.DeprecatedSince)
+	}
+	if opts.MaxBodySize == nil || *opts.MaxBodySize != 100<<20 {
+		t.Error("MaxBodySize should be 100MB")
+	}
+	if opts.Deadline == nil || *opts.Deadline != 0 {
+		t.Error("Deadline should be 0")
+	}
+
+	opts = DefaultGroupOptions()
+	GroupSunset(sunset, "https://example.com/migrate")(opts)
+	if !opts.Deprecated || !opts.Sunset.Equal(sunset) || opts.DeprecationLink != "https://example.com/migrate" {
+		t.Errorf("GroupSunset should imply deprecated, got %+v", opts)
+	}
+}
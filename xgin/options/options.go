@@ -28,6 +28,21 @@ func EnableZHTranslations(enableZHTranslations bool) Option {
 	}
 }
 
+// EnableTranslations 启用多语言校验错误翻译，首个为默认语言，如 EnableTranslations("zh", "en", "ja")
+// 按请求的 lang 参数或 Accept-Language 选择语言，见 trans.ToErr；启用 zh 时同时兼容 trans.ToZHErr
+func EnableTranslations(locales ...string) Option {
+	return func(o *Options) {
+		o.TranslationLocales = append(o.TranslationLocales, locales...)
+	}
+}
+
+// TranslationMessageFiles 设置自定义 tag 的翻译文件（YAML，locale -> tag -> 翻译模板），见 trans.WithMessageFiles
+func TranslationMessageFiles(files ...string) Option {
+	return func(o *Options) {
+		o.TranslationMessageFiles = append(o.TranslationMessageFiles, files...)
+	}
+}
+
 func EnableMetricMiddleware(enableMetricMiddleware bool) Option {
 	return func(o *Options) {
 		o.EnableMetricMiddleware = enableMetricMiddleware
@@ -42,6 +57,62 @@ func MetricsPath(path string) Option {
 	}
 }
 
+// EnableCompressMiddleware 是否启用响应压缩中间件（zstd/br/gzip），默认关闭
+func EnableCompressMiddleware(enableCompressMiddleware bool) Option {
+	return func(o *Options) {
+		o.EnableCompressMiddleware = enableCompressMiddleware
+	}
+}
+
+// CompressMinSize 设置最小压缩阈值（字节），默认 1024
+func CompressMinSize(size int) Option {
+	return func(o *Options) {
+		o.CompressMinSize = size
+	}
+}
+
+// CompressContentTypes 设置允许压缩的 Content-Type 列表，覆盖默认列表
+func CompressContentTypes(contentTypes ...string) Option {
+	return func(o *Options) {
+		o.CompressContentTypes = append(o.CompressContentTypes, contentTypes...)
+	}
+}
+
+// CompressEncodings 设置支持的压缩编码及偏好顺序，可选 "zstd"、"br"、"gzip"
+func CompressEncodings(encodings ...string) Option {
+	return func(o *Options) {
+		o.CompressEncodings = append(o.CompressEncodings, encodings...)
+	}
+}
+
+// CompressSkipPaths 设置压缩中间件忽略的路由，匹配规则同 LogSkipPaths
+func CompressSkipPaths(paths ...string) Option {
+	return func(o *Options) {
+		o.CompressSkipPaths = append(o.CompressSkipPaths, paths...)
+	}
+}
+
+// OpenAPIValidateFile 设置契约校验使用的 OpenAPI 3 文档路径，Build 时加载，为空表示不启用
+func OpenAPIValidateFile(file string) Option {
+	return func(o *Options) {
+		o.OpenAPIValidateFile = file
+	}
+}
+
+// OpenAPIValidateResponse 非 release 模式下是否同时校验响应，违反契约时记录日志，默认关闭
+func OpenAPIValidateResponse(enable bool) Option {
+	return func(o *Options) {
+		o.OpenAPIValidateResponse = enable
+	}
+}
+
+// OpenAPIValidateSkipPaths 设置契约校验忽略的路由，匹配规则同 LogSkipPaths
+func OpenAPIValidateSkipPaths(paths ...string) Option {
+	return func(o *Options) {
+		o.OpenAPIValidateSkipPaths = append(o.OpenAPIValidateSkipPaths, paths...)
+	}
+}
+
 type Option func(*Options)
 
 type Options struct {
@@ -51,6 +122,19 @@ type Options struct {
 	EnableMetricMiddleware bool
 	LogSkipPaths           []string // 日志中间件忽略的路由列表
 	MetricsPath            string   // Prometheus metrics 端点路径，默认 "/metrics"
+
+	TranslationLocales      []string // 多语言翻译启用的语言，为空表示不启用
+	TranslationMessageFiles []string // 自定义 tag 翻译文件
+
+	EnableCompressMiddleware bool
+	CompressMinSize          int      // 最小压缩阈值（字节），<= 0 时使用默认值 1024
+	CompressContentTypes     []string // 允许压缩的 Content-Type 列表，为空时使用默认列表
+	CompressEncodings        []string // 压缩编码偏好顺序，为空时使用默认顺序 zstd > br > gzip
+	CompressSkipPaths        []string // 压缩中间件忽略的路由列表
+
+	OpenAPIValidateFile      string   // 契约校验使用的 OpenAPI 3 文档路径，为空表示不启用
+	OpenAPIValidateResponse  bool     // 非 release 模式下是否校验响应
+	OpenAPIValidateSkipPaths []string // 契约校验忽略的路由列表
 }
 
 func DefaultOptions() *Options {
@@ -61,5 +145,7 @@ func DefaultOptions() *Options {
 		EnableZHTranslations:   false,
 		LogSkipPaths:           make([]string, 0),
 		MetricsPath:            "/metrics",
+
+		EnableCompressMiddleware: false,
 	}
 }
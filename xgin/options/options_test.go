@@ -1,6 +1,9 @@
 package options
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestDefaultOptions(t *testing.T) {
 	opts := DefaultOptions()
@@ -68,6 +71,22 @@ func TestEnableTraceMiddleware(t *testing.T) {
 	}
 }
 
+func TestEnableTranslations(t *testing.T) {
+	opts := DefaultOptions()
+	if len(opts.TranslationLocales) != 0 {
+		t.Error("translations should be disabled by default")
+	}
+	EnableTranslations("zh", "en")(opts)
+	EnableTranslations("ja")(opts)
+	TranslationMessageFiles("a.yaml", "b.yaml")(opts)
+	if strings.Join(opts.TranslationLocales, ",") != "zh,en,ja" {
+		t.Errorf("unexpected locales %v", opts.TranslationLocales)
+	}
+	if len(opts.TranslationMessageFiles) != 2 {
+		t.Errorf("unexpected message files %v", opts.TranslationMessageFiles)
+	}
+}
+
 func TestEnableZHTranslations(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -169,3 +188,53 @@ func TestMultipleOptions(t *testing.T) {
 		t.Error("EnableZHTranslations should be true")
 	}
 }
+
+func TestCompressOptions(t *testing.T) {
+	opts := DefaultOptions()
+	if opts.EnableCompressMiddleware {
+		t.Error("EnableCompressMiddleware should be false by default")
+	}
+
+	EnableCompressMiddleware(true)(opts)
+	CompressMinSize(2048)(opts)
+	CompressContentTypes("application/json")(opts)
+	CompressEncodings("gzip", "br")(opts)
+	CompressSkipPaths("/download/")(opts)
+
+	if !opts.EnableCompressMiddleware {
+		t.Error("EnableCompressMiddleware should be true")
+	}
+	if opts.CompressMinSize != 2048 {
+		t.Errorf("expected CompressMinSize 2048, got %d", opts.CompressMinSize)
+	}
+	if len(opts.CompressContentTypes) != 1 || opts.CompressContentTypes[0] != "application/json" {
+		t.Errorf("unexpected CompressContentTypes %v", opts.CompressContentTypes)
+	}
+	if len(opts.CompressEncodings) != 2 || opts.CompressEncodings[0] != "gzip" {
+		t.Errorf("unexpected CompressEncodings %v", opts.CompressEncodings)
+	}
+	if len(opts.CompressSkipPaths) != 1 || opts.CompressSkipPaths[0] != "/download/" {
+		t.Errorf("unexpected CompressSkipPaths %v", opts.CompressSkipPaths)
+	}
+}
+
+func TestOpenAPIValidateOptions(t *testing.T) {
+	opts := DefaultOptions()
+	if opts.OpenAPIValidateFile != "" || opts.OpenAPIValidateResponse {
+		t.Error("openapi validation should be disabled by default")
+	}
+
+	OpenAPIValidateFile("./api/openapi.yaml")(opts)
+	OpenAPIValidateResponse(true)(opts)
+	OpenAPIValidateSkipPaths("/internal/")(opts)
+
+	if opts.OpenAPIValidateFile != "./api/openapi.yaml" {
+		t.Errorf("unexpected OpenAPIValidateFile %q", opts.OpenAPIValidateFile)
+	}
+	if !opts.OpenAPIValidateResponse {
+		t.Error("OpenAPIValidateResponse should be true")
+	}
+	if len(opts.OpenAPIValidateSkipPaths) != 1 || opts.OpenAPIValidateSkipPaths[0] != "/internal/" {
+		t.Errorf("unexpected OpenAPIValidateSkipPaths %v", opts.OpenAPIValidateSkipPaths)
+	}
+}
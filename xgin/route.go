@@ -0,0 +1,42 @@
+package xgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/openapi"
+)
+
+// GET 注册类型化 GET 路由并写入 OpenAPI 文档，见 openapi.Handle
+// 使用示例：
+//
+//	type GetUserReq struct {
+//		ID int64 `uri:"id" binding:"required,min=1"`
+//	}
+//
+//	xgin.GET(e, "/users/:id", func(c *gin.Context, req *GetUserReq) (*User, error) {
+//		return svc.GetUser(c, req.ID)
+//	}, openapi.Summary("查询用户"))
+func GET[Req, Resp any](r gin.IRoutes, path string, h openapi.Handler[Req, Resp], opts ...openapi.RouteOption) gin.IRoutes {
+	return openapi.Handle(r, http.MethodGet, path, h, opts...)
+}
+
+// POST 注册类型化 POST 路由并写入 OpenAPI 文档，见 openapi.Handle
+func POST[Req, Resp any](r gin.IRoutes, path string, h openapi.Handler[Req, Resp], opts ...openapi.RouteOption) gin.IRoutes {
+	return openapi.Handle(r, http.MethodPost, path, h, opts...)
+}
+
+// PUT 注册类型化 PUT 路由并写入 OpenAPI 文档，见 openapi.Handle
+func PUT[Req, Resp any](r gin.IRoutes, path string, h openapi.Handler[Req, Resp], opts ...openapi.RouteOption) gin.IRoutes {
+	return openapi.Handle(r, http.MethodPut, path, h, opts...)
+}
+
+// PATCH 注册类型化 PATCH 路由并写入 OpenAPI 文档，见 openapi.Handle
+func PATCH[Req, Resp any](r gin.IRoutes, path string, h openapi.Handler[Req, Resp], opts ...openapi.RouteOption) gin.IRoutes {
+	return openapi.Handle(r, http.MethodPatch, path, h, opts...)
+}
+
+// DELETE 注册类型化 DELETE 路由并写入 OpenAPI 文档，见 openapi.Handle
+func DELETE[Req, Resp any](r gin.IRoutes, path string, h openapi.Handler[Req, Resp], opts ...openapi.RouteOption) gin.IRoutes {
+	return openapi.Handle(r, http.MethodDelete, path, h, opts...)
+}
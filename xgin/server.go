@@ -0,0 +1,45 @@
+package xgin
+
+import (
+	"net/http"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"golang.org/x/net/http2"
+)
+
+// newHTTPServer 按配置创建 http server，超时与请求头上限同时作用于主监听和额外监听
+func newHTTPServer(c *Config, addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: xutil.ToDuration(c.ReadHeaderTimeout),
+		ReadTimeout:       xutil.ToDuration(c.ReadTimeout),
+		WriteTimeout:      xutil.ToDuration(c.WriteTimeout),
+		IdleTimeout:       xutil.ToDuration(c.IdleTimeout),
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// newHTTP2Server 按 XGin.HTTP2 配置创建 http2.Server，未配置的超时沿用 http.Server 的设置
+func newHTTP2Server(c *HTTP2Config) *http2.Server {
+	if c == nil {
+		c = http2ConfigMergeDefault(nil)
+	}
+	return &http2.Server{
+		MaxConcurrentStreams:         c.MaxConcurrentStreams,
+		MaxReadFrameSize:             c.MaxReadFrameSize,
+		MaxUploadBufferPerConnection: c.MaxUploadBufferPerConnection,
+		MaxUploadBufferPerStream:     c.MaxUploadBufferPerStream,
+		IdleTimeout:                  xutil.ToDuration(c.IdleTimeout),
+		ReadIdleTimeout:              xutil.ToDuration(c.ReadIdleTimeout),
+		PingTimeout:                  xutil.ToDuration(c.PingTimeout),
+		WriteByteTimeout:             xutil.ToDuration(c.WriteByteTimeout),
+	}
+}
+
+// configureHTTP2 为 TLS server 启用按配置设置的 HTTP/2，需在 TLSConfig 赋值后调用
+// 每个 http.Server 使用独立的 http2.Server，保证 Shutdown 时各自通知连接关闭
+func configureHTTP2(srv *http.Server, c *HTTP2Config) error {
+	return http2.ConfigureServer(srv, newHTTP2Server(c))
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xcache"
+)
+
+// localCache xcache.Cache 中 cacheStore 依赖的方法子集，便于测试替换
+type localCache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any) bool
+	SetWithTTL(key string, value any, ttl time.Duration) bool
+	Del(key string)
+	Wait()
+}
+
+// cacheStore 基于 xcache 的本地存储
+// 注意：ristretto 在容量打满时可能拒绝写入，且只在进程内生效，仅适用于单实例或对一致性要求不高的场景
+type cacheStore struct {
+	mu    sync.Mutex // 保证 SetNX 的 check-and-set 原子性
+	cache localCache
+}
+
+// NewCacheStore 基于 xcache 创建 Store，一般传入 xcache.C(name)
+func NewCacheStore(c *xcache.Cache) Store {
+	if c == nil {
+		return &cacheStore{}
+	}
+	return &cacheStore{cache: c}
+}
+
+func (s *cacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	if s.cache == nil {
+		return nil, false, ErrNilBackend
+	}
+	v, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, false, nil
+	}
+	return b, true, nil
+}
+
+func (s *cacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if s.cache == nil {
+		return ErrNilBackend
+	}
+	s.set(key, value, ttl)
+	return nil
+}
+
+func (s *cacheStore) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if s.cache == nil {
+		return false, ErrNilBackend
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cache.Get(key); ok {
+		return false, nil
+	}
+	s.set(key, value, ttl)
+	return true, nil
+}
+
+func (s *cacheStore) Del(_ context.Context, key string) error {
+	if s.cache == nil {
+		return ErrNilBackend
+	}
+	s.cache.Del(key)
+	return nil
+}
+
+// set 写入后等待 ristretto 缓冲区落地，保证紧随其后的 Get 可见
+func (s *cacheStore) set(key string, value []byte, ttl time.Duration) {
+	stored := append([]byte(nil), value...)
+	if ttl > 0 {
+		s.cache.SetWithTTL(key, stored, ttl)
+	} else {
+		s.cache.Set(key, stored)
+	}
+	s.cache.Wait()
+}
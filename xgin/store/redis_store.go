@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore 基于 xredis 的分布式存储，适用于多实例部署
+type redisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore 基于 redis client 创建 Store，一般传入 xredis.C(name)
+// prefix 会拼接在所有 key 之前，用于隔离不同业务
+func NewRedisStore(client redis.Cmdable, prefix string) Store {
+	// xredis.C() 未配置时返回 nil *redis.Client，需转为 nil 接口避免调用时 panic
+	if c, ok := client.(*redis.Client); ok && c == nil {
+		client = nil
+	}
+	return &redisStore{client: client, prefix: prefix}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if s.client == nil {
+		return nil, false, ErrNilBackend
+	}
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if s.client == nil {
+		return ErrNilBackend
+	}
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+func (s *redisStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if s.client == nil {
+		return false, ErrNilBackend
+	}
+	return s.client.SetNX(ctx, s.prefix+key, value, ttl).Result()
+}
+
+func (s *redisStore) Del(ctx context.Context, key string) error {
+	if s.client == nil {
+		return ErrNilBackend
+	}
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNilBackend 底层存储实例为空（一般是 xcache/xredis 未配置）
+var ErrNilBackend = errors.New("store backend is nil")
+
+// Store XGin 中间件使用的 KV 存储抽象（nonce 防重放、幂等、响应缓存等）
+// 内置 xcache（本地）和 xredis（分布式）两种实现，多实例部署时建议使用 xredis
+type Store interface {
+	// Get 获取 key 对应的值，key 不存在时返回 (nil, false, nil)
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set 设置 key 的值，ttl<=0 表示使用后端默认过期时间
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// SetNX key 不存在时才设置，返回是否设置成功
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+
+	// Del 删除 key
+	Del(ctx context.Context, key string) error
+}
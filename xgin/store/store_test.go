@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeCache 测试用 localCache
+type fakeCache struct {
+	mu   sync.Mutex
+	data map[string]any
+	ttls map[string]time.Duration
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{data: make(map[string]any), ttls: make(map[string]time.Duration)}
+}
+
+func (f *fakeCache) Get(key string) (any, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.data[key]
+	return v, ok
+}
+
+func (f *fakeCache) Set(key string, value any) bool {
+	return f.SetWithTTL(key, value, 0)
+}
+
+func (f *fakeCache) SetWithTTL(key string, value any, ttl time.Duration) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = value
+	f.ttls[key] = ttl
+	return true
+}
+
+func (f *fakeCache) Del(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.data, key)
+}
+
+func (f *fakeCache) Wait() {}
+
+func TestCacheStore(t *testing.T) {
+	fc := newFakeCache()
+	s := &cacheStore{cache: fc}
+	ctx := context.Background()
+
+	if _, ok, err := s.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+
+	val := []byte("v")
+	if err := s.Set(ctx, "k", val, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	val[0] = 'x' // Set 需要拷贝，外部修改不影响已存储的值
+	if v, ok, _ := s.Get(ctx, "k"); !ok || string(v) != "v" {
+		t.Fatalf("expected v, got %s", v)
+	}
+	if fc.ttls["k"] != time.Minute {
+		t.Fatalf("expected ttl 1m, got %v", fc.ttls["k"])
+	}
+
+	if ok, _ := s.SetNX(ctx, "k", []byte("v2"), 0); ok {
+		t.Fatal("SetNX should fail on existing key")
+	}
+	if ok, _ := s.SetNX(ctx, "k2", []byte("v2"), 0); !ok {
+		t.Fatal("SetNX should succeed on new key")
+	}
+
+	_ = s.Del(ctx, "k")
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Fatal("expected key deleted")
+	}
+
+	// 非 []byte 值视为未命中
+	fc.Set("other", 1)
+	if _, ok, _ := s.Get(ctx, "other"); ok {
+		t.Fatal("non []byte value should be treated as miss")
+	}
+}
+
+func TestCacheStoreConcurrentSetNX(t *testing.T) {
+	s := &cacheStore{cache: newFakeCache()}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	success := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.SetNX(context.Background(), "nonce", []byte("1"), time.Minute); ok {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if success != 1 {
+		t.Fatalf("expected exactly one SetNX success, got %d", success)
+	}
+}
+
+func TestNilBackend(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range []Store{NewCacheStore(nil), NewRedisStore(nil, ""), NewRedisStore((*redis.Client)(nil), "")} {
+		if _, _, err := s.Get(ctx, "k"); err != ErrNilBackend {
+			t.Fatalf("expected ErrNilBackend, got %v", err)
+		}
+		if err := s.Set(ctx, "k", nil, 0); err != ErrNilBackend {
+			t.Fatalf("expected ErrNilBackend, got %v", err)
+		}
+		if _, err := s.SetNX(ctx, "k", nil, 0); err != ErrNilBackend {
+			t.Fatalf("expected ErrNilBackend, got %v", err)
+		}
+		if err := s.Del(ctx, "k"); err != ErrNilBackend {
+			t.Fatalf("expected ErrNilBackend, got %v", err)
+		}
+	}
+}
+
+// fakeRedis 仅实现 redisStore 用到的命令
+type fakeRedis struct {
+	redis.Cmdable
+	data map[string]string
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	cmd := redis.NewStringCmd(ctx)
+	if v, ok := f.data[key]; ok {
+		cmd.SetVal(v)
+	} else {
+		cmd.SetErr(redis.Nil)
+	}
+	return cmd
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value any, _ time.Duration) *redis.StatusCmd {
+	f.data[key] = string(value.([]byte))
+	cmd := redis.NewStatusCmd(ctx)
+	cmd.SetVal("OK")
+	return cmd
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key string, value any, _ time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx)
+	if _, ok := f.data[key]; ok {
+		cmd.SetVal(false)
+		return cmd
+	}
+	f.data[key] = string(value.([]byte))
+	cmd.SetVal(true)
+	return cmd
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, k := range keys {
+		delete(f.data, k)
+	}
+	return redis.NewIntCmd(ctx)
+}
+
+func TestRedisStore(t *testing.T) {
+	fr := &fakeRedis{data: make(map[string]string)}
+	s := NewRedisStore(fr, "p:")
+	ctx := context.Background()
+
+	if _, ok, err := s.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+	_ = s.Set(ctx, "k", []byte("v"), time.Minute)
+	if fr.data["p:k"] != "v" {
+		t.Fatalf("expected prefixed key, got %v", fr.data)
+	}
+	if v, ok, _ := s.Get(ctx, "k"); !ok || string(v) != "v" {
+		t.Fatalf("expected v, got %s", v)
+	}
+	if ok, _ := s.SetNX(ctx, "k", []byte("v2"), time.Minute); ok {
+		t.Fatal("SetNX should fail on existing key")
+	}
+	_ = s.Del(ctx, "k")
+	if ok, _ := s.SetNX(ctx, "k", []byte("v2"), time.Minute); !ok {
+		t.Fatal("SetNX should succeed after Del")
+	}
+}
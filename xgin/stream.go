@@ -0,0 +1,19 @@
+package xgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/stream"
+)
+
+// Event SSE 事件，见 stream.Event
+type Event = stream.Event
+
+// SSE 以 Server-Sent Events 输出 events 中的事件，见 stream.SSE
+func SSE(c *gin.Context, events <-chan Event, opts ...stream.Option) error {
+	return stream.SSE(c, events, opts...)
+}
+
+// WebSocket 将当前请求升级为 WebSocket 并执行 handler，见 stream.WebSocket
+func WebSocket(c *gin.Context, handler func(conn *stream.WSConn) error, opts ...stream.Option) error {
+	return stream.WebSocket(c, handler, opts...)
+}
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHeartbeat    = 15 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	defaultReadLimit    = 1 << 20 // 1MB
+)
+
+// WithHeartbeat 设置心跳间隔，SSE 发送注释行，WebSocket 发送 Ping 并要求在 2 倍间隔内收到 Pong
+// <= 0 表示关闭心跳，默认 15s
+func WithHeartbeat(d time.Duration) Option {
+	return func(o *Options) {
+		o.Heartbeat = d
+	}
+}
+
+// WithWriteTimeout 设置单条消息的写超时，默认 10s
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.WriteTimeout = d
+		}
+	}
+}
+
+// WithReadLimit 设置 WebSocket 单条消息最大字节数，默认 1MB
+func WithReadLimit(limit int64) Option {
+	return func(o *Options) {
+		if limit > 0 {
+			o.ReadLimit = limit
+		}
+	}
+}
+
+// WithCheckOrigin 设置 WebSocket 握手的 Origin 校验，默认要求与 Host 同源
+func WithCheckOrigin(f func(r *http.Request) bool) Option {
+	return func(o *Options) {
+		o.CheckOrigin = f
+	}
+}
+
+// WithSubprotocols 设置 WebSocket 服务端支持的子协议（按偏好顺序）
+func WithSubprotocols(protocols ...string) Option {
+	return func(o *Options) {
+		o.Subprotocols = append(o.Subprotocols, protocols...)
+	}
+}
+
+// WithCompression 是否启用 WebSocket permessage-deflate 压缩，默认关闭
+func WithCompression(enable bool) Option {
+	return func(o *Options) {
+		o.EnableCompression = enable
+	}
+}
+
+type Option func(*Options)
+
+type Options struct {
+	Heartbeat         time.Duration
+	WriteTimeout      time.Duration
+	ReadLimit         int64
+	CheckOrigin       func(r *http.Request) bool
+	Subprotocols      []string
+	EnableCompression bool
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Heartbeat:         defaultHeartbeat,
+		WriteTimeout:      defaultWriteTimeout,
+		ReadLimit:         defaultReadLimit,
+		CheckOrigin:       nil,
+		Subprotocols:      make([]string, 0),
+		EnableCompression: false,
+	}
+}
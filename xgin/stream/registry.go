@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrServerShutdown 服务关闭时主动结束流的原因，可通过 context.Cause 获取
+var ErrServerShutdown = errors.New("stream closed by server shutdown")
+
+// registryKey gin.Context 中存放 Registry 的 key
+const registryKey = "__xgin_stream_registry__"
+
+// defaultRegistry 请求未经 Registry.Middleware 注入时（如直接使用 gin.Engine）使用
+var defaultRegistry = NewRegistry()
+
+// Registry 记录活跃的流，服务关闭时统一结束
+// 每个 XGin 实例持有独立的 Registry 并通过 Middleware 注入请求，Stop 时只结束本实例的流
+type Registry struct {
+	mu      sync.Mutex
+	streams map[*activeStream]struct{}
+}
+
+type activeStream struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// NewRegistry 创建 Registry
+func NewRegistry() *Registry {
+	return &Registry{streams: make(map[*activeStream]struct{})}
+}
+
+// Middleware 将 Registry 注入请求，之后的 SSE/WebSocket 登记到该 Registry
+func (r *Registry) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(registryKey, r)
+	}
+}
+
+// registryFrom 获取请求注入的 Registry，未注入时返回 defaultRegistry
+func registryFrom(c *gin.Context) *Registry {
+	if v, ok := c.Get(registryKey); ok {
+		if r, ok := v.(*Registry); ok && r != nil {
+			return r
+		}
+	}
+	return defaultRegistry
+}
+
+func (r *Registry) register(cancel context.CancelCauseFunc) *activeStream {
+	s := &activeStream{cancel: cancel, done: make(chan struct{})}
+	r.mu.Lock()
+	r.streams[s] = struct{}{}
+	r.mu.Unlock()
+	return s
+}
+
+func (r *Registry) unregister(s *activeStream) {
+	r.mu.Lock()
+	delete(r.streams, s)
+	r.mu.Unlock()
+	close(s.done)
+}
+
+// CloseAll 结束 Registry 中所有活跃的流并等待其退出，ctx 到期时不再等待
+// XGin.Stop 在关闭 http.Server 前调用：SSE 会阻塞 Shutdown 直到超时，而被 Hijack 的 WebSocket 连接不受 Shutdown 管理
+func (r *Registry) CloseAll(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	streams := make([]*activeStream, 0, len(r.streams))
+	for s := range r.streams {
+		streams = append(streams, s)
+	}
+	r.mu.Unlock()
+
+	for _, s := range streams {
+		s.cancel(ErrServerShutdown)
+	}
+	for _, s := range streams {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ActiveCount Registry 中活跃的流数量
+func (r *Registry) ActiveCount() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.streams)
+}
+
+// CloseAll 结束未注入 Registry 的请求中活跃的流，见 Registry.CloseAll
+func CloseAll(ctx context.Context) error {
+	return defaultRegistry.CloseAll(ctx)
+}
+
+// ActiveCount 未注入 Registry 的请求中活跃的流数量
+func ActiveCount() int {
+	return defaultRegistry.ActiveCount()
+}
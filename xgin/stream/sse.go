@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+)
+
+// heartbeatComment SSE 心跳，以冒号开头的注释行会被客户端忽略
+var heartbeatComment = []byte(": ping\n\n")
+
+// Event SSE 事件
+type Event struct {
+	ID    string        // 事件 ID，客户端重连时通过 Last-Event-ID 带回
+	Event string        // 事件类型，为空时客户端按 message 处理
+	Data  any           // 事件数据，string/[]byte 原样输出（多行拆分为多个 data 字段），其它类型序列化为 JSON
+	Retry time.Duration // 建议客户端重连间隔，0 表示不设置
+}
+
+// SSE 以 Server-Sent Events 输出 events 中的事件，直到 events 关闭、客户端断开或服务关闭
+// 使用示例：
+//
+//	func chat(c *gin.Context) {
+//		events := make(chan stream.Event)
+//		go produce(c.Request.Context(), events) // 生产方负责 close(events)
+//		_ = stream.SSE(c, events)
+//	}
+//
+// 返回值：events 关闭时返回 nil；客户端断开返回 context.Canceled；服务关闭返回 ErrServerShutdown；写失败返回写错误
+// 生产方应同时监听 c.Request.Context()，避免 SSE 返回后阻塞在发送上
+func SSE(c *gin.Context, events <-chan Event, opts ...Option) error {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ctx, cancel := context.WithCancelCause(c.Request.Context())
+	defer cancel(nil)
+	registry := registryFrom(c)
+	active := registry.register(cancel)
+	defer registry.unregister(active)
+
+	stats := middleware.NewStreamStats(c, middleware.StreamTypeSSE)
+	defer stats.Finish()
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // 关闭 nginx 代理缓冲
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	w := &sseWriter{c: c, rc: http.NewResponseController(c.Writer), timeout: o.WriteTimeout}
+	// 清除 XGin.ReadTimeout 设置的读超时，否则 HTTP/1 连接到期后请求 context 被取消，事件流中断
+	_ = w.rc.SetReadDeadline(time.Time{})
+
+	var heartbeat <-chan time.Time
+	if o.Heartbeat > 0 {
+		ticker := time.NewTicker(o.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			data, err := encodeEvent(ev)
+			if err != nil {
+				return err
+			}
+			if err := w.write(data); err != nil {
+				return err
+			}
+			stats.RecordSent(len(data))
+		case <-heartbeat:
+			if err := w.write(heartbeatComment); err != nil {
+				return err
+			}
+			stats.RecordSentBytes(len(heartbeatComment))
+		}
+	}
+}
+
+// sseWriter 按消息设置写超时并立即 Flush
+type sseWriter struct {
+	c       *gin.Context
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (w *sseWriter) write(data []byte) error {
+	deadlineSet := w.rc.SetWriteDeadline(time.Now().Add(w.timeout)) == nil
+	_, err := w.c.Writer.Write(data)
+	if err == nil {
+		w.c.Writer.Flush()
+	}
+	if deadlineSet {
+		// 清除写超时，避免影响下一条消息前的空闲等待
+		_ = w.rc.SetWriteDeadline(time.Time{})
+	}
+	return err
+}
+
+// encodeEvent 按 SSE 协议编码事件
+func encodeEvent(ev Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(stripNewlines(ev.ID))
+		buf.WriteByte('\n')
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(stripNewlines(ev.Event))
+		buf.WriteByte('\n')
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		buf.WriteByte('\n')
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Join(errors.New("encode sse event data failed"), err)
+		}
+		data = string(b)
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
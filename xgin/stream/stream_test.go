@@ -0,0 +1,307 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func newServer(t *testing.T, path string, h gin.HandlerFunc) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET(path, h)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func waitActive(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for ActiveCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d active streams, got %d", n, ActiveCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEncodeEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		want string
+	}{
+		{"string", Event{Data: "hello"}, "data: hello\n\n"},
+		{"multiline", Event{Data: "a\r\nb\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+		{"bytes", Event{Data: []byte("raw")}, "data: raw\n\n"},
+		{"json", Event{Data: map[string]int{"n": 1}}, "data: {\"n\":1}\n\n"},
+		{"nil", Event{Event: "done"}, "event: done\ndata: \n\n"},
+		{"fields", Event{ID: "1\n", Event: "token", Retry: 3 * time.Second, Data: "x"}, "id: 1\nevent: token\nretry: 3000\ndata: x\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeEvent(tt.ev)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := encodeEvent(Event{Data: make(chan int)}); err == nil {
+		t.Error("expected error for unsupported data")
+	}
+}
+
+func TestSSE_Events(t *testing.T) {
+	errCh := make(chan error, 1)
+	srv := newServer(t, "/events", func(c *gin.Context) {
+		events := make(chan Event, 2)
+		events <- Event{ID: "1", Data: "hello"}
+		events <- Event{ID: "2", Event: "done", Data: map[string]string{"k": "v"}}
+		close(events)
+		errCh <- SSE(c, events, WithHeartbeat(0))
+	})
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if resp.Header.Get("Cache-Control") != "no-cache" || resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+	var sb strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		sb.WriteString(sc.Text() + "\n")
+	}
+	want := "id: 1\ndata: hello\n\nid: 2\nevent: done\ndata: {\"k\":\"v\"}\n\n"
+	if sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestSSE_HeartbeatAndDisconnect(t *testing.T) {
+	errCh := make(chan error, 1)
+	srv := newServer(t, "/events", func(c *gin.Context) {
+		errCh <- SSE(c, make(chan Event), WithHeartbeat(10*time.Millisecond))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != ": ping\n" {
+		t.Fatalf("expected heartbeat, got %q, err=%v", line, err)
+	}
+	cancel()
+	_ = resp.Body.Close()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SSE did not return after client disconnect")
+	}
+	waitActive(t, 0)
+}
+
+func TestSSE_CloseAll(t *testing.T) {
+	errCh := make(chan error, 1)
+	srv := newServer(t, "/events", func(c *gin.Context) {
+		errCh <- SSE(c, make(chan Event), WithHeartbeat(0))
+	})
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	waitActive(t, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := CloseAll(ctx); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, ErrServerShutdown) {
+		t.Errorf("expected ErrServerShutdown, got %v", err)
+	}
+	if ActiveCount() != 0 {
+		t.Errorf("expected no active streams, got %d", ActiveCount())
+	}
+}
+
+func TestSSE_RegistryScoped(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r1, r2 := NewRegistry(), NewRegistry()
+	errCh := make(chan error, 2)
+	newScoped := func(reg *Registry) *httptest.Server {
+		e := gin.New()
+		e.Use(reg.Middleware())
+		e.GET("/events", func(c *gin.Context) {
+			errCh <- SSE(c, make(chan Event), WithHeartbeat(0))
+		})
+		srv := httptest.NewServer(e)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	for _, srv := range []*httptest.Server{newScoped(r1), newScoped(r2)} {
+		resp, err := http.Get(srv.URL + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for r1.ActiveCount() != 1 || r2.ActiveCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 1 active stream per registry, got %d and %d", r1.ActiveCount(), r2.ActiveCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ActiveCount() != 0 {
+		t.Errorf("injected streams should not be in the default registry, got %d", ActiveCount())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// 只结束 r1 的流
+	if err := r1.CloseAll(ctx); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, ErrServerShutdown) {
+		t.Errorf("expected ErrServerShutdown, got %v", err)
+	}
+	if r1.ActiveCount() != 0 || r2.ActiveCount() != 1 {
+		t.Errorf("expected 0 and 1 active streams, got %d and %d", r1.ActiveCount(), r2.ActiveCount())
+	}
+	if err := r2.CloseAll(ctx); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+	<-errCh
+}
+
+func wsURL(srv *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + path
+}
+
+func TestWebSocket_Echo(t *testing.T) {
+	errCh := make(chan error, 1)
+	srv := newServer(t, "/ws", func(c *gin.Context) {
+		errCh <- WebSocket(c, func(conn *WSConn) error {
+			for {
+				mt, data, err := conn.ReadMessage()
+				if err != nil {
+					return err
+				}
+				if err := conn.WriteMessage(mt, data); err != nil {
+					return err
+				}
+			}
+		}, WithHeartbeat(0))
+	})
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(srv, "/ws"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected 101, got %d", resp.StatusCode)
+	}
+	for _, msg := range []string{"a", "bb"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil || string(data) != msg {
+			t.Fatalf("unexpected echo %q, err=%v", data, err)
+		}
+	}
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	_ = conn.Close()
+
+	select {
+	case err := <-errCh:
+		if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			t.Errorf("expected normal close error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return after client close")
+	}
+	waitActive(t, 0)
+}
+
+func TestWebSocket_Shutdown(t *testing.T) {
+	srv := newServer(t, "/ws", func(c *gin.Context) {
+		_ = WebSocket(c, func(conn *WSConn) error {
+			<-conn.Context().Done()
+			return context.Cause(conn.Context())
+		}, WithHeartbeat(20*time.Millisecond))
+	})
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(srv, "/ws"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitActive(t, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := CloseAll(ctx); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going away close, got %v", err)
+	}
+}
+
+func TestWebSocket_UpgradeFailed(t *testing.T) {
+	srv := newServer(t, "/ws", func(c *gin.Context) {
+		if err := WebSocket(c, func(*WSConn) error { return nil }); err == nil {
+			t.Error("expected upgrade error")
+		}
+	})
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,201 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+)
+
+// WSConn WebSocket 连接，封装写锁、写超时与消息统计
+// 读操作只能在一个 goroutine 中进行；写操作并发安全
+type WSConn struct {
+	conn         *websocket.Conn
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+	writeMu      sync.Mutex
+	writeTimeout time.Duration
+	stats        *middleware.StreamStats
+}
+
+// Context 连接的 context，客户端断开、读失败或服务关闭时结束，可通过 context.Cause 获取原因
+func (c *WSConn) Context() context.Context {
+	return c.ctx
+}
+
+// ReadMessage 读取一条消息，读失败时结束连接 context
+func (c *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = c.conn.ReadMessage()
+	if err != nil {
+		c.cancel(err)
+		return messageType, data, err
+	}
+	c.stats.RecordReceived(len(data))
+	return messageType, data, nil
+}
+
+// ReadJSON 读取一条消息并反序列化为 JSON
+func (c *WSConn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage 写出一条消息，使用 WithWriteTimeout 设置的写超时
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := context.Cause(c.ctx); err != nil {
+		return err
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if err := c.conn.WriteMessage(messageType, data); err != nil {
+		c.cancel(err)
+		return err
+	}
+	c.stats.RecordSent(len(data))
+	return nil
+}
+
+// WriteJSON 序列化为 JSON 后以文本消息写出
+func (c *WSConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close 正常关闭连接（发送 CloseNormalClosure）
+func (c *WSConn) Close() error {
+	c.cancel(nil)
+	return nil
+}
+
+// Conn 底层 gorilla/websocket 连接，直接读写时不经过统计与写锁
+func (c *WSConn) Conn() *websocket.Conn {
+	return c.conn
+}
+
+func (c *WSConn) writeControl(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteControl(messageType, data, time.Now().Add(c.writeTimeout))
+}
+
+// WebSocket 将当前请求升级为 WebSocket 连接并执行 handler，handler 返回后关闭连接
+// 使用示例：
+//
+//	func echo(c *gin.Context) {
+//		_ = stream.WebSocket(c, func(conn *stream.WSConn) error {
+//			for {
+//				mt, data, err := conn.ReadMessage()
+//				if err != nil {
+//					return err
+//				}
+//				if err := conn.WriteMessage(mt, data); err != nil {
+//					return err
+//				}
+//			}
+//		})
+//	}
+//
+// 握手失败时已向客户端返回错误响应，并返回握手错误；否则返回 handler 的返回值
+// 服务关闭时连接 context 以 ErrServerShutdown 结束，并向客户端发送 CloseGoingAway
+func WebSocket(c *gin.Context, handler func(conn *WSConn) error, opts ...Option) error {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin:       o.CheckOrigin,
+		Subprotocols:      o.Subprotocols,
+		EnableCompression: o.EnableCompression,
+	}
+	// 记录 101 状态码供 Log/Metric 中间件使用，gin 仅在首次写出时才真正发送 header
+	c.Writer.WriteHeader(http.StatusSwitchingProtocols)
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return err
+	}
+	conn.SetReadLimit(o.ReadLimit)
+
+	ctx, cancel := context.WithCancelCause(c.Request.Context())
+	defer cancel(nil)
+	registry := registryFrom(c)
+	active := registry.register(cancel)
+	defer registry.unregister(active)
+
+	stats := middleware.NewStreamStats(c, middleware.StreamTypeWebSocket)
+	defer stats.Finish()
+
+	ws := &WSConn{conn: conn, ctx: ctx, cancel: cancel, writeTimeout: o.WriteTimeout, stats: stats}
+
+	var wg sync.WaitGroup
+	if o.Heartbeat > 0 {
+		readTimeout := 2 * o.Heartbeat
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(readTimeout))
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ws.pingLoop(o.Heartbeat)
+		}()
+	}
+
+	// 连接结束时发送 Close 帧并关闭底层连接，阻塞在 ReadMessage 的 handler 会因此返回
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		ws.sendClose(context.Cause(ctx))
+		_ = conn.Close()
+	}()
+
+	err = handler(ws)
+	cancel(nil)
+	wg.Wait()
+	return err
+}
+
+func (c *WSConn) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.writeControl(websocket.PingMessage, nil); err != nil {
+				c.cancel(err)
+				return
+			}
+		}
+	}
+}
+
+// sendClose 根据结束原因发送 Close 帧，连接已异常断开时不发送
+func (c *WSConn) sendClose(cause error) {
+	code := websocket.CloseNormalClosure
+	switch {
+	case errors.Is(cause, ErrServerShutdown):
+		code = websocket.CloseGoingAway
+	case errors.Is(cause, context.Canceled):
+	default:
+		// 读写失败（对端关闭、超时等）时连接已不可用
+		return
+	}
+	_ = c.writeControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
+}
@@ -0,0 +1,156 @@
+package xgin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+)
+
+// certReloadCheckInterval 握手时检查证书文件是否变更的最小间隔
+var certReloadCheckInterval = 5 * time.Second
+
+// newTLSConfig 构建 TLS 配置，证书通过 GetCertificate 按需从磁盘重新加载
+// clientCAFile 非空时启用 mTLS，clientAuth 为空时要求并校验客户端证书
+func newTLSConfig(certFile, keyFile, clientCAFile, clientAuth string) (*tls.Config, error) {
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	authType, err := parseClientAuth(clientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if clientCAFile == "" {
+		if authType >= tls.VerifyClientCertIfGiven {
+			return nil, fmt.Errorf("ClientAuth %q requires ClientCAFile", clientAuth)
+		}
+		cfg.ClientAuth = authType
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ClientCAFile failed, file=[%s], err=[%v]", clientCAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificate found in ClientCAFile, file=[%s]", clientCAFile)
+	}
+	cfg.ClientCAs = pool
+	if clientAuth == "" {
+		authType = tls.RequireAndVerifyClientCert
+	}
+	cfg.ClientAuth = authType
+	return cfg, nil
+}
+
+// parseClientAuth 解析客户端证书校验方式，空字符串返回 tls.NoClientCert
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid ClientAuth %q, must be one of none/request/require/verify_if_given/require_and_verify", s)
+	}
+}
+
+// certReloader 证书或私钥文件修改时间变化后重新加载，加载失败时沿用旧证书
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < certReloadCheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	oldCertMod, oldKeyMod := r.certMod, r.keyMod
+	r.mu.Unlock()
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		xutil.WarnIfEnableDebug("XGin check TLS cert failed, keep current cert, err=[%v]", err)
+		return
+	}
+	if certMod.Equal(oldCertMod) && keyMod.Equal(oldKeyMod) {
+		return
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		xutil.WarnIfEnableDebug("XGin reload TLS cert failed, keep current cert, err=[%v]", err)
+		return
+	}
+	xutil.InfoIfEnableDebug("XGin TLS cert reloaded, cert=[%s], key=[%s]", r.certFile, r.keyFile)
+}
+
+func (r *certReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS cert failed, cert=[%s], key=[%s], err=[%v]", r.certFile, r.keyFile, err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat CertFile failed, err=[%v]", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat KeyFile failed, err=[%v]", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,84 @@
+package trans
+
+import (
+	"github.com/go-playground/locales"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+
+	arLocale "github.com/go-playground/locales/ar"
+	deLocale "github.com/go-playground/locales/de"
+	enLocale "github.com/go-playground/locales/en"
+	esLocale "github.com/go-playground/locales/es"
+	faLocale "github.com/go-playground/locales/fa"
+	frLocale "github.com/go-playground/locales/fr"
+	idLocale "github.com/go-playground/locales/id"
+	itLocale "github.com/go-playground/locales/it"
+	jaLocale "github.com/go-playground/locales/ja"
+	koLocale "github.com/go-playground/locales/ko"
+	lvLocale "github.com/go-playground/locales/lv"
+	nlLocale "github.com/go-playground/locales/nl"
+	plLocale "github.com/go-playground/locales/pl"
+	ptLocale "github.com/go-playground/locales/pt"
+	ptBRLocale "github.com/go-playground/locales/pt_BR"
+	ruLocale "github.com/go-playground/locales/ru"
+	thLocale "github.com/go-playground/locales/th"
+	trLocale "github.com/go-playground/locales/tr"
+	ukLocale "github.com/go-playground/locales/uk"
+	viLocale "github.com/go-playground/locales/vi"
+	zhLocale "github.com/go-playground/locales/zh"
+	zhTWLocale "github.com/go-playground/locales/zh_Hant_TW"
+	arTrans "github.com/go-playground/validator/v10/translations/ar"
+	deTrans "github.com/go-playground/validator/v10/translations/de"
+	enTrans "github.com/go-playground/validator/v10/translations/en"
+	esTrans "github.com/go-playground/validator/v10/translations/es"
+	faTrans "github.com/go-playground/validator/v10/translations/fa"
+	frTrans "github.com/go-playground/validator/v10/translations/fr"
+	idTrans "github.com/go-playground/validator/v10/translations/id"
+	itTrans "github.com/go-playground/validator/v10/translations/it"
+	jaTrans "github.com/go-playground/validator/v10/translations/ja"
+	koTrans "github.com/go-playground/validator/v10/translations/ko"
+	lvTrans "github.com/go-playground/validator/v10/translations/lv"
+	nlTrans "github.com/go-playground/validator/v10/translations/nl"
+	plTrans "github.com/go-playground/validator/v10/translations/pl"
+	ptTrans "github.com/go-playground/validator/v10/translations/pt"
+	ptBRTrans "github.com/go-playground/validator/v10/translations/pt_BR"
+	ruTrans "github.com/go-playground/validator/v10/translations/ru"
+	thTrans "github.com/go-playground/validator/v10/translations/th"
+	trTrans "github.com/go-playground/validator/v10/translations/tr"
+	ukTrans "github.com/go-playground/validator/v10/translations/uk"
+	viTrans "github.com/go-playground/validator/v10/translations/vi"
+	zhTrans "github.com/go-playground/validator/v10/translations/zh"
+	zhTWTrans "github.com/go-playground/validator/v10/translations/zh_tw"
+)
+
+// localeEntry 语言的 locales 实现与 validator 内置翻译
+type localeEntry struct {
+	newLocale    func() locales.Translator
+	registerFunc func(v *validator.Validate, t ut.Translator) error
+}
+
+// supportedLocales validator 提供内置翻译的语言，key 为 WithLocales 使用的语言名
+var supportedLocales = map[string]localeEntry{
+	"ar":    {arLocale.New, arTrans.RegisterDefaultTranslations},
+	"de":    {deLocale.New, deTrans.RegisterDefaultTranslations},
+	"en":    {enLocale.New, enTrans.RegisterDefaultTranslations},
+	"es":    {esLocale.New, esTrans.RegisterDefaultTranslations},
+	"fa":    {faLocale.New, faTrans.RegisterDefaultTranslations},
+	"fr":    {frLocale.New, frTrans.RegisterDefaultTranslations},
+	"id":    {idLocale.New, idTrans.RegisterDefaultTranslations},
+	"it":    {itLocale.New, itTrans.RegisterDefaultTranslations},
+	"ja":    {jaLocale.New, jaTrans.RegisterDefaultTranslations},
+	"ko":    {koLocale.New, koTrans.RegisterDefaultTranslations},
+	"lv":    {lvLocale.New, lvTrans.RegisterDefaultTranslations},
+	"nl":    {nlLocale.New, nlTrans.RegisterDefaultTranslations},
+	"pl":    {plLocale.New, plTrans.RegisterDefaultTranslations},
+	"pt":    {ptLocale.New, ptTrans.RegisterDefaultTranslations},
+	"pt_BR": {ptBRLocale.New, ptBRTrans.RegisterDefaultTranslations},
+	"ru":    {ruLocale.New, ruTrans.RegisterDefaultTranslations},
+	"th":    {thLocale.New, thTrans.RegisterDefaultTranslations},
+	"tr":    {trLocale.New, trTrans.RegisterDefaultTranslations},
+	"uk":    {ukLocale.New, ukTrans.RegisterDefaultTranslations},
+	"vi":    {viLocale.New, viTrans.RegisterDefaultTranslations},
+	"zh":    {zhLocale.New, zhTrans.RegisterDefaultTranslations},
+	"zh_tw": {zhTWLocale.New, zhTWTrans.RegisterDefaultTranslations},
+}
@@ -1,6 +1,7 @@
 package trans
 
 import (
+	"context"
 	"errors"
 	"sort"
 	"strings"
@@ -61,6 +62,55 @@ func ToZHErr(err error) error {
 	return &ZHErr{Msg: strings.Join(errMessages, ", "), CauseErr: err}
 }
 
+// ToErrMsg 按请求语言翻译错误信息字符串，见 ToErr
+func ToErrMsg(ctx context.Context, err error) string {
+	if err = ToErr(ctx, err); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// ToErr 按请求语言（见 Locale）翻译校验错误，返回按字段组织的 *ValidationErr
+// 未调用 RegisterTranslations 或 err 不是校验错误时原样返回
+func ToErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	r := registry.Load()
+	if r == nil {
+		return err
+	}
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return err
+	}
+
+	locale := r.locale(ctx)
+	t := r.translators[locale]
+	result := make(map[string][]string)
+	for _, e := range ves {
+		field := fieldKey(e)
+		result[field] = append(result[field], e.Translate(t))
+	}
+	kvs := make([]VErrKV, 0, len(result))
+	for k, v := range result {
+		kvs = append(kvs, VErrKV{Field: k, Transl: strings.Join(v, ", ")})
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Field < kvs[j].Field
+	})
+	return &ValidationErr{Locale: locale, Errors: kvs, CauseErr: err}
+}
+
+// fieldKey 去掉顶层结构体名的字段路径，如 User.Address.City -> Address.City，Items[0].Name 保持不变
+func fieldKey(e validator.FieldError) string {
+	ns := e.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return e.Field()
+}
+
 type VErrKV struct {
 	Field  string
 	Transl string
@@ -83,3 +133,36 @@ func (e *ZHErr) Cause() error {
 func (e *ZHErr) Unwrap() error {
 	return e.CauseErr
 }
+
+// ValidationErr 按字段组织的校验错误，Errors 按字段升序
+type ValidationErr struct {
+	Locale   string   // 翻译使用的语言
+	Errors   []VErrKV // 字段路径与翻译后的错误信息，同一字段多条错误以 ", " 连接
+	CauseErr error
+}
+
+func (e *ValidationErr) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, kv := range e.Errors {
+		msgs = append(msgs, kv.Transl)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// Fields 返回字段路径到错误信息的映射
+func (e *ValidationErr) Fields() map[string]string {
+	m := make(map[string]string, len(e.Errors))
+	for _, kv := range e.Errors {
+		m[kv.Field] = kv.Transl
+	}
+	return m
+}
+
+func (e *ValidationErr) Cause() error {
+	return e.CauseErr
+}
+
+// Unwrap 实现 Go 标准库 errors.Unwrap 接口
+func (e *ValidationErr) Unwrap() error {
+	return e.CauseErr
+}
@@ -1,10 +1,15 @@
 package trans
 
 import (
+	"context"
 	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
@@ -326,3 +331,134 @@ func TestRegisterZHTranslations_RegisterDefaultTranslationsFail(t *testing.T) {
 		So(err.Error(), ShouldEqual, "register failed")
 	})
 }
+
+// ==================== 多语言翻译测试 ====================
+
+type i18nUser struct {
+	Name    string `binding:"required"`
+	Phone   string `binding:"mobile"`
+	Address struct {
+		City string `binding:"required"`
+	}
+}
+
+func newI18nValidator(t *testing.T) *validator.Validate {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		t.Fatal("gin validator engine should be *validator.Validate")
+	}
+	_ = v.RegisterValidation("mobile", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String()) == 11
+	})
+	return v
+}
+
+func newI18nContext(target string, acceptLanguage string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	if acceptLanguage != "" {
+		c.Request.Header.Set("Accept-Language", acceptLanguage)
+	}
+	return c
+}
+
+func TestRegisterTranslations(t *testing.T) {
+	PatchConvey("TestRegisterTranslations", t, func() {
+		defer registry.Store(nil)
+		v := newI18nValidator(t)
+		file := filepath.Join(t.TempDir(), "messages.yaml")
+		So(os.WriteFile(file, []byte("zh:\n  mobile: \"{0}必须是有效的手机号\"\nen:\n  mobile: \"{0} must be a valid mobile number\"\n"), 0644), ShouldBeNil)
+
+		err := RegisterTranslations(
+			WithLocales("en", "zh", "ja", "zh_tw"),
+			WithMessageFiles(file),
+			WithMessages("ja", map[string]string{"mobile": "{0}は有効な携帯番号である必要があります"}),
+		)
+		So(err, ShouldBeNil)
+		verr := v.Struct(&i18nUser{Phone: "123"})
+
+		PatchConvey("DefaultLocale", func() {
+			So(Locale(context.Background()), ShouldEqual, "en")
+			ve, ok := ToErr(context.Background(), verr).(*ValidationErr)
+			So(ok, ShouldBeTrue)
+			So(ve.Locale, ShouldEqual, "en")
+			So(ve.Fields(), ShouldResemble, map[string]string{
+				"Address.City": "City is a required field",
+				"Name":         "Name is a required field",
+				"Phone":        "Phone must be a valid mobile number",
+			})
+			So(ve.Errors[0].Field, ShouldEqual, "Address.City")
+			var ves validator.ValidationErrors
+			So(errors.As(ve, &ves), ShouldBeTrue)
+		})
+
+		PatchConvey("AcceptLanguage", func() {
+			c := newI18nContext("/", "fr-FR;q=0.9, zh-CN;q=0.8")
+			So(Locale(c), ShouldEqual, "zh")
+			ve := ToErr(c, verr).(*ValidationErr)
+			So(ve.Fields()["Phone"], ShouldEqual, "Phone必须是有效的手机号")
+			So(ve.Fields()["Name"], ShouldEqual, "Name为必填字段")
+
+			So(Locale(newI18nContext("/", "zh-TW")), ShouldEqual, "zh_tw")
+			So(Locale(newI18nContext("/", "fr-FR")), ShouldEqual, "en")
+		})
+
+		PatchConvey("QueryParam", func() {
+			c := newI18nContext("/?lang=ja", "zh-CN")
+			So(Locale(c), ShouldEqual, "ja")
+			So(ToErrMsg(c, verr), ShouldContainSubstring, "Phoneは有効な携帯番号である必要があります")
+
+			// 不支持的语言回退到 Accept-Language
+			So(Locale(newI18nContext("/?lang=xx", "zh-CN")), ShouldEqual, "zh")
+		})
+
+		PatchConvey("ContextWithLocale", func() {
+			c := newI18nContext("/?lang=ja", "")
+			c.Request = c.Request.WithContext(ContextWithLocale(c.Request.Context(), "zh-TW"))
+			So(Locale(c), ShouldEqual, "zh_tw")
+			So(Locale(ContextWithLocale(context.Background(), "zh")), ShouldEqual, "zh")
+		})
+
+		PatchConvey("ToZHErrCompatible", func() {
+			So(trans, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRegisterTranslationsInvalid(t *testing.T) {
+	PatchConvey("TestRegisterTranslationsInvalid", t, func() {
+		defer registry.Store(nil)
+
+		So(RegisterTranslations(WithLocales("xx")).Error(), ShouldContainSubstring, "unsupported locale")
+		So(RegisterTranslations(WithLocales("en"), WithMessages("zh", map[string]string{"mobile": "x"})).Error(),
+			ShouldContainSubstring, "not enabled")
+		So(RegisterTranslations(WithMessageFiles("/not/exist.yaml")).Error(), ShouldContainSubstring, "read message file failed")
+
+		file := filepath.Join(t.TempDir(), "bad.yaml")
+		So(os.WriteFile(file, []byte("zh: [1, 2"), 0644), ShouldBeNil)
+		So(RegisterTranslations(WithMessageFiles(file)).Error(), ShouldContainSubstring, "parse message file failed")
+
+		So(registry.Load(), ShouldBeNil)
+	})
+}
+
+func TestToErrWithoutRegistry(t *testing.T) {
+	registry.Store(nil)
+	err := errors.New("plain error")
+	if ToErr(context.Background(), err) != err {
+		t.Error("ToErr should return err as-is when translations not registered")
+	}
+	if ToErr(context.Background(), nil) != nil || ToErrMsg(context.Background(), nil) != "" {
+		t.Error("ToErr(nil) should return nil")
+	}
+	if Locale(context.Background()) != "" {
+		t.Error("Locale should be empty when translations not registered")
+	}
+}
+
+func TestSupportedLocales(t *testing.T) {
+	locales := SupportedLocales()
+	if !sort.StringsAreSorted(locales) || len(locales) != len(supportedLocales) {
+		t.Fatalf("unexpected locales %v", locales)
+	}
+}
@@ -0,0 +1,290 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/text/language"
+)
+
+const defaultLocaleQueryParam = "lang"
+
+// registry 当前生效的多语言翻译器，RegisterTranslations 成功后整体替换
+var registry atomic.Pointer[translatorRegistry]
+
+type translatorRegistry struct {
+	locales     []string // 按注册顺序，首个为默认语言
+	translators map[string]ut.Translator
+	matcher     language.Matcher
+	queryParam  string
+}
+
+// TranslationOption 多语言翻译配置
+type TranslationOption func(*TranslationOptions)
+
+type TranslationOptions struct {
+	Locales      []string                     // 启用的语言，首个为默认语言
+	QueryParam   string                       // 指定语言的 query 参数名，为空时只读取 Accept-Language
+	MessageFiles []string                     // 自定义 tag 翻译文件（YAML）
+	Messages     map[string]map[string]string // 自定义 tag 翻译，locale -> tag -> 翻译模板
+}
+
+// WithLocales 设置启用的语言，首个为默认语言（请求未指定或不支持时使用），可选值见 SupportedLocales
+func WithLocales(locales ...string) TranslationOption {
+	return func(o *TranslationOptions) {
+		o.Locales = append(o.Locales, locales...)
+	}
+}
+
+// WithLocaleQueryParam 设置指定语言的 query 参数名，优先于 Accept-Language，默认 "lang"，为空时不读取 query
+func WithLocaleQueryParam(name string) TranslationOption {
+	return func(o *TranslationOptions) {
+		o.QueryParam = name
+	}
+}
+
+// WithMessageFiles 从 YAML 文件加载自定义 tag 的翻译，文件格式为 locale -> tag -> 翻译模板：
+//
+//	zh:
+//	  mobile: "{0}必须是有效的手机号"
+//	en:
+//	  mobile: "{0} must be a valid mobile number"
+//
+// 模板中 {0} 为字段名，{1} 为 tag 参数；同名 tag 会覆盖 validator 内置翻译
+func WithMessageFiles(files ...string) TranslationOption {
+	return func(o *TranslationOptions) {
+		o.MessageFiles = append(o.MessageFiles, files...)
+	}
+}
+
+// WithMessages 设置自定义 tag 的翻译，格式同 WithMessageFiles，晚于文件加载，同名 tag 覆盖文件中的翻译
+func WithMessages(locale string, messages map[string]string) TranslationOption {
+	return func(o *TranslationOptions) {
+		if o.Messages == nil {
+			o.Messages = make(map[string]map[string]string)
+		}
+		if o.Messages[locale] == nil {
+			o.Messages[locale] = make(map[string]string)
+		}
+		for tag, msg := range messages {
+			o.Messages[locale][tag] = msg
+		}
+	}
+}
+
+// SupportedLocales 返回 WithLocales 可选的语言（validator 提供内置翻译的语言）
+func SupportedLocales() []string {
+	names := make([]string, 0, len(supportedLocales))
+	for name := range supportedLocales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterTranslations 为 gin 默认 validator 注册多语言翻译，未指定语言时启用 zh、en
+// 可重复调用，成功后替换之前的配置；启用 zh 时 ToZHErr 同样生效
+func RegisterTranslations(opts ...TranslationOption) error {
+	o := &TranslationOptions{QueryParam: defaultLocaleQueryParam}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.Locales) == 0 {
+		o.Locales = []string{"zh", "en"}
+	}
+
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin binding.Validator.Engine() type assign to *validator.Validate failed")
+	}
+
+	messages, err := loadMessages(o.MessageFiles)
+	if err != nil {
+		return err
+	}
+	for locale, msgs := range o.Messages {
+		if messages[locale] == nil {
+			messages[locale] = make(map[string]string)
+		}
+		for tag, msg := range msgs {
+			messages[locale][tag] = msg
+		}
+	}
+
+	transMu.Lock()
+	defer transMu.Unlock()
+
+	r, err := newTranslatorRegistry(v, o.Locales, messages)
+	if err != nil {
+		return err
+	}
+	r.queryParam = o.QueryParam
+	registry.Store(r)
+
+	// 兼容 ToZHErr
+	if t, ok := r.translators["zh"]; ok && trans == nil {
+		trans = t
+	}
+	return nil
+}
+
+func newTranslatorRegistry(v *validator.Validate, names []string, messages map[string]map[string]string) (*translatorRegistry, error) {
+	r := &translatorRegistry{translators: make(map[string]ut.Translator, len(names))}
+	tags := make([]language.Tag, 0, len(names))
+
+	var uni *ut.UniversalTranslator
+	for _, name := range names {
+		entry, ok := supportedLocales[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported locale %q, must be one of %v", name, SupportedLocales())
+		}
+		if _, ok := r.translators[name]; ok {
+			continue
+		}
+		l := entry.newLocale()
+		if uni == nil {
+			uni = ut.New(l, l)
+		} else if err := uni.AddTranslator(l, true); err != nil {
+			return nil, fmt.Errorf("add %s translator failed, err=[%v]", name, err)
+		}
+		t, _ := uni.GetTranslator(l.Locale())
+		if err := entry.registerFunc(v, t); err != nil {
+			return nil, fmt.Errorf("register %s translations failed, err=[%v]", name, err)
+		}
+		r.translators[name] = t
+		r.locales = append(r.locales, name)
+		tags = append(tags, language.Make(strings.ReplaceAll(name, "_", "-")))
+	}
+
+	for locale, msgs := range messages {
+		t, ok := r.translators[locale]
+		if !ok {
+			return nil, fmt.Errorf("messages for locale %q not enabled, enabled locales %v", locale, r.locales)
+		}
+		for tag, msg := range msgs {
+			if err := v.RegisterTranslation(tag, t, addTranslation(tag, msg), translateField); err != nil {
+				return nil, fmt.Errorf("register %s translation for tag %q failed, err=[%v]", locale, tag, err)
+			}
+		}
+	}
+
+	r.matcher = language.NewMatcher(tags)
+	return r, nil
+}
+
+func addTranslation(tag, msg string) validator.RegisterTranslationsFunc {
+	return func(t ut.Translator) error {
+		return t.Add(tag, msg, true)
+	}
+}
+
+func translateField(t ut.Translator, fe validator.FieldError) string {
+	msg, err := t.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
+
+// loadMessages 读取自定义 tag 翻译文件，后加载的文件覆盖同名 tag
+func loadMessages(files []string) (map[string]map[string]string, error) {
+	messages := make(map[string]map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read message file failed, file=[%s], err=[%v]", file, err)
+		}
+		m := make(map[string]map[string]string)
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parse message file failed, file=[%s], err=[%v]", file, err)
+		}
+		for locale, msgs := range m {
+			if messages[locale] == nil {
+				messages[locale] = make(map[string]string)
+			}
+			for tag, msg := range msgs {
+				messages[locale][tag] = msg
+			}
+		}
+	}
+	return messages, nil
+}
+
+type localeContextKey struct{}
+
+// ContextWithLocale 为请求显式指定语言，优先于 query 参数与 Accept-Language
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// Locale 返回请求使用的语言，未注册多语言翻译时返回空字符串
+// 优先级：ContextWithLocale > query 参数（默认 lang） > Accept-Language > 默认语言
+// ctx 为 *gin.Context 时才读取 query 参数与 Accept-Language
+func Locale(ctx context.Context) string {
+	r := registry.Load()
+	if r == nil {
+		return ""
+	}
+	return r.locale(ctx)
+}
+
+func (r *translatorRegistry) locale(ctx context.Context) string {
+	if ctx == nil {
+		return r.locales[0]
+	}
+	c, isGin := ctx.(*gin.Context)
+	reqCtx := ctx
+	if isGin && c.Request != nil {
+		reqCtx = c.Request.Context()
+	}
+	if l, ok := reqCtx.Value(localeContextKey{}).(string); ok {
+		if name, ok := r.match(l); ok {
+			return name
+		}
+	}
+	if !isGin || c.Request == nil {
+		return r.locales[0]
+	}
+
+	if r.queryParam != "" {
+		if q := c.Query(r.queryParam); q != "" {
+			if name, ok := r.match(q); ok {
+				return name
+			}
+		}
+	}
+	if h := c.GetHeader("Accept-Language"); h != "" {
+		if tags, _, err := language.ParseAcceptLanguage(h); err == nil && len(tags) > 0 {
+			if _, i, conf := r.matcher.Match(tags...); conf != language.No {
+				return r.locales[i]
+			}
+		}
+	}
+	return r.locales[0]
+}
+
+// match 将 zh-TW、zh_tw、pt-br 等写法匹配到已启用的语言
+func (r *translatorRegistry) match(s string) (string, bool) {
+	if _, ok := r.translators[s]; ok {
+		return s, true
+	}
+	tag, err := language.Parse(strings.ReplaceAll(s, "_", "-"))
+	if err != nil {
+		return "", false
+	}
+	_, i, conf := r.matcher.Match(tag)
+	if conf == language.No {
+		return "", false
+	}
+	return r.locales[i], true
+}
@@ -3,6 +3,7 @@ package xgin
 import (
 	"context"
 	"errors"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -14,7 +15,9 @@ import (
 	"github.com/xiaoshicae/xone/v2/xconfig"
 	"github.com/xiaoshicae/xone/v2/xerror"
 	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/openapi"
 	"github.com/xiaoshicae/xone/v2/xgin/options"
+	"github.com/xiaoshicae/xone/v2/xgin/stream"
 	"github.com/xiaoshicae/xone/v2/xgin/swagger"
 	"github.com/xiaoshicae/xone/v2/xgin/trans"
 	"github.com/xiaoshicae/xone/v2/xserver"
@@ -22,7 +25,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/swaggo/swag"
-	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
@@ -37,12 +39,16 @@ func New(opts ...options.Option) *XGin {
 	engine.HandleMethodNotAllowed = true // 允许处理405
 	return &XGin{
 		engine:          engine,
+		streams:         stream.NewRegistry(),
 		opts:            opts,
 		routerRegisters: make([]func(*gin.Engine), 0),
+		groups:          make([]*routeGroup, 0),
 		middlewares:     make([]gin.HandlerFunc, 0),
 		recoveryFunc:    nil,
 		swaggerInfo:     nil,
 		swaggerOpts:     make([]options.SwaggerOption, 0),
+		openAPIOpts:     make([]openapi.Option, 0),
+		enableOpenAPI:   false,
 		build:           false,
 	}
 }
@@ -50,16 +56,32 @@ func New(opts ...options.Option) *XGin {
 // XGin Gin Web 框架集成
 type XGin struct {
 	engine          *gin.Engine
+	streams         *stream.Registry // 本实例的 SSE/WebSocket 流，Stop 时结束
 	opts            []options.Option
 	routerRegisters []func(*gin.Engine)
+	groups          []*routeGroup
 	middlewares     []gin.HandlerFunc
 	recoveryFunc    gin.RecoveryFunc
 	swaggerInfo     *swag.Spec
 	swaggerOpts     []options.SwaggerOption
-
-	srvMu sync.Mutex   // 保护 srv 字段的并发访问
-	srv   *http.Server // 对gin进行包装后的http server
-	build bool         // XGin实例是否已经build完成
+	openAPIOpts     []openapi.Option
+	enableOpenAPI   bool
+
+	srvMu           sync.Mutex        // 保护 srv 字段的并发访问
+	srv             *http.Server      // 对gin进行包装后的http server
+	build           bool              // XGin实例是否已经build完成
+	buildErr        error             // Build 过程中的错误（如契约文档加载失败），Run 时返回
+	accessLogCloser io.Closer         // 访问日志文件，Stop 时在 server 关闭后关闭
+	grpcHandler     http.Handler      // 共用端口的 gRPC handler，为 nil 时不分流
+	listeners       []*listenerServer // XGin.Listeners 配置的额外监听，受 srvMu 保护
+
+	// 依赖 XGin 配置的中间件，Build 时 xconfig 可能尚未初始化（如 New().Build().Start()），在 Run 或首个请求时创建
+	configOnce          sync.Once
+	configErr           error
+	enableLogMiddleware bool
+	logSkipPaths        []string
+	logMiddleware       gin.HandlerFunc
+	bodyLimitMiddleware gin.HandlerFunc
 }
 
 func (g *XGin) WithRouteRegister(f ...func(*gin.Engine)) *XGin {
@@ -67,6 +89,21 @@ func (g *XGin) WithRouteRegister(f ...func(*gin.Engine)) *XGin {
 	return g
 }
 
+// WithGroup 注册路由组，组内可单独配置中间件链、认证策略、日志 body 记录、指标采集、版本前缀与废弃声明
+// 使用示例：
+//
+//	xgin.New().WithGroup("/internal", func(rg *gin.RouterGroup) {
+//		rg.GET("/stats", stats)
+//	}, options.GroupAuth(auth.Require(auth.RequireScopes("ops"))), options.GroupLogBody(false), options.GroupMetric(false))
+func (g *XGin) WithGroup(prefix string, register func(*gin.RouterGroup), opts ...options.GroupOption) *XGin {
+	o := options.DefaultGroupOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	g.groups = append(g.groups, &routeGroup{prefix: prefix, register: register, o: o})
+	return g
+}
+
 func (g *XGin) WithMiddleware(m ...gin.HandlerFunc) *XGin {
 	g.middlewares = append(g.middlewares, m...)
 	return g
@@ -78,6 +115,14 @@ func (g *XGin) WithSwagger(swaggerInfo *swag.Spec, opts ...options.SwaggerOption
 	return g
 }
 
+// WithOpenAPI 输出类型化路由（xgin.GET/POST 等）生成的 OpenAPI 3.1 文档，默认路径 /openapi.json
+// 可与 WithSwagger 同时使用，二者路由互不影响
+func (g *XGin) WithOpenAPI(opts ...openapi.Option) *XGin {
+	g.enableOpenAPI = true
+	g.openAPIOpts = opts
+	return g
+}
+
 func (g *XGin) WithRecoverFunc(recoveryFunc gin.RecoveryFunc) *XGin {
 	g.recoveryFunc = recoveryFunc
 	return g
@@ -101,6 +146,12 @@ func (g *XGin) Build() *XGin {
 		injectSwaggerInfo(g.swaggerInfo, g.engine, g.swaggerOpts...)
 	}
 
+	// 注册 OpenAPI 文档路由
+	if g.enableOpenAPI {
+		openapi.Configure(g.openAPIOpts...)
+		g.engine.GET(openapi.Path(), openapi.SpecHandler())
+	}
+
 	// 注册中文翻译器
 	if ginXOptions.EnableZHTranslations {
 		if err := trans.RegisterZHTranslations(); err != nil {
@@ -108,6 +159,18 @@ func (g *XGin) Build() *XGin {
 		}
 	}
 
+	// 注册多语言翻译器，语言或翻译文件无效时 Run 返回错误
+	if len(ginXOptions.TranslationLocales) > 0 {
+		err := trans.RegisterTranslations(
+			trans.WithLocales(ginXOptions.TranslationLocales...),
+			trans.WithMessageFiles(ginXOptions.TranslationMessageFiles...),
+		)
+		if err != nil && g.buildErr == nil {
+			g.buildErr = xerror.Newf("xgin", "build", "register translations failed, err=[%v]", err)
+			xutil.ErrorIfEnableDebug("%v", g.buildErr)
+		}
+	}
+
 	g.build = true
 	return g
 }
@@ -129,6 +192,12 @@ func (g *XGin) Run() error {
 	if !g.build {
 		g.Build()
 	}
+	if g.buildErr != nil {
+		return g.buildErr
+	}
+	if err := g.initConfigMiddlewares(); err != nil {
+		return err
+	}
 
 	// 从 xconfig 读取配置（此时 xconfig 已通过 BeforeStart hook 初始化）
 	ginConfig := GetConfig()
@@ -138,6 +207,12 @@ func (g *XGin) Run() error {
 		return xerror.Newf("xgin", "run", "TLS config incomplete: CertFile and KeyFile must be both set or both empty")
 	}
 
+	// 设置可信代理，gin 的 c.ClientIP() 与 middleware.GetClientInfo 使用同一份配置
+	// 注意 gin 不解析 RFC 7239 Forwarded 头，存在 Forwarded 时两者结果可能不同，应以 GetClientInfo 为准
+	if err := g.applyTrustedProxies(ginConfig.TrustedProxies); err != nil {
+		return err
+	}
+
 	// 填充 swagger 配置
 	if g.swaggerInfo != nil {
 		setGinSwaggerInfo(g.swaggerInfo)
@@ -149,36 +224,67 @@ func (g *XGin) Run() error {
 
 	xutil.InfoIfEnableDebug("gin server listen on: %s", addr)
 
-	// 构建 handler，根据配置决定是否启用 h2c
+	// 构建 handler，根据配置决定是否启用 h2c，共用 gRPC 端口时需要 h2c
 	handler := g.engine.Handler()
-	if ginConfig.UseH2C && ginConfig.CertFile == "" && ginConfig.KeyFile == "" {
-		// 非 TLS 模式下使用 h2c（HTTP/2 Cleartext）
-		h2s := &http2.Server{}
-		handler = h2c.NewHandler(handler, h2s)
+	if g.grpcHandler != nil {
+		handler = grpcDispatchHandler(handler, g.grpcHandler)
+		xutil.InfoIfEnableDebug("gin server share port with grpc")
+	}
+	tlsHandler, plainHandler := handler, handler
+	if ginConfig.UseH2C || g.grpcHandler != nil {
+		// 非 TLS 监听使用 h2c（HTTP/2 Cleartext）
+		plainHandler = h2c.NewHandler(handler, newHTTP2Server(ginConfig.HTTP2))
 		xutil.InfoIfEnableDebug("gin server use h2c (HTTP/2 Cleartext)")
 	}
 
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: handler,
+	useTLS := ginConfig.CertFile != "" && ginConfig.KeyFile != ""
+	srv := newHTTPServer(ginConfig, addr, plainHandler)
+	if useTLS {
+		// 证书通过 GetCertificate 加载，文件变更后自动重新加载
+		tlsConfig, err := newTLSConfig(ginConfig.CertFile, ginConfig.KeyFile, ginConfig.ClientCAFile, ginConfig.ClientAuth)
+		if err != nil {
+			return xerror.Newf("xgin", "run", "invalid TLS config, err=[%v]", err)
+		}
+		srv.Handler = tlsHandler
+		srv.TLSConfig = tlsConfig
+		if err := configureHTTP2(srv, ginConfig.HTTP2); err != nil {
+			return xerror.Newf("xgin", "run", "invalid HTTP2 config, err=[%v]", err)
+		}
+	}
+
+	// 额外监听先于主监听创建，配置错误或端口占用时直接返回
+	listeners, err := newListenerServers(ginConfig, tlsHandler, plainHandler)
+	if err != nil {
+		return err
 	}
 
 	g.srvMu.Lock()
 	g.srv = srv
+	g.listeners = listeners
 	g.srvMu.Unlock()
 
+	listenerErrChan := make(chan error, 1)
+	serveListeners(listeners, srv, listenerErrChan)
+
 	// 根据 TLS 配置决定启动方式
-	var err error
-	if ginConfig.CertFile != "" && ginConfig.KeyFile != "" {
-		xutil.InfoIfEnableDebug("gin server use TLS, cert=[%s], key=[%s]", ginConfig.CertFile, ginConfig.KeyFile)
-		err = srv.ListenAndServeTLS(ginConfig.CertFile, ginConfig.KeyFile)
+	if useTLS {
+		xutil.InfoIfEnableDebug("gin server use TLS, cert=[%s], key=[%s], clientCA=[%s]", ginConfig.CertFile, ginConfig.KeyFile, ginConfig.ClientCAFile)
+		err = srv.ListenAndServeTLS("", "")
 	} else {
 		err = srv.ListenAndServe()
 	}
 
+	// 额外监听异常退出会关闭主 server，此时返回额外监听的错误
+	select {
+	case listenerErr := <-listenerErrChan:
+		closeListeners(listeners)
+		return listenerErr
+	default:
+	}
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
+	closeListeners(listeners)
 	return err
 }
 
@@ -186,6 +292,7 @@ func (g *XGin) Run() error {
 func (g *XGin) Stop() error {
 	g.srvMu.Lock()
 	srv := g.srv
+	listeners := g.listeners
 	g.srvMu.Unlock()
 
 	if srv == nil {
@@ -197,10 +304,36 @@ func (g *XGin) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultWaitStopDuration)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	// 先结束 SSE/WebSocket 等长连接，否则 Shutdown 会一直等待到超时
+	if err := g.streams.CloseAll(ctx); err != nil {
+		xutil.WarnIfEnableDebug("XGin close streams failed, err=[%v]", err)
+	}
+
+	if err := shutdownServers(ctx, srv, listeners); err != nil {
 		xutil.ErrorIfEnableDebug("XGin server stop failed, err=[%v]", err)
 		return err
 	}
+
+	// server 关闭后不再有请求写访问日志
+	if g.accessLogCloser != nil {
+		if err := g.accessLogCloser.Close(); err != nil {
+			xutil.WarnIfEnableDebug("XGin close access log failed, err=[%v]", err)
+		}
+	}
+	return nil
+}
+
+func (g *XGin) applyTrustedProxies(proxies []string) error {
+	if len(proxies) == 0 {
+		return nil
+	}
+	if err := g.engine.SetTrustedProxies(proxies); err != nil {
+		return xerror.Newf("xgin", "run", "invalid TrustedProxies, err=[%v]", err)
+	}
+	if err := middleware.SetTrustedProxies(proxies); err != nil {
+		return xerror.Newf("xgin", "run", "invalid TrustedProxies, err=[%v]", err)
+	}
+	xutil.InfoIfEnableDebug("gin server trusted proxies: %v", proxies)
 	return nil
 }
 
@@ -221,6 +354,9 @@ func (g *XGin) registerMiddleware(do *options.Options) {
 	// 提前注入一下 session 相关信息
 	g.engine.Use(middleware.GinXSessionMiddleware())
 
+	// 注入本实例的流 Registry，Stop 时只结束本实例的 SSE/WebSocket
+	g.engine.Use(g.streams.Middleware())
+
 	// 注册trace middleware，需要放在靠前的位置，保证traceid能提前生成，后续middleware和handler能正确获取到
 	if do.EnableTraceMiddleware {
 		g.engine.Use(middleware.GinXTraceMiddleware())
@@ -229,9 +365,21 @@ func (g *XGin) registerMiddleware(do *options.Options) {
 	// 注册recover middleware，需要放在除trace外其它middleware前，保证发生panic能及时recover
 	g.engine.Use(middleware.GinXRecoverMiddleware(g.recoveryFunc))
 
-	// 注册log middleware
+	// 注册compress middleware，需要放在log middleware之前，保证日志捕获的是压缩前的响应 body
+	if do.EnableCompressMiddleware {
+		g.engine.Use(middleware.GinXCompressMiddleware(
+			middleware.WithCompressMinSize(do.CompressMinSize),
+			middleware.WithCompressContentTypes(do.CompressContentTypes...),
+			middleware.WithCompressEncodings(do.CompressEncodings...),
+			middleware.WithCompressSkipPaths(do.CompressSkipPaths...),
+		))
+	}
+
+	// 注册log middleware，按 XGin.AccessLog 配置在 Run 或首个请求时创建，配置无效时 Run 返回错误
 	if do.EnableLogMiddleware {
-		g.engine.Use(middleware.LogMiddleware(middleware.WithSkipPaths(do.LogSkipPaths...)))
+		g.enableLogMiddleware = true
+		g.logSkipPaths = do.LogSkipPaths
+		g.engine.Use(g.configMiddleware(func() gin.HandlerFunc { return g.logMiddleware }))
 	}
 
 	// 注册metric middleware 和 metrics 端点
@@ -240,16 +388,70 @@ func (g *XGin) registerMiddleware(do *options.Options) {
 		g.engine.GET(do.MetricsPath, middleware.MetricsHandler())
 	}
 
+	// 注册请求 body 大小限制 middleware，按 XGin.MaxRequestBodySize 配置创建，放在 log/metric 之后，保证 413 请求也被记录
+	g.engine.Use(g.configMiddleware(func() gin.HandlerFunc { return g.bodyLimitMiddleware }))
+
+	// 注册 OpenAPI 契约校验 middleware，放在 log/metric 之后，保证校验失败的请求也被记录
+	if do.OpenAPIValidateFile != "" {
+		validate, err := middleware.GinXOpenAPIValidateMiddleware(do.OpenAPIValidateFile,
+			middleware.WithOpenAPIValidateResponse(do.OpenAPIValidateResponse),
+			middleware.WithOpenAPIValidateSkipPaths(do.OpenAPIValidateSkipPaths...),
+		)
+		if err != nil {
+			g.buildErr = xerror.Newf("xgin", "build", "load openapi document failed, file=[%s], err=[%v]", do.OpenAPIValidateFile, err)
+			xutil.ErrorIfEnableDebug("%v", g.buildErr)
+		} else {
+			g.engine.Use(validate)
+		}
+	}
+
 	// 注册自定义的 middleware
 	for _, m := range g.middlewares {
 		g.engine.Use(m)
 	}
 }
 
+// initConfigMiddlewares 按 XGin 配置创建中间件，只执行一次
+func (g *XGin) initConfigMiddlewares() error {
+	g.configOnce.Do(func() {
+		if g.enableLogMiddleware {
+			logMiddleware, closer, err := newLogMiddleware(g.logSkipPaths)
+			if err != nil {
+				g.configErr = xerror.Newf("xgin", "build", "invalid AccessLog config, err=[%v]", err)
+				xutil.ErrorIfEnableDebug("%v", g.configErr)
+				return
+			}
+			g.logMiddleware = logMiddleware
+			g.accessLogCloser = closer
+		}
+
+		routes := bodyLimitRoutes(g.groups)
+		if limit := GetConfig().MaxRequestBodySize; limit > 0 || len(routes) > 0 {
+			g.bodyLimitMiddleware = middleware.GinXBodyLimitMiddleware(limit, routes...)
+		}
+	})
+	return g.configErr
+}
+
+// configMiddleware 占位中间件，首次执行时初始化配置，get 返回 nil 时直接执行后续 handler
+func (g *XGin) configMiddleware(get func() gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = g.initConfigMiddlewares()
+		if h := get(); h != nil {
+			h(c)
+			return
+		}
+		c.Next()
+	}
+}
+
 func (g *XGin) registerRoute() {
 	for _, register := range g.routerRegisters {
 		register(g.engine)
 	}
+	for _, rg := range g.groups {
+		rg.registerTo(g.engine)
+	}
 }
 
 func setGinMode() {
@@ -1,16 +1,33 @@
 package xgin
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/swaggo/swag"
 	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xerror"
+	"github.com/xiaoshicae/xone/v2/xgin/middleware"
+	"github.com/xiaoshicae/xone/v2/xgin/openapi"
 	"github.com/xiaoshicae/xone/v2/xgin/options"
 	"github.com/xiaoshicae/xone/v2/xgin/trans"
 	"github.com/xiaoshicae/xone/v2/xserver"
@@ -309,6 +326,53 @@ func TestRunAndStop(t *testing.T) {
 	})
 }
 
+func TestApplyTrustedProxies(t *testing.T) {
+	defer middleware.SetTrustedProxies(nil)
+
+	g := New()
+	if err := g.applyTrustedProxies(nil); err != nil {
+		t.Fatalf("empty proxies should be ignored, got %v", err)
+	}
+	if err := g.applyTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := g.applyTrustedProxies([]string{"bad-cidr/8"}); err == nil {
+		t.Fatal("expected error for invalid cidr")
+	}
+
+	g = New(options.EnableLogMiddleware(false), options.EnableTraceMiddleware(false))
+	_ = g.applyTrustedProxies([]string{"10.0.0.0/8"})
+	var ginIP, xIP string
+	g.WithRouteRegister(func(e *gin.Engine) {
+		e.GET("/ip", func(c *gin.Context) {
+			ginIP = c.ClientIP()
+			xIP = middleware.GetClientInfo(c).IP
+		})
+	}).Build()
+
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.5")
+	g.engine.ServeHTTP(httptest.NewRecorder(), req)
+	if ginIP != "203.0.113.5" || xIP != ginIP {
+		t.Fatalf("gin and xgin client ip should agree, gin=%s xgin=%s", ginIP, xIP)
+	}
+}
+
+func TestRunWithInvalidTrustedProxies(t *testing.T) {
+	PatchConvey("TestRunWithInvalidTrustedProxies", t, func() {
+		Mock(GetConfig).Return(&Config{Host: "127.0.0.1", Port: 0, TrustedProxies: []string{"bad"}}).Build()
+
+		g := New(
+			options.EnableLogMiddleware(false),
+			options.EnableTraceMiddleware(false),
+		)
+		err := g.Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "TrustedProxies")
+	})
+}
+
 func TestGetXGinOptions(t *testing.T) {
 	g := New(
 		options.EnableLogMiddleware(false),
@@ -392,6 +456,53 @@ func TestBuildWithMetricMiddleware_Disabled(t *testing.T) {
 	}
 }
 
+func TestBuildWithCompressMiddleware(t *testing.T) {
+	body := strings.Repeat(`{"k":"v"}`, 32)
+	g := New(
+		options.EnableTraceMiddleware(false),
+		options.EnableMetricMiddleware(false),
+		options.EnableCompressMiddleware(true),
+		options.CompressMinSize(64),
+		options.CompressEncodings("gzip"),
+	)
+	g.WithRouteRegister(func(e *gin.Engine) {
+		e.GET("/data", func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json", []byte(body))
+		})
+	}).Build()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/data", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	g.engine.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestBuildWithCompressMiddleware_Disabled(t *testing.T) {
+	g := New(
+		options.EnableLogMiddleware(false),
+		options.EnableTraceMiddleware(false),
+		options.EnableMetricMiddleware(false),
+	)
+	g.WithRouteRegister(func(e *gin.Engine) {
+		e.GET("/data", func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json", []byte(strings.Repeat("a", 4096)))
+		})
+	}).Build()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/data", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	g.engine.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("compress middleware should be disabled by default")
+	}
+}
+
 func TestBuildWithZHTranslations(t *testing.T) {
 	g := New(
 		options.EnableLogMiddleware(false),
@@ -506,11 +617,12 @@ func TestRunWithHttp2(t *testing.T) {
 
 func TestRunWithTLS(t *testing.T) {
 	PatchConvey("TestRunWithTLS", t, func() {
+		certFile, keyFile := writeTestCert(t, t.TempDir(), "localhost")
 		Mock(GetConfig).Return(&Config{
 			Host:     "127.0.0.1",
 			Port:     8443,
-			CertFile: "/path/to/cert.pem",
-			KeyFile:  "/path/to/key.pem",
+			CertFile: certFile,
+			KeyFile:  keyFile,
 		}).Build()
 		Mock((*http.Server).ListenAndServeTLS).Return(errors.New("for test tls")).Build()
 
@@ -522,6 +634,107 @@ func TestRunWithTLS(t *testing.T) {
 		err := g.Run()
 		So(err, ShouldNotBeNil)
 		So(err.Error(), ShouldEqual, "for test tls")
+		So(g.srv.TLSConfig, ShouldNotBeNil)
+		So(g.srv.TLSConfig.GetCertificate, ShouldNotBeNil)
+	})
+}
+
+func TestRunWithServerLimits(t *testing.T) {
+	PatchConvey("TestRunWithServerLimits", t, func() {
+		certFile, keyFile := writeTestCert(t, t.TempDir(), "localhost")
+		Mock(GetConfig).Return(configMergeDefault(&Config{
+			Host:           "127.0.0.1",
+			Port:           8443,
+			CertFile:       certFile,
+			KeyFile:        keyFile,
+			ReadTimeout:    "0",
+			WriteTimeout:   "1m30s",
+			MaxHeaderBytes: 4096,
+			HTTP2:          &HTTP2Config{MaxConcurrentStreams: 100, ReadIdleTimeout: "30s"},
+		})).Build()
+		Mock((*http.Server).ListenAndServeTLS).Return(errors.New("for test tls")).Build()
+
+		g := New(options.EnableLogMiddleware(false), options.EnableTraceMiddleware(false))
+		So(g.Run().Error(), ShouldEqual, "for test tls")
+
+		So(g.srv.ReadHeaderTimeout, ShouldEqual, 10*time.Second)
+		So(g.srv.ReadTimeout, ShouldEqual, 0)
+		So(g.srv.WriteTimeout, ShouldEqual, 90*time.Second)
+		So(g.srv.IdleTimeout, ShouldEqual, 120*time.Second)
+		So(g.srv.MaxHeaderBytes, ShouldEqual, 4096)
+		// TLS 模式通过 http2.ConfigureServer 启用 HTTP/2
+		So(g.srv.TLSConfig.NextProtos, ShouldContain, "h2")
+		So(g.srv.TLSNextProto, ShouldContainKey, "h2")
+	})
+}
+
+func TestNewHTTP2Server(t *testing.T) {
+	PatchConvey("TestNewHTTP2Server", t, func() {
+		h2s := newHTTP2Server(&HTTP2Config{MaxConcurrentStreams: 100, MaxReadFrameSize: 1 << 16, IdleTimeout: "5m", PingTimeout: "10s"})
+		So(h2s.MaxConcurrentStreams, ShouldEqual, 100)
+		So(h2s.MaxReadFrameSize, ShouldEqual, 1<<16)
+		So(h2s.IdleTimeout, ShouldEqual, 5*time.Minute)
+		So(h2s.PingTimeout, ShouldEqual, 10*time.Second)
+		So(h2s.ReadIdleTimeout, ShouldEqual, 0)
+
+		So(newHTTP2Server(nil).MaxConcurrentStreams, ShouldEqual, defaultMaxConcurrentStreams)
+	})
+}
+
+func TestMaxRequestBodySize(t *testing.T) {
+	PatchConvey("TestMaxRequestBodySize", t, func() {
+		Mock(GetConfig).Return(configMergeDefault(&Config{MaxRequestBodySize: 8})).Build()
+
+		echo := func(c *gin.Context) {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				return
+			}
+			c.String(http.StatusOK, string(body))
+		}
+		g := New(
+			options.EnableLogMiddleware(false),
+			options.EnableTraceMiddleware(false),
+			options.EnableMetricMiddleware(false),
+		).WithRouteRegister(func(e *gin.Engine) {
+			e.POST("/echo", echo)
+		}).WithGroup("/upload", func(rg *gin.RouterGroup) {
+			rg.POST("/:name", echo)
+		}, options.GroupMaxBodySize(0)).Build()
+
+		w := httptest.NewRecorder()
+		g.engine.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("1234")))
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		w = httptest.NewRecorder()
+		g.engine.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("0123456789abcdef")))
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		// 组内不限制
+		w = httptest.NewRecorder()
+		g.engine.ServeHTTP(w, httptest.NewRequest("POST", "/upload/a.txt", strings.NewReader("0123456789abcdef")))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "0123456789abcdef")
+	})
+}
+
+func TestRunWithInvalidTLSCert(t *testing.T) {
+	PatchConvey("TestRunWithInvalidTLSCert", t, func() {
+		Mock(GetConfig).Return(&Config{
+			Host:     "127.0.0.1",
+			Port:     8443,
+			CertFile: "/path/to/cert.pem",
+			KeyFile:  "/path/to/key.pem",
+		}).Build()
+
+		g := New(
+			options.EnableLogMiddleware(false),
+			options.EnableTraceMiddleware(false),
+		)
+
+		err := g.Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "invalid TLS config")
 	})
 }
 
@@ -700,6 +913,37 @@ func TestConfigMergeDefault(t *testing.T) {
 			c := configMergeDefault(&Config{Port: -1})
 			So(c.Port, ShouldEqual, defaultPort)
 		})
+
+		PatchConvey("WithListeners", func() {
+			c := configMergeDefault(&Config{Host: "10.0.0.1", Listeners: []ListenerConfig{{Port: 8443}, {Network: "unix", Host: "127.0.0.1"}}})
+			So(c.Listeners[0].Network, ShouldEqual, "tcp")
+			So(c.Listeners[0].Host, ShouldEqual, "10.0.0.1")
+			So(c.Listeners[1].Network, ShouldEqual, "unix")
+			So(c.Listeners[1].Host, ShouldEqual, "127.0.0.1")
+		})
+
+		PatchConvey("ServerLimits", func() {
+			c := configMergeDefault(&Config{ReadTimeout: "0", MaxRequestBodySize: -1})
+			So(c.ReadHeaderTimeout, ShouldEqual, "10s")
+			So(c.ReadTimeout, ShouldEqual, "0")
+			So(c.WriteTimeout, ShouldEqual, "60s")
+			So(c.IdleTimeout, ShouldEqual, "120s")
+			So(c.MaxHeaderBytes, ShouldEqual, 1<<20)
+			So(c.MaxRequestBodySize, ShouldEqual, -1)
+			So(c.HTTP2.MaxConcurrentStreams, ShouldEqual, 250)
+
+			c = configMergeDefault(&Config{HTTP2: &HTTP2Config{MaxConcurrentStreams: 100}})
+			So(c.MaxRequestBodySize, ShouldEqual, 32<<20)
+			So(c.HTTP2.MaxConcurrentStreams, ShouldEqual, 100)
+		})
+
+		PatchConvey("WithAccessLogFile", func() {
+			c := configMergeDefault(&Config{AccessLog: &AccessLogConfig{File: &AccessLogFileConfig{Name: "gw"}}})
+			So(c.AccessLog.File.Path, ShouldEqual, "./log")
+			So(c.AccessLog.File.Name, ShouldEqual, "gw")
+			So(c.AccessLog.File.MaxAge, ShouldEqual, "7d")
+			So(c.AccessLog.File.RotateTime, ShouldEqual, "1d")
+		})
 	})
 }
 
@@ -718,9 +962,178 @@ func TestSwaggerConfigMergeDefault(t *testing.T) {
 	})
 }
 
+func TestAccessLogConfigLogOptions(t *testing.T) {
+	PatchConvey("TestAccessLogConfigLogOptions", t, func() {
+		PatchConvey("Nil", func() {
+			var c *AccessLogConfig
+			opts, err := c.logOptions()
+			So(err, ShouldBeNil)
+			So(opts, ShouldBeEmpty)
+		})
+
+		PatchConvey("RouteInheritsGlobal", func() {
+			c := &AccessLogConfig{
+				Body:        "request",
+				SampleRate:  0.01,
+				RedactPaths: []string{"$.card.number"},
+				MaskRules:   []AccessLogMaskRule{{Pattern: `\d{11}`}},
+				Routes: []AccessLogRouteConfig{
+					{Method: "POST", Path: "/orders", Body: "both"},
+					{Path: "/internal/", SampleRate: 1},
+				},
+			}
+			opts, err := c.logOptions()
+			So(err, ShouldBeNil)
+
+			lo := &middleware.LogOptions{}
+			for _, opt := range opts {
+				opt(lo)
+			}
+			So(lo.Policy.Body, ShouldEqual, middleware.LogBodyRequest)
+			So(lo.Policy.SampleRate, ShouldEqual, 0.01)
+			So(lo.RedactPaths, ShouldResemble, []string{"$.card.number"})
+			So(lo.MaskRules, ShouldHaveLength, 1)
+			So(lo.RoutePolicies, ShouldHaveLength, 2)
+
+			_, err = middleware.NewLogMiddleware(opts...)
+			So(err, ShouldBeNil)
+		})
+
+		PatchConvey("InvalidBody", func() {
+			_, err := (&AccessLogConfig{Body: "all"}).logOptions()
+			So(err, ShouldNotBeNil)
+
+			_, err = (&AccessLogConfig{Routes: []AccessLogRouteConfig{{Path: "/x", Body: "all"}}}).logOptions()
+			So(err, ShouldNotBeNil)
+
+			_, err = (&AccessLogConfig{Routes: []AccessLogRouteConfig{{Body: "none"}}}).logOptions()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestAccessLogConfigOutput(t *testing.T) {
+	PatchConvey("TestAccessLogConfigOutput", t, func() {
+		PatchConvey("Default", func() {
+			w, closer, f, err := (&AccessLogConfig{Body: "none"}).output()
+			So(err, ShouldBeNil)
+			So(w, ShouldBeNil)
+			So(closer, ShouldBeNil)
+			So(f, ShouldBeNil)
+		})
+
+		PatchConvey("Stdout", func() {
+			w, closer, f, err := (&AccessLogConfig{Format: "otel"}).output()
+			So(err, ShouldBeNil)
+			So(w, ShouldEqual, os.Stdout)
+			So(closer, ShouldBeNil)
+			So(f, ShouldNotBeNil)
+		})
+
+		PatchConvey("InvalidFormat", func() {
+			_, _, _, err := (&AccessLogConfig{Format: "json"}).output()
+			So(err, ShouldNotBeNil)
+		})
+
+		PatchConvey("File", func() {
+			dir := t.TempDir() + "/access"
+			w, closer, f, err := (&AccessLogConfig{Format: "combined", File: &AccessLogFileConfig{Path: dir}}).output()
+			So(err, ShouldBeNil)
+			So(closer != nil, ShouldBeTrue) // 避免格式化 RotateWriter，与其后台清理 goroutine 产生竞争
+			So(f, ShouldNotBeNil)
+
+			_, err = w.Write([]byte("line\n"))
+			So(err, ShouldBeNil)
+			So(closer.Close(), ShouldBeNil)
+
+			content, err := os.ReadFile(dir + "/access.log")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "line\n")
+		})
+	})
+}
+
+func TestBuildWithAccessLogFile(t *testing.T) {
+	PatchConvey("TestBuildWithAccessLogFile", t, func() {
+		dir := t.TempDir()
+		Mock(xconfig.UnmarshalConfig).To(func(key string, conf any) error {
+			if c, ok := conf.(*Config); ok {
+				c.AccessLog = &AccessLogConfig{Format: "combined", File: &AccessLogFileConfig{Path: dir, Name: "gw"}}
+			}
+			return nil
+		}).Build()
+
+		g := New(options.EnableTraceMiddleware(false), options.EnableMetricMiddleware(false))
+		g.WithRouteRegister(func(e *gin.Engine) {
+			e.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+		}).Build()
+		// 配置在首个请求时读取
+		So(g.accessLogCloser, ShouldBeNil)
+
+		g.engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+		So(g.accessLogCloser != nil, ShouldBeTrue)
+		So(g.accessLogCloser.Close(), ShouldBeNil)
+
+		content, err := os.ReadFile(dir + "/gw.log")
+		So(err, ShouldBeNil)
+		So(string(content), ShouldContainSubstring, `"GET /ping HTTP/1.1" 200 4`)
+	})
+}
+
+func TestRunWithInvalidAccessLogConfig(t *testing.T) {
+	PatchConvey("TestRunWithInvalidAccessLogConfig", t, func() {
+		Mock(xconfig.UnmarshalConfig).To(func(key string, conf any) error {
+			if c, ok := conf.(*Config); ok {
+				c.AccessLog = &AccessLogConfig{RedactPaths: []string{"card.number"}}
+			}
+			return nil
+		}).Build()
+
+		err := New().Run()
+		So(err, ShouldNotBeNil)
+		So(xerror.Is(err, "xgin"), ShouldBeTrue)
+	})
+}
+
+func TestAccessLogConfigReadAtRun(t *testing.T) {
+	PatchConvey("TestAccessLogConfigReadAtRun", t, func() {
+		// Build 早于 xconfig 初始化时，不应读取配置
+		g := New().Build()
+
+		Mock(xconfig.UnmarshalConfig).To(func(key string, conf any) error {
+			if c, ok := conf.(*Config); ok {
+				c.Host = "127.0.0.1"
+				c.AccessLog = &AccessLogConfig{RedactPaths: []string{"card.number"}}
+			}
+			return nil
+		}).Build()
+		Mock((*http.Server).ListenAndServe).Return(http.ErrServerClosed).Build()
+
+		err := g.Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "invalid AccessLog config")
+	})
+
+	PatchConvey("TestAccessLogConfigReadAtRun-NilSkipPaths", t, func() {
+		// LogSkipPaths 为 nil 时同样读取配置
+		g := &XGin{enableLogMiddleware: true}
+
+		Mock(xconfig.UnmarshalConfig).To(func(key string, conf any) error {
+			if c, ok := conf.(*Config); ok {
+				c.AccessLog = &AccessLogConfig{RedactPaths: []string{"card.number"}}
+			}
+			return nil
+		}).Build()
+		So(g.initConfigMiddlewares(), ShouldNotBeNil)
+	})
+}
+
 // ==================== xgin.go 补充测试 ====================
 
 func TestStart(t *testing.T) {
+	// mock 未生效时 xserver.Run 会按默认配置初始化 xlog（./log），切到临时目录避免写入包目录
+	t.Chdir(t.TempDir())
+
 	PatchConvey("TestStart", t, func() {
 		Mock(xserver.Run).Return(nil).Build()
 
@@ -907,3 +1320,582 @@ func TestPrintBanner_LongBanner(t *testing.T) {
 		PrintBanner() // 不应 panic
 	})
 }
+
+type openAPIPingReq struct {
+	Name string `form:"name" binding:"required"`
+}
+
+type openAPIPingResp struct {
+	Msg string `json:"msg"`
+}
+
+func TestBuildWithOpenAPI(t *testing.T) {
+	g := New(
+		options.EnableLogMiddleware(false),
+		options.EnableTraceMiddleware(false),
+		options.EnableMetricMiddleware(false),
+	)
+	g.WithSwagger(&swag.Spec{InfoInstanceName: "test-openapi", SwaggerTemplate: "{}"})
+	g.WithOpenAPI(openapi.WithTitle("demo"), openapi.WithVersion("v1"), openapi.WithPath("/docs/openapi.json"))
+	g.WithRouteRegister(func(e *gin.Engine) {
+		GET(e, "/openapi/ping", func(c *gin.Context, req *openAPIPingReq) (*openAPIPingResp, error) {
+			return &openAPIPingResp{Msg: "hi " + req.Name}, nil
+		})
+	}).Build()
+
+	w := httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/openapi/ping?name=xone", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "hi xone") {
+		t.Fatalf("unexpected typed route response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/openapi/ping", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for missing param, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/docs/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"/openapi/ping"`) {
+		t.Fatalf("unexpected openapi document %d %s", w.Code, w.Body.String())
+	}
+
+	// swagger UI 路由仍然可用
+	w = httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/swagger/index.html", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("swagger route should coexist, got %d", w.Code)
+	}
+}
+
+func TestBuildWithOpenAPIValidate(t *testing.T) {
+	file := t.TempDir() + "/openapi.yaml"
+	doc := "openapi: 3.0.3\ninfo: {title: demo, version: '1'}\npaths:\n  /items:\n    get:\n      parameters:\n        - {name: page, in: query, schema: {type: integer}}\n      responses:\n        '200': {description: ok}\n"
+	if err := os.WriteFile(file, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(
+		options.EnableLogMiddleware(false),
+		options.EnableTraceMiddleware(false),
+		options.EnableMetricMiddleware(false),
+		options.OpenAPIValidateFile(file),
+	)
+	g.WithRouteRegister(func(e *gin.Engine) {
+		e.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+	}).Build()
+
+	w := httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/items?page=x", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/items?page=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestRunWithInvalidOpenAPIValidateFile(t *testing.T) {
+	g := New(options.OpenAPIValidateFile(t.TempDir() + "/missing.yaml"))
+	g.Build()
+	err := g.Run()
+	if err == nil || !xerror.Is(err, "xgin") {
+		t.Fatalf("expected xgin build error, got %v", err)
+	}
+}
+
+func TestRunWithInvalidTranslations(t *testing.T) {
+	g := New(options.EnableTranslations("en", "xx"))
+	g.Build()
+	err := g.Run()
+	if err == nil || !strings.Contains(err.Error(), "register translations failed") {
+		t.Fatalf("expected translations build error, got %v", err)
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	calls := make([]string, 0)
+	mark := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			calls = append(calls, name)
+			c.Next()
+		}
+	}
+	denyWithoutToken := func(c *gin.Context) {
+		calls = append(calls, "auth")
+		if c.GetHeader("X-Token") == "" {
+			middleware.AbortWithErrorResponse(c, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		c.Next()
+	}
+
+	g := New(
+		options.EnableLogMiddleware(false),
+		options.EnableTraceMiddleware(false),
+		options.EnableMetricMiddleware(false),
+	)
+	g.WithGroup("/users", func(rg *gin.RouterGroup) {
+		rg.GET("/:id", func(c *gin.Context) {
+			calls = append(calls, "handler")
+			c.JSON(http.StatusOK, gin.H{
+				"id":       c.Param("id"),
+				"log_body": c.GetBool(middleware.LogBodyCaptureContextKey),
+				"skip":     c.GetBool(middleware.MetricSkipContextKey),
+			})
+		})
+	},
+		options.GroupVersion("v1"),
+		options.GroupAuth(denyWithoutToken),
+		options.GroupMiddleware(mark("m1"), mark("m2")),
+		options.GroupLogBody(false),
+		options.GroupMetric(false),
+		options.GroupSunset(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), "https://example.com/v2"),
+	).WithGroup("/users", func(rg *gin.RouterGroup) {
+		rg.GET("/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	}, options.GroupVersion("/v2/")).Build()
+
+	// 认证失败时不执行后续中间件，但仍携带废弃声明头
+	w := httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/v1/users/1", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Thu, 31 Dec 2026 00:00:00 GMT" {
+		t.Fatalf("unexpected deprecation headers %v", w.Header())
+	}
+	if strings.Join(calls, ",") != "auth" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	calls = calls[:0]
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/users/1", nil)
+	req.Header.Set("X-Token", "t")
+	g.engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if strings.Join(calls, ",") != "auth,m1,m2,handler" {
+		t.Fatalf("unexpected middleware order %v", calls)
+	}
+	if !strings.Contains(w.Body.String(), `"log_body":false`) || !strings.Contains(w.Body.String(), `"skip":true`) {
+		t.Fatalf("group policies not applied: %s", w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Link"), `<https://example.com/v2>; rel="deprecation"`) {
+		t.Fatalf("unexpected Link header %q", w.Header().Get("Link"))
+	}
+
+	// 其它版本的同名组互不影响
+	w = httptest.NewRecorder()
+	g.engine.ServeHTTP(w, httptest.NewRequest("GET", "/v2/users/1", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Fatalf("v2 group should not inherit v1 policies, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestWithGRPCHandler(t *testing.T) {
+	PatchConvey("TestWithGRPCHandler", t, func() {
+		grpcHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		PatchConvey("Dispatch", func() {
+			g := New(
+				options.EnableLogMiddleware(false),
+				options.EnableTraceMiddleware(false),
+				options.EnableMetricMiddleware(false),
+			).WithRouteRegister(func(e *gin.Engine) {
+				e.POST("/pkg.Svc/Call", func(c *gin.Context) {
+					c.String(http.StatusOK, "gin")
+				})
+			}).WithGRPCHandler(grpcHandler)
+			h := grpcDispatchHandler(g.Engine(), g.grpcHandler)
+
+			grpcReq := httptest.NewRequest(http.MethodPost, "/pkg.Svc/Call", nil)
+			grpcReq.ProtoMajor = 2
+			grpcReq.Header.Set("Content-Type", "application/grpc+proto")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, grpcReq)
+			So(w.Code, ShouldEqual, http.StatusTeapot)
+
+			// HTTP/1.1 或非 gRPC Content-Type 的请求走 gin
+			httpReq := httptest.NewRequest(http.MethodPost, "/pkg.Svc/Call", nil)
+			httpReq.Header.Set("Content-Type", "application/grpc")
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, httpReq)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "gin")
+		})
+
+		PatchConvey("RunEnablesH2C", func() {
+			Mock(GetConfig).Return(&Config{Host: "127.0.0.1", Port: 0}).Build()
+			var handler http.Handler
+			Mock((*http.Server).ListenAndServe).To(func(srv *http.Server) error {
+				handler = srv.Handler
+				return http.ErrServerClosed
+			}).Build()
+
+			g := New(options.EnableLogMiddleware(false)).WithGRPCHandler(grpcHandler)
+			So(g.Run(), ShouldBeNil)
+
+			So(fmt.Sprintf("%T", handler), ShouldContainSubstring, "h2c")
+		})
+	})
+}
+
+// writeTestCert 生成自签名证书（同时可作为 CA），返回证书与私钥路径
+func writeTestCert(t *testing.T, dir, cn string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		DNSNames:              []string{cn},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, cn+".pem")
+	keyFile := filepath.Join(dir, cn+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	PatchConvey("TestNewTLSConfig", t, func() {
+		dir := t.TempDir()
+		certFile, keyFile := writeTestCert(t, dir, "server")
+		caFile, _ := writeTestCert(t, dir, "client-ca")
+
+		PatchConvey("NoClientCA", func() {
+			cfg, err := newTLSConfig(certFile, keyFile, "", "")
+			So(err, ShouldBeNil)
+			So(cfg.ClientAuth, ShouldEqual, tls.NoClientCert)
+			So(cfg.MinVersion, ShouldEqual, tls.VersionTLS12)
+			cert, err := cfg.GetCertificate(nil)
+			So(err, ShouldBeNil)
+			So(cert, ShouldNotBeNil)
+		})
+
+		PatchConvey("ClientCADefaultRequireAndVerify", func() {
+			cfg, err := newTLSConfig(certFile, keyFile, caFile, "")
+			So(err, ShouldBeNil)
+			So(cfg.ClientAuth, ShouldEqual, tls.RequireAndVerifyClientCert)
+			So(cfg.ClientCAs, ShouldNotBeNil)
+		})
+
+		PatchConvey("ClientCAVerifyIfGiven", func() {
+			cfg, err := newTLSConfig(certFile, keyFile, caFile, "verify_if_given")
+			So(err, ShouldBeNil)
+			So(cfg.ClientAuth, ShouldEqual, tls.VerifyClientCertIfGiven)
+		})
+
+		PatchConvey("VerifyWithoutCA", func() {
+			_, err := newTLSConfig(certFile, keyFile, "", "require_and_verify")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "requires ClientCAFile")
+		})
+
+		PatchConvey("InvalidClientAuth", func() {
+			_, err := newTLSConfig(certFile, keyFile, caFile, "bad")
+			So(err, ShouldNotBeNil)
+		})
+
+		PatchConvey("InvalidCA", func() {
+			_, err := newTLSConfig(certFile, keyFile, keyFile, "")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "no valid certificate")
+
+			_, err = newTLSConfig(certFile, keyFile, filepath.Join(dir, "missing.pem"), "")
+			So(err, ShouldNotBeNil)
+		})
+
+		PatchConvey("MissingCert", func() {
+			_, err := newTLSConfig(filepath.Join(dir, "missing.pem"), keyFile, "", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestCertReloader(t *testing.T) {
+	PatchConvey("TestCertReloader", t, func() {
+		old := certReloadCheckInterval
+		certReloadCheckInterval = 0
+		defer func() { certReloadCheckInterval = old }()
+
+		dir := t.TempDir()
+		certFile, keyFile := writeTestCert(t, dir, "server")
+		r, err := newCertReloader(certFile, keyFile)
+		So(err, ShouldBeNil)
+		first, _ := r.GetCertificate(nil)
+
+		// 未变更时不重新加载
+		second, _ := r.GetCertificate(nil)
+		So(second, ShouldEqual, first)
+
+		// 证书轮换后重新加载
+		newCert, newKey := writeTestCert(t, t.TempDir(), "server")
+		So(os.Rename(newCert, certFile), ShouldBeNil)
+		So(os.Rename(newKey, keyFile), ShouldBeNil)
+		future := time.Now().Add(time.Minute)
+		So(os.Chtimes(certFile, future, future), ShouldBeNil)
+		So(os.Chtimes(keyFile, future, future), ShouldBeNil)
+		third, _ := r.GetCertificate(nil)
+		So(third, ShouldNotEqual, first)
+
+		// 加载失败时沿用旧证书
+		So(os.WriteFile(certFile, []byte("broken"), 0o600), ShouldBeNil)
+		later := future.Add(time.Minute)
+		So(os.Chtimes(certFile, later, later), ShouldBeNil)
+		fourth, err := r.GetCertificate(nil)
+		So(err, ShouldBeNil)
+		So(fourth, ShouldEqual, third)
+	})
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	PatchConvey("TestHTTPSRedirectHandler", t, func() {
+		PatchConvey("GET", func() {
+			w := httptest.NewRecorder()
+			httpsRedirectHandler(443).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com:8080/a?b=1", nil))
+			So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+			So(w.Header().Get("Location"), ShouldEqual, "https://example.com/a?b=1")
+		})
+
+		PatchConvey("POST", func() {
+			w := httptest.NewRecorder()
+			httpsRedirectHandler(8443).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://example.com/a", nil))
+			So(w.Code, ShouldEqual, http.StatusPermanentRedirect)
+			So(w.Header().Get("Location"), ShouldEqual, "https://example.com:8443/a")
+		})
+
+		PatchConvey("IPv6", func() {
+			w := httptest.NewRecorder()
+			httpsRedirectHandler(443).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://[::1]:8080/", nil))
+			So(w.Header().Get("Location"), ShouldEqual, "https://[::1]/")
+		})
+	})
+}
+
+func TestRedirectPort(t *testing.T) {
+	PatchConvey("TestRedirectPort", t, func() {
+		So(redirectPort(&Config{}, ListenerConfig{RedirectPort: 9443}), ShouldEqual, 9443)
+		So(redirectPort(&Config{Port: 8443, CertFile: "c", KeyFile: "k"}, ListenerConfig{}), ShouldEqual, 8443)
+		So(redirectPort(&Config{Listeners: []ListenerConfig{
+			{Network: "unix", CertFile: "c", KeyFile: "k"},
+			{Network: "tcp", Port: 7443, CertFile: "c", KeyFile: "k"},
+		}}, ListenerConfig{}), ShouldEqual, 7443)
+		So(redirectPort(&Config{}, ListenerConfig{}), ShouldEqual, 443)
+	})
+}
+
+func TestRunWithListeners(t *testing.T) {
+	PatchConvey("TestRunWithListeners", t, func() {
+		dir := t.TempDir()
+		certFile, keyFile := writeTestCert(t, dir, "localhost")
+		mainPort, tlsPort, redirectPort := freePort(t), freePort(t), freePort(t)
+		sock := filepath.Join(dir, "xgin.sock")
+		// 残留的 socket 文件会在启动时清理
+		stale, err := net.Listen("unix", sock)
+		So(err, ShouldBeNil)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		_ = stale.Close()
+
+		Mock(GetConfig).Return(configMergeDefault(&Config{
+			Host: "127.0.0.1",
+			Port: mainPort,
+			Listeners: []ListenerConfig{
+				{Port: tlsPort, CertFile: certFile, KeyFile: keyFile},
+				{Network: "unix", Path: sock, SocketMode: "0660"},
+				{Port: redirectPort, RedirectToHTTPS: true},
+			},
+		})).Build()
+
+		g := New(
+			options.EnableLogMiddleware(false),
+			options.EnableTraceMiddleware(false),
+			options.EnableMetricMiddleware(false),
+		).WithRouteRegister(func(e *gin.Engine) {
+			e.GET("/ping", func(c *gin.Context) {
+				c.String(http.StatusOK, "pong")
+			})
+		})
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- g.Run()
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		get := func(client *http.Client, url string) (int, string) {
+			resp, err := client.Get(url)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(body)
+		}
+
+		code, body := get(http.DefaultClient, fmt.Sprintf("http://127.0.0.1:%d/ping", mainPort))
+		So(code, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "pong")
+
+		tlsClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		code, body = get(tlsClient, fmt.Sprintf("https://127.0.0.1:%d/ping", tlsPort))
+		So(code, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "pong")
+
+		unixClient := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		}}}
+		code, body = get(unixClient, "http://unix/ping")
+		So(code, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "pong")
+		fi, err := os.Stat(sock)
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0o660))
+
+		noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := noRedirect.Get(fmt.Sprintf("http://localhost:%d/ping", redirectPort))
+		So(err, ShouldBeNil)
+		_ = resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusMovedPermanently)
+		So(resp.Header.Get("Location"), ShouldEqual, fmt.Sprintf("https://localhost:%d/ping", tlsPort))
+
+		So(g.Stop(), ShouldBeNil)
+		select {
+		case err := <-errCh:
+			So(err, ShouldBeNil)
+		case <-time.After(2 * time.Second):
+			t.Fatal("Run() did not return after Stop()")
+		}
+		_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", tlsPort))
+		So(err, ShouldNotBeNil)
+		_, err = os.Stat(sock)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
+
+func TestRunWithMTLSListener(t *testing.T) {
+	PatchConvey("TestRunWithMTLSListener", t, func() {
+		dir := t.TempDir()
+		certFile, keyFile := writeTestCert(t, dir, "localhost")
+		clientCert, clientKey := writeTestCert(t, dir, "client")
+		mainPort, tlsPort := freePort(t), freePort(t)
+
+		Mock(GetConfig).Return(configMergeDefault(&Config{
+			Host:      "127.0.0.1",
+			Port:      mainPort,
+			Listeners: []ListenerConfig{{Port: tlsPort, CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCert}},
+		})).Build()
+
+		g := New(
+			options.EnableLogMiddleware(false),
+			options.EnableTraceMiddleware(false),
+			options.EnableMetricMiddleware(false),
+		).WithRouteRegister(func(e *gin.Engine) {
+			e.GET("/ping", func(c *gin.Context) {
+				c.String(http.StatusOK, "pong")
+			})
+		})
+		go func() { _ = g.Run() }()
+		time.Sleep(100 * time.Millisecond)
+		defer g.Stop()
+
+		url := fmt.Sprintf("https://127.0.0.1:%d/ping", tlsPort)
+		noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		_, err := noCert.Get(url)
+		So(err, ShouldNotBeNil)
+
+		pair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		So(err, ShouldBeNil)
+		withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{pair}}}}
+		resp, err := withCert.Get(url)
+		So(err, ShouldBeNil)
+		_ = resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+	})
+}
+
+func TestRunWithInvalidListeners(t *testing.T) {
+	PatchConvey("TestRunWithInvalidListeners", t, func() {
+		cases := []struct {
+			listener ListenerConfig
+			errMsg   string
+		}{
+			{ListenerConfig{Network: "udp", Port: 1}, "invalid Network"},
+			{ListenerConfig{Network: "tcp"}, "port is required"},
+			{ListenerConfig{Network: "unix"}, "path is required"},
+			{ListenerConfig{Network: "tcp", Port: 1, CertFile: "c"}, "TLS config incomplete"},
+			{ListenerConfig{Network: "tcp", Port: 1, CertFile: "c", KeyFile: "k", RedirectToHTTPS: true}, "only allowed on plain"},
+			{ListenerConfig{Network: "unix", Path: filepath.Join(t.TempDir(), "s.sock"), SocketMode: "bad"}, "SocketMode"},
+		}
+		for _, tc := range cases {
+			Mock(GetConfig).Return(&Config{Host: "127.0.0.1", Port: 0, Listeners: []ListenerConfig{tc.listener}}).Build()
+			listenCalled := false
+			Mock((*http.Server).ListenAndServe).To(func(*http.Server) error {
+				listenCalled = true
+				return http.ErrServerClosed
+			}).Build()
+
+			g := New(options.EnableLogMiddleware(false))
+			err := g.Run()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Listeners[0]")
+			So(err.Error(), ShouldContainSubstring, tc.errMsg)
+			So(listenCalled, ShouldBeFalse)
+			UnPatchAll()
+		}
+	})
+}
+
+func TestRunMainListenFailedClosesListeners(t *testing.T) {
+	PatchConvey("TestRunMainListenFailedClosesListeners", t, func() {
+		port := freePort(t)
+		Mock(GetConfig).Return(configMergeDefault(&Config{Host: "127.0.0.1", Listeners: []ListenerConfig{{Port: port}}})).Build()
+		Mock((*http.Server).ListenAndServe).Return(errors.New("address in use")).Build()
+
+		g := New(options.EnableLogMiddleware(false))
+		err := g.Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "address in use")
+
+		// 额外监听已关闭，端口可重新监听
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		So(err, ShouldBeNil)
+		_ = ln.Close()
+	})
+}
@@ -0,0 +1,124 @@
+package xgrpc
+
+import (
+	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xutil"
+)
+
+const (
+	grpcConfigKey = "XGrpc"
+
+	defaultHost           = "0.0.0.0"
+	defaultPort           = 9000
+	defaultMaxRecvMsgSize = 4 * 1024 * 1024
+)
+
+// Config gRPC 相关配置
+type Config struct {
+	// Host 服务监听的host
+	// optional default "0.0.0.0"
+	Host string `mapstructure:"Host"`
+
+	// Port 服务端口号，共用 XGin 端口时不生效
+	// optional default 9000
+	Port int `mapstructure:"Port"`
+
+	// CertFile TLS 证书路径
+	// optional default ""
+	CertFile string `mapstructure:"CertFile"`
+
+	// KeyFile TLS 私钥路径
+	// optional default ""
+	KeyFile string `mapstructure:"KeyFile"`
+
+	// MaxRecvMsgSize 单条请求消息大小上限（字节）
+	// optional default 4194304
+	MaxRecvMsgSize int `mapstructure:"MaxRecvMsgSize"`
+
+	// MaxSendMsgSize 单条响应消息大小上限（字节）
+	// optional default 0（不限制，即 math.MaxInt32）
+	MaxSendMsgSize int `mapstructure:"MaxSendMsgSize"`
+
+	// EnableReflection 是否注册 gRPC reflection 服务，供 grpcurl 等工具使用
+	// optional default false
+	EnableReflection bool `mapstructure:"EnableReflection"`
+
+	// Keepalive 连接保活配置
+	// optional default nil（使用 grpc-go 默认值）
+	Keepalive *KeepaliveConfig `mapstructure:"Keepalive"`
+}
+
+// KeepaliveConfig 连接保活配置，时长格式同 "30s"、"5m"、"2h"
+type KeepaliveConfig struct {
+	// Time 连接空闲多久后服务端发送 ping
+	// optional default "2h"
+	Time string `mapstructure:"Time"`
+
+	// Timeout 发送 ping 后等待 ack 的超时时间，超时关闭连接
+	// optional default "20s"
+	Timeout string `mapstructure:"Timeout"`
+
+	// MaxConnectionIdle 连接无活跃 RPC 多久后发送 GOAWAY 关闭
+	// optional default ""（不限制）
+	MaxConnectionIdle string `mapstructure:"MaxConnectionIdle"`
+
+	// MaxConnectionAge 连接最长存活时间，到期后发送 GOAWAY，便于负载均衡重新分配
+	// optional default ""（不限制）
+	MaxConnectionAge string `mapstructure:"MaxConnectionAge"`
+
+	// MaxConnectionAgeGrace MaxConnectionAge 到期后等待进行中 RPC 结束的时间
+	// optional default ""（不限制）
+	MaxConnectionAgeGrace string `mapstructure:"MaxConnectionAgeGrace"`
+
+	// MinTime 允许客户端发送 ping 的最小间隔，过于频繁的客户端会被断开
+	// optional default "5m"
+	MinTime string `mapstructure:"MinTime"`
+
+	// PermitWithoutStream 是否允许客户端在没有活跃 RPC 时发送 ping
+	// optional default false
+	PermitWithoutStream bool `mapstructure:"PermitWithoutStream"`
+}
+
+// GetConfig 获取gRPC相关配置
+func GetConfig() *Config {
+	config := &Config{}
+	if err := xconfig.UnmarshalConfig(grpcConfigKey, config); err != nil {
+		xutil.WarnIfEnableDebug("XGrpc GetConfig unmarshal failed, use default, err=[%v]", err)
+	}
+	return configMergeDefault(config)
+}
+
+func configMergeDefault(c *Config) *Config {
+	if c == nil {
+		c = &Config{}
+	}
+	if c.Host == "" {
+		c.Host = defaultHost
+	}
+	if c.Port <= 0 {
+		c.Port = defaultPort
+	}
+	if c.MaxRecvMsgSize <= 0 {
+		c.MaxRecvMsgSize = defaultMaxRecvMsgSize
+	}
+	if c.Keepalive != nil {
+		c.Keepalive = keepaliveConfigMergeDefault(c.Keepalive)
+	}
+	return c
+}
+
+func keepaliveConfigMergeDefault(c *KeepaliveConfig) *KeepaliveConfig {
+	if c == nil {
+		c = &KeepaliveConfig{}
+	}
+	if c.Time == "" {
+		c.Time = "2h"
+	}
+	if c.Timeout == "" {
+		c.Timeout = "20s"
+	}
+	if c.MinTime == "" {
+		c.MinTime = "5m"
+	}
+	return c
+}
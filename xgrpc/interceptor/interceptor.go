@@ -0,0 +1,86 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// splitMethod 将完整方法名 /pkg.Service/Method 拆分为 pkg.Service 和 Method
+func splitMethod(fullMethod string) (service, method string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "unknown", name
+}
+
+// streamType unary/client_stream/server_stream/bidi_stream
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info == nil:
+		return "unary"
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
+
+// matchMethod 以 / 或 . 结尾时前缀匹配，否则精确匹配
+func matchMethod(fullMethod string, methods []string) bool {
+	for _, m := range methods {
+		if strings.HasSuffix(m, "/") || strings.HasSuffix(m, ".") {
+			if strings.HasPrefix(fullMethod, m) {
+				return true
+			}
+		} else if fullMethod == m {
+			return true
+		}
+	}
+	return false
+}
+
+// wrappedServerStream 替换 stream 的 context，使后续拦截器和 handler 能获取到 span
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedServerStream) Context() context.Context {
+	return w.ctx
+}
+
+func wrapServerStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if w, ok := ss.(*wrappedServerStream); ok {
+		w.ctx = ctx
+		return w
+	}
+	return &wrappedServerStream{ServerStream: ss, ctx: ctx}
+}
+
+// metadataCarrier 适配 propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,241 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/bytedance/mockey"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testMethod = "/pkg.Greeter/SayHello"
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: testMethod}
+
+// fakeServerStream 仅提供 Context 的 ServerStream
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context { return f.ctx }
+
+func TestSplitMethod(t *testing.T) {
+	PatchConvey("TestSplitMethod", t, func() {
+		service, method := splitMethod(testMethod)
+		So(service, ShouldEqual, "pkg.Greeter")
+		So(method, ShouldEqual, "SayHello")
+
+		service, method = splitMethod("bad")
+		So(service, ShouldEqual, "unknown")
+		So(method, ShouldEqual, "bad")
+	})
+}
+
+func TestStreamType(t *testing.T) {
+	PatchConvey("TestStreamType", t, func() {
+		So(streamType(nil), ShouldEqual, "unary")
+		So(streamType(&grpc.StreamServerInfo{IsClientStream: true, IsServerStream: true}), ShouldEqual, "bidi_stream")
+		So(streamType(&grpc.StreamServerInfo{IsClientStream: true}), ShouldEqual, "client_stream")
+		So(streamType(&grpc.StreamServerInfo{IsServerStream: true}), ShouldEqual, "server_stream")
+	})
+}
+
+func TestMatchMethod(t *testing.T) {
+	PatchConvey("TestMatchMethod", t, func() {
+		So(matchMethod(testMethod, []string{testMethod}), ShouldBeTrue)
+		So(matchMethod(testMethod, []string{"/pkg.Greeter/"}), ShouldBeTrue)
+		So(matchMethod(testMethod, []string{"/pkg."}), ShouldBeTrue)
+		So(matchMethod(testMethod, []string{"/pkg.Greeter"}), ShouldBeFalse)
+		So(matchMethod(testMethod, nil), ShouldBeFalse)
+	})
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	PatchConvey("TestMetadataCarrier", t, func() {
+		c := metadataCarrier(metadata.MD{})
+		So(c.Get("traceparent"), ShouldEqual, "")
+		c.Set("Traceparent", "v")
+		So(c.Get("traceparent"), ShouldEqual, "v")
+		So(c.Keys(), ShouldResemble, []string{"traceparent"})
+	})
+}
+
+func TestTraceInterceptor(t *testing.T) {
+	PatchConvey("TestTraceInterceptor", t, func() {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		oldTP, oldProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		defer func() {
+			otel.SetTracerProvider(oldTP)
+			otel.SetTextMapPropagator(oldProp)
+		}()
+
+		parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", parent))
+
+		PatchConvey("Unary", func() {
+			var handlerCtx context.Context
+			_, err := UnaryServerTrace()(ctx, nil, unaryInfo, func(ctx context.Context, req any) (any, error) {
+				handlerCtx = ctx
+				return nil, status.Error(codes.Internal, "boom")
+			})
+			So(status.Code(err), ShouldEqual, codes.Internal)
+			So(trace.SpanContextFromContext(handlerCtx).TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+			spans := recorder.Ended()
+			So(len(spans), ShouldEqual, 1)
+			So(spans[0].Name(), ShouldEqual, "pkg.Greeter/SayHello")
+			So(spans[0].SpanKind(), ShouldEqual, trace.SpanKindServer)
+			So(spans[0].Status().Code, ShouldEqual, otelcodes.Error)
+		})
+
+		PatchConvey("Stream client error is not span error", func() {
+			var handlerCtx context.Context
+			info := &grpc.StreamServerInfo{FullMethod: testMethod, IsServerStream: true}
+			err := StreamServerTrace()(nil, &fakeServerStream{ctx: ctx}, info, func(srv any, ss grpc.ServerStream) error {
+				handlerCtx = ss.Context()
+				return status.Error(codes.InvalidArgument, "bad")
+			})
+			So(status.Code(err), ShouldEqual, codes.InvalidArgument)
+			So(trace.SpanContextFromContext(handlerCtx).IsValid(), ShouldBeTrue)
+
+			spans := recorder.Ended()
+			So(len(spans), ShouldEqual, 1)
+			So(spans[0].Status().Code, ShouldEqual, otelcodes.Unset)
+		})
+	})
+}
+
+func TestRecoverInterceptor(t *testing.T) {
+	PatchConvey("TestRecoverInterceptor", t, func() {
+		PatchConvey("Default", func() {
+			resp, err := UnaryServerRecover(nil)(context.Background(), nil, unaryInfo, func(ctx context.Context, req any) (any, error) {
+				panic("boom")
+			})
+			So(resp, ShouldBeNil)
+			So(status.Code(err), ShouldEqual, codes.Internal)
+		})
+
+		PatchConvey("Custom handler", func() {
+			handle := func(ctx context.Context, p any) error {
+				return status.Errorf(codes.Unavailable, "%v", p)
+			}
+			info := &grpc.StreamServerInfo{FullMethod: testMethod}
+			err := StreamServerRecover(handle)(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv any, ss grpc.ServerStream) error {
+				panic("boom")
+			})
+			So(status.Code(err), ShouldEqual, codes.Unavailable)
+			So(status.Convert(err).Message(), ShouldEqual, "boom")
+		})
+
+		PatchConvey("No panic", func() {
+			resp, err := UnaryServerRecover(nil)(context.Background(), "req", unaryInfo, func(ctx context.Context, req any) (any, error) {
+				return req, nil
+			})
+			So(err, ShouldBeNil)
+			So(resp, ShouldEqual, "req")
+		})
+	})
+}
+
+func TestLogInterceptor(t *testing.T) {
+	PatchConvey("TestLogInterceptor", t, func() {
+		var logged []string
+		Mock(logRequest).To(func(ctx context.Context, fullMethod, rpcType string, _ time.Duration, err error) {
+			logged = append(logged, fullMethod+" "+rpcType)
+		}).Build()
+
+		_, _ = UnaryServerLog("/grpc.health.v1.Health/")(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		So(logged, ShouldBeEmpty)
+
+		_, _ = UnaryServerLog()(context.Background(), nil, unaryInfo, func(ctx context.Context, req any) (any, error) {
+			return nil, errors.New("err")
+		})
+		info := &grpc.StreamServerInfo{FullMethod: testMethod, IsClientStream: true}
+		_ = StreamServerLog()(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv any, ss grpc.ServerStream) error {
+			return nil
+		})
+		So(logged, ShouldResemble, []string{testMethod + " unary", testMethod + " client_stream"})
+	})
+}
+
+func TestMetricInterceptor(t *testing.T) {
+	PatchConvey("TestMetricInterceptor", t, func() {
+		metricOnce = sync.Once{}
+		testRegistry := prometheus.NewRegistry()
+		Mock(xmetric.GetConfig).Return(&xmetric.Config{}).Build()
+		Mock(xmetric.SafeRegister).To(func(c prometheus.Collector) prometheus.Collector {
+			testRegistry.MustRegister(c)
+			return c
+		}).Build()
+
+		unary := UnaryServerMetric()
+		for i := 0; i < 2; i++ {
+			_, _ = unary(context.Background(), nil, unaryInfo, func(ctx context.Context, req any) (any, error) {
+				return nil, nil
+			})
+		}
+		info := &grpc.StreamServerInfo{FullMethod: testMethod, IsServerStream: true}
+		_ = StreamServerMetric()(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv any, ss grpc.ServerStream) error {
+			return status.Error(codes.NotFound, "nf")
+		})
+
+		metrics, err := testRegistry.Gather()
+		So(err, ShouldBeNil)
+		counters := findFamily(metrics, "grpc_server_requests_total")
+		So(counters, ShouldNotBeNil)
+		So(len(counters.Metric), ShouldEqual, 2)
+		for _, m := range counters.Metric {
+			So(labelValue(m, "service"), ShouldEqual, "pkg.Greeter")
+			So(labelValue(m, "method"), ShouldEqual, "SayHello")
+			switch labelValue(m, "type") {
+			case "unary":
+				So(labelValue(m, "code"), ShouldEqual, "OK")
+				So(*m.Counter.Value, ShouldEqual, 2)
+			case "server_stream":
+				So(labelValue(m, "code"), ShouldEqual, "NotFound")
+				So(*m.Counter.Value, ShouldEqual, 1)
+			}
+		}
+		So(findFamily(metrics, "grpc_server_request_duration_ms"), ShouldNotBeNil)
+	})
+}
+
+func findFamily(metrics []*dto.MetricFamily, name string) *dto.MetricFamily {
+	for _, m := range metrics {
+		if *m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, l := range metric.Label {
+		if *l.Name == name {
+			return *l.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerLog 请求日志拦截器，日志通过 logrus 输出，xlog 会自动注入 traceid/spanid
+// skipMethods 以 / 或 . 结尾时前缀匹配，否则精确匹配完整方法名
+func UnaryServerLog(skipMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if matchMethod(info.FullMethod, skipMethods) {
+			return handler(ctx, req)
+		}
+		begin := time.Now()
+		resp, err := handler(ctx, req)
+		logRequest(ctx, info.FullMethod, "unary", time.Since(begin), err)
+		return resp, err
+	}
+}
+
+// StreamServerLog 流式 RPC 的请求日志拦截器，stream 结束时记录一条日志
+func StreamServerLog(skipMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchMethod(info.FullMethod, skipMethods) {
+			return handler(srv, ss)
+		}
+		begin := time.Now()
+		err := handler(srv, ss)
+		logRequest(ss.Context(), info.FullMethod, streamType(info), time.Since(begin), err)
+		return err
+	}
+}
+
+func logRequest(ctx context.Context, fullMethod, rpcType string, elapsed time.Duration, err error) {
+	service, method := splitMethod(fullMethod)
+	s := status.Convert(err)
+	fields := logrus.Fields{
+		"grpc_service":    service,
+		"grpc_method":     method,
+		"grpc_type":       rpcType,
+		"grpc_code":       s.Code().String(),
+		"process_latency": elapsed.Milliseconds(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["peer_address"] = p.Addr.String()
+	}
+	if err != nil {
+		fields["grpc_error"] = s.Message()
+	}
+	logrus.WithContext(ctx).WithFields(fields).Infof("[XGrpc-LogInterceptor] %s request processed.", fullMethod)
+}
@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	metricOnce      sync.Once
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+)
+
+func initMetricCollectors() {
+	metricOnce.Do(func() {
+		cfg := xmetric.GetConfig()
+		ns := cfg.Namespace
+		cl := xmetric.GetConstLabels()
+
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   ns,
+			Name:        "grpc_server_requests_total",
+			Help:        "gRPC 请求总数",
+			ConstLabels: cl,
+		}, []string{"service", "method", "type", "code"})
+
+		histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   ns,
+			Name:        "grpc_server_request_duration_ms",
+			Help:        "gRPC 请求耗时分布（毫秒）",
+			Buckets:     xmetric.GetHttpDurationBuckets(),
+			ConstLabels: cl,
+		}, []string{"service", "method", "type", "code"})
+
+		if rc, ok := xmetric.SafeRegister(counter).(*prometheus.CounterVec); ok {
+			counter = rc
+		}
+		if rh, ok := xmetric.SafeRegister(histogram).(*prometheus.HistogramVec); ok {
+			histogram = rh
+		}
+		requestsTotal = counter
+		requestDuration = histogram
+	})
+}
+
+// UnaryServerMetric 返回 gRPC 请求指标拦截器
+// 采集指标：grpc_server_requests_total（请求数量+状态码）、grpc_server_request_duration_ms（请求耗时+状态码）
+func UnaryServerMetric() grpc.UnaryServerInterceptor {
+	initMetricCollectors()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, "unary", start, err)
+		return resp, err
+	}
+}
+
+// StreamServerMetric 流式 RPC 的指标拦截器，耗时为整个 stream 的生命周期
+func StreamServerMetric() grpc.StreamServerInterceptor {
+	initMetricCollectors()
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(info.FullMethod, streamType(info), start, err)
+		return err
+	}
+}
+
+func observe(fullMethod, rpcType string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	code := status.Code(err).String()
+	durationMs := float64(time.Since(start).Milliseconds())
+
+	requestsTotal.WithLabelValues(service, method, rpcType, code).Inc()
+	requestDuration.WithLabelValues(service, method, rpcType, code).Observe(durationMs)
+}
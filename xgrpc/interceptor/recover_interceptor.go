@@ -0,0 +1,65 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxStackSize = 16384 // 栈信息最大 16KB
+
+// RecoveryHandlerFunc panic 恢复后返回给客户端的错误
+type RecoveryHandlerFunc func(ctx context.Context, p any) error
+
+// UnaryServerRecover panic recover 拦截器，记录 panic 信息与栈，返回 handle 生成的错误
+// handle 为 nil 时返回 codes.Internal
+func UnaryServerRecover(handle RecoveryHandlerFunc) grpc.UnaryServerInterceptor {
+	if handle == nil {
+		handle = defaultHandleRecovery
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logPanic(ctx, info.FullMethod, p)
+				resp, err = nil, handle(ctx, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecover 流式 RPC 的 panic recover 拦截器
+func StreamServerRecover(handle RecoveryHandlerFunc) grpc.StreamServerInterceptor {
+	if handle == nil {
+		handle = defaultHandleRecovery
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logPanic(ss.Context(), info.FullMethod, p)
+				err = handle(ss.Context(), p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func defaultHandleRecovery(_ context.Context, _ any) error {
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func logPanic(ctx context.Context, fullMethod string, p any) {
+	buf := make([]byte, maxStackSize)
+	n := runtime.Stack(buf, false)
+	panicInfo := logrus.Fields{
+		"panic_err":   fmt.Sprintf("%v", p),
+		"panic_stack": string(buf[:n]),
+		"grpc_method": fullMethod,
+	}
+	logrus.WithContext(ctx).WithFields(panicInfo).Errorf("panic recover, err=[%v]", p)
+}
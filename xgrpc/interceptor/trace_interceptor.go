@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tracerName    = "github.com/xiaoshicae/xone/v2/xgrpc"
+	traceIdHeader = "x-trace-id"
+)
+
+// UnaryServerTrace 从请求 metadata 中提取上游链路（使用全局 propagator），并创建 server span
+// trace id 通过响应 header x-trace-id 返回
+func UnaryServerTrace() grpc.UnaryServerInterceptor {
+	propagator := otel.GetTextMapPropagator()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startServerSpan(ctx, propagator, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		finishServerSpan(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerTrace 流式 RPC 的链路追踪拦截器，span 覆盖整个 stream 生命周期
+func StreamServerTrace() grpc.StreamServerInterceptor {
+	propagator := otel.GetTextMapPropagator()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(ss.Context(), propagator, info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, wrapServerStream(ss, ctx))
+		finishServerSpan(span, err)
+		return err
+	}
+}
+
+func startServerSpan(ctx context.Context, propagator propagation.TextMapPropagator, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = propagator.Extract(ctx, metadataCarrier(md.Copy()))
+
+	service, method := splitMethod(fullMethod)
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(method)),
+	}
+	// span 名称遵循 otel 约定：pkg.Service/Method
+	ctx, span := otel.Tracer(tracerName).Start(ctx, service+"/"+method, opts...)
+
+	// 设置 trace id header，header 随首个响应消息或 RPC 结束时发送
+	if span.SpanContext().IsValid() {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(traceIdHeader, span.SpanContext().TraceID().String()))
+	}
+	return ctx, span
+}
+
+func finishServerSpan(span trace.Span, err error) {
+	s := status.Convert(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(s.Code())))
+	if isServerError(s.Code()) {
+		span.SetStatus(otelcodes.Error, s.Message())
+	}
+}
+
+// isServerError 按 otel 语义约定，仅服务端错误将 span 标记为 Error
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
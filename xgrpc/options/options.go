@@ -0,0 +1,59 @@
+package options
+
+import (
+	"github.com/xiaoshicae/xone/v2/xgrpc/interceptor"
+)
+
+func EnableLogInterceptor(enableLogInterceptor bool) Option {
+	return func(o *Options) {
+		o.EnableLogInterceptor = enableLogInterceptor
+	}
+}
+
+// LogSkipMethods 设置日志拦截器忽略的方法，完整方法名形如 "/pkg.Service/Method"
+// 支持精确匹配和前缀匹配，例如：
+//   - "/grpc.health.v1.Health/Check" 精确匹配健康检查
+//   - "/grpc.reflection." 前缀匹配全部 reflection 方法
+func LogSkipMethods(methods ...string) Option {
+	return func(o *Options) {
+		o.LogSkipMethods = append(o.LogSkipMethods, methods...)
+	}
+}
+
+func EnableTraceInterceptor(enableTraceInterceptor bool) Option {
+	return func(o *Options) {
+		o.EnableTraceInterceptor = enableTraceInterceptor
+	}
+}
+
+func EnableMetricInterceptor(enableMetricInterceptor bool) Option {
+	return func(o *Options) {
+		o.EnableMetricInterceptor = enableMetricInterceptor
+	}
+}
+
+// RecoveryHandler 设置 panic 恢复后返回给客户端的错误，默认返回 codes.Internal
+func RecoveryHandler(handler interceptor.RecoveryHandlerFunc) Option {
+	return func(o *Options) {
+		o.RecoveryHandler = handler
+	}
+}
+
+type Option func(*Options)
+
+type Options struct {
+	EnableLogInterceptor    bool
+	EnableTraceInterceptor  bool
+	EnableMetricInterceptor bool
+	LogSkipMethods          []string                        // 日志拦截器忽略的方法列表
+	RecoveryHandler         interceptor.RecoveryHandlerFunc // panic 恢复后返回的错误，为 nil 时返回 codes.Internal
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		EnableLogInterceptor:    true,
+		EnableTraceInterceptor:  true,
+		EnableMetricInterceptor: true,
+		LogSkipMethods:          []string{"/grpc.health.v1.Health/", "/grpc.reflection."},
+	}
+}
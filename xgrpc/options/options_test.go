@@ -0,0 +1,51 @@
+package options
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := DefaultOptions()
+
+	if !opts.EnableLogInterceptor {
+		t.Error("EnableLogInterceptor should be true by default")
+	}
+	if !opts.EnableTraceInterceptor {
+		t.Error("EnableTraceInterceptor should be true by default")
+	}
+	if !opts.EnableMetricInterceptor {
+		t.Error("EnableMetricInterceptor should be true by default")
+	}
+	if len(opts.LogSkipMethods) != 2 {
+		t.Errorf("LogSkipMethods should skip health and reflection by default, got %v", opts.LogSkipMethods)
+	}
+	if opts.RecoveryHandler != nil {
+		t.Error("RecoveryHandler should be nil by default")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	opts := DefaultOptions()
+	handler := func(ctx context.Context, p any) error { return errors.New("panic") }
+	for _, opt := range []Option{
+		EnableLogInterceptor(false),
+		EnableTraceInterceptor(false),
+		EnableMetricInterceptor(false),
+		LogSkipMethods("/pkg.Svc/Ping"),
+		RecoveryHandler(handler),
+	} {
+		opt(opts)
+	}
+
+	if opts.EnableLogInterceptor || opts.EnableTraceInterceptor || opts.EnableMetricInterceptor {
+		t.Error("interceptors should be disabled")
+	}
+	if opts.LogSkipMethods[len(opts.LogSkipMethods)-1] != "/pkg.Svc/Ping" {
+		t.Errorf("LogSkipMethods should append, got %v", opts.LogSkipMethods)
+	}
+	if opts.RecoveryHandler == nil {
+		t.Error("RecoveryHandler should be set")
+	}
+}
@@ -0,0 +1,292 @@
+package xgrpc
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xerror"
+	"github.com/xiaoshicae/xone/v2/xgrpc/interceptor"
+	"github.com/xiaoshicae/xone/v2/xgrpc/options"
+	"github.com/xiaoshicae/xone/v2/xserver"
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+const (
+	defaultWaitStopDuration = 30 * time.Second
+)
+
+// New 创建 XGrpc builder
+func New(opts ...options.Option) *XGrpc {
+	return &XGrpc{
+		opts:               opts,
+		serviceRegisters:   make([]func(*grpc.Server), 0),
+		unaryInterceptors:  make([]grpc.UnaryServerInterceptor, 0),
+		streamInterceptors: make([]grpc.StreamServerInterceptor, 0),
+		serverOpts:         make([]grpc.ServerOption, 0),
+	}
+}
+
+// XGrpc gRPC 服务集成
+// grpc.Server 在首次 Run/Handler 调用时按 XGrpc 配置创建，此时 xconfig 已通过 BeforeStart hook 初始化
+type XGrpc struct {
+	opts               []options.Option
+	serviceRegisters   []func(*grpc.Server)
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	serverOpts         []grpc.ServerOption
+
+	initOnce sync.Once
+	initErr  error
+	server   *grpc.Server
+	health   *health.Server
+
+	mu      sync.Mutex // 保护 serving 字段的并发访问
+	serving bool       // 是否已通过 Run 监听端口
+}
+
+// WithServiceRegister 注册 gRPC 服务，如 pb.RegisterGreeterServer(s, &greeter{})
+func (x *XGrpc) WithServiceRegister(f ...func(*grpc.Server)) *XGrpc {
+	x.serviceRegisters = append(x.serviceRegisters, f...)
+	return x
+}
+
+// WithUnaryInterceptor 追加自定义 unary 拦截器，位于内置拦截器之后
+func (x *XGrpc) WithUnaryInterceptor(i ...grpc.UnaryServerInterceptor) *XGrpc {
+	x.unaryInterceptors = append(x.unaryInterceptors, i...)
+	return x
+}
+
+// WithStreamInterceptor 追加自定义 stream 拦截器，位于内置拦截器之后
+func (x *XGrpc) WithStreamInterceptor(i ...grpc.StreamServerInterceptor) *XGrpc {
+	x.streamInterceptors = append(x.streamInterceptors, i...)
+	return x
+}
+
+// WithServerOption 追加 grpc.ServerOption，在配置生成的选项之后应用，可覆盖配置
+func (x *XGrpc) WithServerOption(opts ...grpc.ServerOption) *XGrpc {
+	x.serverOpts = append(x.serverOpts, opts...)
+	return x
+}
+
+// Server 返回底层 *grpc.Server，首次调用时创建
+func (x *XGrpc) Server() (*grpc.Server, error) {
+	if err := x.init(); err != nil {
+		return nil, err
+	}
+	return x.server, nil
+}
+
+// Health 返回健康检查服务，可按服务名设置 SERVING/NOT_SERVING，首次调用时创建
+func (x *XGrpc) Health() (*health.Server, error) {
+	if err := x.init(); err != nil {
+		return nil, err
+	}
+	return x.health, nil
+}
+
+// Handler 返回处理 gRPC 请求的 http.Handler，用于与 XGin 共用端口（xgin.WithGRPCHandler）
+// 此时由 XGin 负责监听、TLS 和停止，XGrpc 的 Host/Port/CertFile/KeyFile/Keepalive 配置不生效
+func (x *XGrpc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := x.init(); err != nil {
+			xutil.ErrorIfEnableDebug("%v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		x.server.ServeHTTP(w, r)
+	})
+}
+
+// Start 提供快捷启动方式
+func (x *XGrpc) Start() error {
+	return xserver.Run(x)
+}
+
+// Run 实现 xserver.Server 接口
+func (x *XGrpc) Run() error {
+	if err := x.init(); err != nil {
+		return err
+	}
+
+	grpcConfig := GetConfig()
+	addr := net.JoinHostPort(grpcConfig.Host, strconv.Itoa(grpcConfig.Port))
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return xerror.Newf("xgrpc", "run", "listen failed, addr=[%s], err=[%v]", addr, err)
+	}
+
+	xutil.InfoIfEnableDebug("grpc server listen on: %s", addr)
+	return x.serve(lis)
+}
+
+func (x *XGrpc) serve(lis net.Listener) error {
+	x.mu.Lock()
+	x.serving = true
+	x.mu.Unlock()
+
+	err := x.server.Serve(lis)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// Stop 实现 xserver.Server 接口
+// 先将健康状态置为 NOT_SERVING，再等待进行中的 RPC 结束，超时后强制关闭
+func (x *XGrpc) Stop() error {
+	x.mu.Lock()
+	serving := x.serving
+	x.mu.Unlock()
+
+	if !serving {
+		// 信号可能在 Serve 前到达，此时静默返回而非报错
+		xutil.WarnIfEnableDebug("XGrpc Stop called but server not started yet, skip")
+		return nil
+	}
+
+	x.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		x.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(defaultWaitStopDuration):
+		xutil.WarnIfEnableDebug("XGrpc graceful stop timeout after %v, force stop", defaultWaitStopDuration)
+		x.server.Stop()
+	}
+	return nil
+}
+
+func (x *XGrpc) init() error {
+	x.initOnce.Do(func() {
+		x.initErr = x.newServer(GetConfig())
+		if x.initErr != nil {
+			xutil.ErrorIfEnableDebug("%v", x.initErr)
+		}
+	})
+	return x.initErr
+}
+
+func (x *XGrpc) newServer(c *Config) error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return xerror.Newf("xgrpc", "build", "TLS config incomplete: CertFile and KeyFile must be both set or both empty")
+	}
+
+	serverOpts, err := serverOptionsFromConfig(c)
+	if err != nil {
+		return err
+	}
+	do := x.getXGrpcOptions()
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(x.buildUnaryInterceptors(do)...),
+		grpc.ChainStreamInterceptor(x.buildStreamInterceptors(do)...),
+	)
+	serverOpts = append(serverOpts, x.serverOpts...)
+
+	srv := grpc.NewServer(serverOpts...)
+	for _, register := range x.serviceRegisters {
+		register(srv)
+	}
+
+	// 注册健康检查服务，已注册的服务均置为 SERVING
+	hs := health.NewServer()
+	for name := range srv.GetServiceInfo() {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(srv, hs)
+
+	if c.EnableReflection {
+		reflection.Register(srv)
+	}
+
+	x.server = srv
+	x.health = hs
+	return nil
+}
+
+func (x *XGrpc) getXGrpcOptions() *options.Options {
+	do := options.DefaultOptions()
+	for _, opt := range x.opts {
+		opt(do)
+	}
+	return do
+}
+
+// buildUnaryInterceptors 顺序与 XGin 中间件一致：trace -> recover -> log -> metric -> 自定义
+func (x *XGrpc) buildUnaryInterceptors(do *options.Options) []grpc.UnaryServerInterceptor {
+	is := make([]grpc.UnaryServerInterceptor, 0, 4+len(x.unaryInterceptors))
+	if do.EnableTraceInterceptor {
+		is = append(is, interceptor.UnaryServerTrace())
+	}
+	is = append(is, interceptor.UnaryServerRecover(do.RecoveryHandler))
+	if do.EnableLogInterceptor {
+		is = append(is, interceptor.UnaryServerLog(do.LogSkipMethods...))
+	}
+	if do.EnableMetricInterceptor {
+		is = append(is, interceptor.UnaryServerMetric())
+	}
+	return append(is, x.unaryInterceptors...)
+}
+
+func (x *XGrpc) buildStreamInterceptors(do *options.Options) []grpc.StreamServerInterceptor {
+	is := make([]grpc.StreamServerInterceptor, 0, 4+len(x.streamInterceptors))
+	if do.EnableTraceInterceptor {
+		is = append(is, interceptor.StreamServerTrace())
+	}
+	is = append(is, interceptor.StreamServerRecover(do.RecoveryHandler))
+	if do.EnableLogInterceptor {
+		is = append(is, interceptor.StreamServerLog(do.LogSkipMethods...))
+	}
+	if do.EnableMetricInterceptor {
+		is = append(is, interceptor.StreamServerMetric())
+	}
+	return append(is, x.streamInterceptors...)
+}
+
+func serverOptionsFromConfig(c *Config) ([]grpc.ServerOption, error) {
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(c.MaxRecvMsgSize)}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+
+	if c.CertFile != "" && c.KeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, xerror.Newf("xgrpc", "build", "load TLS cert failed, cert=[%s], key=[%s], err=[%v]", c.CertFile, c.KeyFile, err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		xutil.InfoIfEnableDebug("grpc server use TLS, cert=[%s], key=[%s]", c.CertFile, c.KeyFile)
+	}
+
+	if ka := c.Keepalive; ka != nil {
+		opts = append(opts,
+			grpc.KeepaliveParams(keepalive.ServerParameters{
+				Time:                  xutil.ToDuration(ka.Time),
+				Timeout:               xutil.ToDuration(ka.Timeout),
+				MaxConnectionIdle:     xutil.ToDuration(ka.MaxConnectionIdle),
+				MaxConnectionAge:      xutil.ToDuration(ka.MaxConnectionAge),
+				MaxConnectionAgeGrace: xutil.ToDuration(ka.MaxConnectionAgeGrace),
+			}),
+			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+				MinTime:             xutil.ToDuration(ka.MinTime),
+				PermitWithoutStream: ka.PermitWithoutStream,
+			}),
+		)
+	}
+	return opts, nil
+}
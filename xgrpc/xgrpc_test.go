@@ -0,0 +1,187 @@
+package xgrpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xgrpc/options"
+
+	. "github.com/bytedance/mockey"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestConfigMergeDefault(t *testing.T) {
+	PatchConvey("TestConfigMergeDefault", t, func() {
+		PatchConvey("Nil", func() {
+			c := configMergeDefault(nil)
+			So(c.Host, ShouldEqual, defaultHost)
+			So(c.Port, ShouldEqual, defaultPort)
+			So(c.MaxRecvMsgSize, ShouldEqual, defaultMaxRecvMsgSize)
+			So(c.MaxSendMsgSize, ShouldEqual, 0)
+			So(c.Keepalive, ShouldBeNil)
+		})
+
+		PatchConvey("CustomValues", func() {
+			c := configMergeDefault(&Config{Host: "10.0.0.1", Port: 9090, MaxRecvMsgSize: 1024})
+			So(c.Host, ShouldEqual, "10.0.0.1")
+			So(c.Port, ShouldEqual, 9090)
+			So(c.MaxRecvMsgSize, ShouldEqual, 1024)
+		})
+
+		PatchConvey("WithKeepalive", func() {
+			c := configMergeDefault(&Config{Keepalive: &KeepaliveConfig{Time: "30s"}})
+			So(c.Keepalive.Time, ShouldEqual, "30s")
+			So(c.Keepalive.Timeout, ShouldEqual, "20s")
+			So(c.Keepalive.MinTime, ShouldEqual, "5m")
+			So(c.Keepalive.MaxConnectionAge, ShouldEqual, "")
+		})
+	})
+}
+
+func TestServerOptionsFromConfig(t *testing.T) {
+	PatchConvey("TestServerOptionsFromConfig", t, func() {
+		PatchConvey("Default", func() {
+			opts, err := serverOptionsFromConfig(configMergeDefault(nil))
+			So(err, ShouldBeNil)
+			So(len(opts), ShouldEqual, 1)
+		})
+
+		PatchConvey("Full", func() {
+			opts, err := serverOptionsFromConfig(configMergeDefault(&Config{
+				MaxSendMsgSize: 1024,
+				Keepalive:      &KeepaliveConfig{MaxConnectionAge: "30m"},
+			}))
+			So(err, ShouldBeNil)
+			So(len(opts), ShouldEqual, 4)
+		})
+
+		PatchConvey("InvalidCert", func() {
+			_, err := serverOptionsFromConfig(&Config{CertFile: "not-exist.pem", KeyFile: "not-exist.key"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "load TLS cert failed")
+		})
+	})
+}
+
+func TestInitTLSIncomplete(t *testing.T) {
+	PatchConvey("TestInitTLSIncomplete", t, func() {
+		Mock(GetConfig).Return(configMergeDefault(&Config{CertFile: "cert.pem"})).Build()
+
+		x := New()
+		err := x.Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "TLS config incomplete")
+
+		_, err = x.Server()
+		So(err, ShouldNotBeNil)
+
+		w := httptest.NewRecorder()
+		x.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", nil))
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+	})
+}
+
+func TestServeAndGracefulStop(t *testing.T) {
+	PatchConvey("TestServeAndGracefulStop", t, func() {
+		Mock(GetConfig).Return(configMergeDefault(&Config{EnableReflection: true})).Build()
+
+		registered := false
+		x := New(options.EnableMetricInterceptor(false)).WithServiceRegister(func(s *grpc.Server) {
+			registered = true
+		})
+		So(x.init(), ShouldBeNil)
+		So(registered, ShouldBeTrue)
+
+		srv, err := x.Server()
+		So(err, ShouldBeNil)
+		So(srv.GetServiceInfo(), ShouldContainKey, "grpc.health.v1.Health")
+		So(srv.GetServiceInfo(), ShouldContainKey, "grpc.reflection.v1.ServerReflection")
+
+		lis := bufconn.Listen(1024 * 1024)
+		done := make(chan error, 1)
+		go func() {
+			done <- x.serve(lis)
+		}()
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		client := healthpb.NewHealthClient(conn)
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		So(err, ShouldBeNil)
+		So(resp.Status, ShouldEqual, healthpb.HealthCheckResponse_SERVING)
+
+		So(x.Stop(), ShouldBeNil)
+		select {
+		case err := <-done:
+			So(err, ShouldBeNil)
+		case <-time.After(time.Second):
+			t.Fatal("serve did not return after Stop")
+		}
+	})
+}
+
+func TestRunAndStop(t *testing.T) {
+	PatchConvey("TestRunAndStop", t, func() {
+		PatchConvey("StopBeforeRun", func() {
+			So(New().Stop(), ShouldBeNil)
+		})
+
+		PatchConvey("ListenFailed", func() {
+			Mock(GetConfig).Return(&Config{Host: "256.0.0.1", Port: 1}).Build()
+			err := New().Run()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "listen failed")
+		})
+
+		PatchConvey("RunThenStop", func() {
+			Mock(GetConfig).Return(&Config{Host: "127.0.0.1", Port: 0}).Build()
+			x := New()
+			done := make(chan error, 1)
+			go func() {
+				done <- x.Run()
+			}()
+			time.Sleep(50 * time.Millisecond)
+
+			So(x.Stop(), ShouldBeNil)
+			select {
+			case err := <-done:
+				So(err, ShouldBeNil)
+			case <-time.After(time.Second):
+				t.Fatal("Run did not return after Stop")
+			}
+		})
+	})
+}
+
+func TestHandlerOverH2C(t *testing.T) {
+	PatchConvey("TestHandlerOverH2C", t, func() {
+		Mock(GetConfig).Return(configMergeDefault(nil)).Build()
+
+		x := New(options.EnableMetricInterceptor(false), options.EnableLogInterceptor(false))
+		ts := httptest.NewServer(h2c.NewHandler(x.Handler(), &http2.Server{}))
+		defer ts.Close()
+
+		conn, err := grpc.NewClient(ts.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		So(err, ShouldBeNil)
+		So(resp.Status, ShouldEqual, healthpb.HealthCheckResponse_SERVING)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/xiaoshicae/xone/v2/xconfig"
 	"github.com/xiaoshicae/xone/v2/xerror"
 	"github.com/xiaoshicae/xone/v2/xhook"
+	"github.com/xiaoshicae/xone/v2/xlog"
 	"github.com/xiaoshicae/xone/v2/xmetric"
 	"github.com/xiaoshicae/xone/v2/xtrace"
 	"github.com/xiaoshicae/xone/v2/xutil"
@@ -57,7 +58,7 @@ func initHttpClient() error {
 	}
 
 	// 根据是否启用 trace 选择 Transport
-	// 链路：client → HostAwareTransport（设置目标 host 到 ctx）→ otelhttp.Transport → baseTransport
+	// 链路：client → baggageTransport（XLog.Baggage 中的 KV 写入 baggage）→ HostAwareTransport（设置目标 host 到 ctx）→ otelhttp.Transport → baseTransport
 	// HostAwareTransport 使 HeaderPropagator 能按域名过滤透传 Header
 	var finalTransport http.RoundTripper = baseTransport
 	if xtrace.EnableTrace() {
@@ -65,7 +66,7 @@ func initHttpClient() error {
 			otelhttp.WithSpanNameFormatter(spanNameFormatter),
 		}
 		otelTransport := otelhttp.NewTransport(baseTransport, opts...)
-		finalTransport = &xtrace.HostAwareTransport{Next: otelTransport}
+		finalTransport = &baggageTransport{Next: &xtrace.HostAwareTransport{Next: otelTransport}}
 	}
 
 	rawHttpClient := &http.Client{
@@ -94,6 +95,19 @@ func initHttpClient() error {
 	return nil
 }
 
+// baggageTransport 将 ctx KV 容器中 XLog.Baggage 白名单内的 KV 写入 otel Baggage，由 otelhttp 以 W3C baggage header 传给下游
+type baggageTransport struct {
+	Next http.RoundTripper
+}
+
+func (t *baggageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if bctx := xlog.CtxWithBaggage(ctx); bctx != ctx {
+		req = req.WithContext(bctx)
+	}
+	return t.Next.RoundTrip(req)
+}
+
 // spanNameFormatter otelhttp 的 span 命名格式：METHOD PATH
 func spanNameFormatter(_ string, r *http.Request) string {
 	return r.Method + " " + r.URL.Path
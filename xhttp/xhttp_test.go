@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xlog"
 	"github.com/xiaoshicae/xone/v2/xmetric"
 	"github.com/xiaoshicae/xone/v2/xtrace"
 	"github.com/xiaoshicae/xone/v2/xutil"
@@ -369,6 +370,38 @@ func TestSpanNameFormatter(t *testing.T) {
 	})
 }
 
+type captureRoundTripper struct {
+	req *http.Request
+}
+
+func (s *captureRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.req = req
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestBaggageTransport(t *testing.T) {
+	mockey.PatchConvey("TestBaggageTransport-未配置XLog.Baggage时透传原请求", t, func() {
+		next := &captureRoundTripper{}
+		req, _ := http.NewRequest("GET", "http://example.com/api", nil)
+		_, err := (&baggageTransport{Next: next}).RoundTrip(req)
+		c.So(err, c.ShouldBeNil)
+		c.So(next.req, c.ShouldEqual, req)
+	})
+
+	mockey.PatchConvey("TestBaggageTransport-写入baggage后的ctx", t, func() {
+		type key struct{}
+		mockey.Mock(xlog.CtxWithBaggage).To(func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, key{}, "v")
+		}).Build()
+		next := &captureRoundTripper{}
+		req, _ := http.NewRequest("GET", "http://example.com/api", nil)
+		_, err := (&baggageTransport{Next: next}).RoundTrip(req)
+		c.So(err, c.ShouldBeNil)
+		c.So(next.req, c.ShouldNotEqual, req)
+		c.So(next.req.Context().Value(key{}), c.ShouldEqual, "v")
+	})
+}
+
 func TestInitHttpClient_WithMetric(t *testing.T) {
 	mockey.PatchConvey("TestInitHttpClient-启用metric注册Resty中间件", t, func() {
 		prevRawClient := rawHttpClient
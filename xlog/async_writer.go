@@ -4,6 +4,9 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -21,6 +24,13 @@ var logBufPool = sync.Pool{
 	},
 }
 
+// 缓冲区满时的处理策略
+const (
+	overflowBlock      = "block"       // 阻塞调用方直到有空位（不丢日志）
+	overflowDropNewest = "drop_newest" // 丢弃当前写入的日志
+	overflowDropOldest = "drop_oldest" // 丢弃缓冲区中最早的日志，写入当前日志
+)
+
 var errAsyncWriterClosed = errors.New("async writer is closed")
 
 // asyncWriter 异步写入器，通过 channel + goroutine 将同步写入转为异步
@@ -35,9 +45,14 @@ type asyncWriter struct {
 	writeErr  error
 	writeOnce sync.Once
 	closeErr  error
+
+	overflow    string // 缓冲区满时的策略，默认 block
+	name        string // 指标 label
+	dropped     atomic.Uint64
+	dropCounter func() prometheus.Counter // 首次丢弃时创建，避免 xmetric 初始化前注册指标
 }
 
-// newAsyncWriter 创建异步写入器
+// newAsyncWriter 创建异步写入器，缓冲区满时阻塞
 func newAsyncWriter(w io.WriteCloser, bufferSize int) *asyncWriter {
 	if bufferSize <= 0 {
 		bufferSize = defaultAsyncBufferSize
@@ -51,6 +66,20 @@ func newAsyncWriter(w io.WriteCloser, bufferSize int) *asyncWriter {
 	return aw
 }
 
+// withOverflow 设置缓冲区满时的策略，丢弃的条数计入 xmetric 指标 log_dropped_lines_total{output=name}，需在写入前调用
+func (aw *asyncWriter) withOverflow(policy, name string) *asyncWriter {
+	aw.overflow, aw.name = policy, name
+	aw.dropCounter = sync.OnceValue(func() prometheus.Counter {
+		return droppedLinesCounter(name)
+	})
+	return aw
+}
+
+// Dropped 因缓冲区满丢弃的日志条数
+func (aw *asyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
 // Write 将数据拷贝后发送到 channel
 func (aw *asyncWriter) Write(p []byte) (n int, err error) {
 	// 从 pool 获取 buffer，必须拷贝（调用方可能复用 buffer）
@@ -65,10 +94,7 @@ func (aw *asyncWriter) Write(p []byte) (n int, err error) {
 	aw.mu.Lock()
 	if aw.closed {
 		aw.mu.Unlock()
-		// 归还 buffer 到 pool
-		if cap(buf) <= maxPoolBufSize {
-			logBufPool.Put(buf[:0])
-		}
+		aw.release(buf)
 		return 0, errAsyncWriterClosed
 	}
 	aw.mu.Unlock()
@@ -77,18 +103,56 @@ func (aw *asyncWriter) Write(p []byte) (n int, err error) {
 	// 极端情况下 Close() 可能在 Unlock 和发送之间关闭 channel，用 recover 处理
 	defer func() {
 		if r := recover(); r != nil {
-			if cap(buf) <= maxPoolBufSize {
-				logBufPool.Put(buf[:0])
-			}
+			aw.release(buf)
 			n = 0
 			err = errAsyncWriterClosed
 		}
 	}()
 
-	aw.ch <- buf
+	switch aw.overflow {
+	case overflowDropNewest:
+		select {
+		case aw.ch <- buf:
+		default:
+			aw.release(buf)
+			aw.drop()
+		}
+	case overflowDropOldest:
+		for sent := false; !sent; {
+			select {
+			case aw.ch <- buf:
+				sent = true
+			default:
+				select {
+				case old, ok := <-aw.ch:
+					if ok {
+						aw.release(old)
+						aw.drop()
+					}
+				default:
+				}
+			}
+		}
+	default:
+		aw.ch <- buf
+	}
 	return len(p), nil
 }
 
+func (aw *asyncWriter) drop() {
+	aw.dropped.Add(1)
+	if aw.dropCounter != nil {
+		aw.dropCounter().Inc()
+	}
+}
+
+// release 归还 buffer 到 pool，不归还过大的 buffer，避免 pool 持有过多内存
+func (aw *asyncWriter) release(buf []byte) {
+	if cap(buf) <= maxPoolBufSize {
+		logBufPool.Put(buf[:0])
+	}
+}
+
 // Close 关闭 channel，等待所有数据写完，再关闭底层 writer
 // 多次调用安全，底层 writer 只关闭一次
 func (aw *asyncWriter) Close() error {
@@ -115,9 +179,6 @@ func (aw *asyncWriter) loop() {
 				aw.writeErr = err
 			})
 		}
-		// 不归还过大的 buffer，避免 pool 持有过多内存
-		if cap(buf) <= maxPoolBufSize {
-			logBufPool.Put(buf[:0])
-		}
+		aw.release(buf)
 	}
 }
@@ -0,0 +1,133 @@
+package xlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// baggagePolicy XLog.Baggage：ctx KV 容器中允许经 W3C Baggage 跨服务传递的 key 及大小限制
+type baggagePolicy struct {
+	keys         []string
+	allowed      map[string]struct{}
+	maxValueSize int
+	maxSize      int
+}
+
+func newBaggagePolicy(c *BaggageConfig) (*baggagePolicy, error) {
+	if c == nil || len(c.Keys) == 0 {
+		return nil, nil
+	}
+	p := &baggagePolicy{
+		allowed:      make(map[string]struct{}, len(c.Keys)),
+		maxValueSize: c.MaxValueSize,
+		maxSize:      c.MaxSize,
+	}
+	for _, k := range c.Keys {
+		// W3C baggage key 需为 RFC 7230 token
+		if _, err := baggage.NewMember(k, ""); err != nil {
+			return nil, fmt.Errorf("invalid XLog.Baggage key [%s], err=[%v]", k, err)
+		}
+		if _, ok := p.allowed[k]; ok {
+			continue
+		}
+		p.allowed[k] = struct{}{}
+		p.keys = append(p.keys, k)
+	}
+	return p, nil
+}
+
+// admit 按大小限制判断 key=value 能否加入，used 为已加入的字节数
+func (p *baggagePolicy) admit(key, value string, used *int) bool {
+	if len(value) > p.maxValueSize {
+		return false
+	}
+	n := len(key) + len(value) + 1
+	if *used+n > p.maxSize {
+		return false
+	}
+	*used += n
+	return true
+}
+
+// CtxWithBaggage 将 ctx KV 容器中 XLog.Baggage.Keys 列出的 KV 写入 otel Baggage，出站请求经 otel propagator 以 W3C baggage header 传递到下游
+// xhttp 出站请求自动调用，自建的 otelhttp 客户端可手动调用；值需为字符串、数字、bool 或 fmt.Stringer，超出大小限制的 KV 不传递
+func CtxWithBaggage(ctx context.Context) context.Context {
+	c := activeCore.Load()
+	if c == nil || c.baggage == nil {
+		return ctx
+	}
+	kvs := getXLogContainerFromCtx(ctx)
+	if len(kvs) == 0 {
+		return ctx
+	}
+
+	b := baggage.FromContext(ctx)
+	changed, used := false, 0
+	for _, k := range c.baggage.keys {
+		v, ok := baggageValue(kvs[k])
+		if !ok || !c.baggage.admit(k, v, &used) {
+			continue
+		}
+		m, err := baggage.NewMemberRaw(k, v)
+		if err != nil {
+			continue
+		}
+		if b, err = b.SetMember(m); err != nil {
+			xutil.WarnIfEnableDebug("XOne xlog set baggage member failed, key=[%s], err=[%v]", k, err)
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, b)
+}
+
+// BaggageKV 从入站请求的 W3C baggage header 中取出 XLog.Baggage.Keys 列出的成员，用于写入 ctx KV 容器，GinXSessionMiddleware 自动调用
+// 未在白名单中、超出大小限制的成员忽略，未启用或没有可用成员时返回 nil
+func BaggageKV(h http.Header) map[string]any {
+	c := activeCore.Load()
+	if c == nil || c.baggage == nil || len(h.Values("baggage")) == 0 {
+		return nil
+	}
+	b := baggage.FromContext(propagation.Baggage{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+
+	var kvs map[string]any
+	used := 0
+	for _, k := range c.baggage.keys {
+		m := b.Member(k)
+		if m.Key() == "" || !c.baggage.admit(k, m.Value(), &used) {
+			continue
+		}
+		if kvs == nil {
+			kvs = make(map[string]any, len(c.baggage.keys))
+		}
+		kvs[k] = m.Value()
+	}
+	return kvs
+}
+
+func baggageValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case fmt.Stringer:
+		if isNilValue(v) {
+			return "", false
+		}
+		return v.String(), true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
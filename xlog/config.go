@@ -21,10 +21,16 @@ type Config struct {
 	// optional default false
 	Console bool `mapstructure:"Console"`
 
-	// ConsoleFormatIsRaw 在控制台打印的日志是否为原始格式(即底层的json格式)，为false时，打印level+time+filename+func+traceid+内容
+	// ConsoleFormatIsRaw 在控制台打印的日志是否为原始格式(即底层的json格式)，为false时按 ConsoleTemplate 打印
 	// optional default false
 	ConsoleFormatIsRaw bool `mapstructure:"ConsoleFormatIsRaw"`
 
+	// ConsoleTemplate 控制台输出模板（ConsoleFormatIsRaw 为 false 时生效），占位符：{time} {level} {caller} {msg}，
+	// {fields}（模板未引用的其余字段，logfmt 形式），以及 {任意字段名} 如 {traceid}、{user_id}
+	// 设置 NO_COLOR 环境变量或标准输出不是终端时不使用颜色，调用栈与多层错误链在日志行之后逐行缩进输出
+	// optional default "{level}[{time}] {caller} {traceid} {msg}"
+	ConsoleTemplate string `mapstructure:"ConsoleTemplate"`
+
 	// MaxAge 日志保存最大时间
 	// optional default "7d"
 	MaxAge string `mapstructure:"MaxAge"`
@@ -33,9 +39,267 @@ type Config struct {
 	// optional default "1d"
 	RotateTime string `mapstructure:"RotateTime"`
 
-	// Timezone 日志时间的时区
+	// MaxSize 单个日志文件最大大小（MB），超过后切割为 <Name>.log.<时间>.1、.2 ...
+	// optional default 0（不按大小切割）
+	MaxSize int `mapstructure:"MaxSize"`
+
+	// MaxBackups 历史日志文件保留个数
+	// optional default 0（不限制，仅按 MaxAge 清理）
+	MaxBackups int `mapstructure:"MaxBackups"`
+
+	// Compress 是否在后台 gzip 压缩历史日志文件
+	// optional default false
+	Compress bool `mapstructure:"Compress"`
+
+	// FilePattern 日志文件名中的时间格式（按 Timezone 的本地时间），支持 %Y %m %d %H %M %S
+	// optional default "%Y%m%d"
+	FilePattern string `mapstructure:"FilePattern"`
+
+	// Timezone 日志时间的时区，同时作用于日志文件的切割时间与文件名
 	// optional default "Asia/Shanghai"
 	Timezone string `mapstructure:"Timezone"`
+
+	// Outputs 多路日志输出，配置后替代默认的 <Path>/<Name>.log，如业务日志文件 + 仅 error 的文件 + syslog
+	// optional default nil
+	Outputs []OutputConfig `mapstructure:"Outputs"`
+
+	// Levels 按包设置日志级别，key 为包路径（匹配包路径末尾的若干段，同时作用于子包，最长匹配优先），如 {"xgorm": "warn", "mypkg/billing": "debug"}
+	// optional default nil
+	Levels map[string]string `mapstructure:"Levels"`
+
+	// DebugHeader 请求级 debug 的请求头，命中的请求不受全局级别限制输出 debug 日志（需 GinXSessionMiddleware），为空表示不启用
+	// optional default ""
+	DebugHeader string `mapstructure:"DebugHeader"`
+
+	// DebugHeaderValue 非空时 DebugHeader 的值需与之相等才命中，建议配置，避免任意请求开启 debug
+	// optional default ""（值非空且不为 0/false 即命中）
+	DebugHeaderValue string `mapstructure:"DebugHeaderValue"`
+
+	// DebugTraceIDs 请求级 debug 的 trace id 白名单，运行时可通过 xlog.AddDebugTraceID 追加
+	// optional default nil
+	DebugTraceIDs []string `mapstructure:"DebugTraceIDs"`
+
+	// Sampling 日志采样，防止同一日志短时间内大量输出（日志风暴），为空表示不采样
+	// optional default nil
+	Sampling *SamplingConfig `mapstructure:"Sampling"`
+
+	// AsyncBufferSize 异步写入缓冲区大小（条），作用于 file 与 syslog 输出
+	// optional default 4096
+	AsyncBufferSize int `mapstructure:"AsyncBufferSize"`
+
+	// Overflow 异步写入缓冲区满时的策略：block（阻塞调用方）/drop_newest（丢弃当前日志）/drop_oldest（丢弃最早的日志）
+	// optional default "block"
+	Overflow string `mapstructure:"Overflow"`
+
+	// Remote 远程日志投递（OTLP/HTTP、Loki、通用 HTTP），用于没有日志采集 agent 的环境，与文件输出同时生效
+	// optional default nil
+	Remote []RemoteConfig `mapstructure:"Remote"`
+
+	// ErrorAlert 错误日志聚合与告警，按调用方 + 归一化后的日志内容聚合，新错误或突增时通知，为空表示不启用
+	// optional default nil
+	ErrorAlert *ErrorAlertConfig `mapstructure:"ErrorAlert"`
+
+	// Baggage 允许经 W3C Baggage 跨服务传递的 ctx KV（xlog.CtxWithKV 写入），为空表示不传递
+	// optional default nil
+	Baggage *BaggageConfig `mapstructure:"Baggage"`
+}
+
+// BaggageConfig ctx KV 跨服务传递配置：出站请求将白名单中的 KV 写入 W3C baggage header，入站请求从中提取回 ctx KV 容器
+type BaggageConfig struct {
+	// Keys 允许传递的 KV key 白名单，出站只写入、入站只提取这些 key
+	// required
+	Keys []string `mapstructure:"Keys"`
+
+	// MaxValueSize 单个值的最大字节数，超出的 KV 不传递
+	// optional default 256
+	MaxValueSize int `mapstructure:"MaxValueSize"`
+
+	// MaxSize 传递的 KV 总字节数上限（按 key=value 累计），超出后其余 KV 不传递
+	// optional default 1024
+	MaxSize int `mapstructure:"MaxSize"`
+}
+
+// ErrorAlertConfig 错误日志聚合与告警配置
+type ErrorAlertConfig struct {
+	// Level 聚合不低于此级别的日志
+	// optional default "error"
+	Level string `mapstructure:"Level"`
+
+	// Cooldown 同一指纹两次通知的最小间隔
+	// optional default "10m"
+	Cooldown string `mapstructure:"Cooldown"`
+
+	// SpikeWindow 突增统计窗口
+	// optional default "1m"
+	SpikeWindow string `mapstructure:"SpikeWindow"`
+
+	// SpikeThreshold 同一指纹在 SpikeWindow 内出现次数达到该值时通知，0 表示不检测突增
+	// optional default 0
+	SpikeThreshold int `mapstructure:"SpikeThreshold"`
+
+	// MaxFingerprints 最多保留的指纹数，超出时淘汰最久未出现的
+	// optional default 1000
+	MaxFingerprints int `mapstructure:"MaxFingerprints"`
+
+	// Notifiers 通知列表，也可通过 xlog.AddErrorNotifier 追加自定义通知
+	// optional default nil
+	Notifiers []ErrorNotifierConfig `mapstructure:"Notifiers"`
+}
+
+// ErrorNotifierConfig 错误告警通知配置
+type ErrorNotifierConfig struct {
+	// Type 通知类型，webhook（POST 告警事件 JSON）/slack/dingtalk/feishu（对应机器人 webhook 的消息格式）
+	// optional default "webhook"
+	Type string `mapstructure:"Type"`
+
+	// URL webhook 地址
+	// required
+	URL string `mapstructure:"URL"`
+
+	// Headers 请求头
+	// optional default nil
+	Headers map[string]string `mapstructure:"Headers"`
+
+	// Timeout 单次请求超时时间
+	// optional default "5s"
+	Timeout string `mapstructure:"Timeout"`
+}
+
+// RemoteConfig 远程日志投递配置
+type RemoteConfig struct {
+	// Name 投递名称，用于指标与错误信息
+	// optional default Type
+	Name string `mapstructure:"Name"`
+
+	// Type 投递类型，otlp（OTLP/HTTP JSON）/loki（Loki push API）/http（通用 HTTP 批量）
+	// required
+	Type string `mapstructure:"Type"`
+
+	// Endpoint 投递地址，otlp 未指定路径时为 /v1/logs，loki 未指定路径时为 /loki/api/v1/push
+	// required
+	Endpoint string `mapstructure:"Endpoint"`
+
+	// Headers 请求头，如鉴权、Loki 多租户的 X-Scope-OrgID
+	// optional default nil
+	Headers map[string]string `mapstructure:"Headers"`
+
+	// Level 只投递不低于此级别的日志，为空时不额外过滤
+	// optional default ""
+	Level string `mapstructure:"Level"`
+
+	// Labels loki 类型额外的 stream 标签（servername 与 level 始终作为标签）
+	// optional default nil
+	Labels map[string]string `mapstructure:"Labels"`
+
+	// Format http 类型的 body 格式，json（JSON 数组）/ndjson（每行一条）
+	// optional default "json"
+	Format string `mapstructure:"Format"`
+
+	// BatchSize 单次请求最多发送的日志条数，缓冲达到该条数时立即发送
+	// optional default 500
+	BatchSize int `mapstructure:"BatchSize"`
+
+	// FlushInterval 定时发送间隔
+	// optional default "1s"
+	FlushInterval string `mapstructure:"FlushInterval"`
+
+	// BufferSize 待发送日志的缓冲上限（条），满时丢弃最早的日志
+	// optional default 10000
+	BufferSize int `mapstructure:"BufferSize"`
+
+	// MaxRetries 网络错误、429 与 5xx 的重试次数，重试间隔从 RetryBackoff 开始翻倍（最长 10s），小于 0 时不重试
+	// optional default 3
+	MaxRetries int `mapstructure:"MaxRetries"`
+
+	// RetryBackoff 首次重试间隔
+	// optional default "500ms"
+	RetryBackoff string `mapstructure:"RetryBackoff"`
+
+	// Timeout 单次请求超时时间
+	// optional default "5s"
+	Timeout string `mapstructure:"Timeout"`
+}
+
+// SamplingConfig 日志采样配置：同一日志（级别 + 日志模板 + 调用位置）每个周期内前 First 条全部保留，之后每 Thereafter 条保留 1 条
+type SamplingConfig struct {
+	// Interval 采样周期
+	// optional default "1s"
+	Interval string `mapstructure:"Interval"`
+
+	// First 每个周期内全部保留的条数，0 表示不保留（每条都按 Thereafter 采样）
+	// optional default 100（First 与 Thereafter 均未配置时才使用默认值）
+	First int `mapstructure:"First"`
+
+	// Thereafter 超过 First 后每 Thereafter 条保留 1 条，0 表示超过 First 后全部丢弃
+	// optional default 100（First 与 Thereafter 均未配置时才使用默认值）
+	Thereafter int `mapstructure:"Thereafter"`
+
+	// KeepLevel 不低于该级别的日志始终保留，不参与采样
+	// optional default "error"
+	KeepLevel string `mapstructure:"KeepLevel"`
+}
+
+// OutputConfig 单路日志输出配置
+type OutputConfig struct {
+	// Name 输出名称，file 类型同时作为日志文件名（<Path>/<Name>.log）
+	// optional default XLog.Name
+	Name string `mapstructure:"Name"`
+
+	// Type 输出类型，file/stdout/stderr/syslog
+	// optional default "file"
+	Type string `mapstructure:"Type"`
+
+	// Level 该输出只记录不低于此级别的日志，为空时不额外过滤（即沿用 XLog.Level 与 XLog.Levels）
+	// optional default ""
+	Level string `mapstructure:"Level"`
+
+	// Format 日志格式，json/logfmt/text
+	// optional default "json"
+	Format string `mapstructure:"Format"`
+
+	// Path file 类型的日志文件夹路径
+	// optional default XLog.Path
+	Path string `mapstructure:"Path"`
+
+	// MaxAge file 类型的日志保存最大时间
+	// optional default XLog.MaxAge
+	MaxAge string `mapstructure:"MaxAge"`
+
+	// RotateTime file 类型的日志切割时长
+	// optional default XLog.RotateTime
+	RotateTime string `mapstructure:"RotateTime"`
+
+	// MaxSize file 类型单个日志文件最大大小（MB）
+	// optional default XLog.MaxSize
+	MaxSize int `mapstructure:"MaxSize"`
+
+	// MaxBackups file 类型历史日志文件保留个数
+	// optional default XLog.MaxBackups
+	MaxBackups int `mapstructure:"MaxBackups"`
+
+	// Compress file 类型是否压缩历史日志文件
+	// optional default XLog.Compress
+	Compress *bool `mapstructure:"Compress"`
+
+	// FilePattern file 类型日志文件名中的时间格式
+	// optional default XLog.FilePattern
+	FilePattern string `mapstructure:"FilePattern"`
+
+	// Network syslog 类型的网络协议，tcp/udp
+	// optional default "udp"
+	Network string `mapstructure:"Network"`
+
+	// Address syslog 服务地址，如 "127.0.0.1:514"
+	// optional default ""（syslog 类型必填）
+	Address string `mapstructure:"Address"`
+
+	// Facility syslog facility，kern/user/mail/daemon/auth/syslog/lpr/news/uucp/cron/authpriv/ftp/local0~local7
+	// optional default "local0"
+	Facility string `mapstructure:"Facility"`
+
+	// Overflow file/syslog 类型异步写入缓冲区满时的策略，block/drop_newest/drop_oldest
+	// optional default XLog.Overflow
+	Overflow string `mapstructure:"Overflow"`
 }
 
 func configMergeDefault(c *Config) *Config {
@@ -48,6 +312,9 @@ func configMergeDefault(c *Config) *Config {
 	if c.Level == "" {
 		c.Level = "info"
 	}
+	if c.ConsoleTemplate == "" {
+		c.ConsoleTemplate = defaultConsoleTemplate
+	}
 	if c.Path == "" {
 		c.Path = "./log"
 	}
@@ -57,8 +324,151 @@ func configMergeDefault(c *Config) *Config {
 	if c.RotateTime == "" {
 		c.RotateTime = "1d"
 	}
+	if c.FilePattern == "" {
+		c.FilePattern = defaultFilePattern
+	}
 	if c.Timezone == "" {
 		c.Timezone = "Asia/Shanghai"
 	}
+	if c.AsyncBufferSize <= 0 {
+		c.AsyncBufferSize = defaultAsyncBufferSize
+	}
+	if c.Overflow == "" {
+		c.Overflow = overflowBlock
+	}
+	if c.Sampling != nil {
+		c.Sampling = samplingConfigMergeDefault(c.Sampling)
+	}
+	for i := range c.Outputs {
+		c.Outputs[i] = outputConfigMergeDefault(c.Outputs[i], c)
+	}
+	for i := range c.Remote {
+		c.Remote[i] = remoteConfigMergeDefault(c.Remote[i])
+	}
+	if c.ErrorAlert != nil {
+		c.ErrorAlert = errorAlertConfigMergeDefault(c.ErrorAlert)
+	}
+	if c.Baggage != nil {
+		if c.Baggage.MaxValueSize <= 0 {
+			c.Baggage.MaxValueSize = 256
+		}
+		if c.Baggage.MaxSize <= 0 {
+			c.Baggage.MaxSize = 1024
+		}
+	}
+	return c
+}
+
+func errorAlertConfigMergeDefault(a *ErrorAlertConfig) *ErrorAlertConfig {
+	if a.Level == "" {
+		a.Level = "error"
+	}
+	if a.Cooldown == "" {
+		a.Cooldown = "10m"
+	}
+	if a.SpikeWindow == "" {
+		a.SpikeWindow = "1m"
+	}
+	if a.MaxFingerprints <= 0 {
+		a.MaxFingerprints = 1000
+	}
+	for i := range a.Notifiers {
+		if a.Notifiers[i].Type == "" {
+			a.Notifiers[i].Type = errorNotifierWebhook
+		}
+		if a.Notifiers[i].Timeout == "" {
+			a.Notifiers[i].Timeout = "5s"
+		}
+	}
+	return a
+}
+
+func remoteConfigMergeDefault(r RemoteConfig) RemoteConfig {
+	if r.Name == "" {
+		r.Name = r.Type
+	}
+	if r.Format == "" {
+		r.Format = remoteFormatJSON
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = 500
+	}
+	if r.FlushInterval == "" {
+		r.FlushInterval = "1s"
+	}
+	if r.BufferSize <= 0 {
+		r.BufferSize = 10000
+	}
+	if r.MaxRetries == 0 {
+		r.MaxRetries = 3
+	}
+	if r.RetryBackoff == "" {
+		r.RetryBackoff = "500ms"
+	}
+	if r.Timeout == "" {
+		r.Timeout = "5s"
+	}
+	return r
+}
+
+func samplingConfigMergeDefault(c *SamplingConfig) *SamplingConfig {
+	if c == nil {
+		c = &SamplingConfig{}
+	}
+	if c.Interval == "" {
+		c.Interval = "1s"
+	}
+	// 只配置其一时另一个的 0 按字面含义生效，不替换为默认值
+	if c.First == 0 && c.Thereafter == 0 {
+		c.First = 100
+		c.Thereafter = 100
+	}
+	if c.KeepLevel == "" {
+		c.KeepLevel = "error"
+	}
 	return c
 }
+
+func outputConfigMergeDefault(o OutputConfig, c *Config) OutputConfig {
+	if o.Name == "" {
+		o.Name = c.Name
+	}
+	if o.Type == "" {
+		o.Type = outputTypeFile
+	}
+	if o.Format == "" {
+		o.Format = logFormatNameJSON
+	}
+	if o.Path == "" {
+		o.Path = c.Path
+	}
+	if o.MaxAge == "" {
+		o.MaxAge = c.MaxAge
+	}
+	if o.RotateTime == "" {
+		o.RotateTime = c.RotateTime
+	}
+	if o.MaxSize == 0 {
+		o.MaxSize = c.MaxSize
+	}
+	if o.MaxBackups == 0 {
+		o.MaxBackups = c.MaxBackups
+	}
+	if o.Compress == nil {
+		compress := c.Compress
+		o.Compress = &compress
+	}
+	if o.FilePattern == "" {
+		o.FilePattern = c.FilePattern
+	}
+	if o.Network == "" {
+		o.Network = "udp"
+	}
+	if o.Facility == "" {
+		o.Facility = "local0"
+	}
+	if o.Overflow == "" {
+		o.Overflow = c.Overflow
+	}
+	return o
+}
@@ -0,0 +1,199 @@
+package xlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConsoleTemplate = "{level}[{time}] {caller} {traceid} {msg}"
+
+	colorCaller = 34 // 调用位置
+)
+
+// consoleHiddenFields {fields} 中不输出的公共字段（可在模板中显式引用）
+var consoleHiddenFields = map[string]struct{}{
+	"servername": {}, "ip": {}, "pid": {}, "filename": {}, "lineid": {}, "spanid": {},
+}
+
+var consolePlaceholder = regexp.MustCompile(`\{([A-Za-z0-9_.\-]+)\}`)
+
+// defaultConsole 未经 initXLogByConfig 创建的 Handler 使用的控制台格式
+var defaultConsole = newConsoleFormatter(defaultConsoleTemplate, true)
+
+// consoleSegment 模板片段，field 为空时输出 literal
+type consoleSegment struct {
+	literal string
+	field   string
+}
+
+// consoleFormatter 控制台输出格式：首行按 XLog.ConsoleTemplate 渲染，调用栈与错误链逐行缩进输出在其后
+type consoleFormatter struct {
+	segments []consoleSegment
+	used     map[string]struct{} // 模板中引用的字段，{fields} 中不再重复输出
+	color    bool
+}
+
+func newConsoleFormatter(template string, color bool) *consoleFormatter {
+	f := &consoleFormatter{used: make(map[string]struct{}), color: color}
+	last := 0
+	for _, m := range consolePlaceholder.FindAllStringSubmatchIndex(template, -1) {
+		if m[0] > last {
+			f.segments = append(f.segments, consoleSegment{literal: template[last:m[0]]})
+		}
+		name := template[m[2]:m[3]]
+		f.segments = append(f.segments, consoleSegment{field: name})
+		f.used[name] = struct{}{}
+		last = m[1]
+	}
+	if last < len(template) {
+		f.segments = append(f.segments, consoleSegment{literal: template[last:]})
+	}
+	return f
+}
+
+// consoleColorEnabled 设置了 NO_COLOR 环境变量（https://no-color.org）或输出不是终端时不使用颜色
+func consoleColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (f *consoleFormatter) format(level slog.Level, t time.Time, msg string, data map[string]any, frame *runtime.Frame) []byte {
+	b := make([]byte, 0, 256)
+	for _, s := range f.segments {
+		switch s.field {
+		case "":
+			b = append(b, s.literal...)
+		case "time":
+			b = append(b, t.Format(logTimeFormat)...)
+		case "level":
+			b = f.appendColored(b, getLogConsoleLogColor(level), strings.ToUpper(levelText(level)))
+		case "caller":
+			b = f.appendColored(b, colorCaller, callerPretty(frame))
+		case "msg":
+			b = append(b, msg...)
+		case "fields":
+			b = f.appendFields(b, data)
+		default:
+			b = append(b, logfmtValue(data[s.field])...)
+		}
+	}
+	b = append(b, '\n')
+	return f.appendDetails(b, data)
+}
+
+func (f *consoleFormatter) appendColored(b []byte, color int, s string) []byte {
+	if !f.color {
+		return append(b, s...)
+	}
+	return fmt.Appendf(b, "\x1b[%dm%s\x1b[0m", color, s)
+}
+
+// appendFields 按 key 排序以 logfmt 形式输出模板未引用的字段，忽略空值、公共字段及在首行之后单独输出的调用栈
+func (f *consoleFormatter) appendFields(b []byte, data map[string]any) []byte {
+	keys := make([]string, 0, len(data))
+	for k, v := range data {
+		if _, ok := f.used[k]; ok {
+			continue
+		}
+		if _, ok := consoleHiddenFields[k]; ok || isStackField(k) || v == nil || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = f.appendColored(b, colorGray, k)
+		b = append(b, '=')
+		if v := logfmtValue(data[k]); needsQuote(v) {
+			b = strconv.AppendQuote(b, v)
+		} else {
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// appendDetails 在首行之后逐行缩进输出调用栈（panic_stack、<key>_stack）与多层错误链
+func (f *consoleFormatter) appendDetails(b []byte, data map[string]any) []byte {
+	var keys []string
+	for k, v := range data {
+		if isStackField(k) && v != nil && v != "" {
+			keys = append(keys, k)
+		} else if err, ok := v.(error); ok && unwrapErrors(err) != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b = f.appendColored(append(b, "  "...), colorGray, k)
+		b = append(b, ":\n"...)
+		if err, ok := data[k].(error); ok {
+			b = appendErrorChain(b, err, "    ")
+			continue
+		}
+		b = appendIndented(b, logfmtValue(data[k]), "    ")
+	}
+	return b
+}
+
+func isStackField(k string) bool {
+	return strings.HasSuffix(k, "_stack")
+}
+
+// unwrapErrors 返回 err 直接包装的错误，支持 errors.Join 等 Unwrap() []error
+func unwrapErrors(err error) []error {
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		return u.Unwrap()
+	case interface{ Unwrap() error }:
+		if e := u.Unwrap(); e != nil {
+			return []error{e}
+		}
+	}
+	return nil
+}
+
+// appendErrorChain 每层错误一行，被包装的错误多缩进一级，errors.Join 合并的错误各自展开
+func appendErrorChain(b []byte, err error, indent string) []byte {
+	if _, ok := err.(interface{ Unwrap() []error }); !ok {
+		b = appendIndented(b, err.Error(), indent)
+		indent += "  "
+	}
+	for _, e := range unwrapErrors(err) {
+		if e != nil {
+			b = appendErrorChain(b, e, indent)
+		}
+	}
+	return b
+}
+
+// appendIndented 多行内容逐行加缩进输出
+func appendIndented(b []byte, s, indent string) []byte {
+	for line := range strings.SplitSeq(strings.TrimRight(s, "\n"), "\n") {
+		b = append(b, indent...)
+		b = append(b, strings.TrimRight(line, "\r")...)
+		b = append(b, '\n')
+	}
+	return b
+}
@@ -0,0 +1,213 @@
+package xlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// XLogDebugKey ctx KV 容器中标记请求级 debug 的 key，命中的请求日志中会带上 "xlog_debug": true
+const XLogDebugKey = "xlog_debug"
+
+var errXLogNotInitialized = errors.New("xlog not initialized")
+
+// activeCore 当前生效的 Handler，initXLogByConfig 成功后设置
+var activeCore atomic.Pointer[handlerCore]
+
+// debugState 请求级 debug 配置：命中 DebugHeader 或 trace id 在白名单中的请求，不受全局级别限制输出 debug 日志
+type debugState struct {
+	header      string
+	headerValue string
+	mu          sync.RWMutex
+	traceIDs    map[string]*time.Timer // trace id -> 到期移除的 timer（永久有效时为 nil）
+	traceIDNum  atomic.Int64           // 白名单数量，为 0 时跳过 trace id 查询
+}
+
+// SetLevel 运行时调整全局日志级别，ttl > 0 时到期自动恢复为最近一次永久设置的级别（初始为 XLog.Level）
+// 再次调用会取消上一次未到期的恢复
+func SetLevel(level string, ttl time.Duration) error {
+	c := activeCore.Load()
+	if c == nil {
+		return errXLogNotInitialized
+	}
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	c.setLevel(toSlogLevel(l), ttl)
+	return nil
+}
+
+// GetLevel 返回当前生效的全局日志级别，未初始化时返回 XLog.Level 配置
+func GetLevel() string {
+	c := activeCore.Load()
+	if c == nil {
+		return strings.ToLower(XLogLevel())
+	}
+	return levelText(c.level.Level())
+}
+
+// AddDebugTraceID 将 trace id 加入 debug 白名单，该链路的日志不受全局级别限制输出 debug 日志，ttl > 0 时到期自动移除
+func AddDebugTraceID(traceID string, ttl time.Duration) error {
+	c := activeCore.Load()
+	if c == nil {
+		return errXLogNotInitialized
+	}
+	c.debug.addTraceID(traceID, ttl)
+	c.syncLogrusLevel()
+	return nil
+}
+
+// RemoveDebugTraceID 将 trace id 移出 debug 白名单
+func RemoveDebugTraceID(traceID string) {
+	if c := activeCore.Load(); c != nil {
+		c.debug.removeTraceID(traceID)
+		c.syncLogrusLevel()
+	}
+}
+
+// CtxWithDebug 标记 ctx 所在请求输出 debug 日志（经 ctx KV 容器传递）
+func CtxWithDebug(ctx context.Context) context.Context {
+	return CtxWithKV(ctx, map[string]any{XLogDebugKey: true})
+}
+
+// DebugRequested 请求头是否命中 XLog.DebugHeader，未配置或未初始化时返回 false
+// 配置了 XLog.DebugHeaderValue 时请求头的值需与之相等，否则值非空且不为 0/false 即命中
+func DebugRequested(h http.Header) bool {
+	c := activeCore.Load()
+	if c == nil || c.debug == nil || c.debug.header == "" {
+		return false
+	}
+	v := h.Get(c.debug.header)
+	if c.debug.headerValue != "" {
+		return v == c.debug.headerValue
+	}
+	switch strings.ToLower(v) {
+	case "", "0", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+func newDebugState(c *Config) *debugState {
+	d := &debugState{header: c.DebugHeader, headerValue: c.DebugHeaderValue, traceIDs: make(map[string]*time.Timer)}
+	for _, id := range c.DebugTraceIDs {
+		d.addTraceID(id, 0)
+	}
+	return d
+}
+
+// active 是否可能有请求需要输出 debug 日志，此时 logrus 需放行 debug 级别
+func (d *debugState) active() bool {
+	return d != nil && (d.header != "" || d.traceIDNum.Load() > 0)
+}
+
+// matches ctx 是否标记了请求级 debug，或其 trace id 在白名单中
+func (d *debugState) matches(ctx context.Context) bool {
+	if d == nil || ctx == nil {
+		return false
+	}
+	if debug, _ := getXLogContainerFromCtx(ctx)[XLogDebugKey].(bool); debug {
+		return true
+	}
+	if d.traceIDNum.Load() == 0 {
+		return false
+	}
+	traceID := xutil.GetTraceIDFromCtx(ctx)
+	if traceID == "" {
+		return false
+	}
+	d.mu.RLock()
+	_, ok := d.traceIDs[traceID]
+	d.mu.RUnlock()
+	return ok
+}
+
+func (d *debugState) addTraceID(traceID string, ttl time.Duration) {
+	if traceID == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if old, ok := d.traceIDs[traceID]; ok && old != nil {
+		old.Stop()
+	}
+	var timer *time.Timer
+	if ttl > 0 {
+		timer = time.AfterFunc(ttl, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			// 仅移除本次添加的记录，期间被重新添加时不受影响
+			if cur, ok := d.traceIDs[traceID]; ok && cur == timer {
+				delete(d.traceIDs, traceID)
+			}
+			d.traceIDNum.Store(int64(len(d.traceIDs)))
+		})
+	}
+	d.traceIDs[traceID] = timer
+	d.traceIDNum.Store(int64(len(d.traceIDs)))
+}
+
+func (d *debugState) removeTraceID(traceID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old, ok := d.traceIDs[traceID]; ok {
+		if old != nil {
+			old.Stop()
+		}
+		delete(d.traceIDs, traceID)
+	}
+	d.traceIDNum.Store(int64(len(d.traceIDs)))
+}
+
+// setLevel 设置全局级别，ttl > 0 时到期恢复为 baseLevel
+func (c *handlerCore) setLevel(l slog.Level, ttl time.Duration) {
+	c.levelMu.Lock()
+	defer c.levelMu.Unlock()
+
+	if c.revertTimer != nil {
+		c.revertTimer.Stop()
+		c.revertTimer = nil
+	}
+	c.level.Set(l)
+	if ttl > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			c.levelMu.Lock()
+			defer c.levelMu.Unlock()
+			if c.revertTimer != timer { // 已被之后的 SetLevel 取代
+				return
+			}
+			c.revertTimer = nil
+			c.level.Set(c.baseLevel)
+			c.syncLogrusLevel()
+		})
+		c.revertTimer = timer
+	} else {
+		c.baseLevel = l
+	}
+	c.syncLogrusLevel()
+}
+
+// syncLogrusLevel logrus 只作为入口，按 Handler 可能输出的最低级别放行，由 Handler 再按包、请求过滤
+func (c *handlerCore) syncLogrusLevel() {
+	if activeCore.Load() != c {
+		return
+	}
+	l := c.minLevel()
+	if c.debug.active() {
+		l = min(l, slog.LevelDebug)
+	}
+	logrus.SetLevel(toLogrusLevel(l))
+}
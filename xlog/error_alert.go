@@ -0,0 +1,395 @@
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ErrorEventNew   = "new"   // 首次出现的错误
+	ErrorEventSpike = "spike" // 统计窗口内次数达到阈值
+
+	errorNotifierWebhook  = "webhook"
+	errorNotifierSlack    = "slack"
+	errorNotifierDingTalk = "dingtalk"
+	errorNotifierFeishu   = "feishu"
+
+	// errorEventQueueSize 待发送通知的缓冲，满时丢弃
+	errorEventQueueSize = 128
+
+	// errorKeyIndexSize 日志模板 + 调用方到指纹的索引上限，超过时清空重建
+	errorKeyIndexSize = 4096
+)
+
+var errErrorAlertDisabled = errors.New("xlog ErrorAlert not enabled")
+
+// ErrorFingerprint 一类错误（调用方 + 归一化后的日志内容）的聚合信息
+type ErrorFingerprint struct {
+	ID           string    `json:"id"`
+	Caller       string    `json:"caller"`  // 调用方函数，file:line 为最近一次出现的位置
+	Message      string    `json:"message"` // 归一化后的日志内容（数字、UUID 等替换为占位符）
+	Sample       string    `json:"sample"`  // 最近一次的原始日志内容
+	Level        string    `json:"level"`
+	Count        uint64    `json:"count"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	LastNotified time.Time `json:"last_notified,omitzero"`
+
+	windowStart time.Time
+	windowCount int
+}
+
+// ErrorEvent 错误告警事件
+type ErrorEvent struct {
+	Reason      string           `json:"reason"` // new / spike
+	ServerName  string           `json:"servername"`
+	Fingerprint ErrorFingerprint `json:"fingerprint"`
+}
+
+// ErrorNotifier 错误告警通知，Notify 在后台 goroutine 中串行调用
+type ErrorNotifier interface {
+	Notify(ctx context.Context, e ErrorEvent) error
+}
+
+// errorAggregator 错误日志聚合：按指纹统计首次/最近出现时间与次数，新错误或突增时通知（同一指纹受 Cooldown 限制）
+type errorAggregator struct {
+	level           slog.Level
+	cooldown        time.Duration
+	spikeWindow     time.Duration
+	spikeThreshold  int
+	maxFingerprints int
+	serverName      string
+
+	mu        sync.Mutex
+	fps       map[string]*ErrorFingerprint
+	ids       map[errorKey]string // 日志模板 + 调用方 -> 指纹 ID，命中时无需再做归一化
+	notifiers []ErrorNotifier
+	closed    bool
+
+	events chan ErrorEvent
+	done   chan struct{}
+}
+
+func newErrorAggregator(c *ErrorAlertConfig, serverName string) (*errorAggregator, error) {
+	if c == nil {
+		return nil, nil
+	}
+	l, err := logrus.ParseLevel(c.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ErrorAlert.Level [%s]", c.Level)
+	}
+	a := &errorAggregator{
+		level:           toSlogLevel(l),
+		cooldown:        xutil.ToDuration(c.Cooldown),
+		spikeWindow:     xutil.ToDuration(c.SpikeWindow),
+		spikeThreshold:  c.SpikeThreshold,
+		maxFingerprints: c.MaxFingerprints,
+		serverName:      serverName,
+		fps:             make(map[string]*ErrorFingerprint),
+		ids:             make(map[errorKey]string),
+		events:          make(chan ErrorEvent, errorEventQueueSize),
+		done:            make(chan struct{}),
+	}
+	if a.spikeThreshold > 0 && a.spikeWindow <= 0 {
+		return nil, fmt.Errorf("invalid ErrorAlert.SpikeWindow [%s]", c.SpikeWindow)
+	}
+	for _, nc := range c.Notifiers {
+		n, err := NewErrorNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		a.notifiers = append(a.notifiers, n)
+	}
+	go a.loop()
+	return a, nil
+}
+
+// errorKey 归一化前的指纹索引，调用方以函数名区分，避免代码行号变化后产生新指纹
+type errorKey struct {
+	caller   string
+	template string
+}
+
+// observe 记录一条日志，template 为格式化前的日志模板（无则为日志内容）
+// 先按模板 + 调用方查找已有指纹，未命中时才做归一化（正则替换）
+func (a *errorAggregator) observe(level slog.Level, template, msg string, frame *runtime.Frame, t time.Time) {
+	if a == nil || level < a.level {
+		return
+	}
+	function, caller := "", ""
+	if frame != nil {
+		function, caller = frame.Function, callerPretty(frame)
+	}
+	key := errorKey{caller: xutil.GetOrDefault(function, caller), template: template}
+
+	a.mu.Lock()
+	id, ok := a.ids[key]
+	normalized := ""
+	if !ok {
+		// 归一化不持有锁
+		a.mu.Unlock()
+		normalized = normalizeErrorMessage(template)
+		id = errorFingerprintID(key.caller, normalized)
+		a.mu.Lock()
+		if len(a.ids) >= errorKeyIndexSize {
+			clear(a.ids)
+		}
+		a.ids[key] = id
+	}
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+
+	fp, ok := a.fps[id]
+	if !ok {
+		if normalized == "" {
+			// 索引命中但指纹已被淘汰
+			normalized = normalizeErrorMessage(template)
+		}
+		a.evict()
+		fp = &ErrorFingerprint{ID: id, Message: normalized, Level: levelText(level), FirstSeen: t, windowStart: t}
+		a.fps[id] = fp
+	}
+	fp.Caller = strings.TrimSpace(function + " " + caller)
+	fp.Sample = msg
+	fp.Count++
+	fp.LastSeen = t
+	if a.spikeWindow > 0 && t.Sub(fp.windowStart) >= a.spikeWindow {
+		fp.windowStart, fp.windowCount = t, 0
+	}
+	fp.windowCount++
+
+	reason := ""
+	switch {
+	case !ok:
+		reason = ErrorEventNew
+	case a.spikeThreshold > 0 && fp.windowCount == a.spikeThreshold:
+		reason = ErrorEventSpike
+	}
+	if reason == "" || len(a.notifiers) == 0 || (!fp.LastNotified.IsZero() && t.Sub(fp.LastNotified) < a.cooldown) {
+		return
+	}
+	fp.LastNotified = t
+	select {
+	case a.events <- ErrorEvent{Reason: reason, ServerName: a.serverName, Fingerprint: *fp}:
+	default:
+		xutil.WarnIfEnableDebug("XOne xlog error alert queue full, event dropped, fingerprint=[%s]", id)
+	}
+}
+
+// errorFingerprintID 按调用方与归一化后的日志内容计算指纹 ID
+func errorFingerprintID(caller, normalized string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(caller))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(normalized))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// evict 超过 MaxFingerprints 时淘汰最久未出现的指纹，需持有 mu
+func (a *errorAggregator) evict() {
+	if a.maxFingerprints <= 0 || len(a.fps) < a.maxFingerprints {
+		return
+	}
+	var oldest *ErrorFingerprint
+	for _, fp := range a.fps {
+		if oldest == nil || fp.LastSeen.Before(oldest.LastSeen) {
+			oldest = fp
+		}
+	}
+	delete(a.fps, oldest.ID)
+}
+
+func (a *errorAggregator) loop() {
+	defer close(a.done)
+	for e := range a.events {
+		a.mu.Lock()
+		notifiers := a.notifiers
+		a.mu.Unlock()
+		for _, n := range notifiers {
+			// 通知失败不能经 xlog 输出 error，避免再次触发聚合
+			if err := n.Notify(context.Background(), e); err != nil {
+				xutil.WarnIfEnableDebug("XOne xlog error alert notify failed, fingerprint=[%s], err=[%v]", e.Fingerprint.ID, err)
+			}
+		}
+	}
+}
+
+func (a *errorAggregator) addNotifier(n ErrorNotifier) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.notifiers = append(a.notifiers, n)
+}
+
+func (a *errorAggregator) list() []ErrorFingerprint {
+	a.mu.Lock()
+	res := make([]ErrorFingerprint, 0, len(a.fps))
+	for _, fp := range a.fps {
+		res = append(res, *fp)
+	}
+	a.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastSeen.After(res[j].LastSeen)
+	})
+	return res
+}
+
+// Close 停止聚合，等待已产生的通知发送完成
+func (a *errorAggregator) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.events)
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hexPattern    = regexp.MustCompile(`\b(0x[0-9a-fA-F]+|[0-9a-fA-F]{8,})\b`)
+	numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// normalizeErrorMessage 将 UUID、十六进制串（如 trace id、地址）、数字替换为占位符，使参数不同的同类错误得到相同指纹
+func normalizeErrorMessage(msg string) string {
+	msg = uuidPattern.ReplaceAllString(msg, "<uuid>")
+	msg = hexPattern.ReplaceAllStringFunc(msg, func(s string) string {
+		// 不含数字的视为普通单词
+		if strings.ContainsAny(s, "0123456789") {
+			return "<hex>"
+		}
+		return s
+	})
+	return numberPattern.ReplaceAllString(msg, "<n>")
+}
+
+// ErrorFingerprints 返回当前的错误指纹，按最近出现时间倒序，未启用 XLog.ErrorAlert 时返回 nil
+func ErrorFingerprints() []ErrorFingerprint {
+	c := activeCore.Load()
+	if c == nil || c.errors == nil {
+		return nil
+	}
+	return c.errors.list()
+}
+
+// ErrorFingerprintsHandler 以 JSON 返回 ErrorFingerprints，用于挂载到管理端口，如 e.GET("/admin/errors", gin.WrapH(xlog.ErrorFingerprintsHandler()))
+func ErrorFingerprintsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fps := ErrorFingerprints()
+		if fps == nil {
+			fps = []ErrorFingerprint{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(fps)
+	})
+}
+
+// AddErrorNotifier 追加自定义错误告警通知，需启用 XLog.ErrorAlert
+func AddErrorNotifier(n ErrorNotifier) error {
+	c := activeCore.Load()
+	if c == nil {
+		return errXLogNotInitialized
+	}
+	if c.errors == nil {
+		return errErrorAlertDisabled
+	}
+	c.errors.addNotifier(n)
+	return nil
+}
+
+// NewErrorNotifier 按配置创建内置的 webhook 通知：webhook（POST ErrorEvent JSON）/slack/dingtalk/feishu（对应机器人消息格式）
+func NewErrorNotifier(c ErrorNotifierConfig) (ErrorNotifier, error) {
+	kind := strings.ToLower(xutil.GetOrDefault(c.Type, errorNotifierWebhook))
+	switch kind {
+	case errorNotifierWebhook, errorNotifierSlack, errorNotifierDingTalk, errorNotifierFeishu:
+	default:
+		return nil, fmt.Errorf("unsupported error notifier type [%s], must be one of webhook/slack/dingtalk/feishu", c.Type)
+	}
+	if c.URL == "" {
+		return nil, fmt.Errorf("error notifier [%s] requires URL", kind)
+	}
+	timeout := xutil.ToDuration(c.Timeout)
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &webhookNotifier{kind: kind, url: c.URL, headers: c.Headers, client: &http.Client{Timeout: timeout}}, nil
+}
+
+type webhookNotifier struct {
+	kind    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, e ErrorEvent) error {
+	var payload any
+	switch n.kind {
+	case errorNotifierSlack:
+		payload = map[string]any{"text": errorEventText(e)}
+	case errorNotifierDingTalk:
+		payload = map[string]any{"msgtype": "text", "text": map[string]any{"content": errorEventText(e)}}
+	case errorNotifierFeishu:
+		payload = map[string]any{"msg_type": "text", "content": map[string]any{"text": errorEventText(e)}}
+	default:
+		payload = e
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// errorEventText 机器人消息文本
+func errorEventText(e ErrorEvent) string {
+	title := "新错误"
+	if e.Reason == ErrorEventSpike {
+		title = "错误突增"
+	}
+	fp := e.Fingerprint
+	return fmt.Sprintf("[%s] %s\n位置: %s\n内容: %s\n次数: %d\n首次: %s\n最近: %s\n指纹: %s",
+		e.ServerName, title, fp.Caller, fp.Sample, fp.Count,
+		fp.FirstSeen.Format(logTimeFormat), fp.LastSeen.Format(logTimeFormat), fp.ID)
+}
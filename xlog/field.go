@@ -0,0 +1,178 @@
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xerror"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+type fieldKind uint8
+
+const (
+	fieldSkip fieldKind = iota
+	fieldString
+	fieldInt64
+	fieldUint64
+	fieldFloat64
+	fieldBool
+	fieldDuration
+	fieldTime
+	fieldError
+	fieldStringer
+	fieldLazy
+	fieldAny
+)
+
+// Field 结构化日志字段，按类型保存值；日志不输出（级别、包级别、请求级 debug 或采样过滤）时不分配、不求值
+// 例外：该级别注册了其他 logrus hook（如 xmetric 的 Error 级别 hook）时，为保证 hook 收到日志仍会求值
+// 输出时字段写入 logrus.Fields 经 logrus 入口输出，与 xlog.Info + KV 相比分配较少但不是零分配
+type Field struct {
+	Key  string
+	kind fieldKind
+	num  uint64
+	str  string
+	val  any
+}
+
+func String(key, v string) Field {
+	return Field{Key: key, kind: fieldString, str: v}
+}
+
+func Int(key string, v int) Field {
+	return Field{Key: key, kind: fieldInt64, num: uint64(v)}
+}
+
+func Int64(key string, v int64) Field {
+	return Field{Key: key, kind: fieldInt64, num: uint64(v)}
+}
+
+func Uint64(key string, v uint64) Field {
+	return Field{Key: key, kind: fieldUint64, num: v}
+}
+
+func Float64(key string, v float64) Field {
+	return Field{Key: key, kind: fieldFloat64, num: math.Float64bits(v)}
+}
+
+func Bool(key string, v bool) Field {
+	f := Field{Key: key, kind: fieldBool}
+	if v {
+		f.num = 1
+	}
+	return f
+}
+
+// Duration 输出为 time.Duration.String() 格式，如 "1.5s"
+func Duration(key string, v time.Duration) Field {
+	return Field{Key: key, kind: fieldDuration, num: uint64(v)}
+}
+
+func Time(key string, v time.Time) Field {
+	return Field{Key: key, kind: fieldTime, val: v}
+}
+
+// Err 以 "error" 为 key 记录错误，nil 时忽略，见 NamedErr
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr 记录错误信息，错误链中包含 xerror.XOneError 时追加 <key>_module、<key>_op，
+// 包含 github.com/pkg/errors 调用栈时追加 <key>_stack，nil 时忽略
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{kind: fieldSkip}
+	}
+	return Field{Key: key, kind: fieldError, val: err}
+}
+
+// Stringer 输出时才调用 v.String()
+func Stringer(key string, v fmt.Stringer) Field {
+	return Field{Key: key, kind: fieldStringer, val: v}
+}
+
+// Lazy 输出时才调用 fn 求值，用于开销较大的字段（日志不输出时不会调用，例外见 Field）
+func Lazy(key string, fn func() any) Field {
+	return Field{Key: key, kind: fieldLazy, val: fn}
+}
+
+// Any 任意类型的值，按 JSON 序列化输出
+func Any(key string, v any) Field {
+	return Field{Key: key, kind: fieldAny, val: v}
+}
+
+// addTo 求值并写入日志字段
+func (f Field) addTo(data map[string]any) {
+	switch f.kind {
+	case fieldString:
+		data[f.Key] = f.str
+	case fieldInt64:
+		data[f.Key] = int64(f.num)
+	case fieldUint64:
+		data[f.Key] = f.num
+	case fieldFloat64:
+		data[f.Key] = math.Float64frombits(f.num)
+	case fieldBool:
+		data[f.Key] = f.num == 1
+	case fieldDuration:
+		data[f.Key] = time.Duration(f.num).String()
+	case fieldTime:
+		data[f.Key] = f.val
+	case fieldError:
+		addError(data, f.Key, f.val.(error))
+	case fieldStringer:
+		if s, ok := f.val.(fmt.Stringer); ok && !isNilValue(s) {
+			data[f.Key] = s.String()
+		} else {
+			data[f.Key] = nil
+		}
+	case fieldLazy:
+		if fn, _ := f.val.(func() any); fn != nil {
+			data[f.Key] = fn()
+		}
+	case fieldAny:
+		data[f.Key] = f.val
+	}
+}
+
+// addError 保留 error 值（控制台据此展开错误链），JSON/logfmt/远程投递编码时输出为 err.Error()
+func addError(data map[string]any, key string, err error) {
+	data[key] = err
+	var xe *xerror.XOneError
+	if errors.As(err, &xe) {
+		data[key+"_module"] = xe.Module
+		data[key+"_op"] = xe.Op
+	}
+	if stack := errorStack(err); stack != "" {
+		data[key+"_stack"] = stack
+	}
+}
+
+// stackTracer github.com/pkg/errors 创建的错误实现的接口
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// errorStack 沿错误链查找第一个带调用栈的错误，返回 %+v 格式的调用栈
+func errorStack(err error) string {
+	var st stackTracer
+	if errors.As(err, &st) {
+		return fmt.Sprintf("%+v", st.StackTrace())
+	}
+	return ""
+}
+
+func isNilValue(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,379 @@
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 与 logrus trace/fatal/panic 对应的 slog 级别
+const (
+	LevelTrace = slog.Level(-8)
+	LevelFatal = slog.Level(12)
+	LevelPanic = slog.Level(16)
+)
+
+// 控制台颜色
+const (
+	colorRed    = 31
+	colorYellow = 33
+	colorBlue   = 36
+	colorGray   = 37
+)
+
+const logTimeFormat = "2006-01-02 15:04:05.999"
+
+// Handler xlog 的 slog.Handler 实现，xlog.Info 等方法（经 logrus）与 slog.Info 最终都由它输出
+// 补充 servername/ip/pid/filename/lineid/traceid/spanid 及 ctx 中的 KV，写入各日志输出（默认 <Name>.log，或 XLog.Outputs），XLog.Console 开启时同时打印到控制台
+// WithGroup 之后的字段以 "group.key" 的形式平铺输出
+type Handler struct {
+	core   *handlerCore
+	attrs  map[string]any // WithAttrs 预先解析的字段
+	prefix string         // WithGroup 累积的字段前缀
+}
+
+type handlerCore struct {
+	level          *slog.LevelVar
+	levelMu        sync.Mutex     // 保护 baseLevel、revertTimer
+	baseLevel      slog.Level     // SetLevel 临时调整到期后恢复的级别
+	revertTimer    *time.Timer    // SetLevel 临时调整的恢复 timer
+	pkgLevels      *packageLevels // XLog.Levels，nil 表示未配置
+	debug          *debugState    // 请求级 debug，nil 表示未启用
+	sampler        *sampler       // 日志采样，nil 表示不采样
+	serverName     string
+	ip             string
+	pid            string
+	suffixToIgnore []string
+	location       *time.Location
+	outputs        []*output
+	remotes        []*remoteSink    // XLog.Remote 远程投递
+	errors         *errorAggregator // XLog.ErrorAlert 错误聚合，nil 表示未启用
+	baggage        *baggagePolicy   // XLog.Baggage 跨服务传递的 KV，nil 表示不传递
+	console        io.Writer        // 控制台，nil 表示不打印
+	consoleRaw     bool
+	consoleFmt     *consoleFormatter // XLog.ConsoleTemplate，nil 时使用默认模板
+}
+
+func newHandler(core *handlerCore) *Handler {
+	if core.level == nil {
+		core.level = new(slog.LevelVar)
+	}
+	return &Handler{core: core}
+}
+
+// Enabled 仅按全局与各包配置中的最低级别及请求级 debug 粗略判断，调用方所在包的级别在 Handle 中判断
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.core.minLevel() || h.core.debugEnabled(ctx, l)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	frame := h.core.caller(r.PC)
+	if !h.core.admit(ctx, r.Level, r.Message, r.Message, frame, r.Time) {
+		return nil
+	}
+
+	data := make(map[string]any, len(h.attrs)+r.NumAttrs()+8)
+	maps.Copy(data, h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(data, h.prefix, a)
+		return true
+	})
+
+	enrichFields(ctx, data, frame, h.core.serverName, h.core.ip, h.core.pid)
+	return h.core.write(r.Level, r.Time, r.Message, data, frame)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = make(map[string]any, len(h.attrs)+len(attrs))
+	maps.Copy(h2.attrs, h.attrs)
+	for _, a := range attrs {
+		addAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// caller 优先使用 slog.Record 记录的 PC，未记录时（如 log 包转发的日志）从调用栈查找
+func (c *handlerCore) caller(pc uintptr) *runtime.Frame {
+	if pc == 0 {
+		return xutil.GetLogCaller(-2, c.suffixToIgnore)
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &f
+}
+
+// minLevel 全局级别与各包级别中的最低级别
+func (c *handlerCore) minLevel() slog.Level {
+	l := c.level.Level()
+	if c.pkgLevels != nil {
+		l = min(l, c.pkgLevels.min)
+	}
+	return l
+}
+
+// enabled 调用方所在包配置了级别（XLog.Levels）时按包级别判断，否则按全局级别判断，请求级 debug 时放行 debug 及以上级别
+func (c *handlerCore) enabled(ctx context.Context, l slog.Level, frame *runtime.Frame) bool {
+	if pl, ok := c.pkgLevels.resolve(frame); ok {
+		if l >= pl {
+			return true
+		}
+	} else if l >= c.level.Level() {
+		return true
+	}
+	return c.debugEnabled(ctx, l)
+}
+
+// admit 判断日志是否输出：先按级别过滤，再做错误聚合（被采样丢弃的错误同样计数），最后采样
+func (c *handlerCore) admit(ctx context.Context, l slog.Level, template, msg string, frame *runtime.Frame, t time.Time) bool {
+	if !c.enabled(ctx, l, frame) {
+		return false
+	}
+	c.errors.observe(l, template, msg, frame, t)
+	return c.sampler.keep(l, template, frame, t)
+}
+
+// debugEnabled 请求命中请求级 debug 时放行 debug 及以上级别
+func (c *handlerCore) debugEnabled(ctx context.Context, l slog.Level) bool {
+	return l >= slog.LevelDebug && c.debug.matches(ctx)
+}
+
+// write 按各输出的级别与格式写出，并按需打印到控制台，同一格式只编码一次
+func (c *handlerCore) write(level slog.Level, t time.Time, msg string, data map[string]any, frame *runtime.Frame) error {
+	if c.location != nil {
+		t = t.In(c.location)
+	}
+
+	var (
+		lines [logFormatCount][]byte
+		errs  []error
+	)
+	line := func(f logFormat) ([]byte, error) {
+		if lines[f] == nil {
+			b, err := encode(f, level, t, msg, data, callerPretty(frame))
+			if err != nil {
+				return nil, err
+			}
+			lines[f] = b
+		}
+		return lines[f], nil
+	}
+
+	for _, o := range c.outputs {
+		if !o.enabled(level) {
+			continue
+		}
+		b, err := line(o.format)
+		if err != nil {
+			return err
+		}
+		if o.syslog != nil {
+			b = append(o.syslog.append(make([]byte, 0, len(b)+96), level, t), b...)
+		}
+		if _, err := o.writer.Write(b); err != nil {
+			errs = append(errs, fmt.Errorf("write output [%s] failed, err=[%v]", o.name, err))
+		}
+	}
+
+	var rec *remoteRecord
+	for _, r := range c.remotes {
+		if !r.enabled(level) {
+			continue
+		}
+		if rec == nil { // 异步发送，保存字段快照
+			rec = &remoteRecord{level: level, time: t, msg: msg, data: maps.Clone(data)}
+		}
+		r.enqueue(rec)
+	}
+
+	if c.console != nil {
+		b, err := line(logFormatJSON)
+		if err != nil {
+			return err
+		}
+		if err := c.consolePrint(level, t, msg, data, frame, b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *handlerCore) consolePrint(level slog.Level, t time.Time, msg string, data map[string]any, frame *runtime.Frame, line []byte) error {
+	// 打印原始json格式（纯 JSON，无前缀）
+	if c.consoleRaw {
+		_, err := c.console.Write(line)
+		return err
+	}
+
+	f := c.consoleFmt
+	if f == nil {
+		f = defaultConsole
+	}
+	_, err := c.console.Write(f.format(level, t, msg, data, frame))
+	return err
+}
+
+// formatJSON 输出与原 logrus.JSONFormatter 一致的格式：key 有序，与 time/level/msg 冲突的字段加 "fields." 前缀，error 输出为字符串
+func formatJSON(level slog.Level, t time.Time, msg string, data map[string]any) ([]byte, error) {
+	out := make(map[string]any, len(data)+3)
+	for k, v := range data {
+		switch k {
+		case "time", "level", "msg":
+			k = "fields." + k
+		}
+		if e, ok := v.(error); ok {
+			v = e.Error()
+		}
+		out[k] = v
+	}
+	out["time"] = t.Format(logTimeFormat)
+	out["level"] = levelText(level)
+	out["msg"] = msg
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(out); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, err=[%v]", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// enrichFields 补充公共字段，logrus hook 与 slog Handler 共用
+func enrichFields(ctx context.Context, data map[string]any, frame *runtime.Frame, serverName, ip, pid string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := data["servername"]; !ok {
+		data["servername"] = serverName
+	}
+	data["ip"] = ip
+	data["pid"] = pid
+
+	// 设置文件名和行号
+	if frame != nil {
+		data["filename"] = path.Base(frame.File)
+		data["lineid"] = strconv.Itoa(frame.Line)
+	}
+
+	// 设置trace信息
+	data["traceid"] = xutil.GetTraceIDFromCtx(ctx)
+	data["spanid"] = xutil.GetSpanIDFromCtx(ctx)
+
+	// 设置一些ctx中kv
+	for k, v := range getXLogContainerFromCtx(ctx) {
+		data[k] = v
+	}
+}
+
+func addAttr(data map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(data, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	data[prefix+a.Key] = a.Value.Any()
+}
+
+// levelText 日志中的级别名称，沿用 logrus 的命名（warn 输出为 warning）
+func levelText(l slog.Level) string {
+	switch {
+	case l >= LevelPanic:
+		return "panic"
+	case l >= LevelFatal:
+		return "fatal"
+	case l >= slog.LevelError:
+		return "error"
+	case l >= slog.LevelWarn:
+		return "warning"
+	case l >= slog.LevelInfo:
+		return "info"
+	case l >= slog.LevelDebug:
+		return "debug"
+	default:
+		return "trace"
+	}
+}
+
+// toSlogLevel logrus 级别转为 slog 级别
+func toSlogLevel(l logrus.Level) slog.Level {
+	switch l {
+	case logrus.PanicLevel:
+		return LevelPanic
+	case logrus.FatalLevel:
+		return LevelFatal
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	default:
+		return LevelTrace
+	}
+}
+
+func getLogConsoleLogColor(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return colorRed
+	case l >= slog.LevelWarn:
+		return colorYellow
+	case l >= slog.LevelInfo:
+		return colorBlue
+	default:
+		return colorGray
+	}
+}
+
+func callerPretty(f *runtime.Frame) string {
+	if f == nil {
+		return "???"
+	}
+	return fmt.Sprintf("%s:%d", path.Base(f.File), f.Line)
+}
+
+func getXLogContainerFromCtx(ctx context.Context) map[string]any {
+	kvContainer, ok := ctx.Value(XLogCtxKVContainerKey).(map[string]any)
+	if !ok || kvContainer == nil {
+		return nil
+	}
+	return kvContainer
+}
@@ -0,0 +1,123 @@
+package xlog
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// packageLevels 按调用方所在包设置的日志级别（XLog.Levels）
+type packageLevels struct {
+	entries []packageLevel // 按 key 长度降序，最长匹配优先
+	min     slog.Level
+	cache   sync.Map // 调用方函数名 -> packageLevelResult
+}
+
+type packageLevel struct {
+	pkg   string
+	level slog.Level
+}
+
+type packageLevelResult struct {
+	level slog.Level
+	ok    bool
+}
+
+// newPackageLevels 解析 XLog.Levels，未配置时返回 nil
+func newPackageLevels(levels map[string]string) (*packageLevels, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+	p := &packageLevels{entries: make([]packageLevel, 0, len(levels)), min: LevelPanic}
+	for pkg, s := range levels {
+		l, err := logrus.ParseLevel(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level [%s] for package [%s]", s, pkg)
+		}
+		pkg = strings.Trim(pkg, "/")
+		level := toSlogLevel(l)
+		p.entries = append(p.entries, packageLevel{pkg: pkg, level: level})
+		p.min = min(p.min, level)
+	}
+	sort.Slice(p.entries, func(i, j int) bool {
+		if len(p.entries[i].pkg) != len(p.entries[j].pkg) {
+			return len(p.entries[i].pkg) > len(p.entries[j].pkg)
+		}
+		return p.entries[i].pkg < p.entries[j].pkg
+	})
+	return p, nil
+}
+
+// resolve 返回调用方所在包配置的级别，未匹配时 ok 为 false
+func (p *packageLevels) resolve(frame *runtime.Frame) (slog.Level, bool) {
+	if p == nil || frame == nil || frame.Function == "" {
+		return 0, false
+	}
+	if r, ok := p.cache.Load(frame.Function); ok {
+		res := r.(packageLevelResult)
+		return res.level, res.ok
+	}
+
+	pkg := funcPackage(frame.Function)
+	var res packageLevelResult
+	for _, e := range p.entries {
+		if matchPackage(pkg, e.pkg) {
+			res = packageLevelResult{level: e.level, ok: true}
+			break
+		}
+	}
+	p.cache.Store(frame.Function, res)
+	return res.level, res.ok
+}
+
+// funcPackage 从函数全名提取包路径，如 github.com/a/b.(*T).M -> github.com/a/b
+func funcPackage(fn string) string {
+	slash := strings.LastIndexByte(fn, '/') + 1
+	if dot := strings.IndexByte(fn[slash:], '.'); dot >= 0 {
+		return fn[:slash+dot]
+	}
+	return fn
+}
+
+// matchPackage key 匹配包路径末尾的若干段，同时匹配其子包
+// 如 key "mypkg/billing" 匹配 example.com/mypkg/billing 与 example.com/mypkg/billing/invoice
+func matchPackage(pkg, key string) bool {
+	if key == "" {
+		return false
+	}
+	for {
+		if pkg == key || strings.HasSuffix(pkg, "/"+key) {
+			return true
+		}
+		i := strings.LastIndexByte(pkg, '/')
+		if i < 0 {
+			return false
+		}
+		pkg = pkg[:i]
+	}
+}
+
+// toLogrusLevel slog 级别转为 logrus 级别
+func toLogrusLevel(l slog.Level) logrus.Level {
+	switch {
+	case l >= LevelPanic:
+		return logrus.PanicLevel
+	case l >= LevelFatal:
+		return logrus.FatalLevel
+	case l >= slog.LevelError:
+		return logrus.ErrorLevel
+	case l >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case l >= slog.LevelInfo:
+		return logrus.InfoLevel
+	case l >= slog.LevelDebug:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
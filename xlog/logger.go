@@ -0,0 +1,116 @@
+package xlog
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+func ErrorS(ctx context.Context, msg string, fields ...Field) {
+	logS(ctx, logrus.ErrorLevel, msg, nil, fields)
+}
+
+func WarnS(ctx context.Context, msg string, fields ...Field) {
+	logS(ctx, logrus.WarnLevel, msg, nil, fields)
+}
+
+func InfoS(ctx context.Context, msg string, fields ...Field) {
+	logS(ctx, logrus.InfoLevel, msg, nil, fields)
+}
+
+func DebugS(ctx context.Context, msg string, fields ...Field) {
+	logS(ctx, logrus.DebugLevel, msg, nil, fields)
+}
+
+// LogS 结构化日志，msg 不做格式化，字段按类型传入（xlog.String/Int/Err 等），日志不输出时字段不求值（例外见 Field）
+func LogS(ctx context.Context, level logrus.Level, msg string, fields ...Field) {
+	logS(ctx, level, msg, nil, fields)
+}
+
+// Logger 绑定 ctx 与公共字段的子 logger
+type Logger struct {
+	ctx    context.Context
+	fields []Field
+}
+
+// With 创建绑定 ctx 与公共字段的子 logger，之后每条日志都带上这些字段（同名字段以单条日志传入的为准）
+func With(ctx context.Context, fields ...Field) *Logger {
+	return &Logger{ctx: ctx, fields: fields}
+}
+
+// With 在当前字段基础上追加字段，返回新的子 logger
+func (l *Logger) With(fields ...Field) *Logger {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	return &Logger{ctx: l.ctx, fields: append(merged, fields...)}
+}
+
+// Ctx 返回绑定的 ctx
+func (l *Logger) Ctx() context.Context {
+	return l.ctx
+}
+
+func (l *Logger) Error(msg string, fields ...Field) {
+	logS(l.ctx, logrus.ErrorLevel, msg, l.fields, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...Field) {
+	logS(l.ctx, logrus.WarnLevel, msg, l.fields, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...Field) {
+	logS(l.ctx, logrus.InfoLevel, msg, l.fields, fields)
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) {
+	logS(l.ctx, logrus.DebugLevel, msg, l.fields, fields)
+}
+
+func (l *Logger) Log(level logrus.Level, msg string, fields ...Field) {
+	logS(l.ctx, level, msg, l.fields, fields)
+}
+
+func logS(ctx context.Context, level logrus.Level, msg string, bound, fields []Field) {
+	if ctx == nil {
+		return
+	}
+	logger := logrus.StandardLogger()
+	if !logger.IsLevelEnabled(level) {
+		return
+	}
+
+	// logrus 级别会因包级别、请求级 debug 而调低，这里按 Handler 的实际规则与采样提前过滤，被丢弃的日志不分配、不求值
+	// 该级别注册了其他 hook（如 xmetric）时不提前过滤，交由 xLogHook 处理，保证这些 hook 仍能收到日志
+	now := time.Now()
+	var caller *runtime.Frame
+	admitted := false
+	if core := activeCore.Load(); core != nil && !hasExternalHook(level) {
+		l := toSlogLevel(level)
+		if l < core.minLevel() && !core.debugEnabled(ctx, l) {
+			return
+		}
+		caller = xutil.GetLogCaller(-2, findFrameIgnoreFileNames)
+		if !core.admit(ctx, l, msg, msg, caller, now) {
+			return
+		}
+		admitted = true
+	}
+
+	// 字段 + xLogHook 补充的公共字段
+	data := make(logrus.Fields, len(bound)+len(fields)+9)
+	for _, f := range bound {
+		f.addTo(data)
+	}
+	for _, f := range fields {
+		f.addTo(data)
+	}
+	if admitted {
+		data[xlogAdmittedKey] = caller
+	}
+	entry := &logrus.Entry{Logger: logger, Data: data, Context: ctx, Time: now}
+	entry.Log(level, msg)
+}
@@ -0,0 +1,56 @@
+package xlog
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xiaoshicae/xone/v2/xmetric"
+)
+
+var (
+	metricOnce   sync.Once
+	droppedLines *prometheus.CounterVec
+	sampledLines *prometheus.CounterVec
+)
+
+// initMetricCollectors 首次丢弃日志时注册指标，此时 xmetric 已完成初始化，namespace 与常量标签生效
+func initMetricCollectors() {
+	metricOnce.Do(func() {
+		cfg := xmetric.GetConfig()
+
+		dropped := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   "log",
+			Name:        "dropped_lines_total",
+			Help:        "异步写入缓冲区满时丢弃的日志条数（按输出）",
+			ConstLabels: xmetric.GetConstLabels(),
+		}, []string{"output"})
+		if rc, ok := xmetric.SafeRegister(dropped).(*prometheus.CounterVec); ok {
+			dropped = rc
+		}
+		droppedLines = dropped
+
+		sampled := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   "log",
+			Name:        "sampled_lines_total",
+			Help:        "被采样丢弃的日志条数（按级别）",
+			ConstLabels: xmetric.GetConstLabels(),
+		}, []string{"level"})
+		if rc, ok := xmetric.SafeRegister(sampled).(*prometheus.CounterVec); ok {
+			sampled = rc
+		}
+		sampledLines = sampled
+	})
+}
+
+func droppedLinesCounter(output string) prometheus.Counter {
+	initMetricCollectors()
+	return droppedLines.WithLabelValues(output)
+}
+
+func recordSampled(level slog.Level) {
+	initMetricCollectors()
+	sampledLines.WithLabelValues(levelText(level)).Inc()
+}
@@ -0,0 +1,329 @@
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outputTypeFile   = "file"
+	outputTypeStdout = "stdout"
+	outputTypeStderr = "stderr"
+	outputTypeSyslog = "syslog"
+
+	logFormatNameJSON   = "json"
+	logFormatNameLogfmt = "logfmt"
+	logFormatNameText   = "text"
+)
+
+type logFormat int
+
+const (
+	logFormatJSON logFormat = iota
+	logFormatLogfmt
+	logFormatText
+	logFormatCount
+)
+
+var logFormats = map[string]logFormat{
+	logFormatNameJSON:   logFormatJSON,
+	logFormatNameLogfmt: logFormatLogfmt,
+	logFormatNameText:   logFormatText,
+}
+
+// output 单路日志输出
+type output struct {
+	name     string
+	level    slog.Level
+	hasLevel bool // 为 false 时不额外过滤级别
+	format   logFormat
+	writer   io.Writer
+	syslog   *syslogHeader // syslog 类型时为每行添加 RFC 5424 头
+}
+
+func (o *output) enabled(l slog.Level) bool {
+	return !o.hasLevel || l >= o.level
+}
+
+// newOutput 按配置创建日志输出，返回的 io.Closer 需在退出时关闭（stdout/stderr 为 nil），loc 为日志文件切割与命名的时区
+func newOutput(c OutputConfig, serverName string, bufferSize int, loc *time.Location) (*output, io.Closer, error) {
+	o := &output{name: c.Name}
+
+	overflow := strings.ToLower(c.Overflow)
+	switch overflow {
+	case "", overflowBlock, overflowDropNewest, overflowDropOldest:
+	default:
+		return nil, nil, fmt.Errorf("output [%s] unsupported overflow [%s], must be one of block/drop_newest/drop_oldest", c.Name, c.Overflow)
+	}
+
+	format, ok := logFormats[strings.ToLower(c.Format)]
+	if !ok {
+		return nil, nil, fmt.Errorf("output [%s] unsupported format [%s], must be one of json/logfmt/text", c.Name, c.Format)
+	}
+	o.format = format
+
+	if c.Level != "" {
+		l, err := logrus.ParseLevel(c.Level)
+		if err != nil {
+			return nil, nil, fmt.Errorf("output [%s] invalid level [%s]", c.Name, c.Level)
+		}
+		o.level, o.hasLevel = toSlogLevel(l), true
+	}
+
+	switch strings.ToLower(c.Type) {
+	case outputTypeFile:
+		w, err := newFileWriter(c, loc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("output [%s] %v", c.Name, err)
+		}
+		aw := newAsyncWriter(w, bufferSize).withOverflow(overflow, c.Name)
+		o.writer = aw
+		return o, aw, nil
+	case outputTypeStdout:
+		o.writer = os.Stdout
+		return o, nil, nil
+	case outputTypeStderr:
+		o.writer = os.Stderr
+		return o, nil, nil
+	case outputTypeSyslog:
+		h, err := newSyslogHeader(c.Facility, serverName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("output [%s] %v", c.Name, err)
+		}
+		if c.Address == "" {
+			return nil, nil, fmt.Errorf("syslog output [%s] requires Address", c.Name)
+		}
+		network := strings.ToLower(c.Network)
+		if network != "tcp" && network != "udp" {
+			return nil, nil, fmt.Errorf("syslog output [%s] unsupported network [%s], must be tcp or udp", c.Name, c.Network)
+		}
+		aw := newAsyncWriter(&netWriter{network: network, address: c.Address}, bufferSize).withOverflow(overflow, c.Name)
+		o.writer, o.syslog = aw, h
+		return o, aw, nil
+	default:
+		return nil, nil, fmt.Errorf("output [%s] unsupported type [%s], must be one of file/stdout/stderr/syslog", c.Name, c.Type)
+	}
+}
+
+// newFileWriter 创建日志文件 <Path>/<Name>.log（指向当前文件的软链接），按时间与大小切割
+func newFileWriter(c OutputConfig, loc *time.Location) (io.WriteCloser, error) {
+	return NewRotateWriter(RotateConfig{
+		Path:        c.Path,
+		Name:        c.Name,
+		RotateTime:  xutil.ToDuration(c.RotateTime),
+		MaxSize:     int64(c.MaxSize) << 20,
+		MaxAge:      xutil.ToDuration(c.MaxAge),
+		MaxBackups:  c.MaxBackups,
+		Compress:    c.Compress != nil && *c.Compress,
+		FilePattern: c.FilePattern,
+		Location:    loc,
+	})
+}
+
+// encode 按格式编码日志行（以换行结尾）
+func encode(format logFormat, level slog.Level, t time.Time, msg string, data map[string]any, caller string) ([]byte, error) {
+	switch format {
+	case logFormatLogfmt:
+		return formatLogfmt(level, t, msg, data), nil
+	case logFormatText:
+		return formatText(level, t, msg, data, caller), nil
+	default:
+		return formatJSON(level, t, msg, data)
+	}
+}
+
+// formatLogfmt 输出 time/level/msg 后按 key 排序输出其余字段，含空格、引号、等号的值加引号
+func formatLogfmt(level slog.Level, t time.Time, msg string, data map[string]any) []byte {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := make([]byte, 0, 256)
+	b = appendLogfmt(b, "time", t.Format(logTimeFormat))
+	b = appendLogfmt(b, "level", levelText(level))
+	b = appendLogfmt(b, "msg", msg)
+	for _, k := range keys {
+		key := k
+		switch k {
+		case "time", "level", "msg":
+			key = "fields." + k
+		}
+		b = appendLogfmt(b, key, logfmtValue(data[k]))
+	}
+	b[len(b)-1] = '\n'
+	return b
+}
+
+func appendLogfmt(b []byte, k, v string) []byte {
+	b = append(b, k...)
+	b = append(b, '=')
+	if needsQuote(v) {
+		b = strconv.AppendQuote(b, v)
+	} else {
+		b = append(b, v...)
+	}
+	return append(b, ' ')
+}
+
+func logfmtValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatText 与控制台格式一致（无颜色）：[LEVEL][time] filename:line traceid msg
+func formatText(level slog.Level, t time.Time, msg string, data map[string]any, caller string) []byte {
+	b := fmt.Appendf(make([]byte, 0, 256), "[%s][%s] %s %s %s\n",
+		strings.ToUpper(levelText(level)), t.Format(logTimeFormat), caller, data["traceid"], msg)
+	if panicStack, ok := data["panic_stack"]; ok && panicStack != nil {
+		b = fmt.Appendf(b, "%s\n", panicStack)
+	}
+	return b
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogHeader RFC 5424 头：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - -
+type syslogHeader struct {
+	facility int
+	hostname string
+	appName  string
+	pid      string
+}
+
+func newSyslogHeader(facility, serverName string) (*syslogHeader, error) {
+	f, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported syslog facility [%s]", facility)
+	}
+	hostname, _ := os.Hostname()
+	return &syslogHeader{
+		facility: f,
+		hostname: syslogField(hostname),
+		appName:  syslogField(serverName),
+		pid:      strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+func (h *syslogHeader) append(b []byte, level slog.Level, t time.Time) []byte {
+	b = fmt.Appendf(b, "<%d>1 ", h.facility*8+syslogSeverity(level))
+	b = t.AppendFormat(b, time.RFC3339Nano)
+	return fmt.Appendf(b, " %s %s %s - - ", h.hostname, h.appName, h.pid)
+}
+
+// syslogField 空值以 "-" 表示，空白替换为 "_"
+func syslogField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func syslogSeverity(l slog.Level) int {
+	switch {
+	case l >= LevelFatal:
+		return 2 // crit
+	case l >= slog.LevelError:
+		return 3 // err
+	case l >= slog.LevelWarn:
+		return 4 // warning
+	case l >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// netWriter 网络日志写入，首次写入时建立连接，写入失败时重连重试一次
+type netWriter struct {
+	network string
+	address string
+	mu      sync.Mutex
+	conn    net.Conn
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for range 2 {
+		if w.conn == nil {
+			if w.conn, err = net.DialTimeout(w.network, w.address, 3*time.Second); err != nil {
+				w.conn = nil
+				continue
+			}
+		}
+		var n int
+		if n, err = w.conn.Write(p); err == nil {
+			return n, nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// closeAll 依次关闭，返回所有错误
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,535 @@
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	remoteTypeOTLP = "otlp"
+	remoteTypeLoki = "loki"
+	remoteTypeHTTP = "http"
+
+	remoteFormatJSON   = "json"
+	remoteFormatNDJSON = "ndjson"
+
+	// maxRemoteRetryBackoff 重试间隔上限
+	maxRemoteRetryBackoff = 10 * time.Second
+
+	// remoteCloseTimeout Close 时发送剩余日志的总时限，超时未发送的日志计为丢弃
+	// 多个投递依次关闭，需保证总耗时在 xhook 的停止超时内
+	remoteCloseTimeout = 5 * time.Second
+)
+
+// remoteRecord 待投递的日志，data 为写入时的快照
+type remoteRecord struct {
+	level slog.Level
+	time  time.Time
+	msg   string
+	data  map[string]any
+}
+
+// remoteEncoder 将一批日志编码为请求 body
+type remoteEncoder func(batch []*remoteRecord) (body []byte, contentType string, err error)
+
+// remoteSink 远程日志投递：日志写入有界缓冲，后台按 BatchSize/FlushInterval 批量发送，失败按指数退避重试
+// 发送使用独立的 http.Client，不经过 xhttp（避免投递过程中产生的日志与链路再次进入投递）
+type remoteSink struct {
+	name          string
+	level         slog.Level
+	hasLevel      bool
+	endpoint      string
+	headers       map[string]string
+	encode        remoteEncoder
+	client        *http.Client
+	batchSize     int
+	bufferSize    int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+
+	mu     sync.Mutex
+	ring   []*remoteRecord // 环形缓冲，容量为 BufferSize
+	head   int             // 最早一条日志的下标
+	size   int             // 缓冲中的日志条数
+	closed bool
+
+	notify      chan struct{} // 缓冲达到 BatchSize 时通知发送
+	stop        chan struct{}
+	done        chan struct{}
+	dropped     atomic.Uint64
+	dropCounter func() prometheus.Counter
+}
+
+// newRemoteSink 按配置创建远程投递并启动后台发送，退出时需调用 Close 发送剩余日志
+func newRemoteSink(c RemoteConfig, core *handlerCore) (*remoteSink, error) {
+	s := &remoteSink{
+		name:          c.Name,
+		headers:       c.Headers,
+		client:        &http.Client{Timeout: xutil.ToDuration(c.Timeout)},
+		batchSize:     c.BatchSize,
+		bufferSize:    c.BufferSize,
+		flushInterval: xutil.ToDuration(c.FlushInterval),
+		maxRetries:    c.MaxRetries,
+		retryBackoff:  xutil.ToDuration(c.RetryBackoff),
+		notify:        make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if s.batchSize <= 0 || s.bufferSize <= 0 || s.flushInterval <= 0 {
+		return nil, fmt.Errorf("remote [%s] BatchSize, BufferSize and FlushInterval must be positive", c.Name)
+	}
+	s.ring = make([]*remoteRecord, s.bufferSize)
+	if c.Level != "" {
+		l, err := logrus.ParseLevel(c.Level)
+		if err != nil {
+			return nil, fmt.Errorf("remote [%s] invalid level [%s]", c.Name, c.Level)
+		}
+		s.level, s.hasLevel = toSlogLevel(l), true
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("remote [%s] invalid Endpoint [%s]", c.Name, c.Endpoint)
+	}
+	switch strings.ToLower(c.Type) {
+	case remoteTypeOTLP:
+		s.endpoint = withDefaultPath(u, "/v1/logs")
+		s.encode = otlpEncoder(core)
+	case remoteTypeLoki:
+		s.endpoint = withDefaultPath(u, "/loki/api/v1/push")
+		s.encode = lokiEncoder(core.serverName, c.Labels)
+	case remoteTypeHTTP:
+		s.endpoint = u.String()
+		switch strings.ToLower(c.Format) {
+		case remoteFormatJSON:
+			s.encode = httpEncoder(false)
+		case remoteFormatNDJSON:
+			s.encode = httpEncoder(true)
+		default:
+			return nil, fmt.Errorf("remote [%s] unsupported format [%s], must be json or ndjson", c.Name, c.Format)
+		}
+	default:
+		return nil, fmt.Errorf("remote [%s] unsupported type [%s], must be one of otlp/loki/http", c.Name, c.Type)
+	}
+
+	s.dropCounter = sync.OnceValue(func() prometheus.Counter {
+		return droppedLinesCounter(s.name)
+	})
+	go s.loop()
+	return s, nil
+}
+
+func withDefaultPath(u *url.URL, p string) string {
+	if u.Path == "" || u.Path == "/" {
+		u.Path = p
+	}
+	return u.String()
+}
+
+func (s *remoteSink) enabled(l slog.Level) bool {
+	return !s.hasLevel || l >= s.level
+}
+
+// Dropped 因缓冲区满或发送失败丢弃的日志条数
+func (s *remoteSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// enqueue 写入缓冲，缓冲区满时丢弃最早的日志
+func (s *remoteSink) enqueue(r *remoteRecord) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.drop(1)
+		return
+	}
+	dropped := s.size == len(s.ring)
+	if dropped {
+		s.head = (s.head + 1) % len(s.ring)
+		s.size--
+	}
+	s.ring[(s.head+s.size)%len(s.ring)] = r
+	s.size++
+	full := s.size >= s.batchSize
+	s.mu.Unlock()
+
+	if dropped {
+		s.drop(1)
+	}
+	if full {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take 从缓冲头部取出至多 BatchSize 条日志
+func (s *remoteSink) take() []*remoteRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(s.size, s.batchSize)
+	if n == 0 {
+		return nil
+	}
+	batch := make([]*remoteRecord, n)
+	for i := range batch {
+		batch[i] = s.ring[s.head]
+		s.ring[s.head] = nil
+		s.head = (s.head + 1) % len(s.ring)
+	}
+	s.size -= n
+	return batch
+}
+
+func (s *remoteSink) drop(n int) {
+	s.dropped.Add(uint64(n))
+	if s.dropCounter != nil {
+		s.dropCounter().Add(float64(n))
+	}
+}
+
+func (s *remoteSink) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(context.Background())
+		case <-s.notify:
+			s.flush(context.Background())
+		case <-s.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), remoteCloseTimeout)
+			s.flush(ctx)
+			cancel()
+			return
+		}
+	}
+}
+
+// flush 按 BatchSize 分批发送缓冲中的全部日志，ctx 结束后剩余的日志计为丢弃
+func (s *remoteSink) flush(ctx context.Context) {
+	for {
+		batch := s.take()
+		if len(batch) == 0 {
+			return
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = s.send(ctx, batch)
+		}
+		if err != nil {
+			s.drop(len(batch))
+			// 不能经 xlog 输出，避免失败日志再次进入投递
+			xutil.WarnIfEnableDebug("XOne xlog remote [%s] send %d logs failed, err=[%v]", s.name, len(batch), err)
+		}
+	}
+}
+
+// send 发送一批日志，网络错误、429 与 5xx 按指数退避重试 MaxRetries 次
+// Close 后不再重试与等待，尽快发送剩余日志
+func (s *remoteSink) send(ctx context.Context, batch []*remoteRecord) error {
+	body, contentType, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+	delay := s.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.maxRetries || s.stopping() {
+			return err
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-s.stop:
+				timer.Stop()
+				return err
+			}
+			delay = min(delay*2, maxRemoteRetryBackoff)
+		}
+	}
+}
+
+// stopping 是否已调用 Close
+func (s *remoteSink) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *remoteSink) post(ctx context.Context, body []byte, contentType string) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// Close 停止接收日志，并在 remoteCloseTimeout 内发送缓冲中剩余的日志
+func (s *remoteSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// otlpEncoder OTLP/HTTP JSON 编码（https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding）
+// servername/ip/pid 作为 resource 属性，traceid/spanid 写入 traceId/spanId 用于关联链路，其余字段作为日志属性
+func otlpEncoder(core *handlerCore) remoteEncoder {
+	hostname, _ := os.Hostname()
+	pid, _ := strconv.Atoi(core.pid)
+	resource := otlpResource{Attributes: []otlpKeyValue{
+		{Key: "service.name", Value: otlpValue{StringValue: &core.serverName}},
+		{Key: "host.name", Value: otlpValue{StringValue: &hostname}},
+		{Key: "host.ip", Value: otlpValue{StringValue: &core.ip}},
+		{Key: "process.pid", Value: otlpAnyValue(pid)},
+	}}
+
+	return func(batch []*remoteRecord) ([]byte, string, error) {
+		records := make([]otlpLogRecord, 0, len(batch))
+		for _, r := range batch {
+			msg := r.msg
+			lr := otlpLogRecord{
+				TimeUnixNano:         strconv.FormatInt(r.time.UnixNano(), 10),
+				ObservedTimeUnixNano: strconv.FormatInt(r.time.UnixNano(), 10),
+				SeverityNumber:       otlpSeverity(r.level),
+				SeverityText:         strings.ToUpper(levelText(r.level)),
+				Body:                 otlpValue{StringValue: &msg},
+			}
+			keys := make([]string, 0, len(r.data))
+			for k, v := range r.data {
+				switch k {
+				case "servername", "ip", "pid":
+				case "traceid":
+					lr.TraceID, _ = v.(string)
+				case "spanid":
+					lr.SpanID, _ = v.(string)
+				default:
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				lr.Attributes = append(lr.Attributes, otlpKeyValue{Key: k, Value: otlpAnyValue(r.data[k])})
+			}
+			records = append(records, lr)
+		}
+
+		body, err := json.Marshal(otlpLogsData{ResourceLogs: []otlpResourceLogs{{
+			Resource:  resource,
+			ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: "xlog"}, LogRecords: records}},
+		}}})
+		return body, "application/json", err
+	}
+}
+
+type otlpLogsData struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpValue      `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 按 JSON 编码规范使用字符串
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func otlpAnyValue(v any) otlpValue {
+	switch val := v.(type) {
+	case string:
+		return otlpValue{StringValue: &val}
+	case bool:
+		return otlpValue{BoolValue: &val}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprint(val)
+		return otlpValue{IntValue: &s}
+	case float32:
+		f := float64(val)
+		return otlpValue{DoubleValue: &f}
+	case float64:
+		return otlpValue{DoubleValue: &val}
+	case error:
+		s := val.Error()
+		return otlpValue{StringValue: &s}
+	default:
+		s := fmt.Sprint(val)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+// otlpSeverity 对应 OTel 日志数据模型的 SeverityNumber
+func otlpSeverity(l slog.Level) int {
+	switch {
+	case l >= LevelPanic:
+		return 24 // FATAL4
+	case l >= LevelFatal:
+		return 21 // FATAL
+	case l >= slog.LevelError:
+		return 17 // ERROR
+	case l >= slog.LevelWarn:
+		return 13 // WARN
+	case l >= slog.LevelInfo:
+		return 9 // INFO
+	case l >= slog.LevelDebug:
+		return 5 // DEBUG
+	default:
+		return 1 // TRACE
+	}
+}
+
+// lokiEncoder Loki push API JSON 编码，按 servername + level（及配置的 Labels）分 stream，日志行为 JSON 格式
+func lokiEncoder(serverName string, labels map[string]string) remoteEncoder {
+	return func(batch []*remoteRecord) ([]byte, string, error) {
+		streams := make(map[string]*lokiStream)
+		var order []string
+		for _, r := range batch {
+			level := levelText(r.level)
+			line, err := formatJSON(r.level, r.time, r.msg, r.data)
+			if err != nil {
+				return nil, "", err
+			}
+			st, ok := streams[level]
+			if !ok {
+				stream := make(map[string]string, len(labels)+2)
+				maps.Copy(stream, labels)
+				stream["servername"] = serverName
+				stream["level"] = level
+				st = &lokiStream{Stream: stream}
+				streams[level] = st
+				order = append(order, level)
+			}
+			st.Values = append(st.Values, [2]string{strconv.FormatInt(r.time.UnixNano(), 10), string(bytes.TrimSuffix(line, []byte("\n")))})
+		}
+
+		req := lokiPushRequest{Streams: make([]*lokiStream, 0, len(order))}
+		for _, level := range order {
+			req.Streams = append(req.Streams, streams[level])
+		}
+		body, err := json.Marshal(req)
+		return body, "application/json", err
+	}
+}
+
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// httpEncoder 通用 HTTP 批量编码：JSON 数组或 NDJSON，每条日志与文件中的 JSON 格式一致
+func httpEncoder(ndjson bool) remoteEncoder {
+	return func(batch []*remoteRecord) ([]byte, string, error) {
+		var b bytes.Buffer
+		if !ndjson {
+			b.WriteByte('[')
+		}
+		for i, r := range batch {
+			line, err := formatJSON(r.level, r.time, r.msg, r.data)
+			if err != nil {
+				return nil, "", err
+			}
+			if ndjson {
+				b.Write(line)
+				continue
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(bytes.TrimSuffix(line, []byte("\n")))
+		}
+		if ndjson {
+			return b.Bytes(), "application/x-ndjson", nil
+		}
+		b.WriteByte(']')
+		return b.Bytes(), "application/json", nil
+	}
+}
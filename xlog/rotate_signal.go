@@ -0,0 +1,11 @@
+//go:build !windows
+
+package xlog
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals 触发 ReopenLogFiles 的信号
+var reopenSignals = []os.Signal{syscall.SIGUSR1}
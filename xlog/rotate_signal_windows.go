@@ -0,0 +1,6 @@
+package xlog
+
+import "os"
+
+// reopenSignals Windows 不支持 SIGUSR1，需手动调用 ReopenLogFiles
+var reopenSignals []os.Signal
@@ -0,0 +1,449 @@
+package xlog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+)
+
+const defaultFilePattern = "%Y%m%d"
+
+var errRotateWriterClosed = errors.New("rotate writer is closed")
+
+// RotateConfig 日志文件切割配置
+type RotateConfig struct {
+	// Path 日志文件夹路径，不存在时自动创建
+	Path string
+
+	// Name 日志文件名称，当前文件为 <Path>/<Name>.log.<时间>[.<序号>]，<Path>/<Name>.log 为指向当前文件的软链接
+	Name string
+
+	// RotateTime 按时间切割的周期，按 Location 的本地时间对齐（如 1d 在本地零点切割），<= 0 时不按时间切割
+	RotateTime time.Duration
+
+	// MaxSize 单个文件最大字节数，超过后切割为 .1、.2 ...，<= 0 时不按大小切割
+	MaxSize int64
+
+	// MaxAge 历史文件保存时间，<= 0 时不按时间清理
+	MaxAge time.Duration
+
+	// MaxBackups 历史文件保留个数，<= 0 时不限制
+	MaxBackups int
+
+	// Compress 是否在后台 gzip 压缩历史文件
+	Compress bool
+
+	// FilePattern 文件名中的时间格式，支持 %Y %m %d %H %M %S，分隔符支持 - _ .，默认 "%Y%m%d"
+	FilePattern string
+
+	// Location 切割周期与文件名使用的时区，默认 time.Local
+	Location *time.Location
+}
+
+// RotateWriter 按时间与大小切割的日志文件，并发安全
+// 收到 SIGUSR1 时重新打开当前文件，配合外部 logrotate 使用
+type RotateWriter struct {
+	c        RotateConfig
+	layout   string
+	linkPath string
+	now      func() time.Time
+
+	mu         sync.Mutex
+	file       *os.File
+	filePath   string
+	size       int64
+	base       string // 当前周期的文件名（不含序号）
+	index      int    // 当前周期的序号，0 表示不带序号
+	nextRotate time.Time
+	closed     bool
+
+	millCh   chan struct{} // 触发后台清理与压缩
+	millDone chan struct{}
+}
+
+// NewRotateWriter 创建日志文件切割写入器，使用完需调用 Close
+func NewRotateWriter(c RotateConfig) (*RotateWriter, error) {
+	return newRotateWriter(c, time.Now)
+}
+
+func newRotateWriter(c RotateConfig, now func() time.Time) (*RotateWriter, error) {
+	if c.Name == "" {
+		return nil, errors.New("rotate writer requires Name")
+	}
+	layout, err := strftimeLayout(xutil.GetOrDefault(c.FilePattern, defaultFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	if c.Location == nil {
+		c.Location = time.Local
+	}
+	if !xutil.DirExist(c.Path) { // 日志所在文件夹不存在则创建
+		if err := os.MkdirAll(c.Path, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("os.MkdirAll failed, path=[%s], err=[%v]", c.Path, err)
+		}
+	}
+
+	w := &RotateWriter{
+		c:        c,
+		layout:   layout,
+		linkPath: filepath.Join(c.Path, c.Name+".log"),
+		now:      now,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := w.open(w.now(), false); err != nil {
+		return nil, err
+	}
+	go w.millLoop()
+	w.triggerMill()
+	registerRotateWriter(w)
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errRotateWriterClosed
+	}
+	now := w.now()
+	switch {
+	case !w.nextRotate.IsZero() && !now.Before(w.nextRotate):
+		if err := w.rotate(now, false); err != nil {
+			return 0, err
+		}
+	case w.c.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.c.MaxSize:
+		if err := w.rotate(now, true); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Reopen 关闭并重新打开当前文件，文件被外部移走时创建新文件
+func (w *RotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errRotateWriterClosed
+	}
+	_ = w.file.Close()
+	return w.open(w.now(), false)
+}
+
+// Close 关闭当前文件，等待后台清理与压缩结束
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.file.Close()
+	close(w.millCh)
+	w.mu.Unlock()
+
+	unregisterRotateWriter(w)
+	<-w.millDone
+	return err
+}
+
+// rotate 切换到新文件，bySize 为 true 时在当前周期内递增序号
+func (w *RotateWriter) rotate(now time.Time, bySize bool) error {
+	_ = w.file.Close()
+	if err := w.open(now, bySize); err != nil {
+		return err
+	}
+	w.triggerMill()
+	return nil
+}
+
+// open 按当前周期打开文件（追加写），跳过已写满或已压缩的序号，并更新软链接
+func (w *RotateWriter) open(now time.Time, next bool) error {
+	start := w.periodStart(now)
+	base := filepath.Join(w.c.Path, w.c.Name+".log."+start.Format(w.layout))
+	if base != w.base {
+		w.base, w.index = base, 0
+	} else if next {
+		w.index++
+	}
+	for {
+		p := w.indexPath(w.index)
+		if _, err := os.Stat(p + ".gz"); err == nil {
+			w.index++
+			continue
+		}
+		if info, err := os.Stat(p); err == nil && w.c.MaxSize > 0 && info.Size() >= w.c.MaxSize {
+			w.index++
+			continue
+		}
+		break
+	}
+
+	p := w.indexPath(w.index)
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file failed, path=[%s], err=[%v]", p, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file failed, path=[%s], err=[%v]", p, err)
+	}
+	w.file, w.filePath, w.size = f, p, info.Size()
+	if w.c.RotateTime > 0 {
+		w.nextRotate = start.Add(w.c.RotateTime)
+	}
+	return w.link()
+}
+
+func (w *RotateWriter) indexPath(index int) string {
+	if index == 0 {
+		return w.base
+	}
+	return fmt.Sprintf("%s.%d", w.base, index)
+}
+
+// periodStart 当前周期的起始时间，按 Location 的本地时间对齐
+func (w *RotateWriter) periodStart(now time.Time) time.Time {
+	t := now.In(w.c.Location)
+	if w.c.RotateTime <= 0 {
+		return t
+	}
+	_, offset := t.Zone()
+	local := t.UnixNano() + int64(offset)*int64(time.Second)
+	return time.Unix(0, local-local%int64(w.c.RotateTime)-int64(offset)*int64(time.Second)).In(w.c.Location)
+}
+
+// link 将 <Name>.log 指向当前文件（先创建临时链接再 rename，替换过程中软链接始终可用）
+func (w *RotateWriter) link() error {
+	tmp := w.linkPath + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(w.filePath), tmp); err != nil {
+		return fmt.Errorf("create log symlink failed, path=[%s], err=[%v]", w.linkPath, err)
+	}
+	if err := os.Rename(tmp, w.linkPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("create log symlink failed, path=[%s], err=[%v]", w.linkPath, err)
+	}
+	return nil
+}
+
+// triggerMill 通知后台清理与压缩，需持有 mu
+func (w *RotateWriter) triggerMill() {
+	if w.c.MaxAge <= 0 && w.c.MaxBackups <= 0 && !w.c.Compress {
+		return
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *RotateWriter) millLoop() {
+	defer close(w.millDone)
+	for range w.millCh {
+		w.mill()
+	}
+}
+
+type rotateBackup struct {
+	path    string
+	modTime time.Time
+}
+
+// mill 按 MaxAge、MaxBackups 删除历史文件，并压缩其余未压缩的历史文件
+func (w *RotateWriter) mill() {
+	// 先列目录再取当前文件：之后切割出的新文件不在本次列表中
+	entries, err := os.ReadDir(w.c.Path)
+	if err != nil {
+		xutil.WarnIfEnableDebug("XOne RotateWriter read dir [%s] failed, err=[%v]", w.c.Path, err)
+		return
+	}
+	w.mu.Lock()
+	current := w.filePath
+	w.mu.Unlock()
+
+	prefix := w.c.Name + ".log."
+	var backups []rotateBackup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !w.isBackupName(name[len(prefix):]) {
+			continue
+		}
+		p := filepath.Join(w.c.Path, name)
+		if p == current {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, rotateBackup{path: p, modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	cutoff := w.now().Add(-w.c.MaxAge)
+	for i, b := range backups {
+		if (w.c.MaxBackups > 0 && i >= w.c.MaxBackups) || (w.c.MaxAge > 0 && b.modTime.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil {
+				xutil.WarnIfEnableDebug("XOne RotateWriter remove [%s] failed, err=[%v]", b.path, err)
+			}
+			continue
+		}
+		if w.c.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				xutil.WarnIfEnableDebug("XOne RotateWriter compress [%s] failed, err=[%v]", b.path, err)
+			}
+		}
+	}
+}
+
+// isBackupName 文件名后缀是否为 <时间>[.<序号>][.gz]
+func (w *RotateWriter) isBackupName(s string) bool {
+	s = strings.TrimSuffix(s, ".gz")
+	if _, err := time.ParseInLocation(w.layout, s, w.c.Location); err == nil {
+		return true
+	}
+	i := strings.LastIndexByte(s, '.')
+	if i < 0 || i == len(s)-1 || strings.Trim(s[i+1:], "0123456789") != "" {
+		return false
+	}
+	_, err := time.ParseInLocation(w.layout, s[:i], w.c.Location)
+	return err == nil
+}
+
+// compressFile 压缩为 <src>.gz 并删除原文件，保留修改时间以便按 MaxAge 清理
+func compressFile(src string) (err error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := src + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	gw := gzip.NewWriter(out)
+	if _, err = io.Copy(gw, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, src+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+var strftimeVerbs = map[byte]string{
+	'Y': "2006",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+}
+
+// strftimeLayout 将 %Y%m%d 形式的文件名时间格式转换为 Go 时间 layout
+func strftimeLayout(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch ch {
+		case '-', '_', '.':
+			b.WriteByte(ch)
+		case '%':
+			if i+1 < len(pattern) {
+				if v, ok := strftimeVerbs[pattern[i+1]]; ok {
+					b.WriteString(v)
+					i++
+					continue
+				}
+			}
+			return "", fmt.Errorf("invalid FilePattern [%s], only %%Y %%m %%d %%H %%M %%S are supported", pattern)
+		default:
+			return "", fmt.Errorf("invalid FilePattern [%s], only - _ . are allowed as separators", pattern)
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("invalid FilePattern [%s]", pattern)
+	}
+	return b.String(), nil
+}
+
+var (
+	rotateWriters    sync.Map // *RotateWriter -> struct{}
+	reopenSignalOnce sync.Once
+)
+
+// ReopenLogFiles 重新打开所有 RotateWriter 的当前文件，外部 logrotate 移走日志文件后调用（收到 SIGUSR1 时自动调用）
+func ReopenLogFiles() error {
+	var errs []error
+	rotateWriters.Range(func(k, _ any) bool {
+		if err := k.(*RotateWriter).Reopen(); err != nil && !errors.Is(err, errRotateWriterClosed) {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+func registerRotateWriter(w *RotateWriter) {
+	rotateWriters.Store(w, struct{}{})
+	reopenSignalOnce.Do(func() {
+		if len(reopenSignals) == 0 {
+			return
+		}
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, reopenSignals...)
+		go func() {
+			for range ch {
+				if err := ReopenLogFiles(); err != nil {
+					xutil.WarnIfEnableDebug("XOne reopen log files failed, err=[%v]", err)
+				}
+			}
+		}()
+	})
+}
+
+func unregisterRotateWriter(w *RotateWriter) {
+	rotateWriters.Delete(w)
+}
@@ -0,0 +1,111 @@
+package xlog
+
+import (
+	"fmt"
+	"hash/maphash"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiaoshicae/xone/v2/xutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// samplerSlots 采样计数槽数量，不同 key 哈希冲突时共享计数（与 zap 的做法一致，以少量误差换取无锁与固定内存）
+const samplerSlots = 4096
+
+// xlogTemplateKey RawLog 向 logrus entry 传递格式化前的日志模板，供采样按模板聚合，输出前移除
+const xlogTemplateKey = "__xlog_template__"
+
+// xlogAdmittedKey xlog.InfoS 等已完成级别判断、错误聚合与采样时，向 logrus entry 传递调用方（*runtime.Frame），输出前移除
+const xlogAdmittedKey = "__xlog_admitted__"
+
+// sampler 日志采样：同一 key（级别 + 日志模板 + 调用位置）每个周期内前 First 条全部保留，之后每 Thereafter 条保留 1 条
+// 不低于 KeepLevel 的日志不采样
+type sampler struct {
+	interval   time.Duration
+	first      uint64
+	thereafter uint64
+	keepLevel  slog.Level
+	seed       maphash.Seed
+	counters   [samplerSlots]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt atomic.Int64
+	n       atomic.Uint64
+}
+
+// samplingEnabled 当前是否开启了日志采样
+func samplingEnabled() bool {
+	c := activeCore.Load()
+	return c != nil && c.sampler != nil
+}
+
+// newSampler 按配置创建采样器，未配置时返回 nil
+func newSampler(c *SamplingConfig) (*sampler, error) {
+	if c == nil {
+		return nil, nil
+	}
+	keep, err := logrus.ParseLevel(c.KeepLevel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sampling.KeepLevel [%s]", c.KeepLevel)
+	}
+	interval := xutil.ToDuration(c.Interval)
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid Sampling.Interval [%s]", c.Interval)
+	}
+	if c.First < 0 || c.Thereafter < 0 {
+		return nil, fmt.Errorf("Sampling.First and Sampling.Thereafter must not be negative")
+	}
+	return &sampler{
+		interval:   interval,
+		first:      uint64(c.First),
+		thereafter: uint64(c.Thereafter),
+		keepLevel:  toSlogLevel(keep),
+		seed:       maphash.MakeSeed(),
+	}, nil
+}
+
+// keep 判断日志是否保留，被丢弃时计入 xmetric 指标 log_sampled_lines_total{level}
+func (s *sampler) keep(level slog.Level, template string, frame *runtime.Frame, t time.Time) bool {
+	if s == nil || level >= s.keepLevel {
+		return true
+	}
+
+	var (
+		h   maphash.Hash
+		buf [20]byte
+	)
+	h.SetSeed(s.seed)
+	_ = h.WriteByte(byte(level))
+	_, _ = h.WriteString(template)
+	if frame != nil {
+		_, _ = h.WriteString(frame.File)
+		_, _ = h.Write(strconv.AppendInt(buf[:0], int64(frame.Line), 10))
+	}
+
+	n := s.counters[h.Sum64()%samplerSlots].inc(t.UnixNano(), s.interval)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+	recordSampled(level)
+	return false
+}
+
+// inc 计数 +1，超过周期时重置
+func (c *samplerCounter) inc(now int64, interval time.Duration) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.n.Add(1)
+	}
+	c.n.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now+interval.Nanoseconds()) {
+		// 其他 goroutine 已重置
+		return c.n.Add(1)
+	}
+	return 1
+}
@@ -50,8 +50,15 @@ func RawLog(ctx context.Context, level logrus.Level, msg string, args ...any) {
 		}
 	}
 
+	// 开启采样时传递格式化前的模板，使同一模板不同参数的日志聚合采样
+	withTemplate := len(logArgs) > 0 && samplingEnabled()
+
 	if len(opts) == 0 {
 		// 无 Option，直接用 logArgs 格式化输出
+		if withTemplate {
+			logrus.WithContext(ctx).WithField(xlogTemplateKey, msg).Logf(level, msg, logArgs...)
+			return
+		}
 		logrus.WithContext(ctx).Logf(level, msg, logArgs...)
 		return
 	}
@@ -65,6 +72,9 @@ func RawLog(ctx context.Context, level logrus.Level, msg string, args ...any) {
 	for k, v := range dos.KV {
 		fields[k] = v
 	}
+	if withTemplate {
+		fields[xlogTemplateKey] = msg
+	}
 
 	logrus.WithContext(ctx).WithFields(fields).Logf(level, msg, logArgs...)
 }
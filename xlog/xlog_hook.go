@@ -1,36 +1,22 @@
 package xlog
 
 import (
-	"context"
-	"fmt"
-	"io"
-	"path"
 	"runtime"
-	"strconv"
-	"strings"
-	"time"
+	"sync/atomic"
 
 	"github.com/xiaoshicae/xone/v2/xutil"
 
 	"github.com/sirupsen/logrus"
 )
 
-// 控制台颜色
-const (
-	colorRed    = 31
-	colorYellow = 33
-	colorBlue   = 36
-	colorGray   = 37
-)
-
+// xLogHook logrus 到 slog Handler 的桥接
+// 作为第一个 hook 将公共字段写入 entry.Data（xmetric 等后续 hook 依赖 filename/lineid/traceid），再交给 Handler 输出
 type xLogHook struct {
-	IP                 string
-	ServerName         string
-	PidStr             string // 缓存 Pid 字符串，避免重复转换
-	SuffixToIgnore     []string
-	Console            bool
-	ConsoleFormatIsRaw bool
-	Writer             io.Writer
+	IP             string
+	ServerName     string
+	PidStr         string // 缓存 Pid 字符串，避免重复转换
+	SuffixToIgnore []string
+	Handler        *Handler
 }
 
 func (m *xLogHook) Levels() []logrus.Level {
@@ -38,64 +24,78 @@ func (m *xLogHook) Levels() []logrus.Level {
 }
 
 func (m *xLogHook) Fire(entry *logrus.Entry) error {
-	if _, ok := entry.Data["servername"]; !ok {
-		entry.Data["servername"] = m.ServerName
+	template := entry.Message
+	if t, ok := entry.Data[xlogTemplateKey].(string); ok {
+		template = t
+		delete(entry.Data, xlogTemplateKey)
 	}
 
-	entry.Data["ip"] = m.IP
-	entry.Data["pid"] = m.PidStr
-
-	// 设置文件名和行号
-	caller := m.ensureCaller(entry)
-	entry.Data["filename"] = path.Base(caller.File)
-	entry.Data["lineid"] = strconv.Itoa(caller.Line)
-
-	// 设置trace信息
-	entry.Data["traceid"] = xutil.GetTraceIDFromCtx(entry.Context)
-	entry.Data["spanid"] = xutil.GetSpanIDFromCtx(entry.Context)
-
-	// 设置一些ctx中kv
-	for k, v := range getXLogContainerFromCtx(entry.Context) {
-		entry.Data[k] = v
+	// xlog.InfoS 等已完成级别判断、错误聚合与采样，直接使用其获取的调用方
+	if caller, ok := entry.Data[xlogAdmittedKey].(*runtime.Frame); ok {
+		delete(entry.Data, xlogAdmittedKey)
+		if m.Handler != nil {
+			enrichFields(entry.Context, entry.Data, caller, m.ServerName, m.IP, m.PidStr)
+			return m.Handler.core.write(toSlogLevel(entry.Level), entry.Time, entry.Message, entry.Data, caller)
+		}
 	}
 
-	// 打印到控制台
-	if m.Console {
-		return m.consolePrint(entry, caller)
+	// 该级别注册了其他 hook（如 xmetric 按 filename/lineid 统计错误）时，即使 Handler 不输出也先补充公共字段
+	var caller *runtime.Frame
+	enriched := m.Handler == nil || hasExternalHook(entry.Level)
+	if enriched {
+		caller = m.ensureCaller(entry)
+		enrichFields(entry.Context, entry.Data, caller, m.ServerName, m.IP, m.PidStr)
 	}
-
-	return nil
-}
-
-func (m *xLogHook) consolePrint(entry *logrus.Entry, caller *runtime.Frame) error {
-	line, err := entry.Bytes()
-	if err != nil {
-		return err
+	if m.Handler == nil {
+		return nil
 	}
 
-	fileName := callerPretty(caller)
+	level := toSlogLevel(entry.Level)
+	// 为包级别、请求级 debug 放行的低级别日志，未命中时尽早返回，避免获取调用栈
+	if !m.Handler.Enabled(entry.Context, level) {
+		return nil
+	}
+	if !enriched {
+		caller = m.ensureCaller(entry)
+	}
+	core := m.Handler.core
+	if !core.admit(entry.Context, level, template, entry.Message, caller, entry.Time) {
+		return nil
+	}
+	if !enriched {
+		enrichFields(entry.Context, entry.Data, caller, m.ServerName, m.IP, m.PidStr)
+	}
+	return core.write(level, entry.Time, entry.Message, entry.Data, caller)
+}
 
-	levelColor := getLogConsoleLogColor(entry.Level)
-	levelText := strings.ToUpper(entry.Level.String())
-	logTimeText := entry.Time.Format("2006-01-02 15:04:05.999")
-	traceId := entry.Data["traceid"]
-	panicStack := entry.Data["panic_stack"]
+// externalHookLevels 注册了 xLogHook 以外 hook 的 logrus 级别（按位），在 replaceXLogHook 时更新
+// xlog 初始化之后才通过 logrus.AddHook 注册的 hook 不在其中，Handler 不输出的日志不会为其补充公共字段
+var externalHookLevels atomic.Uint32
 
-	// 预分配容量
-	msg := make([]byte, 0, len(line)+128)
+// hasExternalHook 该级别是否注册了 xLogHook 以外的 hook
+func hasExternalHook(level logrus.Level) bool {
+	return externalHookLevels.Load()&(1<<level) != 0
+}
 
-	// 打印原始json格式（纯 JSON，无前缀）
-	if m.ConsoleFormatIsRaw {
-		msg = append(msg, line...)
-	} else {
-		msg = fmt.Appendf(msg, "\x1b[%dm%s\x1b[0m[%s] \x1b[34m%s\x1b[0m %s %s\n", levelColor, levelText, logTimeText, fileName, traceId, entry.Message)
-		if panicStack != nil {
-			msg = fmt.Appendf(msg, "%s\n", panicStack)
+// replaceXLogHook 将 xLogHook 注册为各级别的第一个 hook，并移除之前注册的 xLogHook，重复初始化时不会重复输出
+// 其他模块注册的 hook（如 xmetric）保持原有顺序，排在 xLogHook 之后，从而能拿到其补充的公共字段
+func replaceXLogHook(hook *xLogHook) {
+	std := logrus.StandardLogger()
+	hooks := make(logrus.LevelHooks)
+	for _, level := range hook.Levels() {
+		hooks[level] = append(hooks[level], hook)
+	}
+	var external uint32
+	for level, hs := range std.Hooks {
+		for _, h := range hs {
+			if _, ok := h.(*xLogHook); !ok {
+				hooks[level] = append(hooks[level], h)
+				external |= 1 << level
+			}
 		}
 	}
-
-	_, err = m.Writer.Write(msg)
-	return err
+	std.ReplaceHooks(hooks)
+	externalHookLevels.Store(external)
 }
 
 // ensureCaller 确保获取到调用者信息，避免重复代码
@@ -111,49 +111,9 @@ func (m *xLogHook) getCaller(callDepth int) *runtime.Frame {
 	return xutil.GetLogCaller(callDepth, m.SuffixToIgnore)
 }
 
-type timeFormatter struct {
-	logrus.Formatter
-	Location *time.Location
-}
-
-// Format 时间format
-// 复制 entry 避免修改共享状态，保证多 writer 并发调用安全
-// time.In() 是幂等操作，多次调用结果一致，无需标记防重复
-func (t timeFormatter) Format(e *logrus.Entry) ([]byte, error) {
-	entryCopy := *e
-	if entryCopy.Context == nil {
-		entryCopy.Context = context.Background()
-	}
-	if t.Location != nil {
-		entryCopy.Time = entryCopy.Time.In(t.Location)
-	}
-	return t.Formatter.Format(&entryCopy)
-}
-
-func getLogConsoleLogColor(l logrus.Level) int {
-	switch l {
-	case logrus.DebugLevel, logrus.TraceLevel:
-		return colorGray
-	case logrus.WarnLevel:
-		return colorYellow
-	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
-		return colorRed
-	default:
-		return colorBlue
-	}
-}
-
-func callerPretty(f *runtime.Frame) string {
-	if f == nil {
-		return "???"
-	}
-	return fmt.Sprintf("%s:%d", path.Base(f.File), f.Line)
-}
+// discardFormatter 日志统一由 xLogHook 交给 Handler 输出，logrus 自身不再格式化
+type discardFormatter struct{}
 
-func getXLogContainerFromCtx(ctx context.Context) map[string]any {
-	kvContainer, ok := ctx.Value(XLogCtxKVContainerKey).(map[string]any)
-	if !ok || kvContainer == nil {
-		return nil
-	}
-	return kvContainer
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
 }
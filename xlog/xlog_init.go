@@ -2,11 +2,9 @@ package xlog
 
 import (
 	"io"
+	"log/slog"
 	"os"
-	"path"
-	"runtime"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/xiaoshicae/xone/v2/xconfig"
@@ -14,16 +12,22 @@ import (
 	"github.com/xiaoshicae/xone/v2/xhook"
 	"github.com/xiaoshicae/xone/v2/xutil"
 
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"github.com/sirupsen/logrus"
-	logwriter "github.com/sirupsen/logrus/hooks/writer"
 )
 
 var (
 	findFrameIgnoreFileNames = []string{
 		"/xlog/util.go",
+		"/xlog/logger.go",
 		"/xlog/xlog_hook.go",
+		"/xlog/handler.go",
 	}
+
+	// slogFrameIgnoreFileNames slog.Record 未记录 PC 时（如标准库 log 包转发的日志）查找调用方需额外跳过的文件
+	slogFrameIgnoreFileNames = append([]string{
+		"/log/slog/logger.go",
+		"/log/log.go",
+	}, findFrameIgnoreFileNames...)
 )
 
 func init() {
@@ -41,78 +45,120 @@ func initXLog() error {
 }
 
 func initXLogByConfig(c *Config) error {
-	if !xutil.DirExist(c.Path) { // 日志所在文件夹不存在则创建
-		if err := os.MkdirAll(c.Path, os.ModePerm); err != nil {
-			return xerror.Newf("xlog", "init", "os.MkdirAll failed, path=[%s], err=[%v]", c.Path, err)
-		}
+	// 加载时区
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		xutil.WarnIfEnableDebug("XOne initXLogByConfig load timezone [%s] failed, using Local timezone, err=[%v]", c.Timezone, err)
+		loc = time.Local
 	}
 
-	// 创建 file writer
-	logFilePath := path.Join(c.Path, c.Name+".log")
-	fileWriter, err := rotatelogs.New(
-		logFilePath+".%Y%m%d",
-		rotatelogs.WithLinkName(logFilePath),
-		rotatelogs.WithMaxAge(xutil.ToDuration(c.MaxAge)),
-		rotatelogs.WithRotationTime(xutil.ToDuration(c.RotateTime)),
-	)
+	pkgLevels, err := newPackageLevels(c.Levels)
 	if err != nil {
-		return xerror.Newf("xlog", "init", "rotatelogs.New failed, err=[%v]", err)
+		return xerror.Newf("xlog", "init", "parse XLog.Levels failed, err=[%v]", err)
 	}
 
-	// 使用异步写入器包装，避免日志 I/O 阻塞调用方
-	asyncFileWriter := newAsyncWriter(fileWriter, defaultAsyncBufferSize)
-
-	// 注册关闭钩子（Close 会等待缓冲区写完再关闭底层 writer）
-	// 使用高 Order 值确保日志系统在其他模块关闭之后再关闭，避免关闭阶段日志丢失
-	xhook.BeforeStop(func() error {
-		return asyncFileWriter.Close()
-	}, xhook.Order(9999))
+	smp, err := newSampler(c.Sampling)
+	if err != nil {
+		return xerror.Newf("xlog", "init", "parse XLog.Sampling failed, err=[%v]", err)
+	}
 
-	// 加载时区
-	loc, err := time.LoadLocation(c.Timezone)
+	bp, err := newBaggagePolicy(c.Baggage)
 	if err != nil {
-		xutil.WarnIfEnableDebug("XOne initXLogByConfig load timezone [%s] failed, using Local timezone, err=[%v]", c.Timezone, err)
-		loc = time.Local
+		return xerror.Newf("xlog", "init", "parse XLog.Baggage failed, err=[%v]", err)
 	}
 
-	logrus.SetOutput(io.Discard)
+	serverName := xconfig.GetServerName()
 
-	// 设置日志输出格式
-	logrus.SetFormatter(timeFormatter{
-		Formatter: &logrus.JSONFormatter{
-			TimestampFormat: "2006-01-02 15:04:05.999",
-			CallerPrettyfier: func(*runtime.Frame) (function string, file string) {
-				return "", "" // 去掉自带的file和func字段
-			},
-		},
-		Location: loc,
-	})
+	// 创建日志输出，file/syslog 使用异步写入器包装，避免日志 I/O 阻塞调用方
+	outputs := make([]*output, 0, len(c.Outputs)+1)
+	var closers []io.Closer
+	for _, oc := range outputConfigs(c) {
+		o, closer, err := newOutput(oc, serverName, c.AsyncBufferSize, loc)
+		if err != nil {
+			_ = closeAll(closers)
+			return xerror.Newf("xlog", "init", "create output failed, err=[%v]", err)
+		}
+		outputs = append(outputs, o)
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
 
 	localIP, _ := xutil.GetLocalIP()
 	localIP = xutil.GetOrDefault(localIP, "0.0.0.0")
-
-	// 自定义hook，进行日志format和打印到屏幕
-	logrus.AddHook(&xLogHook{
-		SuffixToIgnore:     findFrameIgnoreFileNames,
-		ServerName:         xconfig.GetServerName(),
-		IP:                 localIP,
-		PidStr:             strconv.Itoa(os.Getpid()), // 初始化时转换，避免每次日志都转换
-		Console:            c.Console,
-		ConsoleFormatIsRaw: c.ConsoleFormatIsRaw,
-		Writer:             os.Stdout,
-	})
-
-	// file writer hook
-	logrus.AddHook(&logwriter.Hook{
-		Writer:    asyncFileWriter,
-		LogLevels: resolveLevels(c.Level),
-	})
+	pidStr := strconv.Itoa(os.Getpid()) // 初始化时转换，避免每次日志都转换
 
 	l, err := logrus.ParseLevel(c.Level)
 	if err != nil {
 		l = logrus.InfoLevel
 	}
-	logrus.SetLevel(l)
+	level := new(slog.LevelVar)
+	level.Set(toSlogLevel(l))
+
+	core := &handlerCore{
+		level:          level,
+		baseLevel:      level.Level(),
+		pkgLevels:      pkgLevels,
+		debug:          newDebugState(c),
+		sampler:        smp,
+		baggage:        bp,
+		serverName:     serverName,
+		ip:             localIP,
+		pid:            pidStr,
+		suffixToIgnore: slogFrameIgnoreFileNames,
+		location:       loc,
+		outputs:        outputs,
+		console:        consoleWriter(c),
+		consoleRaw:     c.ConsoleFormatIsRaw,
+		consoleFmt:     newConsoleFormatter(c.ConsoleTemplate, consoleColorEnabled(consoleWriter(c))),
+	}
+
+	// 远程投递，后台批量发送
+	for _, rc := range c.Remote {
+		r, err := newRemoteSink(rc, core)
+		if err != nil {
+			_ = closeAll(closers)
+			return xerror.Newf("xlog", "init", "create remote failed, err=[%v]", err)
+		}
+		core.remotes = append(core.remotes, r)
+		closers = append(closers, r)
+	}
+
+	// 错误聚合与告警
+	agg, err := newErrorAggregator(c.ErrorAlert, serverName)
+	if err != nil {
+		_ = closeAll(closers)
+		return xerror.Newf("xlog", "init", "parse XLog.ErrorAlert failed, err=[%v]", err)
+	}
+	if agg != nil {
+		core.errors = agg
+		closers = append(closers, agg)
+	}
+
+	// 注册关闭钩子（Close 会等待缓冲区写完再关闭底层 writer，远程投递发送剩余日志）
+	// 使用高 Order 值确保日志系统在其他模块关闭之后再关闭，避免关闭阶段日志丢失
+	xhook.BeforeStop(func() error {
+		return closeAll(closers)
+	}, xhook.Order(9999))
+
+	handler := newHandler(core)
+
+	// logrus 只作为 xlog.Info 等方法的入口，格式化与输出统一交给 Handler
+	logrus.SetOutput(io.Discard)
+	logrus.SetFormatter(discardFormatter{})
+	replaceXLogHook(&xLogHook{
+		SuffixToIgnore: findFrameIgnoreFileNames,
+		ServerName:     serverName,
+		IP:             localIP,
+		PidStr:         pidStr,
+		Handler:        handler,
+	})
+	// 配置了更低的包级别或请求级 debug 时 logrus 需放行，由 Handler 按调用方所在包、请求过滤
+	activeCore.Store(handler.core)
+	handler.core.syncLogrusLevel()
+
+	// 使用 slog 及标准库 log 包的三方库日志同样经 Handler 输出
+	slog.SetDefault(slog.New(handler))
 
 	return nil
 }
@@ -127,19 +173,17 @@ func getConfig() (*Config, error) {
 	return c, nil
 }
 
-// levelMapping 日志级别映射，避免使用魔术数字
-var levelMapping = map[string][]logrus.Level{
-	"debug": {logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel, logrus.DebugLevel},
-	"info":  {logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel},
-	"warn":  {logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel},
-	"error": {logrus.FatalLevel, logrus.ErrorLevel},
-	"fatal": {logrus.FatalLevel},
+// outputConfigs 未配置 XLog.Outputs 时输出到 <Path>/<Name>.log
+func outputConfigs(c *Config) []OutputConfig {
+	if len(c.Outputs) > 0 {
+		return c.Outputs
+	}
+	return []OutputConfig{outputConfigMergeDefault(OutputConfig{}, c)}
 }
 
-func resolveLevels(l string) []logrus.Level {
-	if levels, ok := levelMapping[strings.ToLower(l)]; ok {
-		return levels
+func consoleWriter(c *Config) io.Writer {
+	if c.Console {
+		return os.Stdout
 	}
-	// 默认 info 级别
-	return levelMapping["info"]
+	return nil
 }
@@ -1,20 +1,43 @@
 package xlog
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"log"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/xiaoshicae/xone/v2/xconfig"
+	"github.com/xiaoshicae/xone/v2/xerror"
 	"github.com/xiaoshicae/xone/v2/xutil"
 
 	"github.com/bytedance/mockey"
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	c "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 func TestXLogConfig(t *testing.T) {
@@ -26,9 +49,13 @@ func TestXLogConfig(t *testing.T) {
 			Path:               "./log",
 			Console:            false,
 			ConsoleFormatIsRaw: false,
+			ConsoleTemplate:    "{level}[{time}] {caller} {traceid} {msg}",
 			MaxAge:             "7d",
 			RotateTime:         "1d",
+			FilePattern:        "%Y%m%d",
 			Timezone:           "Asia/Shanghai",
+			AsyncBufferSize:    4096,
+			Overflow:           "block",
 		})
 	})
 
@@ -42,7 +69,14 @@ func TestXLogConfig(t *testing.T) {
 			ConsoleFormatIsRaw: true,
 			MaxAge:             "4",
 			RotateTime:         "5",
+			MaxSize:            100,
+			MaxBackups:         10,
+			Compress:           true,
+			FilePattern:        "%Y%m%d%H",
 			Timezone:           "UTC",
+			AsyncBufferSize:    10,
+			Overflow:           "drop_newest",
+			Sampling:           &SamplingConfig{First: 5},
 		}
 		config = configMergeDefault(config)
 		c.So(config, c.ShouldResemble, &Config{
@@ -51,53 +85,80 @@ func TestXLogConfig(t *testing.T) {
 			Path:               "3",
 			Console:            true,
 			ConsoleFormatIsRaw: true,
+			ConsoleTemplate:    "{level}[{time}] {caller} {traceid} {msg}",
 			MaxAge:             "4",
 			RotateTime:         "5",
+			MaxSize:            100,
+			MaxBackups:         10,
+			Compress:           true,
+			FilePattern:        "%Y%m%d%H",
 			Timezone:           "UTC",
+			AsyncBufferSize:    10,
+			Overflow:           "drop_newest",
+			Sampling:           &SamplingConfig{Interval: "1s", First: 5, Thereafter: 0, KeepLevel: "error"},
 		})
 	})
-}
-
-func TestResolveLevels(t *testing.T) {
-	mockey.PatchConvey("TestResolveLevels", t, func() {
-		mockey.PatchConvey("TestResolveLevels-Debug", func() {
-			levels := resolveLevels("debug")
-			c.So(levels, c.ShouldContain, logrus.DebugLevel)
-			c.So(levels, c.ShouldContain, logrus.InfoLevel)
-		})
 
-		mockey.PatchConvey("TestResolveLevels-Info", func() {
-			levels := resolveLevels("info")
-			c.So(levels, c.ShouldContain, logrus.InfoLevel)
-			c.So(levels, c.ShouldNotContain, logrus.DebugLevel)
-		})
+	mockey.PatchConvey("TestXLogConfig-samplingConfigMergeDefault", t, func() {
+		// 均未配置时使用默认值
+		c.So(samplingConfigMergeDefault(&SamplingConfig{}), c.ShouldResemble, &SamplingConfig{Interval: "1s", First: 100, Thereafter: 100, KeepLevel: "error"})
+		// 只配置其一时另一个的 0 保留
+		c.So(samplingConfigMergeDefault(&SamplingConfig{Thereafter: 10}), c.ShouldResemble, &SamplingConfig{Interval: "1s", First: 0, Thereafter: 10, KeepLevel: "error"})
+		c.So(samplingConfigMergeDefault(&SamplingConfig{First: 5}), c.ShouldResemble, &SamplingConfig{Interval: "1s", First: 5, Thereafter: 0, KeepLevel: "error"})
+	})
 
-		mockey.PatchConvey("TestResolveLevels-Warn", func() {
-			levels := resolveLevels("warn")
-			c.So(levels, c.ShouldContain, logrus.WarnLevel)
-			c.So(levels, c.ShouldNotContain, logrus.InfoLevel)
+	mockey.PatchConvey("TestXLogConfig-configMergeDefault-Outputs", t, func() {
+		config := configMergeDefault(&Config{
+			Path:     "/data/log",
+			MaxSize:  100,
+			Compress: true,
+			Outputs:  []OutputConfig{{}, {Name: "error", Level: "error", Format: "logfmt", Path: "/data/err", MaxAge: "30d", MaxSize: 10, Compress: new(bool)}},
 		})
-
-		mockey.PatchConvey("TestResolveLevels-Error", func() {
-			levels := resolveLevels("error")
-			c.So(levels, c.ShouldContain, logrus.ErrorLevel)
-			c.So(levels, c.ShouldNotContain, logrus.WarnLevel)
-		})
-
-		mockey.PatchConvey("TestResolveLevels-Fatal", func() {
-			levels := resolveLevels("fatal")
-			c.So(levels, c.ShouldContain, logrus.FatalLevel)
-			c.So(len(levels), c.ShouldEqual, 1)
+		compress := true
+		c.So(config.Outputs[0], c.ShouldResemble, OutputConfig{
+			Name:        "app",
+			Type:        "file",
+			Format:      "json",
+			Path:        "/data/log",
+			MaxAge:      "7d",
+			RotateTime:  "1d",
+			MaxSize:     100,
+			Compress:    &compress,
+			FilePattern: "%Y%m%d",
+			Network:     "udp",
+			Facility:    "local0",
+			Overflow:    "block",
 		})
+		c.So(config.Outputs[1].MaxSize, c.ShouldEqual, 10)
+		c.So(*config.Outputs[1].Compress, c.ShouldBeFalse)
+		c.So(config.Outputs[1].Name, c.ShouldEqual, "error")
+		c.So(config.Outputs[1].Format, c.ShouldEqual, "logfmt")
+		c.So(config.Outputs[1].Path, c.ShouldEqual, "/data/err")
+		c.So(config.Outputs[1].MaxAge, c.ShouldEqual, "30d")
+		c.So(config.Outputs[1].RotateTime, c.ShouldEqual, "1d")
+	})
+}
 
-		mockey.PatchConvey("TestResolveLevels-Unknown", func() {
-			levels := resolveLevels("unknown")
-			c.So(levels, c.ShouldContain, logrus.InfoLevel) // default to info
+func TestToSlogLevel(t *testing.T) {
+	mockey.PatchConvey("TestToSlogLevel", t, func() {
+		mockey.PatchConvey("TestToSlogLevel-All", func() {
+			c.So(toSlogLevel(logrus.TraceLevel), c.ShouldEqual, LevelTrace)
+			c.So(toSlogLevel(logrus.DebugLevel), c.ShouldEqual, slog.LevelDebug)
+			c.So(toSlogLevel(logrus.InfoLevel), c.ShouldEqual, slog.LevelInfo)
+			c.So(toSlogLevel(logrus.WarnLevel), c.ShouldEqual, slog.LevelWarn)
+			c.So(toSlogLevel(logrus.ErrorLevel), c.ShouldEqual, slog.LevelError)
+			c.So(toSlogLevel(logrus.FatalLevel), c.ShouldEqual, LevelFatal)
+			c.So(toSlogLevel(logrus.PanicLevel), c.ShouldEqual, LevelPanic)
 		})
 
-		mockey.PatchConvey("TestResolveLevels-UpperCase", func() {
-			levels := resolveLevels("DEBUG")
-			c.So(levels, c.ShouldContain, logrus.DebugLevel)
+		mockey.PatchConvey("TestLevelText", func() {
+			c.So(levelText(LevelTrace), c.ShouldEqual, "trace")
+			c.So(levelText(slog.LevelDebug), c.ShouldEqual, "debug")
+			c.So(levelText(slog.LevelInfo), c.ShouldEqual, "info")
+			c.So(levelText(slog.LevelWarn), c.ShouldEqual, "warning")
+			c.So(levelText(slog.LevelError), c.ShouldEqual, "error")
+			c.So(levelText(LevelFatal), c.ShouldEqual, "fatal")
+			c.So(levelText(LevelPanic), c.ShouldEqual, "panic")
 		})
 	})
 }
@@ -212,37 +273,37 @@ func TestOptions(t *testing.T) {
 func TestGetLogConsoleLogColor(t *testing.T) {
 	mockey.PatchConvey("TestGetLogConsoleLogColor", t, func() {
 		mockey.PatchConvey("TestDebugLevel", func() {
-			color := getLogConsoleLogColor(logrus.DebugLevel)
+			color := getLogConsoleLogColor(slog.LevelDebug)
 			c.So(color, c.ShouldEqual, colorGray)
 		})
 
 		mockey.PatchConvey("TestTraceLevel", func() {
-			color := getLogConsoleLogColor(logrus.TraceLevel)
+			color := getLogConsoleLogColor(LevelTrace)
 			c.So(color, c.ShouldEqual, colorGray)
 		})
 
 		mockey.PatchConvey("TestWarnLevel", func() {
-			color := getLogConsoleLogColor(logrus.WarnLevel)
+			color := getLogConsoleLogColor(slog.LevelWarn)
 			c.So(color, c.ShouldEqual, colorYellow)
 		})
 
 		mockey.PatchConvey("TestErrorLevel", func() {
-			color := getLogConsoleLogColor(logrus.ErrorLevel)
+			color := getLogConsoleLogColor(slog.LevelError)
 			c.So(color, c.ShouldEqual, colorRed)
 		})
 
 		mockey.PatchConvey("TestFatalLevel", func() {
-			color := getLogConsoleLogColor(logrus.FatalLevel)
+			color := getLogConsoleLogColor(LevelFatal)
 			c.So(color, c.ShouldEqual, colorRed)
 		})
 
 		mockey.PatchConvey("TestPanicLevel", func() {
-			color := getLogConsoleLogColor(logrus.PanicLevel)
+			color := getLogConsoleLogColor(LevelPanic)
 			c.So(color, c.ShouldEqual, colorRed)
 		})
 
 		mockey.PatchConvey("TestInfoLevel", func() {
-			color := getLogConsoleLogColor(logrus.InfoLevel)
+			color := getLogConsoleLogColor(slog.LevelInfo)
 			c.So(color, c.ShouldEqual, colorBlue)
 		})
 	})
@@ -343,20 +404,17 @@ func TestXLogHook(t *testing.T) {
 			c.So(entry.Data["custom"], c.ShouldEqual, "value")
 		})
 
-		mockey.PatchConvey("TestXLogHook-Fire-WithConsole", func() {
+		mockey.PatchConvey("TestXLogHook-Fire-WithHandler", func() {
 			writer := &mockWriter{}
 			hook := &xLogHook{
 				IP:         "127.0.0.1",
 				ServerName: "test-server",
 				PidStr:     "12345",
-				Console:    true,
-				Writer:     writer,
+				Handler:    newHandler(&handlerCore{outputs: []*output{{writer: writer}}}),
 			}
-			logger := logrus.New()
-			logger.SetFormatter(&logrus.JSONFormatter{})
 			entry := &logrus.Entry{
-				Logger:  logger,
-				Data:    logrus.Fields{},
+				Logger:  logrus.New(),
+				Data:    logrus.Fields{"k": "v"},
 				Context: context.Background(),
 				Time:    time.Now(),
 				Level:   logrus.InfoLevel,
@@ -364,7 +422,28 @@ func TestXLogHook(t *testing.T) {
 			}
 			err := hook.Fire(entry)
 			c.So(err, c.ShouldBeNil)
-			c.So(len(writer.written), c.ShouldBeGreaterThan, 0)
+			m := decodeLine(writer.written)
+			c.So(m["msg"], c.ShouldEqual, "test")
+			c.So(m["level"], c.ShouldEqual, "info")
+			c.So(m["k"], c.ShouldEqual, "v")
+			c.So(m["servername"], c.ShouldEqual, "test-server")
+			c.So(m["filename"], c.ShouldNotBeEmpty)
+		})
+
+		mockey.PatchConvey("TestXLogHook-Fire-HandlerLevelDisabled", func() {
+			writer := &mockWriter{}
+			hook := &xLogHook{Handler: newHandler(&handlerCore{outputs: []*output{{writer: writer}}})}
+			entry := &logrus.Entry{
+				Logger:  logrus.New(),
+				Data:    logrus.Fields{},
+				Context: context.Background(),
+				Time:    time.Now(),
+				Level:   logrus.DebugLevel,
+				Message: "test",
+			}
+			err := hook.Fire(entry)
+			c.So(err, c.ShouldBeNil)
+			c.So(len(writer.written), c.ShouldEqual, 0)
 		})
 
 		mockey.PatchConvey("TestXLogHook-EnsureCaller-WithCaller", func() {
@@ -393,8 +472,8 @@ func (m *mockWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-func TestXLogHookConsolePrint(t *testing.T) {
-	mockey.PatchConvey("TestXLogHookConsolePrint", t, func() {
+func TestHandlerConsolePrint(t *testing.T) {
+	mockey.PatchConvey("TestHandlerConsolePrint", t, func() {
 		testCaller := &runtime.Frame{
 			Function: "test.TestFunc",
 			File:     "/test/file.go",
@@ -403,144 +482,1431 @@ func TestXLogHookConsolePrint(t *testing.T) {
 
 		mockey.PatchConvey("TestConsolePrint-Raw", func() {
 			writer := &mockWriter{}
-			hook := &xLogHook{
-				IP:                 "127.0.0.1",
-				ServerName:         "test-server",
-				PidStr:             "12345",
-				Console:            true,
-				ConsoleFormatIsRaw: true,
-				Writer:             writer,
-			}
-			logger := logrus.New()
-			logger.SetFormatter(&logrus.JSONFormatter{})
-			entry := &logrus.Entry{
-				Logger:  logger,
-				Data:    logrus.Fields{"traceid": "trace-123"},
-				Context: context.Background(),
-				Time:    time.Now(),
-				Level:   logrus.InfoLevel,
-				Message: "test message",
-			}
-			err := hook.consolePrint(entry, testCaller)
+			core := &handlerCore{console: writer, consoleRaw: true}
+			err := core.write(slog.LevelInfo, time.Now(), "test message", map[string]any{"traceid": "trace-123"}, testCaller)
 			c.So(err, c.ShouldBeNil)
-			c.So(len(writer.written), c.ShouldBeGreaterThan, 0)
+			m := decodeLine(writer.written)
+			c.So(m["msg"], c.ShouldEqual, "test message")
+			c.So(m["traceid"], c.ShouldEqual, "trace-123")
 		})
 
 		mockey.PatchConvey("TestConsolePrint-Formatted", func() {
 			writer := &mockWriter{}
-			hook := &xLogHook{
-				IP:                 "127.0.0.1",
-				ServerName:         "test-server",
-				PidStr:             "12345",
-				Console:            true,
-				ConsoleFormatIsRaw: false,
-				Writer:             writer,
-			}
-			logger := logrus.New()
-			logger.SetFormatter(&logrus.JSONFormatter{})
-			entry := &logrus.Entry{
-				Logger:  logger,
-				Data:    logrus.Fields{"traceid": "trace-123"},
-				Context: context.Background(),
-				Time:    time.Now(),
-				Level:   logrus.InfoLevel,
-				Message: "test message",
-			}
-			err := hook.consolePrint(entry, testCaller)
+			core := &handlerCore{console: writer}
+			err := core.write(slog.LevelWarn, time.Now(), "test message", map[string]any{"traceid": "trace-123"}, testCaller)
 			c.So(err, c.ShouldBeNil)
-			c.So(len(writer.written), c.ShouldBeGreaterThan, 0)
+			out := string(writer.written)
+			c.So(out, c.ShouldContainSubstring, "WARNING")
+			c.So(out, c.ShouldContainSubstring, "file.go:100")
+			c.So(out, c.ShouldContainSubstring, "trace-123 test message")
 		})
 
 		mockey.PatchConvey("TestConsolePrint-WithPanicStack", func() {
 			writer := &mockWriter{}
-			hook := &xLogHook{
-				IP:                 "127.0.0.1",
-				ServerName:         "test-server",
-				PidStr:             "12345",
-				Console:            true,
-				ConsoleFormatIsRaw: false,
-				Writer:             writer,
-			}
-			logger := logrus.New()
-			logger.SetFormatter(&logrus.JSONFormatter{})
-			entry := &logrus.Entry{
-				Logger:  logger,
-				Data:    logrus.Fields{"traceid": "trace-123", "panic_stack": "stack trace"},
-				Context: context.Background(),
-				Time:    time.Now(),
-				Level:   logrus.ErrorLevel,
-				Message: "panic message",
-			}
-			err := hook.consolePrint(entry, testCaller)
+			core := &handlerCore{console: writer}
+			err := core.write(slog.LevelError, time.Now(), "panic message", map[string]any{"traceid": "trace-123", "panic_stack": "stack trace"}, testCaller)
 			c.So(err, c.ShouldBeNil)
 			c.So(string(writer.written), c.ShouldContainSubstring, "panic message")
+			c.So(string(writer.written), c.ShouldContainSubstring, "stack trace")
+		})
+
+		mockey.PatchConvey("TestConsolePrint-FileAndConsole", func() {
+			file, console := &mockWriter{}, &mockWriter{}
+			core := &handlerCore{outputs: []*output{{writer: file}}, console: console}
+			err := core.write(slog.LevelInfo, time.Now(), "both", map[string]any{}, testCaller)
+			c.So(err, c.ShouldBeNil)
+			c.So(decodeLine(file.written)["msg"], c.ShouldEqual, "both")
+			c.So(string(console.written), c.ShouldContainSubstring, "both")
+		})
+	})
+}
+
+func TestConsoleTemplate(t *testing.T) {
+	mockey.PatchConvey("TestConsoleTemplate", t, func() {
+		frame := &runtime.Frame{File: "/test/file.go", Line: 100}
+		ts := time.Date(2024, 10, 15, 11, 45, 5, 136000000, time.UTC)
+		data := func() map[string]any {
+			return map[string]any{"servername": "svc", "ip": "127.0.0.1", "pid": "1", "filename": "file.go", "lineid": "100", "traceid": "t1", "spanid": "",
+				"user_id": "u1", "note": "a b", "empty": "", "n": 3}
+		}
+
+		mockey.PatchConvey("TestConsoleTemplate-Default", func() {
+			out := string(defaultConsole.format(slog.LevelWarn, ts, "hello", data(), frame))
+			c.So(out, c.ShouldEqual, "\x1b[33mWARNING\x1b[0m[2024-10-15 11:45:05.136] \x1b[34mfile.go:100\x1b[0m t1 hello\n")
+		})
+
+		mockey.PatchConvey("TestConsoleTemplate-Fields", func() {
+			f := newConsoleFormatter("{time} {level} {user_id} {missing}|{msg} {fields}", false)
+			out := string(f.format(slog.LevelInfo, ts, "hello", data(), frame))
+			c.So(out, c.ShouldEqual, "2024-10-15 11:45:05.136 INFO u1 |hello n=3 note=\"a b\" traceid=t1\n")
+
+			f = newConsoleFormatter("{msg} {fields}", true)
+			out = string(f.format(slog.LevelInfo, ts, "hello", map[string]any{"k": "v"}, frame))
+			c.So(out, c.ShouldEqual, "hello \x1b[37mk\x1b[0m=v\n")
+		})
+
+		mockey.PatchConvey("TestConsoleTemplate-Details", func() {
+			f := newConsoleFormatter("{msg} {fields}", false)
+			inner := errors.New("connection refused")
+			err := fmt.Errorf("query user: %w", errors.Join(fmt.Errorf("dial: %w", inner), errors.New("retry exhausted")))
+			d := map[string]any{"panic_stack": "goroutine 1 [running]:\nmain.main()\n\tmain.go:10\n", "error_stack": "", "err": err, "plain": errors.New("plain")}
+			out := string(f.format(slog.LevelError, ts, "failed", d, frame))
+			c.So(out, c.ShouldEqual, "failed err=\"query user: dial: connection refused\\nretry exhausted\" plain=plain\n"+
+				"  err:\n"+
+				"    query user: dial: connection refused\n"+
+				"    retry exhausted\n"+
+				"      dial: connection refused\n"+
+				"        connection refused\n"+
+				"      retry exhausted\n"+
+				"  panic_stack:\n"+
+				"    goroutine 1 [running]:\n"+
+				"    main.main()\n"+
+				"    \tmain.go:10\n")
+		})
+
+		mockey.PatchConvey("TestConsoleTemplate-Color", func() {
+			c.So(consoleColorEnabled(&bytes.Buffer{}), c.ShouldBeFalse)
+			tmp, err := os.CreateTemp(t.TempDir(), "console")
+			c.So(err, c.ShouldBeNil)
+			defer tmp.Close()
+			c.So(consoleColorEnabled(tmp), c.ShouldBeFalse)
+			t.Setenv("NO_COLOR", "1")
+			c.So(consoleColorEnabled(os.Stdout), c.ShouldBeFalse)
+		})
+
+		mockey.PatchConvey("TestConsoleTemplate-Core", func() {
+			writer := &mockWriter{}
+			core := &handlerCore{console: writer, consoleFmt: newConsoleFormatter("{level} {msg} {fields}", false)}
+			c.So(core.write(slog.LevelInfo, ts, "hello", map[string]any{"k": "v"}, frame), c.ShouldBeNil)
+			c.So(string(writer.written), c.ShouldEqual, "INFO hello k=v\n")
+		})
+	})
+}
+
+func TestFormatJSON(t *testing.T) {
+	mockey.PatchConvey("TestFormatJSON", t, func() {
+		mockey.PatchConvey("TestFormatJSON-WithLocation", func() {
+			writer := &mockWriter{}
+			loc := time.FixedZone("UTC+8", 8*3600)
+			core := &handlerCore{outputs: []*output{{writer: writer}}, location: loc}
+			ts := time.Date(2024, 10, 15, 11, 45, 5, 136000000, time.UTC)
+			err := core.write(slog.LevelInfo, ts, "test", map[string]any{}, nil)
+			c.So(err, c.ShouldBeNil)
+			c.So(decodeLine(writer.written)["time"], c.ShouldEqual, "2024-10-15 19:45:05.136")
+		})
+
+		mockey.PatchConvey("TestFormatJSON-FieldClashAndError", func() {
+			line, err := formatJSON(slog.LevelError, time.Now(), "test", map[string]any{
+				"msg":   "field msg",
+				"level": "field level",
+				"err":   errors.New("boom"),
+			})
+			c.So(err, c.ShouldBeNil)
+			c.So(string(line), c.ShouldEndWith, "\n")
+			m := decodeLine(line)
+			c.So(m["msg"], c.ShouldEqual, "test")
+			c.So(m["level"], c.ShouldEqual, "error")
+			c.So(m["fields.msg"], c.ShouldEqual, "field msg")
+			c.So(m["fields.level"], c.ShouldEqual, "field level")
+			c.So(m["err"], c.ShouldEqual, "boom")
+		})
+
+		mockey.PatchConvey("TestFormatJSON-Unsupported", func() {
+			_, err := formatJSON(slog.LevelInfo, time.Now(), "test", map[string]any{"f": func() {}})
+			c.So(err, c.ShouldNotBeNil)
 		})
 	})
 }
 
-func TestTimeFormatter(t *testing.T) {
-	mockey.PatchConvey("TestTimeFormatter", t, func() {
-		mockey.PatchConvey("TestTimeFormatter-NilContext", func() {
-			tf := timeFormatter{
-				Formatter: &logrus.JSONFormatter{},
-				Location:  nil,
+func TestHandler(t *testing.T) {
+	mockey.PatchConvey("TestHandler", t, func() {
+		newTestLogger := func(writer *mockWriter, level slog.Level) *slog.Logger {
+			lv := new(slog.LevelVar)
+			lv.Set(level)
+			return slog.New(newHandler(&handlerCore{
+				level:          lv,
+				serverName:     "test-server",
+				ip:             "127.0.0.1",
+				pid:            "12345",
+				suffixToIgnore: slogFrameIgnoreFileNames,
+				outputs:        []*output{{writer: writer}},
+			}))
+		}
+
+		mockey.PatchConvey("TestHandler-Enrich", func() {
+			writer := &mockWriter{}
+			logger := newTestLogger(writer, slog.LevelInfo)
+			ctx := CtxWithKV(context.Background(), map[string]any{"requestId": "req-1"})
+			logger.InfoContext(ctx, "hello", "user", "alice", "n", 3)
+			m := decodeLine(writer.written)
+			c.So(m["msg"], c.ShouldEqual, "hello")
+			c.So(m["level"], c.ShouldEqual, "info")
+			c.So(m["user"], c.ShouldEqual, "alice")
+			c.So(m["n"], c.ShouldEqual, 3)
+			c.So(m["requestId"], c.ShouldEqual, "req-1")
+			c.So(m["servername"], c.ShouldEqual, "test-server")
+			c.So(m["ip"], c.ShouldEqual, "127.0.0.1")
+			c.So(m["pid"], c.ShouldEqual, "12345")
+			c.So(m["filename"], c.ShouldEqual, "xlog_test.go")
+			c.So(m["lineid"], c.ShouldNotBeEmpty)
+			c.So(m, c.ShouldContainKey, "traceid")
+			c.So(m, c.ShouldContainKey, "spanid")
+		})
+
+		mockey.PatchConvey("TestHandler-TraceID", func() {
+			mockey.Mock(xutil.GetTraceIDFromCtx).Return("trace-123").Build()
+			writer := &mockWriter{}
+			newTestLogger(writer, slog.LevelInfo).Info("hello")
+			c.So(decodeLine(writer.written)["traceid"], c.ShouldEqual, "trace-123")
+		})
+
+		mockey.PatchConvey("TestHandler-Level", func() {
+			writer := &mockWriter{}
+			logger := newTestLogger(writer, slog.LevelWarn)
+			c.So(logger.Enabled(context.Background(), slog.LevelInfo), c.ShouldBeFalse)
+			logger.Info("dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+			logger.Warn("kept")
+			c.So(decodeLine(writer.written)["level"], c.ShouldEqual, "warning")
+		})
+
+		mockey.PatchConvey("TestHandler-WithAttrsAndGroup", func() {
+			writer := &mockWriter{}
+			logger := newTestLogger(writer, slog.LevelInfo).With("module", "order").WithGroup("req").With("id", 1)
+			logger.Info("hello", slog.Group("user", "name", "alice"), "servername", "custom")
+			m := decodeLine(writer.written)
+			c.So(m["module"], c.ShouldEqual, "order")
+			c.So(m["req.id"], c.ShouldEqual, 1)
+			c.So(m["req.user.name"], c.ShouldEqual, "alice")
+			c.So(m["req.servername"], c.ShouldEqual, "custom")
+			c.So(m["servername"], c.ShouldEqual, "test-server")
+		})
+
+		mockey.PatchConvey("TestHandler-WithoutPC", func() {
+			writer := &mockWriter{}
+			h := newTestLogger(writer, slog.LevelInfo).Handler()
+			err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "no pc", 0))
+			c.So(err, c.ShouldBeNil)
+			c.So(decodeLine(writer.written)["filename"], c.ShouldEqual, "xlog_test.go")
+		})
+	})
+}
+
+func TestPackageLevels(t *testing.T) {
+	mockey.PatchConvey("TestPackageLevels", t, func() {
+		mockey.PatchConvey("TestFuncPackage", func() {
+			c.So(funcPackage("github.com/a/b/pkg.(*T).M"), c.ShouldEqual, "github.com/a/b/pkg")
+			c.So(funcPackage("github.com/a/b/pkg.F.func1"), c.ShouldEqual, "github.com/a/b/pkg")
+			c.So(funcPackage("main.main"), c.ShouldEqual, "main")
+		})
+
+		mockey.PatchConvey("TestMatchPackage", func() {
+			c.So(matchPackage("example.com/mypkg/billing", "mypkg/billing"), c.ShouldBeTrue)
+			c.So(matchPackage("example.com/mypkg/billing/invoice", "mypkg/billing"), c.ShouldBeTrue)
+			c.So(matchPackage("example.com/mypkg/billing", "billing"), c.ShouldBeTrue)
+			c.So(matchPackage("example.com/mypkg/billingx", "billing"), c.ShouldBeFalse)
+			c.So(matchPackage("example.com/xbilling", "billing"), c.ShouldBeFalse)
+			c.So(matchPackage("example.com/mypkg", ""), c.ShouldBeFalse)
+		})
+
+		mockey.PatchConvey("TestResolve-LongestMatch", func() {
+			p, err := newPackageLevels(map[string]string{"mypkg": "warn", "mypkg/billing": "debug", "xgorm": "error"})
+			c.So(err, c.ShouldBeNil)
+			c.So(p.min, c.ShouldEqual, slog.LevelDebug)
+
+			l, ok := p.resolve(&runtime.Frame{Function: "example.com/mypkg/billing.Charge"})
+			c.So(ok, c.ShouldBeTrue)
+			c.So(l, c.ShouldEqual, slog.LevelDebug)
+
+			l, ok = p.resolve(&runtime.Frame{Function: "example.com/mypkg/order.(*Svc).Create"})
+			c.So(ok, c.ShouldBeTrue)
+			c.So(l, c.ShouldEqual, slog.LevelWarn)
+
+			_, ok = p.resolve(&runtime.Frame{Function: "example.com/other.F"})
+			c.So(ok, c.ShouldBeFalse)
+			// 命中缓存
+			_, ok = p.resolve(&runtime.Frame{Function: "example.com/other.F"})
+			c.So(ok, c.ShouldBeFalse)
+			_, ok = p.resolve(nil)
+			c.So(ok, c.ShouldBeFalse)
+		})
+
+		mockey.PatchConvey("TestNewPackageLevels-Invalid", func() {
+			p, err := newPackageLevels(nil)
+			c.So(err, c.ShouldBeNil)
+			c.So(p, c.ShouldBeNil)
+			_, err = newPackageLevels(map[string]string{"mypkg": "verbose"})
+			c.So(err, c.ShouldNotBeNil)
+		})
+
+		mockey.PatchConvey("TestToLogrusLevel", func() {
+			for _, l := range logrus.AllLevels {
+				c.So(toLogrusLevel(toSlogLevel(l)), c.ShouldEqual, l)
 			}
-			entry := &logrus.Entry{
-				Logger:  logrus.New(),
-				Data:    logrus.Fields{},
-				Context: nil,
-				Time:    time.Now(),
-				Level:   logrus.InfoLevel,
-				Message: "test",
+		})
+
+		mockey.PatchConvey("TestHandler-PackageLevel", func() {
+			writer := &mockWriter{}
+			lv := new(slog.LevelVar)
+			lv.Set(slog.LevelWarn)
+			pkgLevels, _ := newPackageLevels(map[string]string{"xone/v2/xlog": "debug"})
+			logger := slog.New(newHandler(&handlerCore{level: lv, pkgLevels: pkgLevels, outputs: []*output{{writer: writer}}}))
+			c.So(logger.Enabled(context.Background(), slog.LevelDebug), c.ShouldBeTrue)
+			logger.Debug("debug in xlog")
+			c.So(decodeLine(writer.written)["msg"], c.ShouldEqual, "debug in xlog")
+
+			writer.written = nil
+			pkgLevels, _ = newPackageLevels(map[string]string{"xone/v2/xgorm": "debug"})
+			logger = slog.New(newHandler(&handlerCore{level: lv, pkgLevels: pkgLevels, outputs: []*output{{writer: writer}}}))
+			logger.Debug("debug outside xgorm")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+		})
+	})
+}
+
+func TestOutputs(t *testing.T) {
+	mockey.PatchConvey("TestOutputs", t, func() {
+		ts := time.Date(2024, 10, 15, 19, 45, 5, 136000000, time.UTC)
+		frame := &runtime.Frame{File: "/a/main.go", Line: 44}
+
+		mockey.PatchConvey("TestOutputs-LevelAndFormat", func() {
+			all, errOnly, text := &mockWriter{}, &mockWriter{}, &mockWriter{}
+			core := &handlerCore{outputs: []*output{
+				{name: "app", writer: all},
+				{name: "error", writer: errOnly, format: logFormatLogfmt, level: slog.LevelError, hasLevel: true},
+				{name: "text", writer: text, format: logFormatText},
+			}}
+			data := map[string]any{"traceid": "t1", "user": "alice bob", "n": 3, "err": errors.New("boom")}
+			c.So(core.write(slog.LevelInfo, ts, "hello", data, frame), c.ShouldBeNil)
+			c.So(core.write(slog.LevelError, ts, "failed", data, frame), c.ShouldBeNil)
+
+			lines := bytes.Split(bytes.TrimSpace(all.written), []byte("\n"))
+			c.So(len(lines), c.ShouldEqual, 2)
+			c.So(string(errOnly.written), c.ShouldEqual,
+				`time="2024-10-15 19:45:05.136" level=error msg=failed err=boom n=3 traceid=t1 user="alice bob"`+"\n")
+			c.So(string(text.written), c.ShouldContainSubstring, "[INFO][2024-10-15 19:45:05.136] main.go:44 t1 hello\n")
+			c.So(string(text.written), c.ShouldContainSubstring, "[ERROR][2024-10-15 19:45:05.136] main.go:44 t1 failed\n")
+		})
+
+		mockey.PatchConvey("TestOutputs-WriteError", func() {
+			core := &handlerCore{outputs: []*output{{name: "bad", writer: errWriter{}}}}
+			err := core.write(slog.LevelInfo, ts, "hello", map[string]any{}, frame)
+			c.So(err, c.ShouldNotBeNil)
+			c.So(err.Error(), c.ShouldContainSubstring, "write output [bad] failed")
+		})
+
+		mockey.PatchConvey("TestOutputs-Syslog", func() {
+			for _, network := range []string{"udp", "tcp"} {
+				received := make(chan string, 1)
+				var addr string
+				if network == "udp" {
+					pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+					c.So(err, c.ShouldBeNil)
+					defer pc.Close()
+					addr = pc.LocalAddr().String()
+					go func() {
+						buf := make([]byte, 4096)
+						n, _, _ := pc.ReadFrom(buf)
+						received <- string(buf[:n])
+					}()
+				} else {
+					ln, err := net.Listen("tcp", "127.0.0.1:0")
+					c.So(err, c.ShouldBeNil)
+					defer ln.Close()
+					addr = ln.Addr().String()
+					go func() {
+						conn, err := ln.Accept()
+						if err != nil {
+							return
+						}
+						defer conn.Close()
+						line, _ := bufio.NewReader(conn).ReadString('\n')
+						received <- line
+					}()
+				}
+
+				o, closer, err := newOutput(outputConfigMergeDefault(OutputConfig{Name: "syslog", Type: "syslog", Network: network, Address: addr}, configMergeDefault(nil)), "my app", 16, time.UTC)
+				c.So(err, c.ShouldBeNil)
+				core := &handlerCore{outputs: []*output{o}}
+				c.So(core.write(slog.LevelWarn, ts, "hello", map[string]any{}, frame), c.ShouldBeNil)
+
+				select {
+				case msg := <-received:
+					c.So(msg, c.ShouldStartWith, "<132>1 2024-10-15T19:45:05.136Z ")
+					c.So(msg, c.ShouldContainSubstring, " my_app "+strconv.Itoa(os.Getpid())+" - - {")
+					c.So(msg, c.ShouldContainSubstring, `"msg":"hello"`)
+				case <-time.After(3 * time.Second):
+					c.So("syslog message not received", c.ShouldBeEmpty)
+				}
+				c.So(closer.Close(), c.ShouldBeNil)
 			}
-			bytes, err := tf.Format(entry)
+		})
+
+		mockey.PatchConvey("TestNewOutput-Std", func() {
+			o, closer, err := newOutput(OutputConfig{Name: "out", Type: "stdout", Format: "text", Level: "warn"}, "app", 16, time.UTC)
 			c.So(err, c.ShouldBeNil)
-			c.So(len(bytes), c.ShouldBeGreaterThan, 0)
+			c.So(closer, c.ShouldBeNil)
+			c.So(o.writer, c.ShouldEqual, os.Stdout)
+			c.So(o.enabled(slog.LevelInfo), c.ShouldBeFalse)
+			c.So(o.enabled(slog.LevelWarn), c.ShouldBeTrue)
+
+			o, _, err = newOutput(OutputConfig{Name: "err", Type: "STDERR", Format: "json"}, "app", 16, time.UTC)
+			c.So(err, c.ShouldBeNil)
+			c.So(o.writer, c.ShouldEqual, os.Stderr)
 		})
 
-		mockey.PatchConvey("TestTimeFormatter-WithLocation", func() {
-			loc, _ := time.LoadLocation("UTC")
-			tf := timeFormatter{
-				Formatter: &logrus.JSONFormatter{},
-				Location:  loc,
+		mockey.PatchConvey("TestNewOutput-Invalid", func() {
+			cases := []OutputConfig{
+				{Name: "a", Type: "kafka", Format: "json"},
+				{Name: "a", Type: "stdout", Format: "xml"},
+				{Name: "a", Type: "stdout", Format: "json", Level: "verbose"},
+				{Name: "a", Type: "syslog", Format: "json", Network: "udp", Facility: "local0"},
+				{Name: "a", Type: "syslog", Format: "json", Network: "unix", Facility: "local0", Address: "x"},
+				{Name: "a", Type: "syslog", Format: "json", Network: "udp", Facility: "local9", Address: "x"},
 			}
-			entry := &logrus.Entry{
-				Logger:  logrus.New(),
-				Data:    logrus.Fields{},
-				Context: context.Background(),
-				Time:    time.Now(),
-				Level:   logrus.InfoLevel,
-				Message: "test",
+			for _, oc := range cases {
+				_, _, err := newOutput(oc, "app", 16, time.UTC)
+				c.So(err, c.ShouldNotBeNil)
 			}
-			bytes, err := tf.Format(entry)
+		})
+	})
+}
+
+func TestInitXLogByConfigOutputs(t *testing.T) {
+	std := logrus.StandardLogger()
+	oldHooks, oldOut, oldFormatter, oldLevel := std.ReplaceHooks(make(logrus.LevelHooks)), std.Out, std.Formatter, std.GetLevel()
+	oldSlog := slog.Default()
+	defer func() {
+		std.ReplaceHooks(oldHooks)
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+		logrus.SetLevel(oldLevel)
+		slog.SetDefault(oldSlog)
+	}()
+
+	mockey.PatchConvey("TestInitXLogByConfigOutputs", t, func() {
+		dir := t.TempDir()
+		err := initXLogByConfig(configMergeDefault(&Config{
+			Path:   dir,
+			Level:  "warn",
+			Levels: map[string]string{"xone/v2/xlog": "debug"},
+			Outputs: []OutputConfig{
+				{Name: "app"},
+				{Name: "error", Level: "error", Format: "logfmt"},
+			},
+		}))
+		c.So(err, c.ShouldBeNil)
+		c.So(logrus.GetLevel(), c.ShouldEqual, logrus.DebugLevel)
+
+		ctx := context.Background()
+		Debug(ctx, "debug by package level")
+		Error(ctx, "error to both")
+
+		readLines := func(name string, n int) []string {
+			var lines []string
+			for range 100 {
+				data, _ := os.ReadFile(path.Join(dir, name))
+				lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+				if len(data) > 0 && len(lines) >= n {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			return lines
+		}
+		appLines := readLines("app.log", 2)
+		c.So(len(appLines), c.ShouldEqual, 2)
+		c.So(appLines[0], c.ShouldContainSubstring, "debug by package level")
+		errLines := readLines("error.log", 1)
+		c.So(len(errLines), c.ShouldEqual, 1)
+		c.So(errLines[0], c.ShouldContainSubstring, "msg=\"error to both\"")
+
+		err = initXLogByConfig(configMergeDefault(&Config{Path: dir, Levels: map[string]string{"a": "bad"}}))
+		c.So(err, c.ShouldNotBeNil)
+		err = initXLogByConfig(configMergeDefault(&Config{Path: dir, Outputs: []OutputConfig{{Type: "kafka"}}}))
+		c.So(err, c.ShouldNotBeNil)
+		c.So(err.Error(), c.ShouldContainSubstring, "create output failed")
+	})
+}
+
+func TestSetLevel(t *testing.T) {
+	oldCore, oldLevel := activeCore.Load(), logrus.GetLevel()
+	defer func() {
+		activeCore.Store(oldCore)
+		logrus.SetLevel(oldLevel)
+	}()
+
+	mockey.PatchConvey("TestSetLevel", t, func() {
+		mockey.PatchConvey("TestSetLevel-NotInitialized", func() {
+			activeCore.Store(nil)
+			c.So(SetLevel("debug", 0), c.ShouldEqual, errXLogNotInitialized)
+			c.So(AddDebugTraceID("t1", 0), c.ShouldEqual, errXLogNotInitialized)
+			c.So(DebugRequested(http.Header{"X-Debug": {"1"}}), c.ShouldBeFalse)
+			RemoveDebugTraceID("t1")
+		})
+
+		mockey.PatchConvey("TestSetLevel-TTL", func() {
+			writer := &mockWriter{}
+			lv := new(slog.LevelVar)
+			core := &handlerCore{level: lv, baseLevel: slog.LevelInfo, debug: newDebugState(&Config{}), outputs: []*output{{writer: writer}}}
+			activeCore.Store(core)
+			logger := slog.New(newHandler(core))
+
+			c.So(SetLevel("verbose", 0), c.ShouldNotBeNil)
+
+			c.So(SetLevel("error", 0), c.ShouldBeNil)
+			c.So(GetLevel(), c.ShouldEqual, "error")
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.ErrorLevel)
+			logger.Warn("dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+
+			c.So(SetLevel("debug", 50*time.Millisecond), c.ShouldBeNil)
+			c.So(GetLevel(), c.ShouldEqual, "debug")
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.DebugLevel)
+			logger.Debug("kept")
+			c.So(decodeLine(writer.written)["msg"], c.ShouldEqual, "kept")
+
+			for range 100 {
+				if GetLevel() == "error" {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			c.So(GetLevel(), c.ShouldEqual, "error")
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.ErrorLevel)
+		})
+
+		mockey.PatchConvey("TestSetLevel-Superseded", func() {
+			core := &handlerCore{level: new(slog.LevelVar), baseLevel: slog.LevelInfo, debug: newDebugState(&Config{})}
+			activeCore.Store(core)
+			c.So(SetLevel("debug", 20*time.Millisecond), c.ShouldBeNil)
+			c.So(SetLevel("warn", 0), c.ShouldBeNil)
+			time.Sleep(50 * time.Millisecond)
+			c.So(GetLevel(), c.ShouldEqual, "warning")
+		})
+	})
+}
+
+func TestDebugRequest(t *testing.T) {
+	oldCore, oldLevel := activeCore.Load(), logrus.GetLevel()
+	defer func() {
+		activeCore.Store(oldCore)
+		logrus.SetLevel(oldLevel)
+	}()
+
+	mockey.PatchConvey("TestDebugRequest", t, func() {
+		newCore := func(cfg *Config, writer *mockWriter) *handlerCore {
+			lv := new(slog.LevelVar)
+			core := &handlerCore{level: lv, debug: newDebugState(cfg), outputs: []*output{{writer: writer}}}
+			activeCore.Store(core)
+			core.syncLogrusLevel()
+			return core
+		}
+
+		mockey.PatchConvey("TestDebugRequested", func() {
+			newCore(&Config{DebugHeader: "X-Debug-Log"}, &mockWriter{})
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.DebugLevel)
+			c.So(DebugRequested(http.Header{"X-Debug-Log": {"1"}}), c.ShouldBeTrue)
+			c.So(DebugRequested(http.Header{"X-Debug-Log": {"false"}}), c.ShouldBeFalse)
+			c.So(DebugRequested(http.Header{}), c.ShouldBeFalse)
+
+			newCore(&Config{DebugHeader: "X-Debug-Log", DebugHeaderValue: "s3cret"}, &mockWriter{})
+			c.So(DebugRequested(http.Header{"X-Debug-Log": {"1"}}), c.ShouldBeFalse)
+			c.So(DebugRequested(http.Header{"X-Debug-Log": {"s3cret"}}), c.ShouldBeTrue)
+
+			newCore(&Config{}, &mockWriter{})
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.InfoLevel)
+			c.So(DebugRequested(http.Header{"X-Debug-Log": {"1"}}), c.ShouldBeFalse)
+		})
+
+		mockey.PatchConvey("TestCtxWithDebug", func() {
+			writer := &mockWriter{}
+			core := newCore(&Config{DebugHeader: "X-Debug-Log"}, writer)
+			logger := slog.New(newHandler(core))
+			ctx := CtxWithDebug(context.Background())
+
+			logger.DebugContext(context.Background(), "dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+			logger.DebugContext(ctx, "debug for request")
+			m := decodeLine(writer.written)
+			c.So(m["msg"], c.ShouldEqual, "debug for request")
+			c.So(m[XLogDebugKey], c.ShouldEqual, true)
+
+			// logrus 入口同样生效
+			writer.written = nil
+			hook := &xLogHook{Handler: newHandler(core)}
+			entry := &logrus.Entry{Logger: logrus.New(), Data: logrus.Fields{}, Context: ctx, Time: time.Now(), Level: logrus.DebugLevel, Message: "via logrus"}
+			c.So(hook.Fire(entry), c.ShouldBeNil)
+			c.So(decodeLine(writer.written)["msg"], c.ShouldEqual, "via logrus")
+
+			writer.written = nil
+			entry = &logrus.Entry{Logger: logrus.New(), Data: logrus.Fields{}, Context: context.Background(), Time: time.Now(), Level: logrus.DebugLevel, Message: "dropped"}
+			c.So(hook.Fire(entry), c.ShouldBeNil)
+			c.So(len(writer.written), c.ShouldEqual, 0)
+			// 没有其他 hook 关注该级别时，未输出的日志不获取调用栈、不补充公共字段
+			c.So(entry.Data, c.ShouldNotContainKey, "filename")
+		})
+
+		mockey.PatchConvey("TestDebugTraceID", func() {
+			mockey.Mock(xutil.GetTraceIDFromCtx).Return("trace-1").Build()
+			writer := &mockWriter{}
+			core := newCore(&Config{DebugTraceIDs: []string{"trace-0"}}, writer)
+			logger := slog.New(newHandler(core))
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.DebugLevel)
+
+			logger.Debug("dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+
+			c.So(AddDebugTraceID("trace-1", 0), c.ShouldBeNil)
+			logger.Debug("debug by trace")
+			c.So(decodeLine(writer.written)["msg"], c.ShouldEqual, "debug by trace")
+
+			RemoveDebugTraceID("trace-1")
+			writer.written = nil
+			logger.Debug("dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+
+			c.So(AddDebugTraceID("trace-1", 20*time.Millisecond), c.ShouldBeNil)
+			c.So(AddDebugTraceID("", 0), c.ShouldBeNil)
+			c.So(core.debug.traceIDNum.Load(), c.ShouldEqual, 2)
+			time.Sleep(60 * time.Millisecond)
+			c.So(core.debug.traceIDNum.Load(), c.ShouldEqual, 1)
+			logger.Debug("dropped")
+			c.So(len(writer.written), c.ShouldEqual, 0)
+
+			RemoveDebugTraceID("trace-0")
+			c.So(logrus.GetLevel(), c.ShouldEqual, logrus.InfoLevel)
+		})
+	})
+}
+
+func TestSampler(t *testing.T) {
+	mockey.PatchConvey("TestSampler", t, func() {
+		frame := &runtime.Frame{File: "/a/main.go", Line: 44}
+		now := time.Now()
+
+		mockey.PatchConvey("TestNewSampler", func() {
+			smp, err := newSampler(nil)
 			c.So(err, c.ShouldBeNil)
-			c.So(len(bytes), c.ShouldBeGreaterThan, 0)
+			c.So(smp, c.ShouldBeNil)
+			c.So(smp.keep(slog.LevelInfo, "msg", frame, now), c.ShouldBeTrue)
+
+			_, err = newSampler(&SamplingConfig{Interval: "1s", KeepLevel: "verbose"})
+			c.So(err, c.ShouldNotBeNil)
+			_, err = newSampler(&SamplingConfig{Interval: "0", KeepLevel: "error"})
+			c.So(err, c.ShouldNotBeNil)
+			_, err = newSampler(&SamplingConfig{Interval: "1s", KeepLevel: "error", First: -1})
+			c.So(err, c.ShouldNotBeNil)
 		})
 
-		mockey.PatchConvey("TestTimeFormatter-MultipleCallsIdempotent", func() {
-			loc, _ := time.LoadLocation("UTC")
-			tf := timeFormatter{
-				Formatter: &logrus.JSONFormatter{},
-				Location:  loc,
+		mockey.PatchConvey("TestSampler-FirstThereafter", func() {
+			smp, err := newSampler(&SamplingConfig{Interval: "1s", First: 2, Thereafter: 3, KeepLevel: "error"})
+			c.So(err, c.ShouldBeNil)
+
+			var kept []int
+			for i := 1; i <= 10; i++ {
+				if smp.keep(slog.LevelInfo, "user %s login", frame, now) {
+					kept = append(kept, i)
+				}
 			}
-			entry := &logrus.Entry{
-				Logger:  logrus.New(),
-				Data:    logrus.Fields{},
-				Context: context.Background(),
-				Time:    time.Now(),
-				Level:   logrus.InfoLevel,
-				Message: "test",
+			c.So(kept, c.ShouldResemble, []int{1, 2, 5, 8})
+
+			// 不同模板、调用位置、级别分别计数
+			c.So(smp.keep(slog.LevelInfo, "other", frame, now), c.ShouldBeTrue)
+			c.So(smp.keep(slog.LevelInfo, "user %s login", &runtime.Frame{File: "/a/main.go", Line: 45}, now), c.ShouldBeTrue)
+			c.So(smp.keep(slog.LevelDebug, "user %s login", frame, now), c.ShouldBeTrue)
+
+			// error 始终保留
+			for range 10 {
+				c.So(smp.keep(slog.LevelError, "user %s login", frame, now), c.ShouldBeTrue)
+			}
+
+			// 下一个周期重新计数
+			c.So(smp.keep(slog.LevelInfo, "user %s login", frame, now.Add(time.Second)), c.ShouldBeTrue)
+		})
+
+		mockey.PatchConvey("TestHandler-Sampling", func() {
+			writer := &mockWriter{}
+			smp, _ := newSampler(&SamplingConfig{Interval: "1m", First: 3, Thereafter: 100, KeepLevel: "warn"})
+			logger := slog.New(newHandler(&handlerCore{level: new(slog.LevelVar), sampler: smp, outputs: []*output{{writer: writer}}}))
+			for i := range 10 {
+				logger.Info("storm", "i", i)
+				logger.Warn("kept")
+			}
+			lines := bytes.Split(bytes.TrimSpace(writer.written), []byte("\n"))
+			c.So(len(lines), c.ShouldEqual, 13)
+		})
+	})
+}
+
+// blockingWriter 每次 Write 通知 started，并阻塞到 release 关闭
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	written []string
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	w.written = append(w.written, string(p))
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func (w *blockingWriter) Close() error {
+	return nil
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	mockey.PatchConvey("TestAsyncWriterOverflow", t, func() {
+		run := func(policy string) (*asyncWriter, []string) {
+			bw := &blockingWriter{started: make(chan struct{}, 10), release: make(chan struct{})}
+			aw := newAsyncWriter(bw, 1).withOverflow(policy, "test-"+policy)
+			_, _ = aw.Write([]byte("1"))
+			<-bw.started // loop 已取走 1 并阻塞在写入
+			_, _ = aw.Write([]byte("2"))
+			_, _ = aw.Write([]byte("3"))
+			close(bw.release)
+			c.So(aw.Close(), c.ShouldBeNil)
+			return aw, bw.written
+		}
+
+		mockey.PatchConvey("TestOverflow-DropNewest", func() {
+			aw, written := run(overflowDropNewest)
+			c.So(written, c.ShouldResemble, []string{"1", "2"})
+			c.So(aw.Dropped(), c.ShouldEqual, 1)
+		})
+
+		mockey.PatchConvey("TestOverflow-DropOldest", func() {
+			aw, written := run(overflowDropOldest)
+			c.So(written, c.ShouldResemble, []string{"1", "3"})
+			c.So(aw.Dropped(), c.ShouldEqual, 1)
+		})
+
+		mockey.PatchConvey("TestOverflow-Block", func() {
+			bw := &blockingWriter{started: make(chan struct{}, 10), release: make(chan struct{})}
+			aw := newAsyncWriter(bw, 1).withOverflow(overflowBlock, "test-block")
+			_, _ = aw.Write([]byte("1"))
+			<-bw.started
+			_, _ = aw.Write([]byte("2"))
+			done := make(chan struct{})
+			go func() {
+				_, _ = aw.Write([]byte("3"))
+				close(done)
+			}()
+			select {
+			case <-done:
+				c.So("write should block when buffer is full", c.ShouldBeEmpty)
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(bw.release)
+			<-done
+			c.So(aw.Close(), c.ShouldBeNil)
+			c.So(bw.written, c.ShouldResemble, []string{"1", "2", "3"})
+			c.So(aw.Dropped(), c.ShouldEqual, 0)
+		})
+
+		mockey.PatchConvey("TestNewOutput-InvalidOverflow", func() {
+			_, _, err := newOutput(OutputConfig{Name: "a", Type: "stdout", Format: "json", Overflow: "spill"}, "app", 10, time.UTC)
+			c.So(err, c.ShouldNotBeNil)
+		})
+	})
+}
+
+func TestInitXLogByConfigSampling(t *testing.T) {
+	std := logrus.StandardLogger()
+	oldHooks, oldOut, oldFormatter, oldLevel := std.ReplaceHooks(make(logrus.LevelHooks)), std.Out, std.Formatter, std.GetLevel()
+	oldSlog, oldCore, oldExternal := slog.Default(), activeCore.Load(), externalHookLevels.Load()
+	defer func() {
+		externalHookLevels.Store(oldExternal)
+		std.ReplaceHooks(oldHooks)
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+		logrus.SetLevel(oldLevel)
+		slog.SetDefault(oldSlog)
+		activeCore.Store(oldCore)
+	}()
+
+	mockey.PatchConvey("TestInitXLogByConfigSampling", t, func() {
+		dir := t.TempDir()
+		err := initXLogByConfig(configMergeDefault(&Config{
+			Path:     dir,
+			Name:     "sampling",
+			Sampling: &SamplingConfig{Interval: "1m", First: 2, Thereafter: 1000},
+		}))
+		c.So(err, c.ShouldBeNil)
+
+		ctx := context.Background()
+		for i := range 5 {
+			// 同一模板不同参数按模板聚合
+			Info(ctx, "user %d login", i)
+			Info(ctx, "user %d logout", i, KV("k", "v"))
+			Error(ctx, "failed %d", i)
+		}
+
+		var lines []string
+		for range 100 {
+			data, _ := os.ReadFile(path.Join(dir, "sampling.log"))
+			lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(data) > 0 && len(lines) >= 9 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.So(len(lines), c.ShouldEqual, 9)
+		c.So(strings.Join(lines, "\n"), c.ShouldNotContainSubstring, xlogTemplateKey)
+		c.So(lines[0], c.ShouldContainSubstring, `"msg":"user 0 login"`)
+
+		err = initXLogByConfig(configMergeDefault(&Config{Path: dir, Sampling: &SamplingConfig{KeepLevel: "bad"}}))
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func decodeLine(line []byte) map[string]any {
+	m := map[string]any{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	_ = d.Decode(&m)
+	for k, v := range m {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				m[k] = int(i)
+			}
+		}
+	}
+	return m
+}
+
+func TestRotateWriter(t *testing.T) {
+	mockey.PatchConvey("TestRotateWriter", t, func() {
+		loc := time.FixedZone("UTC+8", 8*3600)
+		readLink := func(dir, name string) string {
+			target, err := os.Readlink(path.Join(dir, name+".log"))
+			c.So(err, c.ShouldBeNil)
+			return target
+		}
+		readFile := func(p string) string {
+			data, err := os.ReadFile(p)
+			c.So(err, c.ShouldBeNil)
+			return string(data)
+		}
+
+		mockey.PatchConvey("TestRotateWriter-Time", func() {
+			dir := t.TempDir()
+			now := time.Date(2024, 10, 15, 23, 59, 0, 0, loc)
+			w, err := newRotateWriter(RotateConfig{Path: dir, Name: "app", RotateTime: 24 * time.Hour, Location: loc}, func() time.Time { return now })
+			c.So(err, c.ShouldBeNil)
+			c.So(readLink(dir, "app"), c.ShouldEqual, "app.log.20241015")
+
+			_, err = w.Write([]byte("a\n"))
+			c.So(err, c.ShouldBeNil)
+			// 按本地时间零点切割（UTC 此时为 16:00）
+			now = time.Date(2024, 10, 16, 0, 0, 1, 0, loc)
+			_, err = w.Write([]byte("b\n"))
+			c.So(err, c.ShouldBeNil)
+			c.So(w.Close(), c.ShouldBeNil)
+
+			c.So(readFile(path.Join(dir, "app.log.20241015")), c.ShouldEqual, "a\n")
+			c.So(readFile(path.Join(dir, "app.log.20241016")), c.ShouldEqual, "b\n")
+			c.So(readLink(dir, "app"), c.ShouldEqual, "app.log.20241016")
+			c.So(readFile(path.Join(dir, "app.log")), c.ShouldEqual, "b\n")
+
+			_, err = w.Write([]byte("c\n"))
+			c.So(err, c.ShouldEqual, errRotateWriterClosed)
+		})
+
+		mockey.PatchConvey("TestRotateWriter-Size", func() {
+			dir := t.TempDir()
+			now := time.Date(2024, 10, 15, 10, 0, 0, 0, loc)
+			cfg := RotateConfig{Path: dir, Name: "app", RotateTime: time.Hour, MaxSize: 10, FilePattern: "%Y-%m-%d_%H", Location: loc}
+			w, err := newRotateWriter(cfg, func() time.Time { return now })
+			c.So(err, c.ShouldBeNil)
+			for _, line := range []string{"1234\n", "5678\n", "abcd\n"} {
+				_, err = w.Write([]byte(line))
+				c.So(err, c.ShouldBeNil)
+			}
+			c.So(w.Close(), c.ShouldBeNil)
+			c.So(readFile(path.Join(dir, "app.log.2024-10-15_10")), c.ShouldEqual, "1234\n5678\n")
+			c.So(readFile(path.Join(dir, "app.log.2024-10-15_10.1")), c.ShouldEqual, "abcd\n")
+			c.So(readLink(dir, "app"), c.ShouldEqual, "app.log.2024-10-15_10.1")
+
+			// 重启后追加到未写满的文件
+			w, err = newRotateWriter(cfg, func() time.Time { return now })
+			c.So(err, c.ShouldBeNil)
+			_, err = w.Write([]byte("ef\n"))
+			c.So(err, c.ShouldBeNil)
+			c.So(w.Close(), c.ShouldBeNil)
+			c.So(readFile(path.Join(dir, "app.log.2024-10-15_10.1")), c.ShouldEqual, "abcd\nef\n")
+		})
+
+		mockey.PatchConvey("TestRotateWriter-Retention", func() {
+			dir := t.TempDir()
+			now := time.Date(2024, 10, 15, 10, 0, 0, 0, loc)
+			for i, name := range []string{"app.log.20241010", "app.log.20241011", "app.log.20241012.1", "app.log.20241013", "app.log.20241014.gz", "other.log.20241010", "app.log.log.20241010"} {
+				p := path.Join(dir, name)
+				c.So(os.WriteFile(p, []byte(name), 0o644), c.ShouldBeNil)
+				mtime := time.Date(2024, 10, 10+i, 12, 0, 0, 0, loc)
+				c.So(os.Chtimes(p, mtime, mtime), c.ShouldBeNil)
+			}
+
+			w, err := newRotateWriter(RotateConfig{
+				Path: dir, Name: "app", RotateTime: 24 * time.Hour, MaxAge: 4 * 24 * time.Hour, MaxBackups: 3, Compress: true, Location: loc,
+			}, func() time.Time { return now })
+			c.So(err, c.ShouldBeNil)
+			c.So(w.Close(), c.ShouldBeNil)
+
+			entries, err := os.ReadDir(dir)
+			c.So(err, c.ShouldBeNil)
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			// 20241010 超过 MaxAge，20241011 超过 MaxBackups；其余压缩；不匹配的文件不受影响
+			c.So(names, c.ShouldResemble, []string{"app.log", "app.log.20241012.1.gz", "app.log.20241013.gz", "app.log.20241014.gz", "app.log.20241015", "app.log.log.20241010", "other.log.20241010"})
+
+			f, err := os.Open(path.Join(dir, "app.log.20241013.gz"))
+			c.So(err, c.ShouldBeNil)
+			defer f.Close()
+			gr, err := gzip.NewReader(f)
+			c.So(err, c.ShouldBeNil)
+			data, err := io.ReadAll(gr)
+			c.So(err, c.ShouldBeNil)
+			c.So(string(data), c.ShouldEqual, "app.log.20241013")
+			info, err := f.Stat()
+			c.So(err, c.ShouldBeNil)
+			c.So(info.ModTime().Equal(time.Date(2024, 10, 13, 12, 0, 0, 0, loc)), c.ShouldBeTrue)
+		})
+
+		mockey.PatchConvey("TestRotateWriter-Reopen", func() {
+			dir := t.TempDir()
+			w, err := NewRotateWriter(RotateConfig{Path: dir, Name: "app", Location: loc})
+			c.So(err, c.ShouldBeNil)
+			defer w.Close()
+			_, err = w.Write([]byte("a\n"))
+			c.So(err, c.ShouldBeNil)
+
+			current := path.Join(dir, readLink(dir, "app"))
+			c.So(os.Rename(current, current+".moved"), c.ShouldBeNil)
+			c.So(w.Reopen(), c.ShouldBeNil)
+			_, err = w.Write([]byte("b\n"))
+			c.So(err, c.ShouldBeNil)
+			c.So(readFile(current), c.ShouldEqual, "b\n")
+
+			// SIGUSR1 触发重新打开
+			c.So(os.Rename(current, current+".moved2"), c.ShouldBeNil)
+			c.So(syscall.Kill(os.Getpid(), syscall.SIGUSR1), c.ShouldBeNil)
+			for i := 0; i < 100 && !xutil.FileExist(current); i++ {
+				time.Sleep(10 * time.Millisecond)
 			}
-			// 多次调用 Format 应产生一致结果（幂等性）
-			bytes1, err := tf.Format(entry)
+			c.So(xutil.FileExist(current), c.ShouldBeTrue)
+		})
+
+		mockey.PatchConvey("TestRotateWriter-InvalidConfig", func() {
+			_, err := NewRotateWriter(RotateConfig{Path: t.TempDir()})
+			c.So(err, c.ShouldNotBeNil)
+			_, err = NewRotateWriter(RotateConfig{Path: t.TempDir(), Name: "app", FilePattern: "%Y%j"})
+			c.So(err, c.ShouldNotBeNil)
+			_, err = NewRotateWriter(RotateConfig{Path: t.TempDir(), Name: "app", FilePattern: "%Y/%m"})
+			c.So(err, c.ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRemote(t *testing.T) {
+	mockey.PatchConvey("TestRemote", t, func() {
+		type request struct {
+			path   string
+			header http.Header
+			body   []byte
+		}
+		newServer := func(status ...int) (*httptest.Server, chan request) {
+			reqs := make(chan request, 100)
+			var n atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				reqs <- request{path: r.URL.Path, header: r.Header, body: body}
+				if i := int(n.Add(1)) - 1; i < len(status) {
+					w.WriteHeader(status[i])
+				}
+			}))
+			return srv, reqs
+		}
+		core := &handlerCore{serverName: "svc", ip: "10.0.0.1", pid: "123", location: time.UTC}
+		ts := time.Date(2024, 10, 15, 11, 45, 5, 136000000, time.UTC)
+		data := func() map[string]any {
+			return map[string]any{"servername": "svc", "ip": "10.0.0.1", "pid": "123", "traceid": "4bf92f3577b34da6a3ce929d0e0e4736", "spanid": "00f067aa0ba902b7", "k": 1, "ok": true}
+		}
+
+		mockey.PatchConvey("TestRemote-OTLP", func() {
+			srv, reqs := newServer()
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "otlp", Endpoint: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}}), core)
+			c.So(err, c.ShouldBeNil)
+			core := &handlerCore{location: time.UTC, remotes: []*remoteSink{r}}
+			c.So(core.write(slog.LevelInfo, ts, "hello", data(), nil), c.ShouldBeNil)
+			c.So(r.Close(), c.ShouldBeNil)
+
+			req := <-reqs
+			c.So(req.path, c.ShouldEqual, "/v1/logs")
+			c.So(req.header.Get("Authorization"), c.ShouldEqual, "Bearer t")
+			c.So(req.header.Get("Content-Type"), c.ShouldEqual, "application/json")
+			var body otlpLogsData
+			c.So(json.Unmarshal(req.body, &body), c.ShouldBeNil)
+			rl := body.ResourceLogs[0]
+			c.So(rl.Resource.Attributes[0].Key, c.ShouldEqual, "service.name")
+			c.So(*rl.Resource.Attributes[0].Value.StringValue, c.ShouldEqual, "svc")
+			lr := rl.ScopeLogs[0].LogRecords[0]
+			c.So(lr.TimeUnixNano, c.ShouldEqual, strconv.FormatInt(ts.UnixNano(), 10))
+			c.So(lr.SeverityNumber, c.ShouldEqual, 9)
+			c.So(lr.SeverityText, c.ShouldEqual, "INFO")
+			c.So(*lr.Body.StringValue, c.ShouldEqual, "hello")
+			c.So(lr.TraceID, c.ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			c.So(lr.SpanID, c.ShouldEqual, "00f067aa0ba902b7")
+			c.So(len(lr.Attributes), c.ShouldEqual, 2)
+			c.So(lr.Attributes[0].Key, c.ShouldEqual, "k")
+			c.So(*lr.Attributes[0].Value.IntValue, c.ShouldEqual, "1")
+			c.So(*lr.Attributes[1].Value.BoolValue, c.ShouldBeTrue)
+		})
+
+		mockey.PatchConvey("TestRemote-Loki", func() {
+			srv, reqs := newServer(http.StatusNoContent)
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "loki", Endpoint: srv.URL + "/", Labels: map[string]string{"env": "prod"}}), core)
+			c.So(err, c.ShouldBeNil)
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "a", data: data()})
+			r.enqueue(&remoteRecord{level: slog.LevelError, time: ts, msg: "b", data: data()})
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts.Add(time.Millisecond), msg: "c", data: data()})
+			c.So(r.Close(), c.ShouldBeNil)
+			c.So(r.Dropped(), c.ShouldEqual, 0)
+
+			req := <-reqs
+			c.So(req.path, c.ShouldEqual, "/loki/api/v1/push")
+			var body lokiPushRequest
+			c.So(json.Unmarshal(req.body, &body), c.ShouldBeNil)
+			c.So(len(body.Streams), c.ShouldEqual, 2)
+			c.So(body.Streams[0].Stream, c.ShouldResemble, map[string]string{"servername": "svc", "level": "info", "env": "prod"})
+			c.So(len(body.Streams[0].Values), c.ShouldEqual, 2)
+			c.So(body.Streams[0].Values[0][0], c.ShouldEqual, strconv.FormatInt(ts.UnixNano(), 10))
+			c.So(decodeLine([]byte(body.Streams[0].Values[1][1]))["msg"], c.ShouldEqual, "c")
+			c.So(body.Streams[1].Stream["level"], c.ShouldEqual, "error")
+		})
+
+		mockey.PatchConvey("TestRemote-HTTP", func() {
+			srv, reqs := newServer()
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv.URL + "/ingest"}), core)
 			c.So(err, c.ShouldBeNil)
-			bytes2, err := tf.Format(entry)
+			r.enqueue(&remoteRecord{level: slog.LevelWarn, time: ts, msg: "a", data: data()})
+			r.enqueue(&remoteRecord{level: slog.LevelWarn, time: ts, msg: "b", data: data()})
+			c.So(r.Close(), c.ShouldBeNil)
+
+			req := <-reqs
+			c.So(req.path, c.ShouldEqual, "/ingest")
+			var lines []map[string]any
+			c.So(json.Unmarshal(req.body, &lines), c.ShouldBeNil)
+			c.So(len(lines), c.ShouldEqual, 2)
+			c.So(lines[0]["msg"], c.ShouldEqual, "a")
+			c.So(lines[0]["level"], c.ShouldEqual, "warning")
+			c.So(lines[0]["traceid"], c.ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+			srv2, reqs2 := newServer()
+			defer srv2.Close()
+			r, err = newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Format: "ndjson", Endpoint: srv2.URL}), core)
 			c.So(err, c.ShouldBeNil)
-			c.So(string(bytes1), c.ShouldEqual, string(bytes2))
+			r.enqueue(&remoteRecord{level: slog.LevelWarn, time: ts, msg: "a", data: data()})
+			r.enqueue(&remoteRecord{level: slog.LevelWarn, time: ts, msg: "b", data: data()})
+			c.So(r.Close(), c.ShouldBeNil)
+			req = <-reqs2
+			c.So(req.header.Get("Content-Type"), c.ShouldEqual, "application/x-ndjson")
+			c.So(bytes.Count(req.body, []byte("\n")), c.ShouldEqual, 2)
+		})
+
+		mockey.PatchConvey("TestRemote-Retry", func() {
+			srv, reqs := newServer(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv.URL, BatchSize: 1, RetryBackoff: "1ms"}), core)
+			c.So(err, c.ShouldBeNil)
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "a", data: data()})
+			for i := 0; i < 300 && len(reqs) < 3; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			c.So(r.Close(), c.ShouldBeNil)
+			c.So(len(reqs), c.ShouldEqual, 3)
+			c.So(r.Dropped(), c.ShouldEqual, 0)
+
+			// 4xx 不重试，整批丢弃
+			srv2, reqs2 := newServer(http.StatusBadRequest)
+			defer srv2.Close()
+			r, err = newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv2.URL, RetryBackoff: "1ms"}), core)
+			c.So(err, c.ShouldBeNil)
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "a", data: data()})
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "b", data: data()})
+			c.So(r.Close(), c.ShouldBeNil)
+			c.So(len(reqs2), c.ShouldEqual, 1)
+			c.So(r.Dropped(), c.ShouldEqual, 2)
+		})
+
+		mockey.PatchConvey("TestRemote-CloseSkipsRetry", func() {
+			srv, reqs := newServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv.URL, BatchSize: 1, MaxRetries: 10, RetryBackoff: "1h"}), core)
+			c.So(err, c.ShouldBeNil)
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "a", data: data()})
+			<-reqs
+
+			// 退避等待中 Close 立即结束等待，剩余日志只发送一次不再重试，失败计为丢弃
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "b", data: data()})
+			begin := time.Now()
+			c.So(r.Close(), c.ShouldBeNil)
+			c.So(time.Since(begin), c.ShouldBeLessThan, time.Second)
+			c.So(r.Dropped(), c.ShouldEqual, 2)
+			c.So(len(reqs), c.ShouldEqual, 1) // b 只发送了一次
+		})
+
+		mockey.PatchConvey("TestRemote-Buffer", func() {
+			srv, reqs := newServer()
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv.URL, BufferSize: 2, BatchSize: 100, FlushInterval: "1h"}), core)
+			c.So(err, c.ShouldBeNil)
+			for i := range 5 {
+				r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: strconv.Itoa(i), data: data()})
+			}
+			c.So(r.Dropped(), c.ShouldEqual, 3)
+			c.So(r.Close(), c.ShouldBeNil)
+
+			var lines []map[string]any
+			c.So(json.Unmarshal((<-reqs).body, &lines), c.ShouldBeNil)
+			c.So(len(lines), c.ShouldEqual, 2)
+			c.So(lines[0]["msg"], c.ShouldEqual, "3")
+			c.So(lines[1]["msg"], c.ShouldEqual, "4")
+
+			// 关闭后写入直接丢弃
+			r.enqueue(&remoteRecord{level: slog.LevelInfo, time: ts, msg: "x", data: data()})
+			c.So(r.Dropped(), c.ShouldEqual, 4)
+		})
+
+		mockey.PatchConvey("TestRemote-BatchSize", func() {
+			srv, reqs := newServer()
+			defer srv.Close()
+			r, err := newRemoteSink(remoteConfigMergeDefault(RemoteConfig{Type: "http", Endpoint: srv.URL, BatchSize: 2, FlushInterval: "1h", Level: "warn"}), core)
+			c.So(err, c.ShouldBeNil)
+			defer r.Close()
+			core := &handlerCore{location: time.UTC, remotes: []*remoteSink{r}}
+			c.So(core.write(slog.LevelInfo, ts, "filtered", data(), nil), c.ShouldBeNil)
+			c.So(core.write(slog.LevelWarn, ts, "a", data(), nil), c.ShouldBeNil)
+			c.So(core.write(slog.LevelError, ts, "b", data(), nil), c.ShouldBeNil)
+
+			select {
+			case req := <-reqs:
+				var lines []map[string]any
+				c.So(json.Unmarshal(req.body, &lines), c.ShouldBeNil)
+				c.So(len(lines), c.ShouldEqual, 2)
+				c.So(lines[0]["msg"], c.ShouldEqual, "a")
+			case <-time.After(3 * time.Second):
+				c.So("batch not sent", c.ShouldBeEmpty)
+			}
+		})
+
+		mockey.PatchConvey("TestRemote-InvalidConfig", func() {
+			for _, rc := range []RemoteConfig{
+				{Type: "kafka", Endpoint: "http://127.0.0.1:9092"},
+				{Type: "otlp", Endpoint: "127.0.0.1:4318"},
+				{Type: "http", Endpoint: "http://127.0.0.1", Format: "xml"},
+				{Type: "loki", Endpoint: "http://127.0.0.1", Level: "verbose"},
+				{Type: "loki", Endpoint: "http://127.0.0.1", FlushInterval: "0"},
+			} {
+				_, err := newRemoteSink(remoteConfigMergeDefault(rc), core)
+				c.So(err, c.ShouldNotBeNil)
+			}
+
+			err := initXLogByConfig(configMergeDefault(&Config{Path: t.TempDir(), Remote: []RemoteConfig{{Type: "kafka"}}}))
+			c.So(err, c.ShouldNotBeNil)
+			c.So(err.Error(), c.ShouldContainSubstring, "create remote failed")
+		})
+	})
+}
+
+func TestReplaceXLogHook(t *testing.T) {
+	std := logrus.StandardLogger()
+	oldHooks, oldExternal := std.ReplaceHooks(make(logrus.LevelHooks)), externalHookLevels.Load()
+	defer func() {
+		std.ReplaceHooks(oldHooks)
+		externalHookLevels.Store(oldExternal)
+	}()
+
+	mockey.PatchConvey("TestReplaceXLogHook", t, func() {
+		other := &logtest.Hook{}
+		logrus.AddHook(other)
+		first := &xLogHook{ServerName: "first"}
+		replaceXLogHook(first)
+		second := &xLogHook{ServerName: "second"}
+		replaceXLogHook(second)
+
+		// 重复初始化只保留最新的 xLogHook 且排在最前，其他 hook 不受影响
+		for _, level := range logrus.AllLevels {
+			hooks := std.Hooks[level]
+			c.So(hooks, c.ShouldHaveLength, 2)
+			c.So(hooks[0] == logrus.Hook(second), c.ShouldBeTrue)
+			c.So(hooks[1] == logrus.Hook(other), c.ShouldBeTrue)
+			c.So(hasExternalHook(level), c.ShouldBeTrue)
+		}
+
+		std.ReplaceHooks(make(logrus.LevelHooks))
+		replaceXLogHook(second)
+		c.So(hasExternalHook(logrus.ErrorLevel), c.ShouldBeFalse)
+	})
+}
+
+func TestReInitXLogHookOrder(t *testing.T) {
+	std := logrus.StandardLogger()
+	oldHooks, oldOut, oldFormatter, oldLevel := std.ReplaceHooks(make(logrus.LevelHooks)), std.Out, std.Formatter, std.GetLevel()
+	oldSlog, oldCore := slog.Default(), activeCore.Load()
+	defer func() {
+		std.ReplaceHooks(oldHooks)
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+		logrus.SetLevel(oldLevel)
+		slog.SetDefault(oldSlog)
+		activeCore.Store(oldCore)
+	}()
+
+	mockey.PatchConvey("TestReInitXLogHookOrder", t, func() {
+		// 与 xmetric 相同，在 xlog 初始化之前注册的 Error 级别 hook，记录触发时 entry 中已有的字段
+		metricHook := &snapshotHook{}
+		logrus.AddHook(metricHook)
+
+		for range 2 {
+			c.So(initXLogByConfig(configMergeDefault(&Config{Path: t.TempDir()})), c.ShouldBeNil)
+			Error(context.Background(), "boom")
+
+			data := metricHook.last
+			c.So(data["filename"], c.ShouldEqual, "xlog_test.go")
+			c.So(data["lineid"], c.ShouldNotBeEmpty)
+			c.So(data, c.ShouldContainKey, "servername")
+			c.So(std.Hooks[logrus.ErrorLevel], c.ShouldHaveLength, 2)
+		}
+	})
+}
+
+func TestXLogHookEnrichWhenDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	useTestHandler(t, &buf, logrus.ErrorLevel)
+	activeCore.Load().level.Set(LevelFatal)
+
+	mockey.PatchConvey("TestXLogHookEnrichWhenDisabled", t, func() {
+		// Handler 不输出的日志，后续 hook 仍能拿到 filename/lineid
+		after := &logtest.Hook{}
+		logrus.AddHook(after)
+		replaceXLogHook(&xLogHook{SuffixToIgnore: findFrameIgnoreFileNames, Handler: newHandler(activeCore.Load())})
+		Error(context.Background(), "dropped by handler")
+
+		c.So(buf.Len(), c.ShouldEqual, 0)
+		entry := after.LastEntry()
+		c.So(entry, c.ShouldNotBeNil)
+		c.So(entry.Data["filename"], c.ShouldEqual, "xlog_test.go")
+		c.So(entry.Data["lineid"], c.ShouldNotBeEmpty)
+	})
+}
+
+// snapshotHook 记录 Fire 时 entry.Data 的快照（排在其后的 hook 对 Data 的修改不可见）
+type snapshotHook struct {
+	last logrus.Fields
+}
+
+func (h *snapshotHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel}
+}
+
+func (h *snapshotHook) Fire(entry *logrus.Entry) error {
+	h.last = maps.Clone(entry.Data)
+	return nil
+}
+
+// useTestHandler 将 logrus 标准 logger 临时替换为经 xLogHook 写入 w 的 Handler
+func useTestHandler(tb testing.TB, w io.Writer, level logrus.Level) {
+	std := logrus.StandardLogger()
+	oldHooks, oldOut, oldFormatter, oldLevel := std.ReplaceHooks(make(logrus.LevelHooks)), std.Out, std.Formatter, std.GetLevel()
+	oldCore, oldExternal := activeCore.Load(), externalHookLevels.Load()
+	tb.Cleanup(func() {
+		externalHookLevels.Store(oldExternal)
+		std.ReplaceHooks(oldHooks)
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+		logrus.SetLevel(oldLevel)
+		activeCore.Store(oldCore)
+	})
+
+	core := &handlerCore{level: new(slog.LevelVar), outputs: []*output{{writer: w}}}
+	core.level.Set(toSlogLevel(level))
+	logrus.SetOutput(io.Discard)
+	logrus.SetFormatter(discardFormatter{})
+	logrus.SetLevel(level)
+	replaceXLogHook(&xLogHook{SuffixToIgnore: findFrameIgnoreFileNames, ServerName: "svc", IP: "127.0.0.1", PidStr: "1", Handler: newHandler(core)})
+	activeCore.Store(core)
+}
+
+type testStringer struct{}
+
+func (*testStringer) String() string { return "stringer" }
+
+func TestFields(t *testing.T) {
+	mockey.PatchConvey("TestFields", t, func() {
+		ts := time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC)
+		data := map[string]any{}
+		var nilStringer *testStringer
+		for _, f := range []Field{
+			String("s", "v"), Int("i", -1), Int64("i64", 2), Uint64("u", 3), Float64("f", 1.5), Bool("b", true), Bool("nb", false),
+			Duration("d", 1500*time.Millisecond), Time("t", ts), Stringer("st", &testStringer{}), Stringer("nst", nilStringer),
+			Lazy("l", func() any { return "lazy" }), Any("a", []int{1}), Err(nil),
+		} {
+			f.addTo(data)
+		}
+		c.So(data, c.ShouldResemble, map[string]any{
+			"s": "v", "i": int64(-1), "i64": int64(2), "u": uint64(3), "f": 1.5, "b": true, "nb": false,
+			"d": "1.5s", "t": ts, "st": "stringer", "nst": nil, "l": "lazy", "a": []int{1},
+		})
+
+		mockey.PatchConvey("TestFields-Err", func() {
+			data := map[string]any{}
+			err := fmt.Errorf("wrap: %w", xerror.Newf("xgorm", "query", "timeout"))
+			Err(err).addTo(data)
+			c.So(data, c.ShouldResemble, map[string]any{
+				"error":        err,
+				"error_module": "xgorm",
+				"error_op":     "query",
+			})
+
+			// 编码时输出为字符串
+			line, jsonErr := formatJSON(slog.LevelError, time.Now(), "failed", data)
+			c.So(jsonErr, c.ShouldBeNil)
+			c.So(string(line), c.ShouldContainSubstring, `"error":"wrap: XOne xgorm query failed, err=[timeout]"`)
+
+			// 控制台展开错误链
+			out := string(newConsoleFormatter("{msg}", false).format(slog.LevelError, time.Now(), "failed", data, nil))
+			c.So(out, c.ShouldContainSubstring, "  error:\n    wrap: XOne xgorm query failed, err=[timeout]\n")
+
+			data = map[string]any{}
+			NamedErr("cause", fmt.Errorf("wrap: %w", pkgerrors.New("boom"))).addTo(data)
+			c.So(fmt.Sprint(data["cause"]), c.ShouldEqual, "wrap: boom")
+			c.So(data["cause_stack"], c.ShouldContainSubstring, "TestFields")
+			c.So(data["cause_stack"], c.ShouldContainSubstring, "xlog_test.go")
 		})
 	})
 }
 
+func TestLogS(t *testing.T) {
+	mockey.PatchConvey("TestLogS", t, func() {
+		writer := &mockWriter{}
+		useTestHandler(t, writer, logrus.InfoLevel)
+		ctx := CtxWithKV(context.Background(), map[string]any{"req": "r1"})
+
+		InfoS(ctx, "order %s created", String("order_id", "o1"), Int("amount", 3), Err(errors.New("e")))
+		line := decodeLine(writer.written)
+		c.So(line["msg"], c.ShouldEqual, "order %s created")
+		c.So(line["level"], c.ShouldEqual, "info")
+		c.So(line["order_id"], c.ShouldEqual, "o1")
+		c.So(line["amount"], c.ShouldEqual, 3)
+		c.So(line["error"], c.ShouldEqual, "e")
+		c.So(line["req"], c.ShouldEqual, "r1")
+		c.So(line["filename"], c.ShouldEqual, "xlog_test.go")
+		c.So(line["servername"], c.ShouldEqual, "svc")
+
+		// 级别未开启时 Lazy 不求值
+		called := false
+		DebugS(ctx, "debug", Lazy("k", func() any { called = true; return 1 }))
+		//nolint:staticcheck // 验证 nil ctx 直接忽略
+		LogS(nil, logrus.ErrorLevel, "nil ctx")
+		c.So(called, c.ShouldBeFalse)
+
+		mockey.PatchConvey("TestLogS-Gate", func() {
+			// logrus 级别因包级别/请求级 debug 调低后，Handler 不输出的日志同样不求值、不分配
+			logrus.SetLevel(logrus.DebugLevel)
+			called := 0
+			lazy := Lazy("k", func() any { called++; return 1 })
+			DebugS(ctx, "debug", lazy)
+			c.So(called, c.ShouldEqual, 0)
+			c.So(testing.AllocsPerRun(10, func() { DebugS(ctx, "debug", String("a", "b"), lazy) }), c.ShouldEqual, 0)
+
+			// 请求级 debug 放行
+			core := activeCore.Load()
+			core.debug = &debugState{}
+			writer.written = nil
+			DebugS(CtxWithDebug(ctx), "debug for request", lazy)
+			c.So(called, c.ShouldEqual, 1)
+			c.So(decodeLine(writer.written)["k"], c.ShouldEqual, 1)
+
+			// 被采样丢弃的日志不求值
+			core.sampler = &sampler{interval: time.Minute, first: 1, keepLevel: slog.LevelError, seed: maphash.MakeSeed()}
+			for range 3 {
+				InfoS(ctx, "sampled", lazy)
+			}
+			c.So(called, c.ShouldEqual, 2)
+		})
+
+		mockey.PatchConvey("TestLogS-With", func() {
+			writer.written = nil
+			l := With(ctx, String("module", "order"), Int("v", 1)).With(String("step", "pay"))
+			c.So(l.Ctx(), c.ShouldEqual, ctx)
+			l.Warn("paid", Int("v", 2))
+			line := decodeLine(writer.written)
+			c.So(line["level"], c.ShouldEqual, "warning")
+			c.So(line["module"], c.ShouldEqual, "order")
+			c.So(line["step"], c.ShouldEqual, "pay")
+			c.So(line["v"], c.ShouldEqual, 2)
+			c.So(line["filename"], c.ShouldEqual, "xlog_test.go")
+
+			writer.written = nil
+			l.Debug("skip")
+			l.Info("i")
+			l.Error("e")
+			l.Log(logrus.InfoLevel, "l")
+			ErrorS(ctx, "e")
+			WarnS(ctx, "w")
+			c.So(bytes.Count(writer.written, []byte("\n")), c.ShouldEqual, 5)
+		})
+	})
+}
+
+func BenchmarkInfo(b *testing.B) {
+	useTestHandler(b, io.Discard, logrus.InfoLevel)
+	ctx := context.Background()
+	err := errors.New("e")
+
+	b.Run("Info-KV", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			Info(ctx, "order created", KV("order_id", "o1"), KV("amount", 3), KV("error", err))
+		}
+	})
+	b.Run("InfoS", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			InfoS(ctx, "order created", String("order_id", "o1"), Int("amount", 3), Err(err))
+		}
+	})
+	b.Run("Debug-KV-Disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			Debug(ctx, "order created", KV("order_id", "o1"), KV("amount", 3), KV("error", err))
+		}
+	})
+	b.Run("DebugS-Disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			DebugS(ctx, "order created", String("order_id", "o1"), Int("amount", 3), Err(err))
+		}
+	})
+}
+
 func TestInitXLogByConfig(t *testing.T) {
 	mockey.PatchConvey("TestInitXLogByConfig-DirNotExist-MkdirFail", t, func() {
 		mockey.Mock(xutil.DirExist).Return(false).Build()
@@ -548,15 +1914,16 @@ func TestInitXLogByConfig(t *testing.T) {
 
 		config := &Config{
 			Path: "/test/path",
+			Name: "test",
 		}
 		err := initXLogByConfig(config)
 		c.So(err, c.ShouldNotBeNil)
 		c.So(err.Error(), c.ShouldContainSubstring, "os.MkdirAll failed")
 	})
 
-	mockey.PatchConvey("TestInitXLogByConfig-RotatelogsFail", t, func() {
+	mockey.PatchConvey("TestInitXLogByConfig-OpenFileFail", t, func() {
 		mockey.Mock(xutil.DirExist).Return(true).Build()
-		mockey.Mock(rotatelogs.New).Return(nil, errors.New("rotatelogs failed")).Build()
+		mockey.Mock(os.OpenFile).Return(nil, errors.New("open failed")).Build()
 
 		config := &Config{
 			Path:       "/test/path",
@@ -566,7 +1933,57 @@ func TestInitXLogByConfig(t *testing.T) {
 		}
 		err := initXLogByConfig(config)
 		c.So(err, c.ShouldNotBeNil)
-		c.So(err.Error(), c.ShouldContainSubstring, "rotatelogs.New failed")
+		c.So(err.Error(), c.ShouldContainSubstring, "open log file failed")
+	})
+}
+
+func TestInitXLogByConfigSlog(t *testing.T) {
+	std := logrus.StandardLogger()
+	oldHooks, oldOut, oldFormatter, oldLevel := std.ReplaceHooks(make(logrus.LevelHooks)), std.Out, std.Formatter, std.GetLevel()
+	oldSlog := slog.Default()
+	defer func() {
+		std.ReplaceHooks(oldHooks)
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+		logrus.SetLevel(oldLevel)
+		slog.SetDefault(oldSlog)
+	}()
+
+	mockey.PatchConvey("TestInitXLogByConfigSlog", t, func() {
+		dir := t.TempDir()
+		err := initXLogByConfig(configMergeDefault(&Config{Path: dir, Name: "test"}))
+		c.So(err, c.ShouldBeNil)
+
+		ctx := CtxWithKV(context.Background(), map[string]any{"requestId": "req-1"})
+		Info(ctx, "from xlog")
+		slog.InfoContext(ctx, "from slog", "k", "v")
+		log.Print("from std log")
+		slog.Debug("dropped")
+
+		var lines []map[string]any
+		for range 100 {
+			data, _ := os.ReadFile(path.Join(dir, "test.log"))
+			lines = lines[:0]
+			for _, l := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				if len(l) > 0 {
+					lines = append(lines, decodeLine(l))
+				}
+			}
+			if len(lines) >= 3 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.So(len(lines), c.ShouldEqual, 3)
+		c.So(lines[0]["msg"], c.ShouldEqual, "from xlog")
+		c.So(lines[0]["requestId"], c.ShouldEqual, "req-1")
+		c.So(lines[0]["filename"], c.ShouldEqual, "xlog_test.go")
+		c.So(lines[1]["msg"], c.ShouldEqual, "from slog")
+		c.So(lines[1]["k"], c.ShouldEqual, "v")
+		c.So(lines[1]["requestId"], c.ShouldEqual, "req-1")
+		c.So(lines[1]["filename"], c.ShouldEqual, "xlog_test.go")
+		c.So(lines[2]["msg"], c.ShouldEqual, "from std log")
+		c.So(lines[2]["filename"], c.ShouldEqual, "xlog_test.go")
 	})
 }
 
@@ -681,3 +2098,263 @@ func TestGetConfig(t *testing.T) {
 		c.So(config.Name, c.ShouldEqual, "app")
 	})
 }
+
+type chanNotifier chan ErrorEvent
+
+func (n chanNotifier) Notify(_ context.Context, e ErrorEvent) error {
+	n <- e
+	return nil
+}
+
+func TestErrorAlert(t *testing.T) {
+	mockey.PatchConvey("TestErrorAlert", t, func() {
+		frame := &runtime.Frame{Function: "github.com/x/svc.(*Order).Pay", File: "/src/svc/order.go", Line: 42}
+		ts := time.Date(2024, 10, 15, 11, 45, 5, 0, time.UTC)
+		newAgg := func(c *ErrorAlertConfig) (*errorAggregator, chanNotifier) {
+			a, err := newErrorAggregator(errorAlertConfigMergeDefault(c), "svc")
+			if err != nil {
+				panic(err)
+			}
+			n := make(chanNotifier, 100)
+			a.addNotifier(n)
+			return a, n
+		}
+
+		mockey.PatchConvey("TestErrorAlert-Normalize", func() {
+			c.So(normalizeErrorMessage("user 123 pay 9.5 failed"), c.ShouldEqual, "user <n> pay <n> failed")
+			c.So(normalizeErrorMessage("req 4bf92f35-77b3-4da6-a3ce-929d0e0e4736 addr 0xc000123abc trace 4bf92f3577b34da6"), c.ShouldEqual, "req <uuid> addr <hex> trace <hex>")
+			c.So(normalizeErrorMessage("deadbeef exceeded"), c.ShouldEqual, "deadbeef exceeded")
+		})
+
+		mockey.PatchConvey("TestErrorAlert-NewAndCooldown", func() {
+			a, n := newAgg(&ErrorAlertConfig{Cooldown: "1m"})
+			a.observe(slog.LevelError, "user %d failed", "user 1 failed", frame, ts)
+			a.observe(slog.LevelError, "user %d failed", "user 2 failed", frame, ts.Add(time.Second))
+			a.observe(slog.LevelWarn, "warn", "warn", frame, ts)
+			// 同一调用方函数不同行号视为同一指纹
+			a.observe(slog.LevelError, "user %d failed", "user 3 failed", &runtime.Frame{Function: frame.Function, File: frame.File, Line: 50}, ts.Add(2*time.Second))
+			a.observe(slog.LevelError, "other", "other", frame, ts.Add(3*time.Second))
+			c.So(a.Close(), c.ShouldBeNil)
+			a.observe(slog.LevelError, "closed", "closed", frame, ts)
+
+			c.So(len(n), c.ShouldEqual, 2)
+			e := <-n
+			c.So(e.Reason, c.ShouldEqual, ErrorEventNew)
+			c.So(e.ServerName, c.ShouldEqual, "svc")
+			c.So(e.Fingerprint.Message, c.ShouldEqual, "user %d failed")
+			c.So(e.Fingerprint.Sample, c.ShouldEqual, "user 1 failed")
+			c.So(e.Fingerprint.Count, c.ShouldEqual, 1)
+			c.So((<-n).Fingerprint.Message, c.ShouldEqual, "other")
+
+			fps := a.list()
+			c.So(len(fps), c.ShouldEqual, 2)
+			c.So(fps[0].Message, c.ShouldEqual, "other")
+			c.So(fps[1].Count, c.ShouldEqual, 3)
+			c.So(fps[1].Sample, c.ShouldEqual, "user 3 failed")
+			c.So(fps[1].Caller, c.ShouldEqual, "github.com/x/svc.(*Order).Pay order.go:50")
+			c.So(fps[1].FirstSeen, c.ShouldEqual, ts)
+			c.So(fps[1].LastSeen, c.ShouldEqual, ts.Add(2*time.Second))
+			c.So(fps[1].Level, c.ShouldEqual, "error")
+		})
+
+		mockey.PatchConvey("TestErrorAlert-NormalizeOnce", func() {
+			calls := 0
+			mockey.Mock(normalizeErrorMessage).To(func(msg string) string {
+				calls++
+				return msg
+			}).Build()
+			a, _ := newAgg(&ErrorAlertConfig{MaxFingerprints: 1})
+			for i := range 3 {
+				a.observe(slog.LevelError, "user %d failed", fmt.Sprintf("user %d failed", i), frame, ts)
+			}
+			c.So(calls, c.ShouldEqual, 1)
+			// 被淘汰后重新出现时再次归一化
+			a.observe(slog.LevelError, "other", "other", frame, ts.Add(time.Second))
+			a.observe(slog.LevelError, "user %d failed", "user 3 failed", frame, ts.Add(2*time.Second))
+			c.So(calls, c.ShouldEqual, 3)
+			c.So(a.Close(), c.ShouldBeNil)
+			fps := a.list()
+			c.So(len(fps), c.ShouldEqual, 1)
+			c.So(fps[0].Message, c.ShouldEqual, "user %d failed")
+			c.So(fps[0].Count, c.ShouldEqual, 1)
+		})
+
+		mockey.PatchConvey("TestErrorAlert-Spike", func() {
+			a, n := newAgg(&ErrorAlertConfig{Cooldown: "1m", SpikeWindow: "10s", SpikeThreshold: 3})
+			for i := range 3 {
+				a.observe(slog.LevelError, "boom", "boom", frame, ts.Add(time.Duration(i)*time.Second))
+			}
+			// 冷却期内的突增不通知
+			for i := range 3 {
+				a.observe(slog.LevelError, "boom", "boom", frame, ts.Add(time.Duration(20+i)*time.Second))
+			}
+			// 冷却期后的突增
+			for i := range 3 {
+				a.observe(slog.LevelError, "boom", "boom", frame, ts.Add(time.Duration(120+i)*time.Second))
+			}
+			c.So(a.Close(), c.ShouldBeNil)
+
+			c.So(len(n), c.ShouldEqual, 2)
+			c.So((<-n).Reason, c.ShouldEqual, ErrorEventNew)
+			e := <-n
+			c.So(e.Reason, c.ShouldEqual, ErrorEventSpike)
+			c.So(e.Fingerprint.Count, c.ShouldEqual, 9)
+		})
+
+		mockey.PatchConvey("TestErrorAlert-Evict", func() {
+			a, _ := newAgg(&ErrorAlertConfig{MaxFingerprints: 2})
+			a.observe(slog.LevelError, "a", "a", frame, ts)
+			a.observe(slog.LevelError, "b", "b", frame, ts.Add(time.Second))
+			a.observe(slog.LevelError, "a", "a", frame, ts.Add(2*time.Second))
+			a.observe(slog.LevelError, "c", "c", frame, ts.Add(3*time.Second))
+			c.So(a.Close(), c.ShouldBeNil)
+			fps := a.list()
+			c.So(len(fps), c.ShouldEqual, 2)
+			c.So(fps[0].Message, c.ShouldEqual, "c")
+			c.So(fps[1].Message, c.ShouldEqual, "a")
+		})
+
+		mockey.PatchConvey("TestErrorAlert-Notifiers", func() {
+			bodies := make(chan string, 10)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies <- r.Header.Get("X-Token") + " " + string(body)
+				if r.URL.Path == "/fail" {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+			defer srv.Close()
+
+			e := ErrorEvent{Reason: ErrorEventSpike, ServerName: "svc", Fingerprint: ErrorFingerprint{ID: "abc", Caller: "pkg.F f.go:1", Sample: "boom", Count: 3, FirstSeen: ts, LastSeen: ts}}
+			for _, tc := range []struct {
+				typ  string
+				want string
+			}{
+				{"webhook", `"reason":"spike","servername":"svc","fingerprint":{"id":"abc"`},
+				{"slack", `{"text":"[svc] 错误突增\n位置: pkg.F f.go:1\n内容: boom\n次数: 3`},
+				{"dingtalk", `{"msgtype":"text","text":{"content":"[svc] 错误突增`},
+				{"feishu", `{"content":{"text":"[svc] 错误突增`},
+			} {
+				nt, err := NewErrorNotifier(ErrorNotifierConfig{Type: tc.typ, URL: srv.URL, Headers: map[string]string{"X-Token": "t"}})
+				c.So(err, c.ShouldBeNil)
+				c.So(nt.Notify(context.Background(), e), c.ShouldBeNil)
+				body := <-bodies
+				c.So(body, c.ShouldStartWith, "t ")
+				c.So(body, c.ShouldContainSubstring, tc.want)
+			}
+
+			nt, err := NewErrorNotifier(ErrorNotifierConfig{URL: srv.URL + "/fail"})
+			c.So(err, c.ShouldBeNil)
+			c.So(nt.Notify(context.Background(), e), c.ShouldNotBeNil)
+
+			_, err = NewErrorNotifier(ErrorNotifierConfig{Type: "email", URL: srv.URL})
+			c.So(err, c.ShouldNotBeNil)
+			_, err = NewErrorNotifier(ErrorNotifierConfig{Type: "slack"})
+			c.So(err, c.ShouldNotBeNil)
+		})
+
+		mockey.PatchConvey("TestErrorAlert-Handler", func() {
+			activeCore.Store(nil)
+			c.So(ErrorFingerprints(), c.ShouldBeNil)
+			c.So(AddErrorNotifier(make(chanNotifier)), c.ShouldEqual, errXLogNotInitialized)
+
+			useTestHandler(t, io.Discard, logrus.InfoLevel)
+			c.So(AddErrorNotifier(make(chanNotifier)), c.ShouldEqual, errErrorAlertDisabled)
+
+			a, _ := newAgg(&ErrorAlertConfig{})
+			defer a.Close()
+			activeCore.Load().errors = a
+			n := make(chanNotifier, 10)
+			c.So(AddErrorNotifier(n), c.ShouldBeNil)
+
+			ctx := context.Background()
+			Error(ctx, "order %d not found", 1)
+			Error(ctx, "order %d not found", 2)
+			Info(ctx, "ignored")
+			c.So((<-n).Fingerprint.Sample, c.ShouldEqual, "order 1 not found")
+
+			rec := httptest.NewRecorder()
+			ErrorFingerprintsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/errors", nil))
+			c.So(rec.Header().Get("Content-Type"), c.ShouldStartWith, "application/json")
+			var fps []ErrorFingerprint
+			c.So(json.Unmarshal(rec.Body.Bytes(), &fps), c.ShouldBeNil)
+			c.So(len(fps), c.ShouldEqual, 1)
+			c.So(fps[0].Message, c.ShouldEqual, "order <n> not found")
+			c.So(fps[0].Count, c.ShouldEqual, 2)
+			c.So(fps[0].Caller, c.ShouldContainSubstring, "xlog_test.go:")
+		})
+
+		mockey.PatchConvey("TestErrorAlert-InvalidConfig", func() {
+			for _, ac := range []*ErrorAlertConfig{
+				{Level: "verbose"},
+				{SpikeThreshold: 3, SpikeWindow: "0"},
+				{Notifiers: []ErrorNotifierConfig{{Type: "email", URL: "http://127.0.0.1"}}},
+			} {
+				_, err := newErrorAggregator(errorAlertConfigMergeDefault(ac), "svc")
+				c.So(err, c.ShouldNotBeNil)
+			}
+			err := initXLogByConfig(configMergeDefault(&Config{Path: t.TempDir(), ErrorAlert: &ErrorAlertConfig{Level: "verbose"}}))
+			c.So(err, c.ShouldNotBeNil)
+			c.So(err.Error(), c.ShouldContainSubstring, "parse XLog.ErrorAlert failed")
+		})
+	})
+}
+
+func TestBaggage(t *testing.T) {
+	mockey.PatchConvey("TestBaggage", t, func() {
+		activeCore.Store(nil)
+		ctx := CtxWithKV(context.Background(), map[string]any{"user_id": "u1"})
+		c.So(CtxWithBaggage(ctx), c.ShouldEqual, ctx)
+		c.So(BaggageKV(http.Header{"Baggage": []string{"user_id=u1"}}), c.ShouldBeNil)
+
+		useTestHandler(t, io.Discard, logrus.InfoLevel)
+		p, err := newBaggagePolicy(configMergeDefault(&Config{Baggage: &BaggageConfig{Keys: []string{"user_id", "tenant", "n", "big", "user_id"}, MaxValueSize: 8, MaxSize: 20}}).Baggage)
+		c.So(err, c.ShouldBeNil)
+		c.So(p.keys, c.ShouldResemble, []string{"user_id", "tenant", "n", "big"})
+		activeCore.Load().baggage = p
+
+		mockey.PatchConvey("TestBaggage-Inject", func() {
+			mem, _ := baggage.NewMemberRaw("upstream", "x")
+			b, _ := baggage.New(mem)
+			ctx := baggage.ContextWithBaggage(context.Background(), b)
+			c.So(CtxWithBaggage(ctx), c.ShouldEqual, ctx)
+
+			ctx = CtxWithKV(ctx, map[string]any{"user_id": "u 1", "tenant": "t1", "n": 42, "big": "123456789", "secret": "s"})
+			h := http.Header{}
+			propagation.Baggage{}.Inject(CtxWithBaggage(ctx), propagation.HeaderCarrier(h))
+			got, err := baggage.Parse(h.Get("baggage"))
+			c.So(err, c.ShouldBeNil)
+			c.So(got.Member("upstream").Value(), c.ShouldEqual, "x")
+			c.So(got.Member("user_id").Value(), c.ShouldEqual, "u 1")
+			c.So(got.Member("tenant").Value(), c.ShouldEqual, "t1")
+			// 超出总大小限制
+			c.So(got.Member("n").Key(), c.ShouldEqual, "")
+			// 超出单个值大小限制
+			c.So(got.Member("big").Key(), c.ShouldEqual, "")
+			// 不在白名单中
+			c.So(got.Member("secret").Key(), c.ShouldEqual, "")
+
+			ctx = CtxWithKV(context.Background(), map[string]any{"user_id": struct{}{}, "tenant": nil})
+			c.So(CtxWithBaggage(ctx), c.ShouldEqual, ctx)
+		})
+
+		mockey.PatchConvey("TestBaggage-Extract", func() {
+			h := http.Header{"Baggage": []string{"user_id=u%201,secret=s", "big=123456789,tenant=t1"}}
+			c.So(BaggageKV(h), c.ShouldResemble, map[string]any{"user_id": "u 1", "tenant": "t1"})
+			c.So(BaggageKV(http.Header{"Baggage": []string{"secret=s"}}), c.ShouldBeNil)
+			c.So(BaggageKV(http.Header{"Baggage": []string{"user_id=" + strings.Repeat("x", 9000)}}), c.ShouldBeNil)
+			c.So(BaggageKV(http.Header{}), c.ShouldBeNil)
+		})
+
+		mockey.PatchConvey("TestBaggage-InvalidConfig", func() {
+			bp, err := newBaggagePolicy(&BaggageConfig{})
+			c.So(bp, c.ShouldBeNil)
+			c.So(err, c.ShouldBeNil)
+			_, err = newBaggagePolicy(&BaggageConfig{Keys: []string{"user id"}})
+			c.So(err, c.ShouldNotBeNil)
+			err = initXLogByConfig(configMergeDefault(&Config{Path: t.TempDir(), Baggage: &BaggageConfig{Keys: []string{"a,b"}}}))
+			c.So(err, c.ShouldNotBeNil)
+			c.So(err.Error(), c.ShouldContainSubstring, "parse XLog.Baggage failed")
+		})
+	})
+}
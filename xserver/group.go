@@ -0,0 +1,71 @@
+package xserver
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/xiaoshicae/xone/v2/xerror"
+)
+
+// Group 将多个 Server 组合为一个，用于同时启动 HTTP 与 gRPC 等服务，如 xserver.Run(xserver.Group(gx, gs))
+// Run 并发启动全部 Server，任一 Server 退出（含出错）时停止其余 Server，并等待全部退出
+func Group(servers ...Server) Server {
+	return &groupServer{servers: servers}
+}
+
+type groupServer struct {
+	servers  []Server
+	stopOnce sync.Once
+	stopErr  error
+}
+
+func (g *groupServer) Run() error {
+	if len(g.servers) == 0 {
+		return nil
+	}
+
+	errChan := make(chan error, len(g.servers))
+	for _, s := range g.servers {
+		go func() {
+			errChan <- safeRunGroupMember(s)
+		}()
+	}
+
+	errs := []error{<-errChan, g.Stop()}
+	for i := 1; i < len(g.servers); i++ {
+		errs = append(errs, <-errChan)
+	}
+	return errors.Join(errs...)
+}
+
+// Stop 并发停止全部 Server，多次调用只执行一次
+func (g *groupServer) Stop() error {
+	g.stopOnce.Do(func() {
+		errs := make([]error, len(g.servers))
+		var wg sync.WaitGroup
+		for i, s := range g.servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = safeInvokeServerStop(s)
+			}()
+		}
+		wg.Wait()
+		g.stopErr = errors.Join(errs...)
+	})
+	return g.stopErr
+}
+
+func safeRunGroupMember(s Server) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = xerror.Newf("xserver", "run", "panic occurred, %v", r)
+		}
+	}()
+
+	if err = s.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
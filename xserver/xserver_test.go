@@ -246,3 +246,61 @@ func TestBlockingServerStopBeforeRun(t *testing.T) {
 		}
 	})
 }
+
+// ==================== group.go ====================
+
+func TestGroupServer(t *testing.T) {
+	PatchConvey("TestGroupServer", t, func() {
+		Convey("Empty", func() {
+			g := Group()
+			So(g.Run(), ShouldBeNil)
+			So(g.Stop(), ShouldBeNil)
+		})
+
+		Convey("Stop all members", func() {
+			a := &blockingServer{}
+			b := &blockingServer{}
+			g := Group(a, b)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- g.Run()
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			So(g.Stop(), ShouldBeNil)
+			select {
+			case err := <-done:
+				So(err, ShouldBeNil)
+			case <-time.After(time.Second):
+				t.Fatal("Group Run did not complete after Stop")
+			}
+		})
+
+		Convey("Member run error stops others", func() {
+			b := &blockingServer{}
+			g := Group(errRunServer{}, b)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- g.Run()
+			}()
+
+			select {
+			case err := <-done:
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "err run")
+			case <-time.After(time.Second):
+				t.Fatal("Group Run did not complete after member error")
+			}
+		})
+
+		Convey("Member panic and stop error", func() {
+			g := Group(panicRunServer{}, errStopServer{})
+			err := g.Run()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "panic run")
+			So(err.Error(), ShouldContainSubstring, "stop err")
+		})
+	})
+}